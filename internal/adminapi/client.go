@@ -0,0 +1,215 @@
+package adminapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+// StatusError is returned by the client when the server answers with an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+	Field      string
+}
+
+func (e *StatusError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("server returned %d: %s (field %s)", e.StatusCode, e.Message, e.Field)
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the admin API of a running guardian.
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// NewClient returns a client for the guardian at baseURL that authenticates with apiKey,
+// which may be an API key or an OIDC token.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ListTenants returns the tenants the caller may see.
+func (c *Client) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	err := c.do(ctx, http.MethodGet, "/tenants", nil, &tenants)
+	return tenants, err
+}
+
+// CreateTenant creates a tenant.
+func (c *Client) CreateTenant(ctx context.Context, req CreateTenantRequest) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := c.do(ctx, http.MethodPost, "/tenants", req, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// UpdateTenant renames a tenant or changes its status.
+func (c *Client) UpdateTenant(ctx context.Context, slug string, req UpdateTenantRequest) (*model.Tenant, error) {
+	var tenant model.Tenant
+	if err := c.do(ctx, http.MethodPatch, "/tenants/"+url.PathEscape(slug), req, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// ExportTenant returns the export bundle of a tenant.
+func (c *Client) ExportTenant(ctx context.Context, slug string) (*model.TenantExport, error) {
+	var export model.TenantExport
+	if err := c.do(ctx, http.MethodGet, "/tenants/"+url.PathEscape(slug)+"/export", nil, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// DeleteTenant deletes a tenant and purges its data.
+func (c *Client) DeleteTenant(ctx context.Context, slug string) error {
+	return c.do(ctx, http.MethodDelete, "/tenants/"+url.PathEscape(slug), nil, nil)
+}
+
+// GetTenantSettings returns a tenant's settings and effective configuration.
+func (c *Client) GetTenantSettings(ctx context.Context, slug string) (*TenantSettingsResponse, error) {
+	var settings TenantSettingsResponse
+	if err := c.do(ctx, http.MethodGet, "/tenants/"+url.PathEscape(slug)+"/settings", nil, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// UpdateTenantSettings changes a tenant's settings.
+func (c *Client) UpdateTenantSettings(ctx context.Context, slug string, req TenantSettingsRequest) (*TenantSettingsResponse, error) {
+	var settings TenantSettingsResponse
+	if err := c.do(ctx, http.MethodPatch, "/tenants/"+url.PathEscape(slug)+"/settings", req, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// ListAPIKeys returns API keys, optionally filtered by tenant slug.
+func (c *Client) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := c.do(ctx, http.MethodGet, "/api-keys"+tenantQuery(tenant), nil, &keys)
+	return keys, err
+}
+
+// CreateAPIKey issues an API key.
+func (c *Client) CreateAPIKey(ctx context.Context, req CreateAPIKeyRequest) (*IssuedAPIKey, error) {
+	var issued IssuedAPIKey
+	if err := c.do(ctx, http.MethodPost, "/api-keys", req, &issued); err != nil {
+		return nil, err
+	}
+	return &issued, nil
+}
+
+// SetAPIKeyRole changes the role of an API key.
+func (c *Client) SetAPIKeyRole(ctx context.Context, id string, req SetAPIKeyRoleRequest) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := c.do(ctx, http.MethodPut, "/api-keys/"+url.PathEscape(id)+"/role", req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RotateAPIKey issues a successor for an API key.
+func (c *Client) RotateAPIKey(ctx context.Context, id string, req RotateAPIKeyRequest) (*IssuedAPIKey, error) {
+	var issued IssuedAPIKey
+	if err := c.do(ctx, http.MethodPost, "/api-keys/"+url.PathEscape(id)+"/rotate", req, &issued); err != nil {
+		return nil, err
+	}
+	return &issued, nil
+}
+
+// RevokeAPIKey revokes an API key.
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api-keys/"+url.PathEscape(id), nil, nil)
+}
+
+// ListBudgets returns budgets, optionally filtered by tenant slug.
+func (c *Client) ListBudgets(ctx context.Context, tenant string) ([]model.Budget, error) {
+	var budgets []model.Budget
+	err := c.do(ctx, http.MethodGet, "/budgets"+tenantQuery(tenant), nil, &budgets)
+	return budgets, err
+}
+
+// SetBudget creates or replaces a budget.
+func (c *Client) SetBudget(ctx context.Context, name string, req BudgetRequest) (*model.Budget, error) {
+	var budget model.Budget
+	if err := c.do(ctx, http.MethodPut, "/budgets/"+url.PathEscape(name), req, &budget); err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func tenantQuery(tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+	return "?tenant=" + url.QueryEscape(tenant)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1/admin"+path, reader)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("call admin api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		statusErr := &StatusError{StatusCode: resp.StatusCode}
+		var apiErr Error
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			statusErr.Message, statusErr.Field = apiErr.Error, apiErr.Field
+		} else {
+			statusErr.Message = strings.TrimSpace(string(data))
+		}
+		return statusErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
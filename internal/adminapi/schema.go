@@ -0,0 +1,31 @@
+package adminapi
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// Schema returns the JSON schema of the request body called name, such as "api-key-create".
+func Schema(name string) ([]byte, bool) {
+	data, err := schemaFiles.ReadFile("schemas/" + name + ".json")
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// SchemaNames lists the request body schemas.
+func SchemaNames() []string {
+	entries, _ := fs.ReadDir(schemaFiles, "schemas")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,386 @@
+// Package adminapi defines the admin REST API for tenants, tenant settings, API keys, and
+// budgets served under /api/v1/admin/: its request and response bodies, their validation and
+// JSON schemas, and a client for calling it.
+package adminapi
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidationError reports a request field that failed validation.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + " " + e.Message
+}
+
+func invalid(field, format string, args ...any) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// Error is the body of every admin API error response. Field names the invalid request field
+// for validation errors.
+type Error struct {
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+}
+
+// CreateTenantRequest creates a tenant. Name defaults to one derived from Slug.
+type CreateTenantRequest struct {
+	Slug string `json:"slug"`
+	Name string `json:"name,omitempty"`
+}
+
+// Validate checks the request.
+func (r *CreateTenantRequest) Validate() error {
+	if err := validateSlug("slug", r.Slug, true); err != nil {
+		return err
+	}
+	return validateName("name", r.Name)
+}
+
+// UpdateTenantRequest renames a tenant or changes its status. Empty fields are left unchanged.
+type UpdateTenantRequest struct {
+	Slug   string `json:"slug,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Validate checks the request.
+func (r *UpdateTenantRequest) Validate() error {
+	if r.Slug == "" && r.Name == "" && r.Status == "" {
+		return invalid("", "set at least one of slug, name, or status")
+	}
+	if err := validateSlug("slug", r.Slug, false); err != nil {
+		return err
+	}
+	if err := validateName("name", r.Name); err != nil {
+		return err
+	}
+	switch r.Status {
+	case "", model.TenantStatusActive, model.TenantStatusDisabled:
+		return nil
+	default:
+		return invalid("status", "must be %s or %s", model.TenantStatusActive, model.TenantStatusDisabled)
+	}
+}
+
+// CreateAPIKeyRequest issues an API key. Name defaults to "default", Role to developer, and
+// SpendPeriod to monthly. ExpiresIn is a duration such as "720h"; empty never expires.
+type CreateAPIKeyRequest struct {
+	Tenant          string   `json:"tenant,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Role            string   `json:"role,omitempty"`
+	AllTenants      bool     `json:"all_tenants,omitempty"`
+	ExpiresIn       string   `json:"expires_in,omitempty"`
+	SpendLimitUSD   float64  `json:"spend_limit_usd,omitempty"`
+	SpendPeriod     string   `json:"spend_period,omitempty"`
+	AllowedProjects []string `json:"allowed_projects,omitempty"`
+}
+
+// Validate fills in the request's defaults and checks it.
+func (r *CreateAPIKeyRequest) Validate() error {
+	if r.Name == "" {
+		r.Name = "default"
+	}
+	if r.Role == "" {
+		r.Role = model.RoleDeveloper
+	}
+	if r.SpendPeriod == "" {
+		r.SpendPeriod = string(model.PeriodMonthly)
+	}
+	if err := validateName("name", r.Name); err != nil {
+		return err
+	}
+	if err := validateRole("role", r.Role); err != nil {
+		return err
+	}
+	if _, err := parseDuration("expires_in", r.ExpiresIn); err != nil {
+		return err
+	}
+	if r.SpendLimitUSD < 0 {
+		return invalid("spend_limit_usd", "must not be negative")
+	}
+	if err := validatePeriod("spend_period", r.SpendPeriod); err != nil {
+		return err
+	}
+	for _, project := range r.AllowedProjects {
+		if strings.TrimSpace(project) == "" {
+			return invalid("allowed_projects", "must not contain empty project names")
+		}
+	}
+	return nil
+}
+
+// ExpiresAt returns when a key created now expires, or nil when it never does.
+func (r *CreateAPIKeyRequest) ExpiresAt(now time.Time) *time.Time {
+	return expiresAt(now, r.ExpiresIn)
+}
+
+// SetAPIKeyRoleRequest changes the role of an API key.
+type SetAPIKeyRoleRequest struct {
+	Role       string `json:"role"`
+	AllTenants bool   `json:"all_tenants,omitempty"`
+}
+
+// Validate checks the request.
+func (r *SetAPIKeyRoleRequest) Validate() error {
+	if r.Role == "" {
+		return invalid("role", "is required")
+	}
+	return validateRole("role", r.Role)
+}
+
+// RotateAPIKeyRequest rotates an API key. Grace is how long the old key keeps working and
+// defaults to "24h"; ExpiresIn sets the new key's expiry.
+type RotateAPIKeyRequest struct {
+	Grace     string `json:"grace,omitempty"`
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// Validate fills in the request's defaults and checks it.
+func (r *RotateAPIKeyRequest) Validate() error {
+	if r.Grace == "" {
+		r.Grace = "24h"
+	}
+	if _, err := parseDuration("grace", r.Grace); err != nil {
+		return err
+	}
+	_, err := parseDuration("expires_in", r.ExpiresIn)
+	return err
+}
+
+// GracePeriod returns the validated grace period.
+func (r *RotateAPIKeyRequest) GracePeriod() time.Duration {
+	grace, _ := time.ParseDuration(r.Grace)
+	return grace
+}
+
+// ExpiresAt returns when a successor created now expires, or nil when it never does.
+func (r *RotateAPIKeyRequest) ExpiresAt(now time.Time) *time.Time {
+	return expiresAt(now, r.ExpiresIn)
+}
+
+// IssuedAPIKey is returned when a key is created or rotated. Key is the raw key, which is
+// shown only once. Replaces is the old key of a rotation.
+type IssuedAPIKey struct {
+	APIKey   model.APIKey  `json:"api_key"`
+	Key      string        `json:"key"`
+	Replaces *model.APIKey `json:"replaces,omitempty"`
+}
+
+// TenantSettingsRequest changes the settings of a tenant. Fields that are set override the
+// global configuration, and Unset names settings, by their JSON names, that inherit it again.
+// Settings the request does not mention are left unchanged. An empty alert URL turns that
+// destination off for the tenant.
+type TenantSettingsRequest struct {
+	DenyOnExceed      *bool    `json:"deny_on_exceed,omitempty"`
+	AddCostHeaders    *bool    `json:"add_cost_headers,omitempty"`
+	DefaultProject    *string  `json:"default_project,omitempty"`
+	AlertThresholdPct *float64 `json:"alert_threshold_pct,omitempty"`
+	AlertCriticalPct  *float64 `json:"alert_critical_pct,omitempty"`
+	SlackWebhookURL   *string  `json:"slack_webhook_url,omitempty"`
+	SlackChannel      *string  `json:"slack_channel,omitempty"`
+	WebhookURL        *string  `json:"webhook_url,omitempty"`
+	WebhookSecret     *string  `json:"webhook_secret,omitempty"`
+	Unset             []string `json:"unset,omitempty"`
+}
+
+// Validate checks the request.
+func (r *TenantSettingsRequest) Validate() error {
+	set := r.Apply(&model.TenantSettings{})
+	if len(set) == 0 && len(r.Unset) == 0 {
+		return invalid("", "set or unset at least one setting")
+	}
+	for _, name := range r.Unset {
+		if !slices.Contains(model.TenantSettingNames, name) {
+			return invalid("unset", "must only contain %s", strings.Join(model.TenantSettingNames, ", "))
+		}
+		if slices.Contains(set, name) {
+			return invalid(name, "cannot be both set and unset")
+		}
+	}
+	if err := validatePct("alert_threshold_pct", r.AlertThresholdPct); err != nil {
+		return err
+	}
+	if err := validatePct("alert_critical_pct", r.AlertCriticalPct); err != nil {
+		return err
+	}
+	if err := validateURL("slack_webhook_url", r.SlackWebhookURL); err != nil {
+		return err
+	}
+	return validateURL("webhook_url", r.WebhookURL)
+}
+
+// Apply unsets and then sets the request's settings on settings. It returns the names of the
+// settings it set.
+func (r *TenantSettingsRequest) Apply(settings *model.TenantSettings) []string {
+	for _, name := range r.Unset {
+		settings.Unset(name)
+	}
+	var set []string
+	override := func(name string, ok bool) {
+		if ok {
+			set = append(set, name)
+		}
+	}
+	override("deny_on_exceed", setOverride(&settings.DenyOnExceed, r.DenyOnExceed))
+	override("add_cost_headers", setOverride(&settings.AddCostHeaders, r.AddCostHeaders))
+	override("default_project", setOverride(&settings.DefaultProject, r.DefaultProject))
+	override("alert_threshold_pct", setOverride(&settings.AlertThresholdPct, r.AlertThresholdPct))
+	override("alert_critical_pct", setOverride(&settings.AlertCriticalPct, r.AlertCriticalPct))
+	override("slack_webhook_url", setOverride(&settings.SlackWebhookURL, r.SlackWebhookURL))
+	override("slack_channel", setOverride(&settings.SlackChannel, r.SlackChannel))
+	override("webhook_url", setOverride(&settings.WebhookURL, r.WebhookURL))
+	override("webhook_secret", setOverride(&settings.WebhookSecret, r.WebhookSecret))
+	return set
+}
+
+// TenantSettingsResponse returns a tenant's settings and its effective configuration, both
+// with secrets redacted.
+type TenantSettingsResponse struct {
+	Settings  model.TenantSettings `json:"settings"`
+	Effective model.TenantConfig   `json:"effective"`
+}
+
+// BudgetRequest creates or replaces a budget. Period defaults to monthly. AlertThresholdPct
+// defaults to the tenant's alert_threshold_pct setting, which is applied by the server.
+type BudgetRequest struct {
+	Tenant            string            `json:"tenant,omitempty"`
+	Project           string            `json:"project,omitempty"`
+	LimitUSD          float64           `json:"limit_usd"`
+	Period            string            `json:"period,omitempty"`
+	AlertThresholdPct float64           `json:"alert_threshold_pct,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+}
+
+// Validate fills in the request's defaults and checks it.
+func (r *BudgetRequest) Validate() error {
+	if r.Period == "" {
+		r.Period = string(model.PeriodMonthly)
+	}
+	if r.LimitUSD <= 0 {
+		return invalid("limit_usd", "must be greater than zero")
+	}
+	if err := validatePeriod("period", r.Period); err != nil {
+		return err
+	}
+	if r.AlertThresholdPct < 0 || r.AlertThresholdPct > 100 {
+		return invalid("alert_threshold_pct", "must be between 0 and 100")
+	}
+	for key := range r.Tags {
+		if strings.TrimSpace(key) == "" {
+			return invalid("tags", "must not contain empty keys")
+		}
+	}
+	return nil
+}
+
+// ValidateBudgetName checks a budget name taken from the request path.
+func ValidateBudgetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return invalid("name", "is required")
+	}
+	return validateName("name", name)
+}
+
+func validateSlug(field, slug string, required bool) error {
+	if slug == "" {
+		if required {
+			return invalid(field, "is required")
+		}
+		return nil
+	}
+	if !slugPattern.MatchString(slug) {
+		return invalid(field, "must be 1-63 lowercase letters, digits, hyphens, or underscores")
+	}
+	return nil
+}
+
+func validateName(field, name string) error {
+	if len(name) > 128 {
+		return invalid(field, "must be at most 128 characters")
+	}
+	return nil
+}
+
+func validatePct(field string, pct *float64) error {
+	if pct != nil && (*pct <= 0 || *pct > 100) {
+		return invalid(field, "must be greater than 0 and at most 100")
+	}
+	return nil
+}
+
+func validateURL(field string, value *string) error {
+	if value == nil || *value == "" {
+		return nil
+	}
+	parsed, err := url.Parse(*value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return invalid(field, "must be an http or https URL")
+	}
+	return nil
+}
+
+func setOverride[T any](dst **T, value *T) bool {
+	if value == nil {
+		return false
+	}
+	v := *value
+	*dst = &v
+	return true
+}
+
+func validateRole(field, role string) error {
+	if !model.ValidRole(role) {
+		return invalid(field, "must be one of %s", strings.Join(model.Roles, ", "))
+	}
+	return nil
+}
+
+func validatePeriod(field, period string) error {
+	switch model.BudgetPeriod(period) {
+	case model.PeriodDaily, model.PeriodWeekly, model.PeriodMonthly:
+		return nil
+	default:
+		return invalid(field, "must be daily, weekly, or monthly")
+	}
+}
+
+func parseDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, invalid(field, "must be a duration such as 24h")
+	}
+	if duration < 0 {
+		return 0, invalid(field, "must not be negative")
+	}
+	return duration, nil
+}
+
+func expiresAt(now time.Time, expiresIn string) *time.Time {
+	duration, err := time.ParseDuration(expiresIn)
+	if err != nil || duration <= 0 {
+		return nil
+	}
+	expires := now.UTC().Add(duration)
+	return &expires
+}
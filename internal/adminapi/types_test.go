@@ -0,0 +1,117 @@
+package adminapi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   interface{ Validate() error }
+		field string
+	}{
+		{"tenant slug required", &adminapi.CreateTenantRequest{}, "slug"},
+		{"tenant slug pattern", &adminapi.CreateTenantRequest{Slug: "Acme Corp"}, "slug"},
+		{"tenant update empty", &adminapi.UpdateTenantRequest{}, ""},
+		{"tenant status", &adminapi.UpdateTenantRequest{Status: "paused"}, "status"},
+		{"key role", &adminapi.CreateAPIKeyRequest{Role: "root"}, "role"},
+		{"key expiry", &adminapi.CreateAPIKeyRequest{ExpiresIn: "30 days"}, "expires_in"},
+		{"key spend period", &adminapi.CreateAPIKeyRequest{SpendPeriod: "yearly"}, "spend_period"},
+		{"set role required", &adminapi.SetAPIKeyRoleRequest{}, "role"},
+		{"rotate grace", &adminapi.RotateAPIKeyRequest{Grace: "-1h"}, "grace"},
+		{"budget limit", &adminapi.BudgetRequest{}, "limit_usd"},
+		{"budget threshold", &adminapi.BudgetRequest{LimitUSD: 10, AlertThresholdPct: 120}, "alert_threshold_pct"},
+		{"settings empty", &adminapi.TenantSettingsRequest{}, ""},
+		{"settings unknown unset", &adminapi.TenantSettingsRequest{Unset: []string{"max_body_size"}}, "unset"},
+		{"settings set and unset", &adminapi.TenantSettingsRequest{DenyOnExceed: model.Ptr(true), Unset: []string{"deny_on_exceed"}}, "deny_on_exceed"},
+		{"settings threshold", &adminapi.TenantSettingsRequest{AlertThresholdPct: model.Ptr(0.0)}, "alert_threshold_pct"},
+		{"settings critical", &adminapi.TenantSettingsRequest{AlertCriticalPct: model.Ptr(101.0)}, "alert_critical_pct"},
+		{"settings webhook url", &adminapi.TenantSettingsRequest{WebhookURL: model.Ptr("hooks.example.com")}, "webhook_url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validation *adminapi.ValidationError
+			require.True(t, errors.As(tt.req.Validate(), &validation))
+			assert.Equal(t, tt.field, validation.Field)
+		})
+	}
+
+	key := &adminapi.CreateAPIKeyRequest{}
+	require.NoError(t, key.Validate())
+	assert.Equal(t, "default", key.Name)
+	assert.Equal(t, "developer", key.Role)
+	assert.Equal(t, "monthly", key.SpendPeriod)
+
+	budget := &adminapi.BudgetRequest{LimitUSD: 10}
+	require.NoError(t, budget.Validate())
+	assert.Zero(t, budget.AlertThresholdPct, "the server fills in the tenant's threshold")
+
+	settings := &adminapi.TenantSettingsRequest{WebhookURL: model.Ptr(""), Unset: []string{"default_project"}}
+	require.NoError(t, settings.Validate(), "an empty URL turns the destination off")
+	current := &model.TenantSettings{DefaultProject: model.Ptr("app"), DenyOnExceed: model.Ptr(true)}
+	assert.Equal(t, []string{"webhook_url"}, settings.Apply(current))
+	assert.Nil(t, current.DefaultProject)
+	assert.Equal(t, "", *current.WebhookURL)
+	assert.True(t, *current.DenyOnExceed, "settings the request does not mention are kept")
+
+	rotate := &adminapi.RotateAPIKeyRequest{}
+	require.NoError(t, rotate.Validate())
+	assert.Equal(t, "24h0m0s", rotate.GracePeriod().String())
+}
+
+func TestSchemasMatchRequests(t *testing.T) {
+	requests := map[string]any{
+		"tenant-create":   adminapi.CreateTenantRequest{},
+		"tenant-update":   adminapi.UpdateTenantRequest{},
+		"api-key-create":  adminapi.CreateAPIKeyRequest{},
+		"api-key-role":    adminapi.SetAPIKeyRoleRequest{},
+		"api-key-rotate":  adminapi.RotateAPIKeyRequest{},
+		"budget":          adminapi.BudgetRequest{},
+		"tenant-settings": adminapi.TenantSettingsRequest{},
+	}
+	require.ElementsMatch(t, adminapi.SchemaNames(), mapKeys(requests))
+
+	for name, req := range requests {
+		data, ok := adminapi.Schema(name)
+		require.True(t, ok, name)
+		var schema struct {
+			AdditionalProperties bool                       `json:"additionalProperties"`
+			Properties           map[string]json.RawMessage `json:"properties"`
+		}
+		require.NoError(t, json.Unmarshal(data, &schema), name)
+		assert.False(t, schema.AdditionalProperties, name)
+		assert.ElementsMatch(t, jsonFields(req), mapKeys(schema.Properties), name)
+	}
+
+	_, ok := adminapi.Schema("../types")
+	assert.False(t, ok)
+}
+
+func jsonFields(v any) []string {
+	typ := reflect.TypeOf(v)
+	fields := make([]string, 0, typ.NumField())
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -17,6 +17,10 @@ import (
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/server"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
@@ -29,6 +33,8 @@ type Service struct {
 	Tracker *tracker.UsageTracker
 	Store   storage.Storage
 	Server  *http.Server
+	// Transcripts is set when prompt and completion capture is enabled.
+	Transcripts *capture.Recorder
 }
 
 // NewLogger creates a structured logger from config.
@@ -112,6 +118,27 @@ func NewNotifiers(cfg *config.Config) []alerts.Notifier {
 	return notifiers
 }
 
+// TenantDefaults maps the global configuration that tenant settings override. Alert
+// destinations are only included when enabled.
+func TenantDefaults(cfg *config.Config) model.TenantConfig {
+	defaults := model.TenantConfig{
+		DenyOnExceed:      cfg.Proxy.DenyOnExceed,
+		AddCostHeaders:    cfg.Proxy.AddCostHeaders,
+		DefaultProject:    cfg.Defaults.Project,
+		AlertThresholdPct: cfg.Alerts.ThresholdPct,
+		AlertCriticalPct:  cfg.Alerts.CriticalPct,
+	}
+	if cfg.Alerts.Slack.Enabled {
+		defaults.SlackWebhookURL = cfg.Alerts.Slack.WebhookURL
+		defaults.SlackChannel = cfg.Alerts.Slack.Channel
+	}
+	if cfg.Alerts.Webhook.Enabled {
+		defaults.WebhookURL = cfg.Alerts.Webhook.URL
+		defaults.WebhookSecret = cfg.Alerts.Webhook.Secret
+	}
+	return defaults
+}
+
 // NewTracker creates a fully wired usage tracker and returns the underlying store.
 func NewTracker(cfg *config.Config) (*tracker.UsageTracker, storage.Storage, *slog.Logger, error) {
 	logger := NewLogger(cfg)
@@ -133,16 +160,200 @@ func NewTracker(cfg *config.Config) (*tracker.UsageTracker, storage.Storage, *sl
 	notifiers := NewNotifiers(cfg)
 	budgetMgr := tracker.NewBudgetManager(store, notifiers, logger)
 	usageTracker := tracker.NewUsageTracker(registry, store, budgetMgr, logger)
+	usageTracker.SetTenantDefaults(TenantDefaults(cfg))
 
 	return usageTracker, store, logger, nil
 }
 
+// WriterConfig maps the asynchronous usage writer settings from config. The WAL defaults to a
+// usage-wal directory next to the database.
+func WriterConfig(cfg *config.Config) tracker.WriterConfig {
+	writer := cfg.Storage.Writer
+	walDir := writer.WALDir
+	if walDir == "" {
+		walDir = filepath.Join(filepath.Dir(cfg.Storage.Path), "usage-wal")
+	}
+	flushInterval, _ := time.ParseDuration(writer.FlushInterval)
+	return tracker.WriterConfig{
+		QueueSize:     writer.QueueSize,
+		Workers:       writer.Workers,
+		BatchSize:     writer.BatchSize,
+		FlushInterval: flushInterval,
+		WALDir:        walDir,
+		WALSync:       writer.WALSync,
+	}
+}
+
+// ProxyOptions maps optional proxy behaviors from config.
+func ProxyOptions(cfg *config.Config) proxy.Options {
+	opts := proxy.DefaultOptions()
+	opts.CancelUpstreamOnDisconnect = cfg.Proxy.CancelUpstreamOnDisconnect
+	if timeout, err := time.ParseDuration(cfg.Proxy.DisconnectDrainTimeout); err == nil && timeout > 0 {
+		opts.DisconnectDrainTimeout = timeout
+	}
+	opts.InjectStreamUsage = cfg.Proxy.InjectStreamUsage
+	opts.WebSocketUsage = cfg.Proxy.WebSocketUsage
+	if window, err := time.ParseDuration(cfg.Proxy.IdempotencyWindow); err == nil && window >= 0 {
+		opts.IdempotencyWindow = window
+	}
+	opts.EndUserTokenHeader = cfg.Proxy.EndUser.TokenHeader
+	opts.EndUserClaim = cfg.Proxy.EndUser.Claim
+	return opts
+}
+
+// EndUserJWTVerifier builds the verifier for end-user tokens. It returns nil when no JWT
+// secret or public key is configured.
+func EndUserJWTVerifier(cfg *config.Config) (*auth.JWTVerifier, error) {
+	endUser := cfg.Proxy.EndUser
+	if endUser.JWTSecret == "" && endUser.JWTPublicKeyFile == "" {
+		return nil, nil
+	}
+	return auth.NewJWTVerifier(auth.JWTConfig{
+		HMACSecret:    endUser.JWTSecret,
+		PublicKeyFile: endUser.JWTPublicKeyFile,
+		Issuer:        endUser.JWTIssuer,
+		Audience:      endUser.JWTAudience,
+		Leeway:        time.Minute,
+	})
+}
+
+// OIDCVerifier builds the verifier and claim mapping for OIDC-issued user tokens. It returns a
+// nil verifier when OIDC is disabled.
+func OIDCVerifier(cfg *config.Config) (*auth.JWTVerifier, httpauth.ClaimMapping, error) {
+	oidc := cfg.Auth.OIDC
+	claims := httpauth.ClaimMapping{
+		TenantClaim: oidc.TenantClaim,
+		UserClaim:   oidc.UserClaim,
+		RoleClaim:   oidc.RoleClaim,
+		DefaultRole: oidc.DefaultRole,
+		Roles:       make(map[string]string, len(oidc.RoleMapping)),
+
+		AllowWildcardTenant: oidc.AllowWildcardTenant,
+		AllowDefaultTenant:  oidc.AllowDefaultTenant,
+	}
+	if !oidc.Enabled {
+		return nil, claims, nil
+	}
+
+	if oidc.DefaultRole != "" && !model.ValidRole(oidc.DefaultRole) {
+		return nil, claims, fmt.Errorf("oidc default_role %q is not a role", oidc.DefaultRole)
+	}
+	for _, mapping := range oidc.RoleMapping {
+		if !model.ValidRole(mapping.Role) {
+			return nil, claims, fmt.Errorf("oidc role_mapping %q: %q is not a role", mapping.Value, mapping.Role)
+		}
+		claims.Roles[mapping.Value] = mapping.Role
+	}
+	refresh, err := time.ParseDuration(oidc.JWKSRefresh)
+	if err != nil {
+		return nil, claims, fmt.Errorf("parse oidc jwks_refresh: %w", err)
+	}
+	jwks, err := auth.NewJWKS(auth.JWKSConfig{File: oidc.JWKSFile, URL: oidc.JWKSURL, RefreshInterval: refresh})
+	if err != nil {
+		return nil, claims, err
+	}
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		JWKS:     jwks,
+		Issuer:   oidc.Issuer,
+		Audience: oidc.Audience,
+		Leeway:   time.Minute,
+	})
+	return verifier, claims, err
+}
+
+// RequestPolicies builds the proxy policy stages. Enabled request transforms run first so model
+// policies see the clamped request; the model policies stored in store always apply; the
+// content policy is added when it is enabled in config.
+func RequestPolicies(cfg *config.Config, store storage.Storage, logger *slog.Logger) ([]proxy.RequestPolicy, error) {
+	var policies []proxy.RequestPolicy
+	if transformCfg := cfg.Proxy.Transform; transformCfg.Enabled {
+		projects := make(map[string]proxy.TransformLimits, len(transformCfg.Projects))
+		for _, project := range transformCfg.Projects {
+			projects[project.Project] = proxy.TransformLimits{MaxTokens: project.MaxTokens, MaxPromptTokens: project.MaxPromptTokens}
+		}
+		defaults := proxy.TransformLimits{MaxTokens: transformCfg.MaxTokens, MaxPromptTokens: transformCfg.MaxPromptTokens}
+		policies = append(policies, proxy.NewTransformPolicy(defaults, projects))
+	}
+	policies = append(policies, proxy.NewModelAccessPolicy(store, logger))
+	policyCfg := cfg.Proxy.Policy
+	if !policyCfg.Enabled {
+		return policies, nil
+	}
+	projectActions := make(map[string]string, len(policyCfg.Projects))
+	for _, project := range policyCfg.Projects {
+		projectActions[project.Project] = project.Action
+	}
+	patterns := make([]policy.Pattern, len(policyCfg.Patterns))
+	for i, pattern := range policyCfg.Patterns {
+		patterns[i] = policy.Pattern{Name: pattern.Name, Pattern: pattern.Pattern}
+	}
+	engine, err := policy.NewEngine(policy.Config{
+		DefaultAction:  policyCfg.DefaultAction,
+		ProjectActions: projectActions,
+		DetectPII:      policyCfg.DetectPII,
+		Patterns:       patterns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(policies, proxy.NewContentPolicy(engine)), nil
+}
+
+// TranscriptRecorder builds the audit transcript recorder. It returns nil when capture is disabled.
+func TranscriptRecorder(cfg *config.Config, store storage.Storage) (*capture.Recorder, error) {
+	if !cfg.Capture.Enabled {
+		return nil, nil
+	}
+	masterKey, err := capture.ParseMasterKey(cfg.Capture.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	retention, err := time.ParseDuration(cfg.Capture.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("parse capture retention: %w", err)
+	}
+	return capture.NewRecorder(store, capture.Config{
+		Projects:       cfg.Capture.Projects,
+		MasterKey:      masterKey,
+		Retention:      retention,
+		MaxBytes:       cfg.Capture.MaxBytes,
+		RedactPatterns: cfg.Capture.RedactPatterns,
+	})
+}
+
 // NewService creates a proxy service with shared tracker, JSON API, and HTTP server wiring.
 func NewService(cfg *config.Config) (*Service, error) {
 	usageTracker, store, logger, err := NewTracker(cfg)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.Storage.Writer.Async {
+		if err := usageTracker.EnableAsyncWrites(WriterConfig(cfg)); err != nil {
+			_ = store.Close()
+			return nil, fmt.Errorf("start usage writer: %w", err)
+		}
+	}
+
+	proxyOptions := ProxyOptions(cfg)
+	proxyOptions.EndUserJWT, err = EndUserJWTVerifier(cfg)
+	if err != nil {
+		_ = usageTracker.Close(context.Background())
+		_ = store.Close()
+		return nil, fmt.Errorf("load end-user jwt key: %w", err)
+	}
+	transcripts, err := TranscriptRecorder(cfg, store)
+	if err != nil {
+		_ = usageTracker.Close(context.Background())
+		_ = store.Close()
+		return nil, fmt.Errorf("configure transcript capture: %w", err)
+	}
+	proxyOptions.Transcripts = transcripts
+	proxyOptions.Policies, err = RequestPolicies(cfg, store, logger)
+	if err != nil {
+		_ = usageTracker.Close(context.Background())
+		_ = store.Close()
+		return nil, fmt.Errorf("configure request policy: %w", err)
+	}
 
 	proxyHandler := proxy.NewHandler(
 		usageTracker,
@@ -151,15 +362,24 @@ func NewService(cfg *config.Config) (*Service, error) {
 		cfg.Proxy.AddCostHeaders,
 		cfg.Proxy.DenyOnExceed,
 		logger,
-	)
-	apiServer := server.NewServer(usageTracker, logger)
+	).WithOptions(proxyOptions)
+	apiServer := server.NewServer(usageTracker, logger).WithTranscripts(transcripts).WithStore(store)
 	authMiddleware := httpauth.New(store, cfg.Auth.MultiTenantEnabled, cfg.Auth.DefaultTenant, cfg.Auth.BootstrapAdminKey, logger)
+	oidcVerifier, oidcClaims, err := OIDCVerifier(cfg)
+	if err != nil {
+		_ = usageTracker.Close(context.Background())
+		_ = store.Close()
+		return nil, fmt.Errorf("configure oidc: %w", err)
+	}
+	if oidcVerifier != nil {
+		authMiddleware.WithJWT(oidcVerifier, oidcClaims)
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/healthz", apiServer.Handler())
 	mux.Handle("/metrics", authMiddleware.Wrap(apiServer.Handler()))
 	mux.Handle("/api/", authMiddleware.Wrap(apiServer.Handler()))
-	mux.Handle("/", authMiddleware.Wrap(proxyHandler))
+	mux.Handle("/", authMiddleware.WrapProxy(proxyHandler))
 
 	readTimeout, _ := time.ParseDuration(cfg.Proxy.ReadTimeout)
 	if readTimeout == 0 {
@@ -181,6 +401,7 @@ func NewService(cfg *config.Config) (*Service, error) {
 			ReadTimeout:  readTimeout,
 			WriteTimeout: writeTimeout,
 		},
+		Transcripts: transcripts,
 	}, nil
 }
 
@@ -191,6 +412,14 @@ func (s *Service) Run(ctx context.Context, onStart func(addr string)) error {
 		return fmt.Errorf("listen: %w", err)
 	}
 
+	if s.Transcripts != nil {
+		purgeInterval, _ := time.ParseDuration(s.Config.Capture.PurgeInterval)
+		go s.Transcripts.RunPurge(ctx, purgeInterval, s.Logger)
+	}
+	if s.Tracker != nil {
+		go s.Tracker.RunRotationSweep(ctx, time.Minute, s.Logger)
+	}
+
 	errCh := make(chan error, 1)
 	go func() {
 		addr := listener.Addr().String()
@@ -228,6 +457,13 @@ func (s *Service) Run(ctx context.Context, onStart func(addr string)) error {
 
 // Close releases resources owned by the service.
 func (s *Service) Close() error {
+	if s.Tracker != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.Tracker.Close(ctx); err != nil && s.Logger != nil {
+			s.Logger.Error("drain usage writer", "error", err)
+		}
+	}
 	if s.Store == nil {
 		return nil
 	}
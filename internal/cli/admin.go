@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+	"github.com/spf13/cobra"
+)
+
+// remoteAnnotation marks commands, and the subcommands of commands, that honor --server.
+const remoteAnnotation = "lcg:remote"
+
+var (
+	serverURL    string
+	serverAPIKey string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&serverURL, "server", "", "Manage tenants, API keys, and budgets through the admin API of the guardian at this URL (env LCG_SERVER)")
+	rootCmd.PersistentFlags().StringVar(&serverAPIKey, "api-key", "", "API key or OIDC token used with --server (env LCG_API_KEY)")
+}
+
+// adminBackend runs the tenant, tenant settings, API key, and budget commands against the
+// local database or, with --server, the admin API of a running guardian.
+type adminBackend interface {
+	ListTenants(ctx context.Context) ([]model.Tenant, error)
+	CreateTenant(ctx context.Context, req adminapi.CreateTenantRequest) (*model.Tenant, error)
+	UpdateTenant(ctx context.Context, slug string, req adminapi.UpdateTenantRequest) (*model.Tenant, error)
+	ExportTenant(ctx context.Context, slug string) (*model.TenantExport, error)
+	DeleteTenant(ctx context.Context, slug string) error
+	GetTenantSettings(ctx context.Context, slug string) (*adminapi.TenantSettingsResponse, error)
+	UpdateTenantSettings(ctx context.Context, slug string, req adminapi.TenantSettingsRequest) (*adminapi.TenantSettingsResponse, error)
+	ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey, error)
+	CreateAPIKey(ctx context.Context, req adminapi.CreateAPIKeyRequest) (*adminapi.IssuedAPIKey, error)
+	SetAPIKeyRole(ctx context.Context, id string, req adminapi.SetAPIKeyRoleRequest) (*model.APIKey, error)
+	RotateAPIKey(ctx context.Context, id string, req adminapi.RotateAPIKeyRequest) (*adminapi.IssuedAPIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	ListBudgets(ctx context.Context, tenant string) ([]model.Budget, error)
+	SetBudget(ctx context.Context, name string, req adminapi.BudgetRequest) (*model.Budget, error)
+	Close() error
+}
+
+// remoteServer returns the guardian URL given with --server or LCG_SERVER.
+func remoteServer() string {
+	if serverURL != "" {
+		return serverURL
+	}
+	return os.Getenv("LCG_SERVER")
+}
+
+// requireLocal refuses --server for commands that only work on the local database.
+func requireLocal(cmd *cobra.Command, _ []string) error {
+	if remoteServer() == "" {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[remoteAnnotation] == "true" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s does not support --server; it works on the local database", cmd.CommandPath())
+}
+
+// openAdmin returns the backend for the tenant, API key, and budget commands.
+func openAdmin() (adminBackend, error) {
+	if server := remoteServer(); server != "" {
+		apiKey := serverAPIKey
+		if apiKey == "" {
+			apiKey = os.Getenv("LCG_API_KEY")
+		}
+		return remoteAdmin{adminapi.NewClient(server, apiKey)}, nil
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	usageTracker, store, err := initTracker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &localAdmin{tracker: usageTracker, store: store, defaultTenant: cfg.Auth.DefaultTenant}, nil
+}
+
+type remoteAdmin struct {
+	*adminapi.Client
+}
+
+func (remoteAdmin) Close() error { return nil }
+
+// localAdmin runs the commands directly on the local database.
+type localAdmin struct {
+	tracker       *tracker.UsageTracker
+	store         storage.Storage
+	defaultTenant string
+}
+
+func (a *localAdmin) ListTenants(ctx context.Context) ([]model.Tenant, error) {
+	return a.store.ListTenants(ctx)
+}
+
+func (a *localAdmin) CreateTenant(ctx context.Context, req adminapi.CreateTenantRequest) (*model.Tenant, error) {
+	tenant := &model.Tenant{Slug: req.Slug, Name: req.Name, Status: model.TenantStatusActive}
+	if err := a.store.CreateTenant(ctx, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (a *localAdmin) UpdateTenant(ctx context.Context, slug string, req adminapi.UpdateTenantRequest) (*model.Tenant, error) {
+	if req.Slug != "" || req.Name != "" {
+		renamed, err := a.store.RenameTenant(ctx, slug, req.Slug, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		slug = renamed.Slug
+	}
+	switch req.Status {
+	case model.TenantStatusActive:
+		if err := a.store.EnableTenant(ctx, slug); err != nil {
+			return nil, err
+		}
+	case model.TenantStatusDisabled:
+		if err := a.store.DisableTenant(ctx, slug); err != nil {
+			return nil, err
+		}
+	}
+	return a.store.GetTenant(ctx, slug)
+}
+
+func (a *localAdmin) ExportTenant(ctx context.Context, slug string) (*model.TenantExport, error) {
+	return a.store.ExportTenant(ctx, slug)
+}
+
+func (a *localAdmin) DeleteTenant(ctx context.Context, slug string) error {
+	return a.store.DeleteTenant(ctx, slug)
+}
+
+func (a *localAdmin) GetTenantSettings(ctx context.Context, slug string) (*adminapi.TenantSettingsResponse, error) {
+	settings, err := a.store.GetTenantSettings(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return a.tenantSettingsResponse(settings), nil
+}
+
+func (a *localAdmin) UpdateTenantSettings(ctx context.Context, slug string, req adminapi.TenantSettingsRequest) (*adminapi.TenantSettingsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	settings, err := a.store.GetTenantSettings(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	req.Apply(settings)
+	if err := a.store.SetTenantSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+	return a.tenantSettingsResponse(settings), nil
+}
+
+func (a *localAdmin) tenantSettingsResponse(settings *model.TenantSettings) *adminapi.TenantSettingsResponse {
+	return &adminapi.TenantSettingsResponse{
+		Settings:  settings.Redacted(),
+		Effective: settings.Apply(a.tracker.TenantDefaults()).Redacted(),
+	}
+}
+
+func (a *localAdmin) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey, error) {
+	return a.store.ListAPIKeys(ctx, tenant)
+}
+
+func (a *localAdmin) CreateAPIKey(ctx context.Context, req adminapi.CreateAPIKeyRequest) (*adminapi.IssuedAPIKey, error) {
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &model.APIKey{
+		Tenant:          req.Tenant,
+		Name:            req.Name,
+		KeyPrefix:       prefix,
+		KeyHash:         hash,
+		Status:          model.APIKeyStatusActive,
+		Role:            req.Role,
+		AllTenants:      req.AllTenants,
+		SpendLimitUSD:   req.SpendLimitUSD,
+		SpendPeriod:     model.BudgetPeriod(req.SpendPeriod),
+		AllowedProjects: req.AllowedProjects,
+		ExpiresAt:       req.ExpiresAt(time.Now()),
+	}
+	if err := a.store.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return &adminapi.IssuedAPIKey{APIKey: *key, Key: rawKey}, nil
+}
+
+func (a *localAdmin) SetAPIKeyRole(ctx context.Context, id string, req adminapi.SetAPIKeyRoleRequest) (*model.APIKey, error) {
+	if err := a.store.SetAPIKeyRole(ctx, id, req.Role, req.AllTenants); err != nil {
+		return nil, err
+	}
+	return a.store.GetAPIKey(ctx, id)
+}
+
+func (a *localAdmin) RotateAPIKey(ctx context.Context, id string, req adminapi.RotateAPIKeyRequest) (*adminapi.IssuedAPIKey, error) {
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	successor := &model.APIKey{KeyPrefix: prefix, KeyHash: hash, ExpiresAt: req.ExpiresAt(time.Now())}
+	old, err := a.tracker.RotateAPIKey(ctx, id, successor, req.GracePeriod())
+	if err != nil {
+		return nil, err
+	}
+	return &adminapi.IssuedAPIKey{APIKey: *successor, Key: rawKey, Replaces: old}, nil
+}
+
+func (a *localAdmin) RevokeAPIKey(ctx context.Context, id string) error {
+	return a.store.RevokeAPIKey(ctx, id)
+}
+
+func (a *localAdmin) ListBudgets(ctx context.Context, tenant string) ([]model.Budget, error) {
+	if tenant == "" {
+		tenant = a.defaultTenant
+	}
+	budgets, err := a.store.ListBudgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []model.Budget
+	for _, budget := range budgets {
+		if tenant == "" || budget.Tenant == tenant {
+			filtered = append(filtered, budget)
+		}
+	}
+	return filtered, nil
+}
+
+func (a *localAdmin) SetBudget(ctx context.Context, name string, req adminapi.BudgetRequest) (*model.Budget, error) {
+	if req.Tenant == "" {
+		req.Tenant = a.defaultTenant
+	}
+	if req.AlertThresholdPct == 0 {
+		config, err := a.tracker.TenantConfig(ctx, req.Tenant)
+		if err != nil {
+			return nil, err
+		}
+		req.AlertThresholdPct = config.AlertThresholdPct
+	}
+	budget := &model.Budget{
+		Tenant:            req.Tenant,
+		Name:              name,
+		Project:           req.Project,
+		LimitUSD:          req.LimitUSD,
+		Period:            model.BudgetPeriod(req.Period),
+		AlertThresholdPct: req.AlertThresholdPct,
+		Tags:              req.Tags,
+	}
+	if err := a.store.SetBudget(ctx, budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (a *localAdmin) Close() error {
+	return a.store.Close()
+}
+
+// durationString renders a duration flag for an admin request, leaving zero empty.
+func durationString(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -3,16 +3,19 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
-	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/spf13/cobra"
 )
 
 var apiKeysCmd = &cobra.Command{
-	Use:   "api-keys",
-	Short: "Manage tenant API keys",
+	Use:         "api-keys",
+	Short:       "Manage tenant API keys",
+	Annotations: map[string]string{remoteAnnotation: "true"},
 }
 
 var apiKeysCreateCmd = &cobra.Command{
@@ -27,6 +30,18 @@ var apiKeysListCmd = &cobra.Command{
 	RunE:  runAPIKeyList,
 }
 
+var apiKeysSetRoleCmd = &cobra.Command{
+	Use:   "set-role",
+	Short: "Change the role of an API key",
+	RunE:  runAPIKeySetRole,
+}
+
+var apiKeysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace an API key, keeping the old key valid for a grace period",
+	RunE:  runAPIKeyRotate,
+}
+
 var apiKeysRevokeCmd = &cobra.Command{
 	Use:   "revoke",
 	Short: "Revoke an API key",
@@ -37,108 +52,231 @@ func init() {
 	rootCmd.AddCommand(apiKeysCmd)
 	apiKeysCmd.AddCommand(apiKeysCreateCmd)
 	apiKeysCmd.AddCommand(apiKeysListCmd)
+	apiKeysCmd.AddCommand(apiKeysSetRoleCmd)
+	apiKeysCmd.AddCommand(apiKeysRotateCmd)
 	apiKeysCmd.AddCommand(apiKeysRevokeCmd)
 
 	apiKeysCreateCmd.Flags().String("tenant", "", "Tenant slug")
 	apiKeysCreateCmd.Flags().String("name", "default", "API key name")
+	apiKeysCreateCmd.Flags().Duration("expires-in", 0, "Expire the key after this duration, e.g. 720h (0 = never)")
+	apiKeysCreateCmd.Flags().String("role", model.RoleDeveloper, "Key role ("+roleList()+")")
+	apiKeysCreateCmd.Flags().Bool("all-tenants", false, "Grant the role in every tenant, not only the key's own")
+	apiKeysCreateCmd.Flags().Float64("spend-limit", 0, "Spend cap in USD per spend period (0 = no cap)")
+	apiKeysCreateCmd.Flags().String("spend-period", string(model.PeriodMonthly), "Spend cap period (daily, weekly, monthly)")
+	apiKeysCreateCmd.Flags().String("projects", "", "Projects the key may proxy for, comma-separated (empty = any)")
 	_ = apiKeysCreateCmd.MarkFlagRequired("tenant")
 
 	apiKeysListCmd.Flags().String("tenant", "", "Tenant slug filter")
 
+	apiKeysSetRoleCmd.Flags().String("id", "", "API key id")
+	apiKeysSetRoleCmd.Flags().String("role", "", "Key role ("+roleList()+")")
+	apiKeysSetRoleCmd.Flags().Bool("all-tenants", false, "Grant the role in every tenant, not only the key's own")
+	_ = apiKeysSetRoleCmd.MarkFlagRequired("id")
+	_ = apiKeysSetRoleCmd.MarkFlagRequired("role")
+
+	apiKeysRotateCmd.Flags().String("id", "", "API key id")
+	apiKeysRotateCmd.Flags().Duration("grace", 24*time.Hour, "How long the old key keeps working, e.g. 72h (0 = revoke at the next sweep)")
+	apiKeysRotateCmd.Flags().Duration("expires-in", 0, "Expire the new key after this duration, e.g. 720h (0 = never)")
+	_ = apiKeysRotateCmd.MarkFlagRequired("id")
+
 	apiKeysRevokeCmd.Flags().String("id", "", "API key id")
 	_ = apiKeysRevokeCmd.MarkFlagRequired("id")
 }
 
 func runAPIKeyCreate(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
-	}
-
 	tenant, _ := cmd.Flags().GetString("tenant")
 	name, _ := cmd.Flags().GetString("name")
+	expiresIn, _ := cmd.Flags().GetDuration("expires-in")
+	role, _ := cmd.Flags().GetString("role")
+	allTenants, _ := cmd.Flags().GetBool("all-tenants")
+	spendLimit, _ := cmd.Flags().GetFloat64("spend-limit")
+	spendPeriod, _ := cmd.Flags().GetString("spend-period")
+	projects, _ := cmd.Flags().GetString("projects")
 
-	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
-	if err != nil {
-		return err
+	if !model.ValidRole(role) {
+		return fmt.Errorf("invalid role %q: must be one of %s", role, roleList())
+	}
+	if expiresIn < 0 {
+		return fmt.Errorf("expires-in must not be negative")
+	}
+	if spendLimit < 0 {
+		return fmt.Errorf("spend-limit must not be negative")
+	}
+	switch model.BudgetPeriod(spendPeriod) {
+	case model.PeriodDaily, model.PeriodWeekly, model.PeriodMonthly:
+	default:
+		return fmt.Errorf("invalid spend-period %q: must be daily, weekly, or monthly", spendPeriod)
 	}
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	key := &model.APIKey{
-		Tenant:    tenant,
-		Name:      name,
-		KeyPrefix: prefix,
-		KeyHash:   hash,
-		Status:    model.APIKeyStatusActive,
-	}
-	if err := store.CreateAPIKey(commandContext(cmd), key); err != nil {
+	issued, err := admin.CreateAPIKey(commandContext(cmd), adminapi.CreateAPIKeyRequest{
+		Tenant:          tenant,
+		Name:            name,
+		Role:            role,
+		AllTenants:      allTenants,
+		ExpiresIn:       durationString(expiresIn),
+		SpendLimitUSD:   spendLimit,
+		SpendPeriod:     spendPeriod,
+		AllowedProjects: splitFlagList(projects),
+	})
+	if err != nil {
 		return fmt.Errorf("create api key: %w", err)
 	}
+	key := &issued.APIKey
 
 	fmt.Printf("API key created:\n")
-	fmt.Printf("  Tenant:    %s\n", tenant)
-	fmt.Printf("  Name:      %s\n", name)
+	fmt.Printf("  Tenant:    %s\n", key.Tenant)
+	fmt.Printf("  Name:      %s\n", key.Name)
 	fmt.Printf("  ID:        %s\n", key.ID)
-	fmt.Printf("  Prefix:    %s\n", prefix)
-	fmt.Printf("  Raw Key:   %s\n", rawKey)
+	fmt.Printf("  Prefix:    %s\n", key.KeyPrefix)
+	fmt.Printf("  Role:      %s\n", keyRole(key))
+	if key.ExpiresAt != nil {
+		fmt.Printf("  Expires:   %s\n", key.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Printf("  Raw Key:   %s\n", issued.Key)
 	return nil
 }
 
 func runAPIKeyList(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
-	}
-
 	tenant, _ := cmd.Flags().GetString("tenant")
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	keys, err := store.ListAPIKeys(commandContext(cmd), tenant)
+	keys, err := admin.ListAPIKeys(commandContext(cmd), tenant)
 	if err != nil {
 		return fmt.Errorf("list api keys: %w", err)
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "ID\tTENANT\tNAME\tPREFIX\tSTATUS\tLAST USED\n")
+	fmt.Fprintf(w, "ID\tTENANT\tNAME\tPREFIX\tSTATUS\tROLE\tEXPIRES\tSPEND LIMIT\tPROJECTS\tLAST USED\n")
+	now := time.Now().UTC()
 	for _, key := range keys {
 		lastUsed := "-"
 		if key.LastUsedAt != nil {
 			lastUsed = key.LastUsedAt.Format("2006-01-02 15:04")
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Tenant, key.Name, key.KeyPrefix, key.Status, lastUsed)
+		status := key.Status
+		if status == model.APIKeyStatusActive && key.Expired(now) {
+			status = "expired"
+		} else if status == model.APIKeyStatusActive && key.SuccessorID != "" {
+			status = "rotating"
+		}
+		expires := "-"
+		if key.ExpiresAt != nil {
+			expires = key.ExpiresAt.Format("2006-01-02 15:04")
+		}
+		spendLimit := "-"
+		if key.SpendLimitUSD > 0 {
+			spendLimit = fmt.Sprintf("$%.2f/%s", key.SpendLimitUSD, key.SpendPeriod)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Tenant, key.Name, key.KeyPrefix, status,
+			keyRole(&key), expires, spendLimit, joinOr(key.AllowedProjects, "any"), lastUsed)
 	}
 	w.Flush()
 	return nil
 }
 
-func runAPIKeyRevoke(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
+func runAPIKeySetRole(cmd *cobra.Command, _ []string) error {
+	id, _ := cmd.Flags().GetString("id")
+	role, _ := cmd.Flags().GetString("role")
+	allTenants, _ := cmd.Flags().GetBool("all-tenants")
+	if !model.ValidRole(role) {
+		return fmt.Errorf("invalid role %q: must be one of %s", role, roleList())
+	}
+
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
+	defer admin.Close()
 
+	key, err := admin.SetAPIKeyRole(commandContext(cmd), id, adminapi.SetAPIKeyRoleRequest{Role: role, AllTenants: allTenants})
+	if err != nil {
+		return fmt.Errorf("set api key role: %w", err)
+	}
+
+	fmt.Printf("API key %s role set to %s\n", id, keyRole(key))
+	return nil
+}
+
+func runAPIKeyRotate(cmd *cobra.Command, _ []string) error {
+	id, _ := cmd.Flags().GetString("id")
+	grace, _ := cmd.Flags().GetDuration("grace")
+	expiresIn, _ := cmd.Flags().GetDuration("expires-in")
+	if grace < 0 {
+		return fmt.Errorf("grace must not be negative")
+	}
+	if expiresIn < 0 {
+		return fmt.Errorf("expires-in must not be negative")
+	}
+
+	admin, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	issued, err := admin.RotateAPIKey(commandContext(cmd), id, adminapi.RotateAPIKeyRequest{
+		Grace:     grace.String(),
+		ExpiresIn: durationString(expiresIn),
+	})
+	if err != nil {
+		return fmt.Errorf("rotate api key: %w", err)
+	}
+	old, successor := issued.Replaces, &issued.APIKey
+
+	lastUsed := "never"
+	if old.LastUsedAt != nil {
+		lastUsed = old.LastUsedAt.Format(time.RFC3339)
+	}
+	fmt.Printf("API key rotated:\n")
+	fmt.Printf("  Tenant:    %s\n", old.Tenant)
+	fmt.Printf("  Name:      %s\n", old.Name)
+	fmt.Printf("  Old ID:    %s (valid until %s, last used %s)\n", old.ID, old.ExpiresAt.Format(time.RFC3339), lastUsed)
+	fmt.Printf("  New ID:    %s\n", successor.ID)
+	fmt.Printf("  Prefix:    %s\n", successor.KeyPrefix)
+	fmt.Printf("  Role:      %s\n", keyRole(successor))
+	if successor.ExpiresAt != nil {
+		fmt.Printf("  Expires:   %s\n", successor.ExpiresAt.Format(time.RFC3339))
+	}
+	fmt.Printf("  Raw Key:   %s\n", issued.Key)
+	return nil
+}
+
+func runAPIKeyRevoke(cmd *cobra.Command, _ []string) error {
 	id, _ := cmd.Flags().GetString("id")
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	if err := store.RevokeAPIKey(commandContext(cmd), id); err != nil {
+	if err := admin.RevokeAPIKey(commandContext(cmd), id); err != nil {
 		return fmt.Errorf("revoke api key: %w", err)
 	}
 
 	fmt.Printf("API key revoked: %s\n", id)
 	return nil
 }
+
+// keyRole describes a key's role, marking roles granted in every tenant.
+func keyRole(key *model.APIKey) string {
+	if key.AllTenants {
+		return key.Role + " (all tenants)"
+	}
+	return key.Role
+}
+
+func roleList() string {
+	return strings.Join(model.Roles, ", ")
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log of administrative changes",
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List audit events, newest first",
+	RunE:  runAuditList,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd)
+
+	auditListCmd.Flags().String("tenant", "", "Tenant slug filter")
+	auditListCmd.Flags().String("actor", "", "Actor filter, e.g. user:dana@example.com or os_user:alice")
+	auditListCmd.Flags().String("source", "", "Source filter (cli, api, system)")
+	auditListCmd.Flags().String("action", "", "Action filter, e.g. api_key.revoke")
+	auditListCmd.Flags().String("resource-type", "", "Resource type filter (tenant, api_key, budget, model_policy)")
+	auditListCmd.Flags().String("resource-id", "", "Resource id filter")
+	auditListCmd.Flags().Duration("since", 0, "Only events from this long ago, e.g. 24h (0 = all)")
+	auditListCmd.Flags().Int("limit", 50, "Maximum number of events (0 = no limit)")
+	auditListCmd.Flags().String("format", "text", "Output format (text, json); json includes before and after values")
+}
+
+func runAuditList(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	filter := model.AuditFilter{}
+	filter.Tenant, _ = cmd.Flags().GetString("tenant")
+	filter.Actor, _ = cmd.Flags().GetString("actor")
+	filter.Source, _ = cmd.Flags().GetString("source")
+	filter.Action, _ = cmd.Flags().GetString("action")
+	filter.ResourceType, _ = cmd.Flags().GetString("resource-type")
+	filter.ResourceID, _ = cmd.Flags().GetString("resource-id")
+	filter.Limit, _ = cmd.Flags().GetInt("limit")
+	since, _ := cmd.Flags().GetDuration("since")
+	format, _ := cmd.Flags().GetString("format")
+	if since < 0 {
+		return fmt.Errorf("since must not be negative")
+	}
+	if since > 0 {
+		filter.StartTime = time.Now().UTC().Add(-since)
+	}
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid format %q: must be text or json", format)
+	}
+
+	_, store, err := initTracker(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	events, err := store.ListAuditEvents(commandContext(cmd), filter)
+	if err != nil {
+		return fmt.Errorf("list audit events: %w", err)
+	}
+
+	if format == "json" {
+		if events == nil {
+			events = []model.AuditEvent{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(events)
+	}
+	if len(events) == 0 {
+		fmt.Println("No audit events found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TIME\tTENANT\tACTOR\tSOURCE\tACTION\tRESOURCE\n")
+	for _, event := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", event.Timestamp.Format("2006-01-02 15:04:05"),
+			valueOrDash(event.Tenant), event.Actor, event.Source, event.Action, event.ResourceID)
+	}
+	w.Flush()
+	return nil
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -3,15 +3,19 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 	"github.com/spf13/cobra"
 )
 
 var budgetCmd = &cobra.Command{
-	Use:   "budget",
-	Short: "Manage spending budgets",
+	Use:         "budget",
+	Short:       "Manage spending budgets",
+	Annotations: map[string]string{remoteAnnotation: "true"},
 }
 
 var budgetSetCmd = &cobra.Command{
@@ -36,93 +40,74 @@ func init() {
 	budgetSetCmd.Flags().String("project", "", "Project scope for this budget (empty = global)")
 	budgetSetCmd.Flags().Float64P("limit", "l", 0, "Spending limit in USD")
 	budgetSetCmd.Flags().StringP("period", "P", "monthly", "Budget period (daily, weekly, monthly)")
-	budgetSetCmd.Flags().Float64("alert-at", 80, "Alert threshold percentage")
+	budgetSetCmd.Flags().Float64("alert-at", 0, "Alert threshold percentage (default from tenant settings)")
+	budgetSetCmd.Flags().String("tags", "", "Only count usage carrying these tags (key=value,key=value)")
 	budgetStatusCmd.Flags().String("tenant", "", "Show budgets for the given tenant (default from config)")
 	budgetStatusCmd.Flags().String("project", "", "Show budgets applicable to the given project")
 	_ = budgetSetCmd.MarkFlagRequired("limit")
 }
 
 func runBudgetSet(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
-	}
-
 	name, _ := cmd.Flags().GetString("name")
 	tenant, _ := cmd.Flags().GetString("tenant")
 	project, _ := cmd.Flags().GetString("project")
 	limit, _ := cmd.Flags().GetFloat64("limit")
 	period, _ := cmd.Flags().GetString("period")
 	alertAt, _ := cmd.Flags().GetFloat64("alert-at")
+	rawTags, _ := cmd.Flags().GetString("tags")
 
-	if tenant == "" {
-		tenant = cfg.Auth.DefaultTenant
+	tags, err := tracker.ParseTags(rawTags)
+	if err != nil {
+		return fmt.Errorf("parse tags: %w", err)
 	}
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	budget := &tracker.Budget{
+	budget, err := admin.SetBudget(commandContext(cmd), name, adminapi.BudgetRequest{
 		Tenant:            tenant,
-		Name:              name,
 		Project:           project,
 		LimitUSD:          limit,
-		Period:            tracker.BudgetPeriod(period),
+		Period:            period,
 		AlertThresholdPct: alertAt,
-	}
-
-	if err := store.SetBudget(commandContext(cmd), budget); err != nil {
+		Tags:              tags,
+	})
+	if err != nil {
 		return fmt.Errorf("set budget: %w", err)
 	}
 
 	fmt.Printf("Budget set:\n")
-	fmt.Printf("  Tenant:    %s\n", tenant)
+	fmt.Printf("  Tenant:    %s\n", budget.Tenant)
 	fmt.Printf("  Name:      %s\n", name)
-	if project == "" {
-		fmt.Printf("  Scope:     global\n")
-	} else {
-		fmt.Printf("  Scope:     project:%s\n", project)
-	}
-	fmt.Printf("  Limit:     $%.2f\n", limit)
-	fmt.Printf("  Period:    %s\n", period)
-	fmt.Printf("  Alert at:  %.0f%%\n", alertAt)
+	fmt.Printf("  Scope:     %s\n", budgetScope(budget))
+	fmt.Printf("  Limit:     $%.2f\n", budget.LimitUSD)
+	fmt.Printf("  Period:    %s\n", budget.Period)
+	fmt.Printf("  Alert at:  %.0f%%\n", budget.AlertThresholdPct)
 
 	return nil
 }
 
 func runBudgetStatus(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-
-	_, store, err := initTracker(cfg)
-	if err != nil {
-		return err
-	}
-	defer store.Close()
+	defer admin.Close()
 
 	tenantFilter, _ := cmd.Flags().GetString("tenant")
-	if tenantFilter == "" {
-		tenantFilter = cfg.Auth.DefaultTenant
-	}
-
-	budgets, err := store.ListBudgets(commandContext(cmd))
+	budgets, err := admin.ListBudgets(commandContext(cmd), tenantFilter)
 	if err != nil {
 		return fmt.Errorf("list budgets: %w", err)
 	}
 
 	projectFilter, _ := cmd.Flags().GetString("project")
-	if tenantFilter != "" || projectFilter != "" {
+	if projectFilter != "" {
 		var filtered []tracker.Budget
 		for _, budget := range budgets {
-			if tenantFilter != "" && budget.Tenant != tenantFilter {
-				continue
-			}
-			if projectFilter == "" || budget.Project == "" || budget.Project == projectFilter {
+			if budget.Project == "" || budget.Project == projectFilter {
 				filtered = append(filtered, budget)
 			}
 		}
@@ -156,13 +141,8 @@ func runBudgetStatus(cmd *cobra.Command, _ []string) error {
 			status = " [WARNING]"
 		}
 
-		scope := "global"
-		if b.Project != "" {
-			scope = "project:" + b.Project
-		}
-
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t$%.2f\t$%.2f\t$%.2f\t%.1f%%%s\t%.0f%%\n",
-			b.Tenant, b.Name, scope, b.Period, b.LimitUSD, b.CurrentSpend,
+			b.Tenant, b.Name, budgetScope(&b), b.Period, b.LimitUSD, b.CurrentSpend,
 			remaining, pct, status, b.AlertThresholdPct,
 		)
 	}
@@ -170,3 +150,18 @@ func runBudgetStatus(cmd *cobra.Command, _ []string) error {
 
 	return nil
 }
+
+// budgetScope describes which usage a budget counts, e.g. "global" or "project:web tags:env=prod".
+func budgetScope(b *tracker.Budget) string {
+	var parts []string
+	if b.Project != "" {
+		parts = append(parts, "project:"+b.Project)
+	}
+	if len(b.Tags) > 0 {
+		parts = append(parts, "tags:"+model.FormatTags(b.Tags))
+	}
+	if len(parts) == 0 {
+		return "global"
+	}
+	return strings.Join(parts, " ")
+}
@@ -2,15 +2,23 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"io"
+	"log/slog"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/server"
+	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
@@ -52,6 +60,8 @@ func testCLIConfig(t *testing.T) (string, string) {
 
 func resetCommandState() {
 	cfgFile = ""
+	serverURL = ""
+	serverAPIKey = ""
 	resetFlags(trackCmd)
 	resetFlags(reportCmd)
 	resetFlags(budgetSetCmd)
@@ -59,12 +69,26 @@ func resetCommandState() {
 	resetFlags(tenantsCreateCmd)
 	resetFlags(tenantsListCmd)
 	resetFlags(tenantsDisableCmd)
+	resetFlags(tenantsEnableCmd)
+	resetFlags(tenantsRenameCmd)
+	resetFlags(tenantsExportCmd)
+	resetFlags(tenantsDeleteCmd)
+	resetFlags(tenantsSettingsShowCmd)
+	resetFlags(tenantsSettingsSetCmd)
+	resetFlags(tenantsSettingsUnsetCmd)
 	resetFlags(apiKeysCreateCmd)
 	resetFlags(apiKeysListCmd)
+	resetFlags(apiKeysSetRoleCmd)
+	resetFlags(apiKeysRotateCmd)
 	resetFlags(apiKeysRevokeCmd)
+	resetFlags(modelPoliciesSetCmd)
+	resetFlags(modelPoliciesListCmd)
+	resetFlags(modelPoliciesDeleteCmd)
+	resetFlags(auditListCmd)
 	resetFlags(anomaliesCmd)
 	resetFlags(forecastCmd)
 	resetFlags(recommendCmd)
+	resetFlags(errorsCmd)
 	resetFlags(promptsOptimizeCmd)
 	resetFlags(providersListCmd)
 	resetFlags(proxyStartCmd)
@@ -176,6 +200,87 @@ func TestRunReport_Detailed(t *testing.T) {
 	assert.NotContains(t, stdout, "claude-3.5-sonnet")
 }
 
+func TestRunReport_TopUsers(t *testing.T) {
+	resetCommandState()
+	cfgPath, dbPath := testCLIConfig(t)
+	cfgFile = cfgPath
+
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, record := range []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 1.00, Project: "proj-a", User: "alice"},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 3.00, Project: "proj-a", User: "bob"},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 0.50, Project: "proj-a", User: "alice"},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 9.00, Project: "proj-a"},
+	} {
+		require.NoError(t, db.RecordUsage(context.Background(), record))
+	}
+
+	require.NoError(t, reportCmd.Flags().Set("top-users", "1"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runReport(reportCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Top Users:")
+	assert.Contains(t, stdout, "bob")
+	assert.NotContains(t, stdout, "alice")
+
+	resetCommandState()
+	cfgFile = cfgPath
+	require.NoError(t, reportCmd.Flags().Set("user", "alice"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runReport(reportCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Total Cost:          $1.5000")
+}
+
+func TestRunReport_GroupByTag(t *testing.T) {
+	resetCommandState()
+	cfgPath, dbPath := testCLIConfig(t)
+	cfgFile = cfgPath
+
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, record := range []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 1.00, Tags: map[string]string{"feature": "search", "env": "prod"}},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 2.00, Tags: map[string]string{"feature": "chat", "env": "prod"}},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 4.00},
+	} {
+		require.NoError(t, db.RecordUsage(context.Background(), record))
+	}
+
+	require.NoError(t, reportCmd.Flags().Set("group-by-tag", "feature"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runReport(reportCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tag feature")
+	assert.Contains(t, stdout, "search")
+	assert.Contains(t, stdout, "(untagged)")
+
+	resetCommandState()
+	cfgFile = cfgPath
+	require.NoError(t, reportCmd.Flags().Set("tags", "env=prod"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runReport(reportCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Total Cost:          $3.0000")
+
+	resetCommandState()
+	cfgFile = cfgPath
+	require.NoError(t, reportCmd.Flags().Set("tags", "bad tag"))
+	_, _, err = captureOutput(t, func() error {
+		return runReport(reportCmd, nil)
+	})
+	assert.ErrorContains(t, err, "parse tags")
+}
+
 func TestRunReport_CSVExport(t *testing.T) {
 	resetCommandState()
 	cfgPath, dbPath := testCLIConfig(t)
@@ -373,6 +478,25 @@ func TestRunTenantAndAPIKeyCommands(t *testing.T) {
 	assert.Contains(t, stdout, "API key created")
 	assert.Contains(t, stdout, "Raw Key:   lcg_")
 
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("name", "ci"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("role", "proxy-only"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("expires-in", "24h"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("spend-limit", "50"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("spend-period", "weekly"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("projects", "chat, search"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAPIKeyCreate(apiKeysCreateCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Role:      proxy-only")
+	assert.Contains(t, stdout, "Expires:")
+
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("role", "root"))
+	_, _, err = captureOutput(t, func() error {
+		return runAPIKeyCreate(apiKeysCreateCmd, nil)
+	})
+	assert.ErrorContains(t, err, `invalid role "root"`)
+
 	require.NoError(t, apiKeysListCmd.Flags().Set("tenant", "acme"))
 	stdout, _, err = captureOutput(t, func() error {
 		return runAPIKeyList(apiKeysListCmd, nil)
@@ -380,6 +504,8 @@ func TestRunTenantAndAPIKeyCommands(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, stdout, "primary")
 	assert.Contains(t, stdout, "acme")
+	assert.Contains(t, stdout, "$50.00/weekly")
+	assert.Contains(t, stdout, "chat,search")
 
 	db, err := storage.NewSQLite(dbPath)
 	require.NoError(t, err)
@@ -387,7 +513,57 @@ func TestRunTenantAndAPIKeyCommands(t *testing.T) {
 
 	keys, err := db.ListAPIKeys(context.Background(), "acme")
 	require.NoError(t, err)
-	require.Len(t, keys, 1)
+	require.Len(t, keys, 2)
+	var ci model.APIKey
+	for _, key := range keys {
+		if key.Name == "ci" {
+			ci = key
+		}
+	}
+	assert.Equal(t, model.RoleProxyOnly, ci.Role)
+	assert.Equal(t, []string{"chat", "search"}, ci.AllowedProjects)
+	require.NotNil(t, ci.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *ci.ExpiresAt, time.Minute)
+
+	require.NoError(t, apiKeysSetRoleCmd.Flags().Set("id", ci.ID))
+	require.NoError(t, apiKeysSetRoleCmd.Flags().Set("role", "finance-viewer"))
+	require.NoError(t, apiKeysSetRoleCmd.Flags().Set("all-tenants", "true"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAPIKeySetRole(apiKeysSetRoleCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "role set to finance-viewer (all tenants)")
+
+	require.NoError(t, apiKeysRotateCmd.Flags().Set("id", ci.ID))
+	require.NoError(t, apiKeysRotateCmd.Flags().Set("grace", "2h"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAPIKeyRotate(apiKeysRotateCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "API key rotated")
+	assert.Contains(t, stdout, "last used never")
+	assert.Contains(t, stdout, "Role:      finance-viewer (all tenants)")
+	assert.Contains(t, stdout, "Raw Key:   lcg_")
+
+	rotated, err := db.GetAPIKey(context.Background(), ci.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, rotated.SuccessorID)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), *rotated.ExpiresAt, time.Minute)
+	successor, err := db.GetAPIKey(context.Background(), rotated.SuccessorID)
+	require.NoError(t, err)
+	assert.Equal(t, ci.ID, successor.PredecessorID)
+	assert.Equal(t, []string{"chat", "search"}, successor.AllowedProjects)
+
+	stdout, _, err = captureOutput(t, func() error {
+		return runAPIKeyList(apiKeysListCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "rotating")
+
+	_, _, err = captureOutput(t, func() error {
+		return runAPIKeyRotate(apiKeysRotateCmd, nil)
+	})
+	assert.ErrorContains(t, err, "already rotated")
 
 	require.NoError(t, apiKeysRevokeCmd.Flags().Set("id", keys[0].ID))
 	stdout, _, err = captureOutput(t, func() error {
@@ -402,6 +578,309 @@ func TestRunTenantAndAPIKeyCommands(t *testing.T) {
 	})
 	require.NoError(t, err)
 	assert.Contains(t, stdout, "Tenant disabled: acme")
+
+	require.NoError(t, auditListCmd.Flags().Set("tenant", "acme"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAuditList(auditListCmd, nil)
+	})
+	require.NoError(t, err)
+	for _, action := range []string{"tenant.create", "api_key.create", "api_key.set_role", "api_key.rotate", "api_key.revoke", "tenant.disable"} {
+		assert.Contains(t, stdout, action)
+	}
+	assert.Contains(t, stdout, "os_user:")
+	assert.Contains(t, stdout, "cli")
+
+	require.NoError(t, auditListCmd.Flags().Set("action", "api_key.set_role"))
+	require.NoError(t, auditListCmd.Flags().Set("format", "json"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAuditList(auditListCmd, nil)
+	})
+	require.NoError(t, err)
+	var events []model.AuditEvent
+	require.NoError(t, json.Unmarshal([]byte(stdout), &events))
+	require.Len(t, events, 1)
+	assert.Equal(t, ci.ID, events[0].ResourceID)
+	assert.Contains(t, string(events[0].Before), `"role": "proxy-only"`)
+	assert.Contains(t, string(events[0].After), `"role": "finance-viewer"`)
+}
+
+func TestRunTenantLifecycleCommands(t *testing.T) {
+	resetCommandState()
+	cfgPath, dbPath := testCLIConfig(t)
+	cfgFile = cfgPath
+
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	require.NoError(t, db.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	require.NoError(t, db.DisableTenant(ctx, "acme"))
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{Tenant: "acme", Provider: "openai", Model: "gpt-4o", CostUSD: 1.25}))
+
+	require.NoError(t, tenantsEnableCmd.Flags().Set("slug", "acme"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runTenantEnable(tenantsEnableCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tenant enabled: acme")
+
+	require.NoError(t, tenantsRenameCmd.Flags().Set("slug", "acme"))
+	require.NoError(t, tenantsRenameCmd.Flags().Set("new-slug", "acme-corp"))
+	require.NoError(t, tenantsRenameCmd.Flags().Set("name", "Acme Corporation"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runTenantRename(tenantsRenameCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Slug:    acme-corp")
+	assert.Contains(t, stdout, "Name:    Acme Corporation")
+
+	exportDir := filepath.Join(t.TempDir(), "export")
+	require.NoError(t, tenantsExportCmd.Flags().Set("slug", "acme-corp"))
+	require.NoError(t, tenantsExportCmd.Flags().Set("format", "csv"))
+	require.NoError(t, tenantsExportCmd.Flags().Set("output", exportDir))
+	stdout, _, err = captureOutput(t, func() error {
+		return runTenantExport(tenantsExportCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "1 usage records")
+	assert.FileExists(t, filepath.Join(exportDir, "usage.csv"))
+
+	// Deleting needs confirmation, and the default tenant is protected.
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("slug", "acme-corp"))
+	_, _, err = captureOutput(t, func() error {
+		return runTenantDelete(tenantsDeleteCmd, nil)
+	})
+	assert.ErrorContains(t, err, "rerun with --yes")
+
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("slug", "default"))
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("no-export", "true"))
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("yes", "true"))
+	_, _, err = captureOutput(t, func() error {
+		return runTenantDelete(tenantsDeleteCmd, nil)
+	})
+	assert.ErrorContains(t, err, "cannot delete the default tenant")
+
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("slug", "acme-corp"))
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("no-export", "false"))
+	require.NoError(t, tenantsDeleteCmd.Flags().Set("output", bundleDir))
+	stdout, _, err = captureOutput(t, func() error {
+		return runTenantDelete(tenantsDeleteCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tenant deleted: acme-corp")
+	data, err := os.ReadFile(filepath.Join(bundleDir, "tenant.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"slug": "acme-corp"`)
+
+	_, err = db.GetTenant(ctx, "acme-corp")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	usage, err := db.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, usage)
+}
+
+func TestRunTenantSettingsCommands(t *testing.T) {
+	resetCommandState()
+	cfgPath, dbPath := testCLIConfig(t)
+	cfgFile = cfgPath
+
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	require.NoError(t, db.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("slug", "acme"))
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("deny-on-exceed", "false"))
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("alert-threshold", "60"))
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("webhook-url", "https://hooks.example.com/acme"))
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("webhook-secret", "s3cret"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runTenantSettingsSet(tenantsSettingsSetCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Tenant settings updated: acme")
+	assert.Regexp(t, `deny_on_exceed\s+false\s+tenant`, stdout)
+	assert.Regexp(t, `alert_threshold_pct\s+60\s+tenant`, stdout)
+	assert.Regexp(t, `alert_critical_pct\s+95\s+global`, stdout)
+	assert.Regexp(t, `webhook_secret\s+\[redacted\]\s+tenant`, stdout)
+	assert.NotContains(t, stdout, "s3cret")
+
+	settings, err := db.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", *settings.WebhookSecret)
+	assert.Nil(t, settings.AddCostHeaders, "flags that are not given are left unchanged")
+
+	// Budgets created without --alert-at take the tenant's threshold.
+	require.NoError(t, budgetSetCmd.Flags().Set("name", "acme-monthly"))
+	require.NoError(t, budgetSetCmd.Flags().Set("tenant", "acme"))
+	require.NoError(t, budgetSetCmd.Flags().Set("limit", "50"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runBudgetSet(budgetSetCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Alert at:  60%")
+
+	require.NoError(t, tenantsSettingsUnsetCmd.Flags().Set("slug", "acme"))
+	require.NoError(t, tenantsSettingsUnsetCmd.Flags().Set("settings", "deny_on_exceed, webhook_url"))
+	_, _, err = captureOutput(t, func() error {
+		return runTenantSettingsUnset(tenantsSettingsUnsetCmd, nil)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, tenantsSettingsShowCmd.Flags().Set("slug", "acme"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runTenantSettingsShow(tenantsSettingsShowCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `deny_on_exceed\s+\S+\s+global`, stdout)
+	assert.Regexp(t, `webhook_url\s+-\s+global`, stdout)
+
+	require.NoError(t, tenantsSettingsUnsetCmd.Flags().Set("settings", "max_body_size"))
+	_, _, err = captureOutput(t, func() error {
+		return runTenantSettingsUnset(tenantsSettingsUnsetCmd, nil)
+	})
+	assert.ErrorContains(t, err, "unset must only contain")
+}
+
+func TestRunAdminCommandsRemote(t *testing.T) {
+	resetCommandState()
+
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "remote.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	ctx := context.Background()
+	_, err = store.EnsureTenant(ctx, "default", "Default")
+	require.NoError(t, err)
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	require.NoError(t, err)
+	require.NoError(t, store.CreateAPIKey(ctx, &model.APIKey{
+		Tenant: "default", Name: "ops", KeyPrefix: prefix, KeyHash: hash,
+		Status: model.APIKeyStatusActive, Role: model.RoleOwner, AllTenants: true,
+	}))
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	apiServer := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).WithStore(store)
+	ts := httptest.NewServer(httpauth.New(store, true, "default", "", logger).Wrap(apiServer.Handler()))
+	t.Cleanup(ts.Close)
+
+	// No config file is needed: every change goes through the admin API.
+	serverURL = ts.URL
+	serverAPIKey = rawKey
+
+	require.NoError(t, tenantsCreateCmd.Flags().Set("slug", "acme"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runTenantCreate(tenantsCreateCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "acme")
+
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("tenant", "acme"))
+	require.NoError(t, apiKeysCreateCmd.Flags().Set("role", model.RoleFinanceViewer))
+	stdout, _, err = captureOutput(t, func() error {
+		return runAPIKeyCreate(apiKeysCreateCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "Role:      finance-viewer")
+	assert.Contains(t, stdout, "Raw Key:   lcg_")
+
+	require.NoError(t, budgetSetCmd.Flags().Set("name", "acme-monthly"))
+	require.NoError(t, budgetSetCmd.Flags().Set("tenant", "acme"))
+	require.NoError(t, budgetSetCmd.Flags().Set("limit", "50"))
+	_, _, err = captureOutput(t, func() error {
+		return runBudgetSet(budgetSetCmd, nil)
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, budgetStatusCmd.Flags().Set("tenant", "acme"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runBudgetStatus(budgetStatusCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "acme-monthly")
+
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("slug", "acme"))
+	require.NoError(t, tenantsSettingsSetCmd.Flags().Set("alert-critical", "90"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runTenantSettingsSet(tenantsSettingsSetCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `alert_critical_pct\s+90\s+tenant`, stdout)
+
+	keys, err := store.ListAPIKeys(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	budget, err := store.GetBudget(ctx, "acme-monthly")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", budget.Tenant)
+
+	// Validation errors come back from the server with the offending field.
+	resetFlags(budgetSetCmd)
+	require.NoError(t, budgetSetCmd.Flags().Set("limit", "-1"))
+	_, _, err = captureOutput(t, func() error {
+		return runBudgetSet(budgetSetCmd, nil)
+	})
+	assert.ErrorContains(t, err, "server returned 400")
+	assert.ErrorContains(t, err, "field limit_usd")
+
+	serverAPIKey = "lcg_invalid"
+	_, _, err = captureOutput(t, func() error {
+		return runTenantList(tenantsListCmd, nil)
+	})
+	assert.ErrorContains(t, err, "server returned 401")
+
+	// Commands that only work on the local database refuse --server.
+	assert.NoError(t, requireLocal(tenantsListCmd, nil))
+	assert.ErrorContains(t, requireLocal(reportCmd, nil), "does not support --server")
+}
+
+func TestRunModelPolicyCommands(t *testing.T) {
+	resetCommandState()
+	cfgPath, dbPath := testCLIConfig(t)
+	cfgFile = cfgPath
+
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("name", "mini-only"))
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("tenant", "acme"))
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("project", "chat"))
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("allow-models", "gpt-4o-mini*, claude-3-*-haiku*"))
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("max-tokens", "1024"))
+	require.NoError(t, modelPoliciesSetCmd.Flags().Set("deny-streaming", "true"))
+	stdout, _, err := captureOutput(t, func() error {
+		return runModelPolicySet(modelPoliciesSetCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, `Model policy "mini-only" set for tenant acme (project chat)`)
+
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	policies, err := db.ListModelPolicies(context.Background(), "acme")
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, []string{"gpt-4o-mini*", "claude-3-*-haiku*"}, policies[0].AllowedModels)
+	assert.True(t, policies[0].DenyStreaming)
+
+	stdout, _, err = captureOutput(t, func() error {
+		return runModelPolicyList(modelPoliciesListCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "mini-only")
+	assert.Contains(t, stdout, "gpt-4o-mini*,claude-3-*-haiku*")
+	assert.Contains(t, stdout, "denied")
+
+	require.NoError(t, modelPoliciesDeleteCmd.Flags().Set("name", "mini-only"))
+	require.NoError(t, modelPoliciesDeleteCmd.Flags().Set("tenant", "acme"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runModelPolicyDelete(modelPoliciesDeleteCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, `Model policy "mini-only" deleted`)
+
+	_, _, err = captureOutput(t, func() error {
+		return runModelPolicyDelete(modelPoliciesDeleteCmd, nil)
+	})
+	require.ErrorIs(t, err, storage.ErrNotFound)
 }
 
 func TestRunAnomaliesCommand(t *testing.T) {
@@ -496,4 +975,21 @@ func TestRunForecastRecommendAndPromptCommands(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, stdout, "assistant")
 	assert.Contains(t, stdout, "Reduce")
+
+	require.NoError(t, db.RecordUsage(context.Background(), &model.UsageRecord{
+		Tenant:     "default",
+		Provider:   "openai",
+		Model:      "gpt-4o",
+		Project:    "assistant",
+		StatusCode: 503,
+		ErrorType:  "server_error",
+		Timestamp:  time.Now().UTC().Add(-time.Hour),
+	}))
+	require.NoError(t, errorsCmd.Flags().Set("tenant", "default"))
+	stdout, _, err = captureOutput(t, func() error {
+		return runErrors(errorsCmd, nil)
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "assistant")
+	assert.Contains(t, stdout, "ERROR%")
 }
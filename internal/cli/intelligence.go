@@ -28,6 +28,12 @@ var recommendCmd = &cobra.Command{
 	RunE:  runRecommend,
 }
 
+var errorsCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "Show upstream error rates and wasted spend",
+	RunE:  runErrors,
+}
+
 var promptsCmd = &cobra.Command{
 	Use:   "prompts",
 	Short: "Prompt optimization insights",
@@ -43,10 +49,11 @@ func init() {
 	rootCmd.AddCommand(anomaliesCmd)
 	rootCmd.AddCommand(forecastCmd)
 	rootCmd.AddCommand(recommendCmd)
+	rootCmd.AddCommand(errorsCmd)
 	rootCmd.AddCommand(promptsCmd)
 	promptsCmd.AddCommand(promptsOptimizeCmd)
 
-	for _, cmd := range []*cobra.Command{anomaliesCmd, forecastCmd, recommendCmd, promptsOptimizeCmd} {
+	for _, cmd := range []*cobra.Command{anomaliesCmd, forecastCmd, recommendCmd, errorsCmd, promptsOptimizeCmd} {
 		cmd.Flags().String("tenant", "", "Filter by tenant (default from config)")
 		cmd.Flags().String("project", "", "Filter by project")
 		cmd.Flags().String("provider", "", "Filter by provider")
@@ -164,6 +171,42 @@ func runRecommend(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
+func runErrors(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	t, store, err := initTracker(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	filter := intelligenceFilter(cmd, cfg)
+
+	rates, err := t.ErrorRates(commandContext(cmd), filter)
+	if err != nil {
+		return err
+	}
+
+	if len(rates) == 0 {
+		fmt.Println("No upstream errors recorded.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TENANT\tPROJECT\tPROVIDER\tMODEL\tREQUESTS\tERRORS\tERROR%%\tWASTED\n")
+	for _, rate := range rates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%.1f%%\t$%.4f\n",
+			rate.Tenant, rate.Project, rate.Provider, rate.Model,
+			rate.RequestCount, rate.ErrorCount, rate.ErrorRate*100, rate.WastedCostUSD,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
 func runPromptOptimize(cmd *cobra.Command, _ []string) error {
 	cfg, err := loadConfig()
 	if err != nil {
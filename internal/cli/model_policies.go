@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+	"github.com/spf13/cobra"
+)
+
+var modelPoliciesCmd = &cobra.Command{
+	Use:   "model-policies",
+	Short: "Manage which providers and models tenants, projects, and API keys may call",
+}
+
+var modelPoliciesSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Create or replace a model policy",
+	RunE:  runModelPolicySet,
+}
+
+var modelPoliciesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List model policies",
+	RunE:  runModelPolicyList,
+}
+
+var modelPoliciesDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a model policy",
+	RunE:  runModelPolicyDelete,
+}
+
+func init() {
+	rootCmd.AddCommand(modelPoliciesCmd)
+	modelPoliciesCmd.AddCommand(modelPoliciesSetCmd)
+	modelPoliciesCmd.AddCommand(modelPoliciesListCmd)
+	modelPoliciesCmd.AddCommand(modelPoliciesDeleteCmd)
+
+	modelPoliciesSetCmd.Flags().String("name", "", "Policy name, unique per tenant")
+	modelPoliciesSetCmd.Flags().String("tenant", "", "Tenant slug (default from config)")
+	modelPoliciesSetCmd.Flags().String("project", "", "Apply only to this project (empty = every project)")
+	modelPoliciesSetCmd.Flags().String("api-key-id", "", "Apply only to this API key (empty = every key)")
+	modelPoliciesSetCmd.Flags().String("providers", "", "Allowed providers, comma-separated (empty = any)")
+	modelPoliciesSetCmd.Flags().String("allow-models", "", "Allowed model globs, comma-separated (empty = any)")
+	modelPoliciesSetCmd.Flags().String("deny-models", "", "Denied model globs, comma-separated")
+	modelPoliciesSetCmd.Flags().Int64("max-tokens", 0, "Maximum max_tokens a request may ask for (0 = no limit)")
+	modelPoliciesSetCmd.Flags().Bool("deny-streaming", false, "Reject streaming requests")
+	_ = modelPoliciesSetCmd.MarkFlagRequired("name")
+
+	modelPoliciesListCmd.Flags().String("tenant", "", "Tenant slug filter")
+
+	modelPoliciesDeleteCmd.Flags().String("name", "", "Policy name")
+	modelPoliciesDeleteCmd.Flags().String("tenant", "", "Tenant slug (default from config)")
+	_ = modelPoliciesDeleteCmd.MarkFlagRequired("name")
+}
+
+func runModelPolicySet(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	tenant, _ := cmd.Flags().GetString("tenant")
+	project, _ := cmd.Flags().GetString("project")
+	apiKeyID, _ := cmd.Flags().GetString("api-key-id")
+	providers, _ := cmd.Flags().GetString("providers")
+	allowModels, _ := cmd.Flags().GetString("allow-models")
+	denyModels, _ := cmd.Flags().GetString("deny-models")
+	maxTokens, _ := cmd.Flags().GetInt64("max-tokens")
+	denyStreaming, _ := cmd.Flags().GetBool("deny-streaming")
+
+	if tenant == "" {
+		tenant = cfg.Auth.DefaultTenant
+	}
+	if maxTokens < 0 {
+		return fmt.Errorf("max-tokens must not be negative")
+	}
+
+	modelPolicy := &model.ModelPolicy{
+		Tenant:           tenant,
+		Name:             name,
+		Project:          project,
+		APIKeyID:         apiKeyID,
+		AllowedProviders: splitFlagList(providers),
+		AllowedModels:    splitFlagList(allowModels),
+		DeniedModels:     splitFlagList(denyModels),
+		MaxTokens:        maxTokens,
+		DenyStreaming:    denyStreaming,
+	}
+	if _, err := policy.CompileModelPolicy(*modelPolicy); err != nil {
+		return err
+	}
+
+	_, store, err := initTracker(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.SetModelPolicy(commandContext(cmd), modelPolicy); err != nil {
+		return fmt.Errorf("set model policy: %w", err)
+	}
+
+	fmt.Printf("Model policy %q set for tenant %s (%s)\n", modelPolicy.Name, modelPolicy.Tenant, modelPolicyScope(modelPolicy))
+	return nil
+}
+
+func runModelPolicyList(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	tenant, _ := cmd.Flags().GetString("tenant")
+
+	_, store, err := initTracker(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	policies, err := store.ListModelPolicies(commandContext(cmd), tenant)
+	if err != nil {
+		return fmt.Errorf("list model policies: %w", err)
+	}
+	if len(policies) == 0 {
+		fmt.Println("No model policies configured.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "TENANT\tNAME\tSCOPE\tPROVIDERS\tALLOW\tDENY\tMAX TOKENS\tSTREAMING\n")
+	for i := range policies {
+		policy := &policies[i]
+		maxTokens := "-"
+		if policy.MaxTokens > 0 {
+			maxTokens = fmt.Sprintf("%d", policy.MaxTokens)
+		}
+		streaming := "allowed"
+		if policy.DenyStreaming {
+			streaming = "denied"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			policy.Tenant, policy.Name, modelPolicyScope(policy),
+			joinOr(policy.AllowedProviders, "any"), joinOr(policy.AllowedModels, "any"), joinOr(policy.DeniedModels, "-"),
+			maxTokens, streaming)
+	}
+	w.Flush()
+	return nil
+}
+
+func runModelPolicyDelete(cmd *cobra.Command, _ []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	tenant, _ := cmd.Flags().GetString("tenant")
+	if tenant == "" {
+		tenant = cfg.Auth.DefaultTenant
+	}
+
+	_, store, err := initTracker(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.DeleteModelPolicy(commandContext(cmd), tenant, name); err != nil {
+		return fmt.Errorf("delete model policy: %w", err)
+	}
+
+	fmt.Printf("Model policy %q deleted for tenant %s\n", name, tenant)
+	return nil
+}
+
+func modelPolicyScope(policy *model.ModelPolicy) string {
+	var scope []string
+	if policy.Project != "" {
+		scope = append(scope, "project "+policy.Project)
+	}
+	if policy.APIKeyID != "" {
+		scope = append(scope, "key "+policy.APIKeyID)
+	}
+	if len(scope) == 0 {
+		return "tenant-wide"
+	}
+	return strings.Join(scope, ", ")
+}
+
+// splitFlagList parses a comma-separated flag value, dropping blank entries.
+func splitFlagList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func joinOr(values []string, empty string) string {
+	if len(values) == 0 {
+		return empty
+	}
+	return strings.Join(values, ",")
+}
@@ -45,7 +45,7 @@ func runProxyStart(cmd *cobra.Command, _ []string) error {
 	}
 	defer service.Close()
 
-	ctx, stop := signal.NotifyContext(commandContext(cmd), syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(baseContext(cmd), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	return service.Run(ctx, func(addr string) {
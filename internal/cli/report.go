@@ -28,6 +28,11 @@ func init() {
 	reportCmd.Flags().StringP("provider", "p", "", "Filter by provider")
 	reportCmd.Flags().StringP("model", "m", "", "Filter by model")
 	reportCmd.Flags().String("project", "", "Filter by project")
+	reportCmd.Flags().String("status", "", "Filter by completion status (complete, client_aborted, upstream_error, timeout)")
+	reportCmd.Flags().String("user", "", "Filter by end user")
+	reportCmd.Flags().Int("top-users", 0, "Show the N end users with the highest spend")
+	reportCmd.Flags().String("tags", "", "Filter by tags (key=value,key=value)")
+	reportCmd.Flags().String("group-by-tag", "", "Break down cost by the values of a tag key")
 	reportCmd.Flags().Bool("detailed", false, "Show individual records")
 	reportCmd.Flags().String("format", "text", "Output format (text, csv, pdf)")
 	reportCmd.Flags().String("output", "", "Output file path for csv/pdf exports")
@@ -44,6 +49,11 @@ func runReport(cmd *cobra.Command, _ []string) error {
 	providerFilter, _ := cmd.Flags().GetString("provider")
 	modelFilter, _ := cmd.Flags().GetString("model")
 	projectFilter, _ := cmd.Flags().GetString("project")
+	statusFilter, _ := cmd.Flags().GetString("status")
+	userFilter, _ := cmd.Flags().GetString("user")
+	topUsers, _ := cmd.Flags().GetInt("top-users")
+	rawTags, _ := cmd.Flags().GetString("tags")
+	groupByTag, _ := cmd.Flags().GetString("group-by-tag")
 	detailed, _ := cmd.Flags().GetBool("detailed")
 	format, _ := cmd.Flags().GetString("format")
 	outputPath, _ := cmd.Flags().GetString("output")
@@ -51,6 +61,10 @@ func runReport(cmd *cobra.Command, _ []string) error {
 	if tenantFilter == "" {
 		tenantFilter = cfg.Auth.DefaultTenant
 	}
+	tags, err := tracker.ParseTags(rawTags)
+	if err != nil {
+		return fmt.Errorf("parse tags: %w", err)
+	}
 
 	t, store, err := initTracker(cfg)
 	if err != nil {
@@ -62,12 +76,16 @@ func runReport(cmd *cobra.Command, _ []string) error {
 	start, end := tracker.PeriodBounds(budgetPeriod)
 
 	filter := tracker.ReportFilter{
-		Tenant:    tenantFilter,
-		Provider:  providerFilter,
-		Model:     modelFilter,
-		Project:   projectFilter,
-		StartTime: start,
-		EndTime:   end,
+		Tenant:           tenantFilter,
+		Provider:         providerFilter,
+		Model:            modelFilter,
+		Project:          projectFilter,
+		StartTime:        start,
+		EndTime:          end,
+		CompletionStatus: statusFilter,
+		User:             userFilter,
+		Tags:             tags,
+		GroupByTag:       groupByTag,
 	}
 
 	summary, err := t.Report(commandContext(cmd), filter)
@@ -89,6 +107,18 @@ func runReport(cmd *cobra.Command, _ []string) error {
 		printCostMap("Provider", summary.ByProvider)
 		printCostMap("Model", summary.ByModel)
 		printCostMap("Project", summary.ByProject)
+		printCostMap("Completion Status", summary.ByCompletionStatus)
+		if groupByTag != "" {
+			printCostMap("Tag "+strings.ToLower(groupByTag), untaggedAsLabel(summary.ByTag))
+		}
+		printLatencyStats(summary.Latency)
+		if topUsers > 0 {
+			users, err := t.TopUsers(commandContext(cmd), filter, topUsers)
+			if err != nil {
+				return fmt.Errorf("query top users: %w", err)
+			}
+			printTopUsers(users)
+		}
 		if detailed {
 			printDetailedRecords(records)
 		}
@@ -109,6 +139,9 @@ func runReport(cmd *cobra.Command, _ []string) error {
 		Records:     records,
 		Chargebacks: reporting.BuildProjectChargebacks(records),
 	}
+	if groupByTag != "" {
+		doc.TagChargebacks = reporting.BuildTagChargebacks(records, groupByTag)
+	}
 
 	switch format {
 	case "csv":
@@ -134,6 +167,9 @@ func printTextSummary(summary *tracker.UsageSummary, period string, start, end t
 	fmt.Printf("Total Input Tokens:  %d\n", summary.TotalInputTokens)
 	fmt.Printf("Total Output Tokens: %d\n", summary.TotalOutputTokens)
 	fmt.Printf("Total Requests:      %d\n", summary.RecordCount)
+	if summary.DuplicateRequests > 0 {
+		fmt.Printf("Duplicate Retries:   %d ($%.4f)\n", summary.DuplicateRequests, summary.DuplicateCostUSD)
+	}
 }
 
 func printCostMap(title string, values map[string]float64) {
@@ -156,6 +192,62 @@ func printCostMap(title string, values map[string]float64) {
 	w.Flush()
 }
 
+// untaggedAsLabel renames the "" key used for spend without the grouped tag.
+func untaggedAsLabel(values map[string]float64) map[string]float64 {
+	if _, ok := values[""]; !ok {
+		return values
+	}
+	labeled := make(map[string]float64, len(values))
+	for key, value := range values {
+		if key == "" {
+			key = "(untagged)"
+		}
+		labeled[key] = value
+	}
+	return labeled
+}
+
+func printLatencyStats(stats []tracker.LatencyStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Printf("\nLatency:\n")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  PROVIDER\tMODEL\tREQUESTS\tP50\tP95\tP99\tTTFT P50\tTTFT P95\tTOK/S P50\n")
+	for _, s := range stats {
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%dms\t%dms\t%dms\t%s\t%s\t%.1f\n",
+			s.Provider, s.Model, s.RequestCount,
+			s.LatencyP50Ms, s.LatencyP95Ms, s.LatencyP99Ms,
+			formatOptionalMs(s.TTFTP50Ms), formatOptionalMs(s.TTFTP95Ms),
+			s.OutputTokensPerSecP50,
+		)
+	}
+	w.Flush()
+}
+
+func printTopUsers(users []tracker.UserSpend) {
+	fmt.Printf("\nTop Users:\n")
+	if len(users) == 0 {
+		fmt.Println("  No attributed usage.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  USER\tTENANT\tREQUESTS\tIN\tOUT\tCOST\n")
+	for _, u := range users {
+		fmt.Fprintf(w, "  %s\t%s\t%d\t%d\t%d\t$%.4f\n",
+			u.User, u.Tenant, u.RequestCount, u.InputTokens, u.OutputTokens, u.CostUSD)
+	}
+	w.Flush()
+}
+
+func formatOptionalMs(value int64) string {
+	if value == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", value)
+}
+
 func printDetailedRecords(records []tracker.UsageRecord) {
 	if len(records) == 0 {
 		return
@@ -163,13 +255,13 @@ func printDetailedRecords(records []tracker.UsageRecord) {
 
 	fmt.Printf("\nDetailed Records:\n")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "  TIMESTAMP\tPROVIDER\tMODEL\tIN\tOUT\tCOST\tPROJECT\n")
+	fmt.Fprintf(w, "  TIMESTAMP\tPROVIDER\tMODEL\tIN\tOUT\tCOST\tPROJECT\tUSER\n")
 	for _, r := range records {
-		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\t$%.6f\t%s\n",
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\t$%.6f\t%s\t%s\n",
 			r.Timestamp.Format("2006-01-02 15:04"),
 			r.Provider, r.Model,
 			r.InputTokens, r.OutputTokens,
-			r.CostUSD, r.Project,
+			r.CostUSD, r.Project, r.User,
 		)
 	}
 	w.Flush()
@@ -3,9 +3,11 @@ package cli
 import (
 	"context"
 	"os"
+	"os/user"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/bootstrap"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/config"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
@@ -23,7 +25,8 @@ var rootCmd = &cobra.Command{
 	Long: `LLM Cost Guardian tracks token usage and costs across multiple LLM providers.
 It provides a transparent proxy for automatic tracking, CLI for manual tracking,
 budget limits with alerts, and reporting capabilities.`,
-	SilenceUsage: true,
+	SilenceUsage:      true,
+	PersistentPreRunE: requireLocal,
 }
 
 // Execute runs the CLI.
@@ -42,13 +45,31 @@ func loadConfig() (*config.Config, error) {
 	return config.Load(cfgFile)
 }
 
+// commandContext returns the command's context, attributing storage changes to the local user.
 func commandContext(cmd *cobra.Command) context.Context {
+	return storage.WithActor(baseContext(cmd), cliActor(), model.AuditSourceCLI)
+}
+
+// baseContext returns the command's context without an actor, for long-running services whose
+// own changes are the system's.
+func baseContext(cmd *cobra.Command) context.Context {
 	if ctx := cmd.Context(); ctx != nil {
 		return ctx
 	}
 	return context.Background()
 }
 
+// cliActor names the operating system user running the CLI.
+func cliActor() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return "os_user:" + current.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return "os_user:" + name
+	}
+	return "os_user:unknown"
+}
+
 // initRegistry creates and populates a provider registry from pricing files.
 func initRegistry(cfg *config.Config) (*providers.Registry, error) {
 	return bootstrap.NewRegistry(cfg)
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/spf13/cobra"
+)
+
+var tenantsSettingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Manage per-tenant overrides of proxy behavior and alerting",
+}
+
+var tenantsSettingsShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show a tenant's effective settings and where each comes from",
+	RunE:  runTenantSettingsShow,
+}
+
+var tenantsSettingsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Override global settings for a tenant; only the given flags change",
+	RunE:  runTenantSettingsSet,
+}
+
+var tenantsSettingsUnsetCmd = &cobra.Command{
+	Use:   "unset",
+	Short: "Make tenant settings inherit the global configuration again",
+	RunE:  runTenantSettingsUnset,
+}
+
+func init() {
+	tenantsCmd.AddCommand(tenantsSettingsCmd)
+	tenantsSettingsCmd.AddCommand(tenantsSettingsShowCmd)
+	tenantsSettingsCmd.AddCommand(tenantsSettingsSetCmd)
+	tenantsSettingsCmd.AddCommand(tenantsSettingsUnsetCmd)
+
+	tenantsSettingsShowCmd.Flags().String("slug", "", "Tenant slug")
+	_ = tenantsSettingsShowCmd.MarkFlagRequired("slug")
+
+	tenantsSettingsSetCmd.Flags().String("slug", "", "Tenant slug")
+	tenantsSettingsSetCmd.Flags().Bool("deny-on-exceed", false, "Reject proxied requests once a budget is exceeded")
+	tenantsSettingsSetCmd.Flags().Bool("add-cost-headers", false, "Add cost headers to proxied responses")
+	tenantsSettingsSetCmd.Flags().String("default-project", "", "Project for proxied requests without X-LCG-Project")
+	tenantsSettingsSetCmd.Flags().Float64("alert-threshold", 0, "Alert threshold percentage for budgets created without one")
+	tenantsSettingsSetCmd.Flags().Float64("alert-critical", 0, "Budget usage percentage at which alerts become critical")
+	tenantsSettingsSetCmd.Flags().String("slack-webhook-url", "", "Slack incoming webhook URL for alerts (empty turns Slack alerts off)")
+	tenantsSettingsSetCmd.Flags().String("slack-channel", "", "Slack channel for alerts")
+	tenantsSettingsSetCmd.Flags().String("webhook-url", "", "Webhook URL for alerts (empty turns webhook alerts off)")
+	tenantsSettingsSetCmd.Flags().String("webhook-secret", "", "HMAC secret for signing webhook alerts")
+	_ = tenantsSettingsSetCmd.MarkFlagRequired("slug")
+
+	tenantsSettingsUnsetCmd.Flags().String("slug", "", "Tenant slug")
+	tenantsSettingsUnsetCmd.Flags().String("settings", "", "Settings to unset, comma-separated ("+strings.Join(model.TenantSettingNames, ", ")+")")
+	_ = tenantsSettingsUnsetCmd.MarkFlagRequired("slug")
+	_ = tenantsSettingsUnsetCmd.MarkFlagRequired("settings")
+}
+
+func runTenantSettingsShow(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+
+	admin, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	settings, err := admin.GetTenantSettings(commandContext(cmd), slug)
+	if err != nil {
+		return fmt.Errorf("get tenant settings: %w", err)
+	}
+	return printTenantSettings(settings)
+}
+
+func runTenantSettingsSet(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+	flags := cmd.Flags()
+
+	var req adminapi.TenantSettingsRequest
+	if flags.Changed("deny-on-exceed") {
+		value, _ := flags.GetBool("deny-on-exceed")
+		req.DenyOnExceed = &value
+	}
+	if flags.Changed("add-cost-headers") {
+		value, _ := flags.GetBool("add-cost-headers")
+		req.AddCostHeaders = &value
+	}
+	for flag, dst := range map[string]**string{
+		"default-project":   &req.DefaultProject,
+		"slack-webhook-url": &req.SlackWebhookURL,
+		"slack-channel":     &req.SlackChannel,
+		"webhook-url":       &req.WebhookURL,
+		"webhook-secret":    &req.WebhookSecret,
+	} {
+		if flags.Changed(flag) {
+			value, _ := flags.GetString(flag)
+			*dst = &value
+		}
+	}
+	for flag, dst := range map[string]**float64{
+		"alert-threshold": &req.AlertThresholdPct,
+		"alert-critical":  &req.AlertCriticalPct,
+	} {
+		if flags.Changed(flag) {
+			value, _ := flags.GetFloat64(flag)
+			*dst = &value
+		}
+	}
+	return updateTenantSettings(cmd, slug, req)
+}
+
+func runTenantSettingsUnset(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+	names, _ := cmd.Flags().GetString("settings")
+
+	var req adminapi.TenantSettingsRequest
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			req.Unset = append(req.Unset, name)
+		}
+	}
+	return updateTenantSettings(cmd, slug, req)
+}
+
+func updateTenantSettings(cmd *cobra.Command, slug string, req adminapi.TenantSettingsRequest) error {
+	admin, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	settings, err := admin.UpdateTenantSettings(commandContext(cmd), slug, req)
+	if err != nil {
+		return fmt.Errorf("update tenant settings: %w", err)
+	}
+	fmt.Printf("Tenant settings updated: %s\n\n", slug)
+	return printTenantSettings(settings)
+}
+
+// printTenantSettings lists every setting's effective value and whether the tenant overrides
+// it or inherits the global configuration.
+func printTenantSettings(settings *adminapi.TenantSettingsResponse) error {
+	overrides, err := jsonFields(settings.Settings)
+	if err != nil {
+		return err
+	}
+	effective, err := jsonFields(settings.Effective)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SETTING\tVALUE\tSOURCE\n")
+	for _, name := range model.TenantSettingNames {
+		value := fmt.Sprint(effective[name])
+		if value == "" {
+			value = "-"
+		}
+		source := "global"
+		if _, ok := overrides[name]; ok {
+			source = "tenant"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, value, source)
+	}
+	return w.Flush()
+}
+
+func jsonFields(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
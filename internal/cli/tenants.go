@@ -5,13 +5,16 @@ import (
 	"os"
 	"text/tabwriter"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/reporting"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/spf13/cobra"
 )
 
 var tenantsCmd = &cobra.Command{
-	Use:   "tenants",
-	Short: "Manage tenants",
+	Use:         "tenants",
+	Short:       "Manage tenants",
+	Annotations: map[string]string{remoteAnnotation: "true"},
 }
 
 var tenantsCreateCmd = &cobra.Command{
@@ -32,11 +35,39 @@ var tenantsDisableCmd = &cobra.Command{
 	RunE:  runTenantDisable,
 }
 
+var tenantsEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Re-enable a disabled tenant",
+	RunE:  runTenantEnable,
+}
+
+var tenantsRenameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "Change a tenant's slug or display name",
+	RunE:  runTenantRename,
+}
+
+var tenantsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a tenant's usage, budgets, API keys, and model policies",
+	RunE:  runTenantExport,
+}
+
+var tenantsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Export a tenant, then delete it and purge all of its data",
+	RunE:  runTenantDelete,
+}
+
 func init() {
 	rootCmd.AddCommand(tenantsCmd)
 	tenantsCmd.AddCommand(tenantsCreateCmd)
 	tenantsCmd.AddCommand(tenantsListCmd)
 	tenantsCmd.AddCommand(tenantsDisableCmd)
+	tenantsCmd.AddCommand(tenantsEnableCmd)
+	tenantsCmd.AddCommand(tenantsRenameCmd)
+	tenantsCmd.AddCommand(tenantsExportCmd)
+	tenantsCmd.AddCommand(tenantsDeleteCmd)
 
 	tenantsCreateCmd.Flags().String("slug", "", "Tenant slug")
 	tenantsCreateCmd.Flags().String("name", "", "Tenant display name")
@@ -44,29 +75,40 @@ func init() {
 
 	tenantsDisableCmd.Flags().String("slug", "", "Tenant slug")
 	_ = tenantsDisableCmd.MarkFlagRequired("slug")
+
+	tenantsEnableCmd.Flags().String("slug", "", "Tenant slug")
+	_ = tenantsEnableCmd.MarkFlagRequired("slug")
+
+	tenantsRenameCmd.Flags().String("slug", "", "Tenant slug")
+	tenantsRenameCmd.Flags().String("new-slug", "", "New tenant slug")
+	tenantsRenameCmd.Flags().String("name", "", "New tenant display name")
+	_ = tenantsRenameCmd.MarkFlagRequired("slug")
+
+	tenantsExportCmd.Flags().String("slug", "", "Tenant slug")
+	tenantsExportCmd.Flags().String("format", "json", "Export format (json, csv)")
+	tenantsExportCmd.Flags().String("output", "", "Output directory (default output/tenants/<slug>-<timestamp>)")
+	_ = tenantsExportCmd.MarkFlagRequired("slug")
+
+	tenantsDeleteCmd.Flags().String("slug", "", "Tenant slug")
+	tenantsDeleteCmd.Flags().String("format", "json", "Export format written before deleting (json, csv)")
+	tenantsDeleteCmd.Flags().String("output", "", "Export directory (default output/tenants/<slug>-<timestamp>)")
+	tenantsDeleteCmd.Flags().Bool("no-export", false, "Delete without writing an export first")
+	tenantsDeleteCmd.Flags().Bool("yes", false, "Confirm the deletion; it cannot be undone")
+	_ = tenantsDeleteCmd.MarkFlagRequired("slug")
 }
 
 func runTenantCreate(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
-	}
-
 	slug, _ := cmd.Flags().GetString("slug")
 	name, _ := cmd.Flags().GetString("name")
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	tenant := &model.Tenant{
-		Slug:   slug,
-		Name:   name,
-		Status: model.TenantStatusActive,
-	}
-	if err := store.CreateTenant(commandContext(cmd), tenant); err != nil {
+	tenant, err := admin.CreateTenant(commandContext(cmd), adminapi.CreateTenantRequest{Slug: slug, Name: name})
+	if err != nil {
 		return fmt.Errorf("create tenant: %w", err)
 	}
 
@@ -78,18 +120,13 @@ func runTenantCreate(cmd *cobra.Command, _ []string) error {
 }
 
 func runTenantList(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
-	if err != nil {
-		return err
-	}
-
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	tenants, err := store.ListTenants(commandContext(cmd))
+	tenants, err := admin.ListTenants(commandContext(cmd))
 	if err != nil {
 		return fmt.Errorf("list tenants: %w", err)
 	}
@@ -104,23 +141,127 @@ func runTenantList(cmd *cobra.Command, _ []string) error {
 }
 
 func runTenantDisable(cmd *cobra.Command, _ []string) error {
-	cfg, err := loadConfig()
+	slug, _ := cmd.Flags().GetString("slug")
+	if err := updateTenant(cmd, slug, adminapi.UpdateTenantRequest{Status: model.TenantStatusDisabled}); err != nil {
+		return fmt.Errorf("disable tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant disabled: %s\n", slug)
+	return nil
+}
+
+func runTenantEnable(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+	if err := updateTenant(cmd, slug, adminapi.UpdateTenantRequest{Status: model.TenantStatusActive}); err != nil {
+		return fmt.Errorf("enable tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant enabled: %s\n", slug)
+	return nil
+}
+
+func updateTenant(cmd *cobra.Command, slug string, req adminapi.UpdateTenantRequest) error {
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
+	defer admin.Close()
+
+	_, err = admin.UpdateTenant(commandContext(cmd), slug, req)
+	return err
+}
 
+func runTenantRename(cmd *cobra.Command, _ []string) error {
 	slug, _ := cmd.Flags().GetString("slug")
+	newSlug, _ := cmd.Flags().GetString("new-slug")
+	name, _ := cmd.Flags().GetString("name")
+	if newSlug == "" && name == "" {
+		return fmt.Errorf("set --new-slug, --name, or both")
+	}
 
-	_, store, err := initTracker(cfg)
+	admin, err := openAdmin()
 	if err != nil {
 		return err
 	}
-	defer store.Close()
+	defer admin.Close()
 
-	if err := store.DisableTenant(commandContext(cmd), slug); err != nil {
-		return fmt.Errorf("disable tenant: %w", err)
+	tenant, err := admin.UpdateTenant(commandContext(cmd), slug, adminapi.UpdateTenantRequest{Slug: newSlug, Name: name})
+	if err != nil {
+		return fmt.Errorf("rename tenant: %w", err)
 	}
 
-	fmt.Printf("Tenant disabled: %s\n", slug)
+	fmt.Printf("Tenant renamed:\n")
+	fmt.Printf("  Slug:    %s\n", tenant.Slug)
+	fmt.Printf("  Name:    %s\n", tenant.Name)
+	return nil
+}
+
+func runTenantExport(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("invalid format %q: must be json or csv", format)
+	}
+
+	admin, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	return exportTenant(cmd, admin, slug, format, output)
+}
+
+func runTenantDelete(cmd *cobra.Command, _ []string) error {
+	slug, _ := cmd.Flags().GetString("slug")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+	noExport, _ := cmd.Flags().GetBool("no-export")
+	confirmed, _ := cmd.Flags().GetBool("yes")
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("invalid format %q: must be json or csv", format)
+	}
+	if !confirmed {
+		return fmt.Errorf("deleting tenant %q purges all of its data; rerun with --yes to confirm", slug)
+	}
+
+	admin, err := openAdmin()
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+
+	if !noExport {
+		if err := exportTenant(cmd, admin, slug, format, output); err != nil {
+			return err
+		}
+	}
+	if err := admin.DeleteTenant(commandContext(cmd), slug); err != nil {
+		return fmt.Errorf("delete tenant: %w", err)
+	}
+
+	fmt.Printf("Tenant deleted: %s\n", slug)
+	return nil
+}
+
+func exportTenant(cmd *cobra.Command, admin adminBackend, slug, format, output string) error {
+	export, err := admin.ExportTenant(commandContext(cmd), slug)
+	if err != nil {
+		return fmt.Errorf("export tenant: %w", err)
+	}
+	if output == "" {
+		output = reporting.DefaultTenantExportDir(export.Tenant.Slug)
+	}
+	files, err := reporting.WriteTenantExport(output, export, format)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tenant %s exported: %d usage records, %d budgets, %d API keys, %d model policies\n",
+		export.Tenant.Slug, len(export.Usage), len(export.Budgets), len(export.APIKeys), len(export.ModelPolicies))
+	for _, file := range files {
+		fmt.Printf("  %s\n", file)
+	}
 	return nil
 }
@@ -18,34 +18,144 @@ type Config struct {
 	Pricing  PricingConfig  `mapstructure:"pricing"`
 	Logging  LoggingConfig  `mapstructure:"logging"`
 	Defaults DefaultsConfig `mapstructure:"defaults"`
+	Capture  CaptureConfig  `mapstructure:"capture"`
 }
 
 // StorageConfig defines database settings.
 type StorageConfig struct {
-	Path string `mapstructure:"path"`
+	Path   string       `mapstructure:"path"`
+	Writer WriterConfig `mapstructure:"writer"`
+}
+
+// WriterConfig defines the asynchronous usage write pipeline used by the proxy.
+type WriterConfig struct {
+	Async         bool   `mapstructure:"async"`
+	QueueSize     int    `mapstructure:"queue_size"`
+	Workers       int    `mapstructure:"workers"`
+	BatchSize     int    `mapstructure:"batch_size"`
+	FlushInterval string `mapstructure:"flush_interval"`
+	WALDir        string `mapstructure:"wal_dir"`
+	// WALSync fsyncs the WAL after every record instead of once per flush interval.
+	WALSync bool `mapstructure:"wal_sync"`
 }
 
 // ProxyConfig defines transparent proxy settings.
 type ProxyConfig struct {
-	Listen         string `mapstructure:"listen"`
-	ReadTimeout    string `mapstructure:"read_timeout"`
-	WriteTimeout   string `mapstructure:"write_timeout"`
-	MaxBodySize    int64  `mapstructure:"max_body_size"`
-	DenyOnExceed   bool   `mapstructure:"deny_on_exceed"`
-	AddCostHeaders bool   `mapstructure:"add_cost_headers"`
+	Listen                     string          `mapstructure:"listen"`
+	ReadTimeout                string          `mapstructure:"read_timeout"`
+	WriteTimeout               string          `mapstructure:"write_timeout"`
+	MaxBodySize                int64           `mapstructure:"max_body_size"`
+	DenyOnExceed               bool            `mapstructure:"deny_on_exceed"`
+	AddCostHeaders             bool            `mapstructure:"add_cost_headers"`
+	CancelUpstreamOnDisconnect bool            `mapstructure:"cancel_upstream_on_disconnect"`
+	DisconnectDrainTimeout     string          `mapstructure:"disconnect_drain_timeout"`
+	InjectStreamUsage          bool            `mapstructure:"inject_stream_usage"`
+	WebSocketUsage             string          `mapstructure:"websocket_usage"`
+	IdempotencyWindow          string          `mapstructure:"idempotency_window"`
+	EndUser                    EndUserConfig   `mapstructure:"end_user"`
+	Policy                     PolicyConfig    `mapstructure:"policy"`
+	Transform                  TransformConfig `mapstructure:"transform"`
+}
+
+// EndUserConfig defines how the proxy verifies end-user JWTs for usage attribution.
+type EndUserConfig struct {
+	TokenHeader      string `mapstructure:"token_header"`
+	Claim            string `mapstructure:"claim"`
+	JWTSecret        string `mapstructure:"jwt_secret"`
+	JWTPublicKeyFile string `mapstructure:"jwt_public_key_file"`
+	JWTIssuer        string `mapstructure:"jwt_issuer"`
+	JWTAudience      string `mapstructure:"jwt_audience"`
+}
+
+// CaptureConfig defines opt-in capture of redacted, encrypted prompts and completions for audit.
+type CaptureConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Projects       []string `mapstructure:"projects"`
+	EncryptionKey  string   `mapstructure:"encryption_key"`
+	Retention      string   `mapstructure:"retention"`
+	PurgeInterval  string   `mapstructure:"purge_interval"`
+	MaxBytes       int      `mapstructure:"max_bytes"`
+	RedactPatterns []string `mapstructure:"redact_patterns"`
+}
+
+// PolicyConfig defines the secret and PII policy applied to requests before they are forwarded.
+type PolicyConfig struct {
+	Enabled       bool                  `mapstructure:"enabled"`
+	DefaultAction string                `mapstructure:"default_action"`
+	DetectPII     bool                  `mapstructure:"detect_pii"`
+	Projects      []ProjectPolicyConfig `mapstructure:"projects"`
+	Patterns      []PolicyPatternConfig `mapstructure:"patterns"`
+}
+
+// ProjectPolicyConfig overrides the policy action for one project.
+type ProjectPolicyConfig struct {
+	Project string `mapstructure:"project"`
+	Action  string `mapstructure:"action"`
+}
+
+// PolicyPatternConfig is a named regular expression detected as PII.
+type PolicyPatternConfig struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// TransformConfig defines the request rewrites that cap output and prompt sizes.
+type TransformConfig struct {
+	Enabled         bool                     `mapstructure:"enabled"`
+	MaxTokens       int64                    `mapstructure:"max_tokens"`
+	MaxPromptTokens int64                    `mapstructure:"max_prompt_tokens"`
+	Projects        []ProjectTransformConfig `mapstructure:"projects"`
+}
+
+// ProjectTransformConfig replaces the default transform limits for one project.
+type ProjectTransformConfig struct {
+	Project         string `mapstructure:"project"`
+	MaxTokens       int64  `mapstructure:"max_tokens"`
+	MaxPromptTokens int64  `mapstructure:"max_prompt_tokens"`
 }
 
 // AuthConfig defines tenant auth settings.
 type AuthConfig struct {
-	MultiTenantEnabled bool   `mapstructure:"multi_tenant_enabled"`
-	DefaultTenant      string `mapstructure:"default_tenant"`
-	BootstrapAdminKey  string `mapstructure:"bootstrap_admin_key"`
+	MultiTenantEnabled bool       `mapstructure:"multi_tenant_enabled"`
+	DefaultTenant      string     `mapstructure:"default_tenant"`
+	BootstrapAdminKey  string     `mapstructure:"bootstrap_admin_key"`
+	OIDC               OIDCConfig `mapstructure:"oidc"`
+}
+
+// OIDCConfig defines how OIDC-issued JWTs authenticate dashboard and API users.
+type OIDCConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Issuer      string `mapstructure:"issuer"`
+	Audience    string `mapstructure:"audience"`
+	JWKSFile    string `mapstructure:"jwks_file"`
+	JWKSURL     string `mapstructure:"jwks_url"`
+	JWKSRefresh string `mapstructure:"jwks_refresh"`
+	TenantClaim string `mapstructure:"tenant_claim"`
+	UserClaim   string `mapstructure:"user_claim"`
+	RoleClaim   string `mapstructure:"role_claim"`
+	DefaultRole string `mapstructure:"default_role"`
+	// RoleMapping translates role claim values, such as identity provider group names, to roles.
+	RoleMapping []RoleMappingConfig `mapstructure:"role_mapping"`
+	// AllowWildcardTenant accepts a tenant claim of "*" as access to every tenant.
+	AllowWildcardTenant bool `mapstructure:"allow_wildcard_tenant"`
+	// AllowDefaultTenant admits tokens without a tenant claim to auth.default_tenant.
+	AllowDefaultTenant bool `mapstructure:"allow_default_tenant"`
+}
+
+// RoleMappingConfig maps one role claim value to a role.
+type RoleMappingConfig struct {
+	Value string `mapstructure:"value"`
+	Role  string `mapstructure:"role"`
 }
 
-// AlertsConfig defines alerting integrations.
+// AlertsConfig defines alerting integrations and thresholds.
 type AlertsConfig struct {
 	Slack   SlackConfig   `mapstructure:"slack"`
 	Webhook WebhookConfig `mapstructure:"webhook"`
+	// ThresholdPct is the alert threshold given to budgets created without one.
+	ThresholdPct float64 `mapstructure:"threshold_pct"`
+	// CriticalPct is the budget usage at which alerts become critical.
+	CriticalPct float64 `mapstructure:"critical_pct"`
 }
 
 // SlackConfig defines Slack webhook settings.
@@ -99,19 +209,60 @@ func Load(cfgFile string) (*Config, error) {
 	// Defaults
 	home, _ := os.UserHomeDir()
 	v.SetDefault("storage.path", filepath.Join(home, ".lcg", "guardian.db"))
+	v.SetDefault("storage.writer.async", true)
+	v.SetDefault("storage.writer.queue_size", 10000)
+	v.SetDefault("storage.writer.workers", 2)
+	v.SetDefault("storage.writer.batch_size", 200)
+	v.SetDefault("storage.writer.flush_interval", "1s")
+	v.SetDefault("storage.writer.wal_dir", "")
+	v.SetDefault("storage.writer.wal_sync", false)
 	v.SetDefault("proxy.listen", ":8080")
 	v.SetDefault("proxy.read_timeout", "30s")
 	v.SetDefault("proxy.write_timeout", "60s")
 	v.SetDefault("proxy.max_body_size", 10*1024*1024) // 10 MB
 	v.SetDefault("proxy.deny_on_exceed", false)
 	v.SetDefault("proxy.add_cost_headers", true)
+	v.SetDefault("proxy.cancel_upstream_on_disconnect", true)
+	v.SetDefault("proxy.disconnect_drain_timeout", "2m")
+	v.SetDefault("proxy.inject_stream_usage", false)
+	v.SetDefault("proxy.websocket_usage", "response")
+	v.SetDefault("proxy.idempotency_window", "10m")
+	v.SetDefault("proxy.end_user.token_header", "X-LCG-User-Token")
+	v.SetDefault("proxy.end_user.claim", "sub")
+	v.SetDefault("proxy.end_user.jwt_secret", "")
+	v.SetDefault("proxy.end_user.jwt_public_key_file", "")
+	v.SetDefault("proxy.end_user.jwt_issuer", "")
+	v.SetDefault("proxy.end_user.jwt_audience", "")
+	v.SetDefault("proxy.policy.enabled", false)
+	v.SetDefault("proxy.policy.default_action", "flag")
+	v.SetDefault("proxy.policy.detect_pii", true)
+	v.SetDefault("proxy.transform.enabled", false)
+	v.SetDefault("proxy.transform.max_tokens", 0)
+	v.SetDefault("proxy.transform.max_prompt_tokens", 0)
 	v.SetDefault("auth.multi_tenant_enabled", false)
 	v.SetDefault("auth.default_tenant", "default")
+	v.SetDefault("auth.oidc.enabled", false)
+	v.SetDefault("auth.oidc.jwks_refresh", "1h")
+	v.SetDefault("auth.oidc.tenant_claim", "tenant")
+	v.SetDefault("auth.oidc.user_claim", "sub")
+	v.SetDefault("auth.oidc.role_claim", "role")
+	v.SetDefault("auth.oidc.default_role", "")
+	v.SetDefault("auth.oidc.allow_wildcard_tenant", false)
+	v.SetDefault("auth.oidc.allow_default_tenant", false)
 	v.SetDefault("pricing.dir", "pricing/")
 	v.SetDefault("logging.level", "info")
 	v.SetDefault("logging.format", "json")
 	v.SetDefault("defaults.project", "default")
 	v.SetDefault("alerts.slack.channel", "#llm-costs")
+	v.SetDefault("alerts.threshold_pct", 80)
+	v.SetDefault("alerts.critical_pct", 95)
+	v.SetDefault("capture.enabled", false)
+	v.SetDefault("capture.projects", []string{})
+	v.SetDefault("capture.encryption_key", "")
+	v.SetDefault("capture.retention", "720h")
+	v.SetDefault("capture.purge_interval", "1h")
+	v.SetDefault("capture.max_bytes", 256*1024)
+	v.SetDefault("capture.redact_patterns", []string{})
 
 	// Environment variables
 	v.SetEnvPrefix("LCG")
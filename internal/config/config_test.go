@@ -14,15 +14,43 @@ func TestLoad_Defaults(t *testing.T) {
 	cfg, err := config.Load("")
 	require.NoError(t, err)
 
+	assert.True(t, cfg.Storage.Writer.Async)
+	assert.Equal(t, 10000, cfg.Storage.Writer.QueueSize)
+	assert.Equal(t, "1s", cfg.Storage.Writer.FlushInterval)
 	assert.Equal(t, ":8080", cfg.Proxy.Listen)
 	assert.Equal(t, "30s", cfg.Proxy.ReadTimeout)
 	assert.Equal(t, "60s", cfg.Proxy.WriteTimeout)
 	assert.True(t, cfg.Proxy.AddCostHeaders)
 	assert.False(t, cfg.Proxy.DenyOnExceed)
+	assert.True(t, cfg.Proxy.CancelUpstreamOnDisconnect)
+	assert.Equal(t, "2m", cfg.Proxy.DisconnectDrainTimeout)
+	assert.False(t, cfg.Proxy.InjectStreamUsage)
+	assert.Equal(t, "response", cfg.Proxy.WebSocketUsage)
+	assert.Equal(t, "10m", cfg.Proxy.IdempotencyWindow)
+	assert.Equal(t, "X-LCG-User-Token", cfg.Proxy.EndUser.TokenHeader)
+	assert.Equal(t, "sub", cfg.Proxy.EndUser.Claim)
+	assert.False(t, cfg.Proxy.Policy.Enabled)
+	assert.Equal(t, "flag", cfg.Proxy.Policy.DefaultAction)
+	assert.True(t, cfg.Proxy.Policy.DetectPII)
+	assert.False(t, cfg.Proxy.Transform.Enabled)
+	assert.Zero(t, cfg.Proxy.Transform.MaxTokens)
+	assert.Zero(t, cfg.Proxy.Transform.MaxPromptTokens)
+	assert.False(t, cfg.Auth.OIDC.Enabled)
+	assert.Equal(t, "1h", cfg.Auth.OIDC.JWKSRefresh)
+	assert.Equal(t, "tenant", cfg.Auth.OIDC.TenantClaim)
+	assert.Equal(t, "sub", cfg.Auth.OIDC.UserClaim)
+	assert.Equal(t, "role", cfg.Auth.OIDC.RoleClaim)
+	assert.Empty(t, cfg.Auth.OIDC.DefaultRole)
 	assert.Equal(t, "info", cfg.Logging.Level)
 	assert.Equal(t, "json", cfg.Logging.Format)
 	assert.Equal(t, "default", cfg.Defaults.Project)
+	assert.Equal(t, 80.0, cfg.Alerts.ThresholdPct)
+	assert.Equal(t, 95.0, cfg.Alerts.CriticalPct)
 	assert.Equal(t, "pricing/", cfg.Pricing.Dir)
+	assert.False(t, cfg.Capture.Enabled)
+	assert.Equal(t, "720h", cfg.Capture.Retention)
+	assert.Equal(t, "1h", cfg.Capture.PurgeInterval)
+	assert.Equal(t, 256*1024, cfg.Capture.MaxBytes)
 }
 
 func TestLoad_FromFile(t *testing.T) {
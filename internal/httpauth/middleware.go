@@ -3,9 +3,12 @@ package httpauth
 import (
 	"context"
 	"crypto/subtle"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
@@ -16,11 +19,61 @@ type contextKey string
 
 const identityKey contextKey = "lcg.identity"
 
-// Identity captures the authenticated tenant and access mode for a request.
+var (
+	errExpiredKey     = errors.New("api key expired")
+	errInactiveTenant = errors.New("tenant is not active")
+)
+
+// Identity captures the authenticated tenant and role for a request.
 type Identity struct {
 	Tenant model.Tenant
 	APIKey *model.APIKey
-	Admin  bool
+	// Role grants permissions inside Tenant; an empty role acts as model.RoleDeveloper.
+	Role string
+	// AllTenants extends Role to every tenant.
+	AllTenants bool
+	// User is the subject of a verified JWT; it is empty for API keys.
+	User string
+}
+
+// Actor names who is acting, for audit logs: the JWT user, the API key, or the tenant for the
+// bootstrap key and deployments without auth.
+func (i Identity) Actor() string {
+	switch {
+	case i.User != "":
+		return "user:" + i.User
+	case i.APIKey != nil:
+		return "api_key:" + i.APIKey.ID
+	default:
+		return "tenant:" + i.Tenant.Slug
+	}
+}
+
+// ClaimMapping names the JWT claims that carry a user's tenant, ID, and role. Claim names are
+// dot-separated paths such as "org.tenant".
+type ClaimMapping struct {
+	TenantClaim string
+	UserClaim   string
+	RoleClaim   string
+	// DefaultRole applies when no role claim value maps to a role; empty refuses such tokens.
+	DefaultRole string
+	// Roles maps role claim values, such as group names, to roles. Values that already name a
+	// role map to themselves.
+	Roles map[string]string
+	// AllowWildcardTenant accepts a tenant claim of "*", which grants the role in every tenant.
+	AllowWildcardTenant bool
+	// AllowDefaultTenant admits tokens without a tenant claim to the default tenant. Otherwise
+	// they are refused.
+	AllowDefaultTenant bool
+}
+
+// Can reports whether the identity's role grants permission.
+func (i Identity) Can(permission model.Permission) bool {
+	role := i.Role
+	if role == "" {
+		role = model.RoleDeveloper
+	}
+	return model.RoleAllows(role, permission)
 }
 
 // Middleware authenticates HTTP requests and injects tenant identity into context.
@@ -30,6 +83,8 @@ type Middleware struct {
 	defaultTenant string
 	bootstrapKey  string
 	logger        *slog.Logger
+	jwt           *keyauth.JWTVerifier
+	claims        ClaimMapping
 }
 
 // New creates a tenant auth middleware.
@@ -46,20 +101,43 @@ func New(store storage.Storage, enabled bool, defaultTenant, bootstrapKey string
 	}
 }
 
-// Wrap applies tenant authentication to a handler.
+// WithJWT makes the middleware accept bearer JWTs verified by verifier, such as OIDC ID or
+// access tokens, alongside API keys. claims selects the tenant, user, and role of each token.
+func (m *Middleware) WithJWT(verifier *keyauth.JWTVerifier, claims ClaimMapping) *Middleware {
+	m.jwt = verifier
+	m.claims = claims
+	return m
+}
+
+// Wrap applies tenant authentication to a handler. Handlers check the identity's permissions.
 func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return m.wrap(next, "")
+}
+
+// WrapProxy applies tenant authentication to the proxy and refuses roles without proxy access.
+func (m *Middleware) WrapProxy(next http.Handler) http.Handler {
+	return m.wrap(next, model.PermProxy)
+}
+
+func (m *Middleware) wrap(next http.Handler, required model.Permission) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		identity, err := m.authenticate(r)
 		if err != nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
+		if required != "" && !identity.Can(required) {
+			http.Error(w, "forbidden: role "+identity.Role+" lacks "+string(required)+" permission", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
 	})
 }
 
-// WithIdentity stores identity in a context.
+// WithIdentity stores identity in a context, and attributes storage changes made with it to
+// the identity's actor.
 func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	ctx = storage.WithActor(ctx, identity.Actor(), model.AuditSourceAPI)
 	return context.WithValue(ctx, identityKey, identity)
 }
 
@@ -75,7 +153,7 @@ func (m *Middleware) authenticate(r *http.Request) (Identity, error) {
 		if err != nil {
 			return Identity{}, err
 		}
-		return Identity{Tenant: *tenant, Admin: true}, nil
+		return Identity{Tenant: *tenant, Role: model.RoleOwner, AllTenants: true}, nil
 	}
 
 	rawKey := keyauth.ExtractAPIKey(r)
@@ -88,7 +166,15 @@ func (m *Middleware) authenticate(r *http.Request) (Identity, error) {
 		if err != nil {
 			return Identity{}, err
 		}
-		return Identity{Tenant: *tenant, Admin: true}, nil
+		return Identity{Tenant: *tenant, Role: model.RoleOwner, AllTenants: true}, nil
+	}
+
+	if m.jwt != nil && looksLikeJWT(rawKey) {
+		identity, err := m.authenticateJWT(r.Context(), rawKey)
+		if err != nil && m.logger != nil {
+			m.logger.Warn("jwt authentication failed", "error", err)
+		}
+		return identity, err
 	}
 
 	key, tenant, err := m.store.ResolveAPIKey(r.Context(), keyauth.HashAPIKey(rawKey))
@@ -98,9 +184,79 @@ func (m *Middleware) authenticate(r *http.Request) (Identity, error) {
 		}
 		return Identity{}, err
 	}
+	if key.Expired(time.Now().UTC()) {
+		if m.logger != nil {
+			m.logger.Warn("api key authentication failed", "api_key_id", key.ID, "error", errExpiredKey)
+		}
+		return Identity{}, errExpiredKey
+	}
 
 	return Identity{
-		Tenant: *tenant,
-		APIKey: key,
+		Tenant:     *tenant,
+		APIKey:     key,
+		Role:       key.Role,
+		AllTenants: key.AllTenants,
 	}, nil
 }
+
+// authenticateJWT verifies token and maps its claims to an identity. A tenant claim of "*"
+// grants the role in every tenant, and a missing tenant claim selects the default tenant, but
+// only when the claim mapping allows it.
+func (m *Middleware) authenticateJWT(ctx context.Context, token string) (Identity, error) {
+	claims, err := m.jwt.Verify(token)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	user := keyauth.ClaimString(claims, m.claims.UserClaim)
+	if user == "" {
+		return Identity{}, fmt.Errorf("jwt: missing user claim %q", m.claims.UserClaim)
+	}
+	role := m.claimRole(claims)
+	if role == "" {
+		return Identity{}, fmt.Errorf("jwt: no role for user %q in claim %q", user, m.claims.RoleClaim)
+	}
+
+	slug := keyauth.ClaimString(claims, m.claims.TenantClaim)
+	allTenants := slug == "*"
+	switch {
+	case allTenants && !m.claims.AllowWildcardTenant:
+		return Identity{}, fmt.Errorf("jwt: wildcard tenant claim %q is not allowed", m.claims.TenantClaim)
+	case slug == "" && !m.claims.AllowDefaultTenant:
+		return Identity{}, fmt.Errorf("jwt: missing tenant claim %q", m.claims.TenantClaim)
+	case slug == "" || allTenants:
+		slug = m.defaultTenant
+	}
+	tenant, err := m.store.GetTenant(ctx, slug)
+	if err != nil {
+		return Identity{}, fmt.Errorf("jwt tenant %q: %w", slug, err)
+	}
+	if tenant.Status != model.TenantStatusActive {
+		return Identity{}, fmt.Errorf("jwt tenant %q: %w", slug, errInactiveTenant)
+	}
+
+	return Identity{Tenant: *tenant, Role: role, AllTenants: allTenants, User: user}, nil
+}
+
+// claimRole returns the most privileged role named by the role claim, or the default role.
+func (m *Middleware) claimRole(claims map[string]any) string {
+	granted := map[string]bool{}
+	for _, value := range keyauth.ClaimStrings(claims, m.claims.RoleClaim) {
+		if role, ok := m.claims.Roles[value]; ok {
+			granted[role] = true
+		} else if model.ValidRole(value) {
+			granted[value] = true
+		}
+	}
+	for _, role := range model.Roles {
+		if granted[role] {
+			return role
+		}
+	}
+	return m.claims.DefaultRole
+}
+
+// looksLikeJWT reports whether a bearer credential is a compact JWS rather than an API key.
+func looksLikeJWT(raw string) bool {
+	return !strings.HasPrefix(raw, "lcg_") && strings.Count(raw, ".") == 2
+}
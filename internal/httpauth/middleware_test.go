@@ -2,12 +2,22 @@ package httpauth_test
 
 import (
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"io"
 	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
 	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
@@ -38,7 +48,8 @@ func TestMiddleware_DisabledUsesDefaultTenant(t *testing.T) {
 	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		identity, ok := httpauth.IdentityFromContext(r.Context())
 		require.True(t, ok)
-		assert.True(t, identity.Admin)
+		assert.Equal(t, model.RoleOwner, identity.Role)
+		assert.True(t, identity.AllTenants)
 		_, _ = w.Write([]byte(identity.Tenant.Slug))
 	}))
 
@@ -105,7 +116,8 @@ func TestMiddleware_BootstrapAdminKey(t *testing.T) {
 	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		identity, ok := httpauth.IdentityFromContext(r.Context())
 		require.True(t, ok)
-		assert.True(t, identity.Admin)
+		assert.Equal(t, model.RoleOwner, identity.Role)
+		assert.True(t, identity.AllTenants)
 		_, _ = w.Write([]byte(identity.Tenant.Slug))
 	}))
 
@@ -117,3 +129,181 @@ func TestMiddleware_BootstrapAdminKey(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "default", w.Body.String())
 }
+
+func createKey(t *testing.T, store storage.Storage, key *model.APIKey) string {
+	t.Helper()
+
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	require.NoError(t, err)
+	key.Tenant = "acme"
+	key.KeyPrefix = prefix
+	key.KeyHash = hash
+	require.NoError(t, store.CreateAPIKey(context.Background(), key))
+	return rawKey
+}
+
+func TestMiddleware_RejectsExpiredKey(t *testing.T) {
+	store := setupAuthStore(t)
+	expired := time.Now().UTC().Add(-time.Minute)
+	rawKey := createKey(t, store, &model.APIKey{Name: "old", ExpiresAt: &expired})
+
+	middleware := httpauth.New(store, true, "default", "", testLogger())
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Fatal("handler should not be called")
+	}))
+
+	req := httptest.NewRequest("GET", "/api/v1/usage", nil)
+	req.Header.Set("X-LCG-API-Key", rawKey)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestMiddleware_RolesAndProxyAccess(t *testing.T) {
+	store := setupAuthStore(t)
+	keys := map[string]string{}
+	for _, role := range model.Roles {
+		keys[role] = createKey(t, store, &model.APIKey{Name: role, Role: role, AllTenants: role == model.RoleOwner})
+	}
+
+	middleware := httpauth.New(store, true, "default", "", testLogger())
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := httpauth.IdentityFromContext(r.Context())
+		require.True(t, ok)
+		_, _ = fmt.Fprintf(w, "%s %t", identity.Role, identity.AllTenants)
+	})
+	api := middleware.Wrap(echo)
+	proxy := middleware.WrapProxy(echo)
+
+	for _, role := range model.Roles {
+		req := httptest.NewRequest("GET", "/api/v1/usage", nil)
+		req.Header.Set("X-LCG-API-Key", keys[role])
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, role)
+		assert.Equal(t, fmt.Sprintf("%s %t", role, role == model.RoleOwner), w.Body.String())
+
+		req = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		req.Header.Set("X-LCG-API-Key", keys[role])
+		w = httptest.NewRecorder()
+		proxy.ServeHTTP(w, req)
+		want := http.StatusOK
+		if role == model.RoleFinanceViewer {
+			want = http.StatusForbidden
+		}
+		assert.Equal(t, want, w.Code, role)
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": "idp-1"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestMiddleware_OIDCTokens(t *testing.T) {
+	store := setupAuthStore(t)
+	ctx := context.Background()
+	_, err := store.EnsureTenant(ctx, "default", "Default")
+	require.NoError(t, err)
+	_, err = store.EnsureTenant(ctx, "acme", "Acme")
+	require.NoError(t, err)
+
+	idpKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks, err := json.Marshal(map[string]any{"keys": []map[string]any{{
+		"kty": "RSA", "kid": "idp-1", "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(idpKey.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idpKey.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, jwks, 0o600))
+
+	keySet, err := keyauth.NewJWKS(keyauth.JWKSConfig{File: jwksFile})
+	require.NoError(t, err)
+	verifier, err := keyauth.NewJWTVerifier(keyauth.JWTConfig{JWKS: keySet, Issuer: "https://idp.example.com", Audience: "lcg"})
+	require.NoError(t, err)
+
+	middleware := httpauth.New(store, true, "default", "", testLogger()).WithJWT(verifier, httpauth.ClaimMapping{
+		TenantClaim: "org.tenant",
+		UserClaim:   "email",
+		RoleClaim:   "groups",
+		Roles:       map[string]string{"lcg-finance": model.RoleFinanceViewer, "lcg-admins": model.RoleAdmin},
+
+		AllowWildcardTenant: true,
+	})
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := httpauth.IdentityFromContext(r.Context())
+		require.True(t, ok)
+		_, _ = fmt.Fprintf(w, "%s %s %s %t", identity.Tenant.Slug, identity.Actor(), identity.Role, identity.AllTenants)
+	}))
+	call := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/summary", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	claims := func(tenant any, groups ...string) map[string]any {
+		return map[string]any{
+			"iss":    "https://idp.example.com",
+			"aud":    "lcg",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"email":  "dana@example.com",
+			"org":    map[string]any{"tenant": tenant},
+			"groups": groups,
+		}
+	}
+
+	w := call(signRS256(t, idpKey, claims("acme", "engineering", "lcg-finance")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme user:dana@example.com finance-viewer false", w.Body.String())
+
+	// The most privileged mapped group wins, and role names map to themselves.
+	w = call(signRS256(t, idpKey, claims("acme", "lcg-finance", "lcg-admins", "developer")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme user:dana@example.com admin false", w.Body.String())
+	w = call(signRS256(t, idpKey, claims("acme", "lcg-finance", "developer")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme user:dana@example.com developer false", w.Body.String())
+
+	w = call(signRS256(t, idpKey, claims("*", model.RoleOwner)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "default user:dana@example.com owner true", w.Body.String())
+
+	// Without allow_default_tenant, a token must name its tenant.
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, idpKey, claims(nil, "lcg-admins"))).Code)
+
+	// Unknown tenants, tokens without a role, and tokens signed by another key are rejected.
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, idpKey, claims("missing", "lcg-admins"))).Code)
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, idpKey, claims("acme", "engineering"))).Code)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, otherKey, claims("acme", "lcg-admins"))).Code)
+
+	expired := claims("acme", "lcg-admins")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, idpKey, expired)).Code)
+
+	// A default role admits users whose groups map to nothing.
+	middleware.WithJWT(verifier, httpauth.ClaimMapping{TenantClaim: "org.tenant", UserClaim: "email", RoleClaim: "groups", DefaultRole: model.RoleProxyOnly, AllowDefaultTenant: true})
+	w = call(signRS256(t, idpKey, claims("acme", "engineering")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme user:dana@example.com proxy-only false", w.Body.String())
+
+	// allow_default_tenant admits tokens without a tenant claim, and a wildcard is refused
+	// unless allowed.
+	w = call(signRS256(t, idpKey, claims(nil, "engineering")))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "default user:dana@example.com proxy-only false", w.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, call(signRS256(t, idpKey, claims("*", model.RoleOwner))).Code)
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+)
+
+const (
+	endUserHeader = "X-LCG-User"
+	// DefaultEndUserTokenHeader carries the end-user JWT when Options.EndUserTokenHeader is empty.
+	DefaultEndUserTokenHeader = "X-LCG-User-Token"
+	// DefaultEndUserClaim is the JWT claim read when Options.EndUserClaim is empty.
+	DefaultEndUserClaim = "sub"
+	maxEndUserLength    = 256
+)
+
+// resolveEndUser returns the end user a request is made for. The X-LCG-User header wins, then
+// the body's user or metadata.user_id field, then the configured claim of a verified JWT.
+func (h *Handler) resolveEndUser(r *http.Request, body []byte) string {
+	if user := normalizeEndUser(r.Header.Get(endUserHeader)); user != "" {
+		return user
+	}
+	if user := normalizeEndUser(extractBodyEndUser(body)); user != "" {
+		return user
+	}
+	if h.options.EndUserJWT == nil {
+		return ""
+	}
+
+	token := strings.TrimSpace(r.Header.Get(h.options.EndUserTokenHeader))
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	if token == "" {
+		return ""
+	}
+	claims, err := h.options.EndUserJWT.Verify(token)
+	if err != nil {
+		h.logger.Warn("ignoring end-user token", "error", err)
+		return ""
+	}
+	return normalizeEndUser(auth.ClaimString(claims, h.options.EndUserClaim))
+}
+
+// extractBodyEndUser reads OpenAI's user field or Anthropic's metadata.user_id.
+func extractBodyEndUser(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var req struct {
+		User     json.RawMessage `json:"user"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+	var user string
+	if json.Unmarshal(req.User, &user) == nil && user != "" {
+		return user
+	}
+	var metadata struct {
+		UserID string `json:"user_id"`
+	}
+	if json.Unmarshal(req.Metadata, &metadata) == nil {
+		return metadata.UserID
+	}
+	return ""
+}
+
+func normalizeEndUser(user string) string {
+	user = strings.TrimSpace(user)
+	if len(user) > maxEndUserLength {
+		user = user[:maxEndUserLength]
+	}
+	return user
+}
+
+// stripEndUserHeaders removes end-user attribution headers before the request goes upstream.
+// A token carried in Authorization is left alone because providers authenticate with it.
+func (h *Handler) stripEndUserHeaders(header http.Header) {
+	header.Del(endUserHeader)
+	if h.options.EndUserJWT != nil && !strings.EqualFold(h.options.EndUserTokenHeader, "Authorization") {
+		header.Del(h.options.EndUserTokenHeader)
+	}
+}
@@ -0,0 +1,103 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hs256Token(secret, payload string) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestProxyHandler_AttributesEndUser(t *testing.T) {
+	upstreamHeaders := make(chan http.Header, 8)
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeaders <- r.Header.Clone()
+		openAIResponseHandler(w, r)
+	}, 4096, false)
+
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{HMACSecret: "s3cret"})
+	require.NoError(t, err)
+	opts := proxy.DefaultOptions()
+	opts.EndUserJWT = verifier
+	opts.EndUserClaim = "ext.uid"
+	env.handler.WithOptions(opts)
+
+	validToken := hs256Token("s3cret", `{"sub":"ignored","ext":{"uid":"jwt-user"},"exp":4102444800}`)
+	tests := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "header wins over body and token",
+			body:    `{"model":"gpt-4o","user":"body-user","messages":[]}`,
+			headers: map[string]string{"X-LCG-User": "header-user", "X-LCG-User-Token": validToken},
+			want:    "header-user",
+		},
+		{
+			name:    "openai user field",
+			body:    `{"model":"gpt-4o","user":"body-user","messages":[]}`,
+			headers: map[string]string{"X-LCG-User-Token": validToken},
+			want:    "body-user",
+		},
+		{
+			name: "anthropic metadata user_id",
+			body: `{"model":"gpt-4o","metadata":{"user_id":"meta-user"},"messages":[]}`,
+			want: "meta-user",
+		},
+		{
+			name:    "verified jwt claim",
+			body:    `{"model":"gpt-4o","messages":[]}`,
+			headers: map[string]string{"X-LCG-User-Token": "Bearer " + validToken},
+			want:    "jwt-user",
+		},
+		{
+			name:    "invalid jwt is ignored",
+			body:    `{"model":"gpt-4o","messages":[]}`,
+			headers: map[string]string{"X-LCG-User-Token": hs256Token("wrong", `{"ext":{"uid":"forged"}}`)},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+			req.Header.Set("X-LCG-Project", tt.name)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			w := httptest.NewRecorder()
+			env.handler.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			forwarded := <-upstreamHeaders
+			assert.Empty(t, forwarded.Get("X-LCG-User"))
+			assert.Empty(t, forwarded.Get("X-LCG-User-Token"))
+
+			records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{Project: tt.name})
+			require.NoError(t, err)
+			require.Len(t, records, 1)
+			assert.Equal(t, tt.want, records[0].User)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+// statusClientClosedRequest is recorded when the client goes away before the upstream responds.
+const statusClientClosedRequest = 499
+
+const (
+	errorTypeClientClosed    = "client_closed"
+	errorTypeTimeout         = "timeout"
+	errorTypeConnection      = "connection_error"
+	errorTypeContentFilter   = "content_filter"
+	errorTypeRateLimited     = "rate_limited"
+	errorTypeAuthentication  = "authentication_error"
+	errorTypeInvalidRequest  = "invalid_request"
+	errorTypeNotFound        = "not_found"
+	errorTypeRequestTooLarge = "request_too_large"
+	errorTypeOverloaded      = "overloaded"
+	errorTypeServer          = "server_error"
+	errorTypeUpstream        = "upstream_error"
+)
+
+var providerRequestIDHeaders = []string{
+	"X-Request-Id",
+	"Request-Id",
+	"X-Amzn-Requestid",
+	"X-Amz-Request-Id",
+	"Apim-Request-Id",
+	"X-Goog-Request-Id",
+}
+
+// providerRequestID returns the upstream request identifier used for provider support tickets.
+func providerRequestID(header http.Header) string {
+	for _, name := range providerRequestIDHeaders {
+		if value := strings.TrimSpace(header.Get(name)); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// classifyUpstreamError returns a normalized error type for an upstream response, or "" on success.
+// Content-filter refusals are reported even when the provider answers with 200.
+func classifyUpstreamError(statusCode int, body []byte) string {
+	detail := providerErrorDetail(body)
+	if strings.Contains(detail, "content_filter") || strings.Contains(detail, "safety") {
+		return errorTypeContentFilter
+	}
+	if statusCode < http.StatusBadRequest {
+		return ""
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests || strings.Contains(detail, "rate_limit"):
+		return errorTypeRateLimited
+	case statusCode == 529 || strings.Contains(detail, "overloaded"):
+		return errorTypeOverloaded
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return errorTypeAuthentication
+	case statusCode == http.StatusNotFound:
+		return errorTypeNotFound
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return errorTypeRequestTooLarge
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return errorTypeTimeout
+	case statusCode >= http.StatusInternalServerError:
+		return errorTypeServer
+	default:
+		return errorTypeInvalidRequest
+	}
+}
+
+// classifyTransportError maps a round-trip failure (no upstream response) to an error type.
+func classifyTransportError(clientCtx context.Context, err error) string {
+	if clientCtx.Err() != nil || errors.Is(err, context.Canceled) {
+		return errorTypeClientClosed
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errorTypeTimeout
+	}
+	return errorTypeConnection
+}
+
+// completionStatusForError maps an error type onto the record completion status.
+func completionStatusForError(errorType string) string {
+	switch errorType {
+	case errorTypeClientClosed:
+		return model.CompletionStatusClientAborted
+	case errorTypeTimeout:
+		return model.CompletionStatusTimeout
+	case "", errorTypeContentFilter:
+		return model.CompletionStatusComplete
+	default:
+		return model.CompletionStatusUpstreamError
+	}
+}
+
+// extractStreamError detects provider error events embedded in a stream
+// (e.g. Anthropic `event: error` or an OpenAI `{"error": {...}}` chunk).
+func extractStreamError(payload []byte) string {
+	var decoded map[string]any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return ""
+	}
+	if _, ok := decoded["error"]; !ok && stringValue(decoded["type"]) != "error" {
+		return ""
+	}
+
+	detail := providerErrorDetail(payload)
+	switch {
+	case strings.Contains(detail, "overloaded"):
+		return errorTypeOverloaded
+	case strings.Contains(detail, "rate_limit"):
+		return errorTypeRateLimited
+	case strings.Contains(detail, "content_filter"):
+		return errorTypeContentFilter
+	default:
+		return errorTypeUpstream
+	}
+}
+
+// providerErrorDetail returns the lower-cased error type/code/status fields and any
+// finish reason from a provider response body, joined for keyword matching.
+func providerErrorDetail(body []byte) string {
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return ""
+	}
+
+	var parts []string
+	if errValue, ok := decoded["error"].(map[string]any); ok {
+		for _, key := range []string{"type", "code", "status"} {
+			if value := stringValue(errValue[key]); value != "" {
+				parts = append(parts, value)
+			}
+		}
+	}
+	if choices, ok := decoded["choices"].([]any); ok {
+		for _, rawChoice := range choices {
+			if choice, ok := rawChoice.(map[string]any); ok {
+				if reason := stringValue(choice["finish_reason"]); reason != "" {
+					parts = append(parts, reason)
+				}
+			}
+		}
+	}
+	if candidates, ok := decoded["candidates"].([]any); ok {
+		for _, rawCandidate := range candidates {
+			if candidate, ok := rawCandidate.(map[string]any); ok {
+				if reason := stringValue(candidate["finishReason"]); reason != "" {
+					parts = append(parts, reason)
+				}
+			}
+		}
+	}
+	if feedback, ok := decoded["promptFeedback"].(map[string]any); ok {
+		if reason := stringValue(feedback["blockReason"]); reason != "" {
+			parts = append(parts, "safety")
+		}
+	}
+	if reason := stringValue(decoded["stopReason"]); reason == "content_filtered" || reason == "guardrail_intervened" {
+		parts = append(parts, "content_filter")
+	}
+	return strings.ToLower(strings.Join(parts, " "))
+}
@@ -11,18 +11,28 @@ import (
 
 // RequestInfo holds extracted information from an LLM API request.
 type RequestInfo struct {
-	Provider     string
-	Model        string
-	Messages     string // Concatenated message content for token counting
-	MessageCount int
-	SystemChars  int
+	Provider        string
+	Model           string
+	Messages        string // Concatenated message content for token counting
+	MessageCount    int
+	SystemChars     int
+	ToolCount       int    // Tool or function definitions sent with the request
+	ToolSchemaChars int    // Compact JSON size of all tool definitions
+	ToolChoice      string // Normalized tool_choice / toolConfig mode
+	ToolResultCount int    // Tool results fed back to the model
+	ToolResultChars int
+	MaxTokens       int64 // Requested output token limit, 0 when the request sets none
 }
 
 // ResponseUsage holds extracted token usage from an LLM API response.
 type ResponseUsage struct {
-	InputTokens  int64
-	OutputTokens int64
-	Model        string
+	InputTokens       int64
+	OutputTokens      int64
+	Model             string
+	ToolCallCount     int
+	ToolCallArgsChars int
+	ReasoningTokens   int64 // Thinking tokens, already included in OutputTokens
+	Estimated         bool  // Token counts were estimated from characters rather than reported
 }
 
 // DetectProvider determines the provider from the request URL or path.
@@ -40,8 +50,9 @@ func DetectProvider(host, requestPath string) string {
 	case strings.Contains(host, "bedrock-runtime.") || strings.Contains(host, "bedrock.") ||
 		(strings.Contains(requestPath, "/model/") && (strings.Contains(requestPath, "/converse") || strings.Contains(requestPath, "/invoke"))):
 		return "bedrock"
-	case strings.Contains(host, "aiplatform.googleapis.com") || strings.Contains(host, "generativelanguage.googleapis.com") ||
-		strings.Contains(requestPath, "/publishers/google/models/"):
+	case strings.Contains(host, "generativelanguage.googleapis.com"):
+		return "gemini"
+	case strings.Contains(host, "aiplatform.googleapis.com") || strings.Contains(requestPath, "/publishers/google/models/"):
 		return "vertex-ai"
 	default:
 		return ""
@@ -66,8 +77,8 @@ func ExtractRequestInfo(body []byte, provider string, endpointPath ...string) (*
 		return extractAnthropicRequest(body)
 	case "bedrock":
 		return extractBedrockRequest(body, requestPath)
-	case "vertex-ai":
-		return extractVertexAIRequest(body, requestPath)
+	case "vertex-ai", "gemini":
+		return extractVertexAIRequest(body, provider, requestPath)
 	default:
 		return nil, nil
 	}
@@ -88,7 +99,7 @@ func ExtractResponseUsage(body []byte, provider string) (*ResponseUsage, error)
 		return extractAnthropicResponse(body)
 	case "bedrock":
 		return extractBedrockResponse(body)
-	case "vertex-ai":
+	case "vertex-ai", "gemini":
 		return extractVertexAIResponse(body)
 	default:
 		return nil, nil
@@ -103,17 +114,34 @@ func extractOpenAIRequest(body []byte) (*RequestInfo, error) {
 
 	var content strings.Builder
 	for _, msg := range req.Messages {
-		content.WriteString(msg.Content)
+		content.WriteString(msg.Content.Text)
 		content.WriteString("\n")
 	}
 
-	return &RequestInfo{
+	info := &RequestInfo{
 		Provider:     "openai",
 		Model:        req.Model,
 		Messages:     content.String(),
 		MessageCount: len(req.Messages),
 		SystemChars:  countOpenAISystemChars(req.Messages),
-	}, nil
+		ToolChoice:   toolChoiceLabel(decodeRaw(req.ToolChoice)),
+		MaxTokens:    max(req.MaxTokens, req.MaxCompletionTokens, req.MaxOutputTokens),
+	}
+	if info.ToolChoice == "" {
+		info.ToolChoice = toolChoiceLabel(decodeRaw(req.FunctionCall))
+	}
+	for _, raw := range []json.RawMessage{req.Tools, req.Functions} {
+		count, size := measureToolSchemas(decodeRaw(raw))
+		info.ToolCount += count
+		info.ToolSchemaChars += size
+	}
+	for _, msg := range req.Messages {
+		if strings.EqualFold(msg.Role, "tool") || strings.EqualFold(msg.Role, "function") {
+			info.ToolResultCount++
+			info.ToolResultChars += len(msg.Content.Text)
+		}
+	}
+	return info, nil
 }
 
 func extractAnthropicRequest(body []byte) (*RequestInfo, error) {
@@ -123,22 +151,31 @@ func extractAnthropicRequest(body []byte) (*RequestInfo, error) {
 	}
 
 	var content strings.Builder
-	if req.System != "" {
-		content.WriteString(req.System)
+	if req.System.Text != "" {
+		content.WriteString(req.System.Text)
 		content.WriteString("\n")
 	}
 	for _, msg := range req.Messages {
-		content.WriteString(msg.Content)
+		content.WriteString(msg.Content.Text)
 		content.WriteString("\n")
 	}
 
-	return &RequestInfo{
+	info := &RequestInfo{
 		Provider:     "anthropic",
 		Model:        req.Model,
 		Messages:     content.String(),
 		MessageCount: len(req.Messages),
-		SystemChars:  len(req.System),
-	}, nil
+		SystemChars:  len(req.System.Text),
+		ToolChoice:   toolChoiceLabel(decodeRaw(req.ToolChoice)),
+		MaxTokens:    req.MaxTokens,
+	}
+	info.ToolCount, info.ToolSchemaChars = measureToolSchemas(decodeRaw(req.Tools))
+	for _, msg := range req.Messages {
+		count, size := countToolResultBlocks(msg.Content.Blocks, "tool_result")
+		info.ToolResultCount += count
+		info.ToolResultChars += size
+	}
+	return info, nil
 }
 
 func extractOpenAIResponse(body []byte) (*ResponseUsage, error) {
@@ -147,11 +184,22 @@ func extractOpenAIResponse(body []byte) (*ResponseUsage, error) {
 		return nil, err
 	}
 
-	return &ResponseUsage{
+	usage := &ResponseUsage{
 		InputTokens:  resp.Usage.PromptTokens,
 		OutputTokens: resp.Usage.CompletionTokens,
 		Model:        resp.Model,
-	}, nil
+	}
+	for _, choice := range resp.Choices {
+		for _, call := range choice.Message.ToolCalls {
+			usage.ToolCallCount++
+			usage.ToolCallArgsChars += len(call.Function.Arguments)
+		}
+		if choice.Message.FunctionCall != nil {
+			usage.ToolCallCount++
+			usage.ToolCallArgsChars += len(choice.Message.FunctionCall.Arguments)
+		}
+	}
+	return usage, nil
 }
 
 func extractAnthropicResponse(body []byte) (*ResponseUsage, error) {
@@ -160,11 +208,18 @@ func extractAnthropicResponse(body []byte) (*ResponseUsage, error) {
 		return nil, err
 	}
 
-	return &ResponseUsage{
+	usage := &ResponseUsage{
 		InputTokens:  resp.Usage.InputTokens,
 		OutputTokens: resp.Usage.OutputTokens,
 		Model:        resp.Model,
-	}, nil
+	}
+	for _, block := range resp.Content {
+		if block.Type == "tool_use" {
+			usage.ToolCallCount++
+			usage.ToolCallArgsChars += compactSize(block.Input)
+		}
+	}
+	return usage, nil
 }
 
 func extractBedrockRequest(body []byte, endpointPath string) (*RequestInfo, error) {
@@ -179,15 +234,44 @@ func extractBedrockRequest(body []byte, endpointPath string) (*RequestInfo, erro
 	appendTextContent(&content, req["inputText"])
 	appendTextContent(&content, req["prompt"])
 
-	return &RequestInfo{
+	info := &RequestInfo{
 		Provider:     "bedrock",
 		Model:        extractModelFromPath("bedrock", endpointPath),
 		Messages:     strings.TrimSpace(content.String()),
 		MessageCount: countMessages(req["messages"]),
-	}, nil
+		MaxTokens:    bedrockMaxTokens(req),
+	}
+	if toolConfig, ok := req["toolConfig"].(map[string]any); ok {
+		info.ToolCount, info.ToolSchemaChars = measureToolSchemas(toolConfig["tools"])
+		info.ToolChoice = toolChoiceLabel(toolConfig["toolChoice"])
+	}
+	if tools, ok := req["tools"]; ok {
+		count, size := measureToolSchemas(tools)
+		info.ToolCount += count
+		info.ToolSchemaChars += size
+	}
+	info.ToolResultCount, info.ToolResultChars = countNestedToolResults(req["messages"], "toolResult")
+	return info, nil
+}
+
+// bedrockMaxTokens reads the output limit from the Converse inferenceConfig or from the
+// model-native InvokeModel body (Anthropic, Titan, Llama, Mistral).
+func bedrockMaxTokens(req map[string]any) int64 {
+	if inference, ok := req["inferenceConfig"].(map[string]any); ok {
+		if limit := int64Value(inference["maxTokens"]); limit > 0 {
+			return limit
+		}
+	}
+	if generation, ok := req["textGenerationConfig"].(map[string]any); ok {
+		if limit := int64Value(generation["maxTokenCount"]); limit > 0 {
+			return limit
+		}
+	}
+	return max(int64Value(req["max_tokens"]), int64Value(req["max_gen_len"]))
 }
 
-func extractVertexAIRequest(body []byte, endpointPath string) (*RequestInfo, error) {
+// extractVertexAIRequest parses the generateContent request shape shared by Vertex AI and the Gemini API.
+func extractVertexAIRequest(body []byte, provider, endpointPath string) (*RequestInfo, error) {
 	var req map[string]any
 	if err := json.Unmarshal(body, &req); err != nil {
 		return nil, err
@@ -197,13 +281,32 @@ func extractVertexAIRequest(body []byte, endpointPath string) (*RequestInfo, err
 	appendTextContent(&content, req["systemInstruction"])
 	appendTextContent(&content, req["contents"])
 
-	return &RequestInfo{
-		Provider:     "vertex-ai",
-		Model:        extractModelFromPath("vertex-ai", endpointPath),
+	info := &RequestInfo{
+		Provider:     provider,
+		Model:        extractModelFromPath(provider, endpointPath),
 		Messages:     strings.TrimSpace(content.String()),
 		MessageCount: countMessages(req["contents"]),
 		SystemChars:  countMessageChars(req["systemInstruction"]),
-	}, nil
+	}
+	if generationConfig, ok := req["generationConfig"].(map[string]any); ok {
+		info.MaxTokens = int64Value(generationConfig["maxOutputTokens"])
+	}
+	if tools, ok := req["tools"].([]any); ok {
+		for _, tool := range tools {
+			entry, ok := tool.(map[string]any)
+			if !ok {
+				continue
+			}
+			declarations, _ := entry["functionDeclarations"].([]any)
+			info.ToolCount += len(declarations)
+			info.ToolSchemaChars += compactSize(mustMarshal(entry))
+		}
+	}
+	if toolConfig, ok := req["toolConfig"].(map[string]any); ok {
+		info.ToolChoice = toolChoiceLabel(toolConfig["functionCallingConfig"])
+	}
+	info.ToolResultCount, info.ToolResultChars = countNestedToolResults(req["contents"], "functionResponse")
+	return info, nil
 }
 
 func extractBedrockResponse(body []byte) (*ResponseUsage, error) {
@@ -217,11 +320,13 @@ func extractBedrockResponse(body []byte) (*ResponseUsage, error) {
 		return nil, nil
 	}
 
-	return &ResponseUsage{
+	usage := &ResponseUsage{
 		InputTokens:  int64Value(usageMap["inputTokens"]),
 		OutputTokens: int64Value(usageMap["outputTokens"]),
 		Model:        stringValue(resp["modelId"]),
-	}, nil
+	}
+	usage.ToolCallCount, usage.ToolCallArgsChars = countNestedToolCalls(resp["output"], "toolUse", "input")
+	return usage, nil
 }
 
 func extractVertexAIResponse(body []byte) (*ResponseUsage, error) {
@@ -235,11 +340,16 @@ func extractVertexAIResponse(body []byte) (*ResponseUsage, error) {
 		return nil, nil
 	}
 
-	return &ResponseUsage{
-		InputTokens:  int64Value(usageMap["promptTokenCount"]),
-		OutputTokens: int64Value(usageMap["candidatesTokenCount"]),
-		Model:        stringValue(resp["modelVersion"]),
-	}, nil
+	// Thinking tokens are reported separately from candidates but billed at the output rate.
+	thoughts := int64Value(usageMap["thoughtsTokenCount"])
+	usage := &ResponseUsage{
+		InputTokens:     int64Value(usageMap["promptTokenCount"]),
+		OutputTokens:    int64Value(usageMap["candidatesTokenCount"]) + thoughts,
+		Model:           stringValue(resp["modelVersion"]),
+		ReasoningTokens: thoughts,
+	}
+	usage.ToolCallCount, usage.ToolCallArgsChars = countNestedToolCalls(resp["candidates"], "functionCall", "args")
+	return usage, nil
 }
 
 func firstPath(paths []string) string {
@@ -274,6 +384,13 @@ func extractModelFromPath(provider, endpointPath string) string {
 			return matches[1]
 		}
 		return path.Base(endpointPath)
+	case "gemini":
+		re := regexp.MustCompile(`/(?:tuned)?[mM]odels/([^/:]+)`)
+		matches := re.FindStringSubmatch(endpointPath)
+		if len(matches) == 2 {
+			return matches[1]
+		}
+		return ""
 	default:
 		return ""
 	}
@@ -350,7 +467,7 @@ func countOpenAISystemChars(messages []openAIMessage) int {
 	total := 0
 	for _, message := range messages {
 		if strings.EqualFold(message.Role, "system") {
-			total += len(message.Content)
+			total += len(message.Content.Text)
 		}
 	}
 	return total
@@ -393,18 +510,43 @@ func countMessageChars(value any) int {
 // OpenAI request/response structures
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
+	Model        string          `json:"model"`
+	Messages     []openAIMessage `json:"messages"`
+	Tools        json.RawMessage `json:"tools,omitempty"`
+	Functions    json.RawMessage `json:"functions,omitempty"`
+	ToolChoice   json.RawMessage `json:"tool_choice,omitempty"`
+	FunctionCall json.RawMessage `json:"function_call,omitempty"`
+
+	MaxTokens           int64 `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int64 `json:"max_completion_tokens,omitempty"`
+	MaxOutputTokens     int64 `json:"max_output_tokens,omitempty"`
 }
 
 type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string         `json:"role"`
+	Content messageContent `json:"content"`
 }
 
 type openAIResponse struct {
-	Model string      `json:"model"`
-	Usage openAIUsage `json:"usage"`
+	Model   string         `json:"model"`
+	Usage   openAIUsage    `json:"usage"`
+	Choices []openAIChoice `json:"choices"`
+}
+
+type openAIChoice struct {
+	Message struct {
+		ToolCalls    []openAIToolCall    `json:"tool_calls"`
+		FunctionCall *openAIFunctionCall `json:"function_call"`
+	} `json:"message"`
+}
+
+type openAIToolCall struct {
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type openAIUsage struct {
@@ -416,19 +558,28 @@ type openAIUsage struct {
 // Anthropic request/response structures
 
 type anthropicRequest struct {
-	Model    string             `json:"model"`
-	System   string             `json:"system,omitempty"`
-	Messages []anthropicMessage `json:"messages"`
+	Model      string             `json:"model"`
+	System     messageContent     `json:"system,omitempty"`
+	Messages   []anthropicMessage `json:"messages"`
+	Tools      json.RawMessage    `json:"tools,omitempty"`
+	ToolChoice json.RawMessage    `json:"tool_choice,omitempty"`
+	MaxTokens  int64              `json:"max_tokens,omitempty"`
 }
 
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string         `json:"role"`
+	Content messageContent `json:"content"`
 }
 
 type anthropicResponse struct {
-	Model string         `json:"model"`
-	Usage anthropicUsage `json:"usage"`
+	Model   string                  `json:"model"`
+	Usage   anthropicUsage          `json:"usage"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 type anthropicUsage struct {
@@ -22,6 +22,7 @@ func TestDetectProvider(t *testing.T) {
 		{"anthropic path only", "localhost", "/v1/messages", "anthropic"},
 		{"bedrock host", "bedrock-runtime.us-east-1.amazonaws.com", "/model/anthropic.claude-3-5-sonnet-20241022-v2:0/converse", "bedrock"},
 		{"vertex host", "us-central1-aiplatform.googleapis.com", "/v1/projects/test/locations/us-central1/publishers/google/models/gemini-1.5-pro:generateContent", "vertex-ai"},
+		{"gemini api host", "generativelanguage.googleapis.com", "/v1beta/models/gemini-2.5-flash:streamGenerateContent", "gemini"},
 		{"unknown", "example.com", "/api/chat", ""},
 	}
 
@@ -202,6 +203,34 @@ func TestExtractResponseUsage_VertexAI(t *testing.T) {
 	assert.Equal(t, "gemini-1.5-pro", usage.Model)
 }
 
+func TestExtractRequestInfo_Gemini(t *testing.T) {
+	body := []byte(`{"contents": [{"role": "user", "parts": [{"text": "Plan a trip"}]}]}`)
+
+	info, err := proxy.ExtractRequestInfo(body, "gemini", "/v1beta/models/gemini-2.5-flash:generateContent")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", info.Provider)
+	assert.Equal(t, "gemini-2.5-flash", info.Model)
+	assert.Contains(t, info.Messages, "Plan a trip")
+}
+
+func TestExtractResponseUsage_GeminiThinkingTokens(t *testing.T) {
+	body := []byte(`{
+		"modelVersion": "gemini-2.5-flash",
+		"usageMetadata": {
+			"promptTokenCount": 12,
+			"candidatesTokenCount": 30,
+			"thoughtsTokenCount": 250,
+			"totalTokenCount": 292
+		}
+	}`)
+
+	usage, err := proxy.ExtractResponseUsage(body, "gemini")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), usage.InputTokens)
+	assert.Equal(t, int64(280), usage.OutputTokens)
+	assert.Equal(t, int64(250), usage.ReasoningTokens)
+}
+
 func TestExtractResponseUsage_Unknown(t *testing.T) {
 	usage, err := proxy.ExtractResponseUsage([]byte(`{}`), "unknown")
 	require.NoError(t, err)
@@ -229,3 +258,92 @@ func TestExtractResponseUsage_InvalidJSON(t *testing.T) {
 	_, err = proxy.ExtractResponseUsage([]byte(`{invalid`), "vertex-ai")
 	assert.Error(t, err)
 }
+
+func TestExtractRequestInfo_OpenAITools(t *testing.T) {
+	body := []byte(`{
+		"model": "gpt-4o",
+		"tool_choice": {"type": "function", "function": {"name": "lookup_order"}},
+		"tools": [
+			{"type": "function", "function": {"name": "lookup_order", "parameters": {"type": "object", "properties": {"id": {"type": "string"}}}}},
+			{"type": "function", "function": {"name": "cancel_order", "parameters": {"type": "object"}}}
+		],
+		"messages": [
+			{"role": "user", "content": "Where is order 42?"},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "lookup_order", "arguments": "{\"id\":\"42\"}"}}]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "{\"status\":\"shipped\"}"}
+		]
+	}`)
+
+	info, err := proxy.ExtractRequestInfo(body, "openai")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.ToolCount)
+	assert.Greater(t, info.ToolSchemaChars, 100)
+	assert.Equal(t, "function:lookup_order", info.ToolChoice)
+	assert.Equal(t, 1, info.ToolResultCount)
+	assert.Equal(t, len(`{"status":"shipped"}`), info.ToolResultChars)
+}
+
+func TestExtractRequestInfo_AnthropicToolBlocks(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-3.5-sonnet",
+		"system": [{"type": "text", "text": "You are a support agent."}],
+		"tool_choice": {"type": "auto"},
+		"tools": [{"name": "get_weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [{"type": "tool_use", "id": "tu_1", "name": "get_weather", "input": {"city": "Paris"}}]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "tu_1", "content": "Sunny, 21C"}]}
+		]
+	}`)
+
+	info, err := proxy.ExtractRequestInfo(body, "anthropic")
+	require.NoError(t, err)
+	assert.Contains(t, info.Messages, "support agent")
+	assert.Contains(t, info.Messages, "Sunny, 21C")
+	assert.Equal(t, 1, info.ToolCount)
+	assert.Equal(t, "auto", info.ToolChoice)
+	assert.Equal(t, 1, info.ToolResultCount)
+	assert.Equal(t, len("Sunny, 21C"), info.ToolResultChars)
+}
+
+func TestExtractRequestInfo_VertexAITools(t *testing.T) {
+	body := []byte(`{
+		"contents": [
+			{"role": "user", "parts": [{"text": "Convert 10 USD"}]},
+			{"role": "function", "parts": [{"functionResponse": {"name": "convert", "response": {"eur": 9.2}}}]}
+		],
+		"tools": [{"functionDeclarations": [{"name": "convert"}, {"name": "rates"}]}],
+		"toolConfig": {"functionCallingConfig": {"mode": "ANY"}}
+	}`)
+
+	info, err := proxy.ExtractRequestInfo(body, "vertex-ai", "/v1/projects/demo/locations/us-central1/publishers/google/models/gemini-1.5-pro:generateContent")
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.ToolCount)
+	assert.Equal(t, "any", info.ToolChoice)
+	assert.Equal(t, 1, info.ToolResultCount)
+}
+
+func TestExtractResponseUsage_ToolCalls(t *testing.T) {
+	openAI := []byte(`{
+		"model": "gpt-4o",
+		"usage": {"prompt_tokens": 120, "completion_tokens": 18},
+		"choices": [{"message": {"role": "assistant", "tool_calls": [
+			{"id": "call_1", "function": {"name": "lookup_order", "arguments": "{\"id\":\"42\"}"}},
+			{"id": "call_2", "function": {"name": "lookup_order", "arguments": "{\"id\":\"43\"}"}}
+		]}}]
+	}`)
+	usage, err := proxy.ExtractResponseUsage(openAI, "openai")
+	require.NoError(t, err)
+	assert.Equal(t, 2, usage.ToolCallCount)
+	assert.Equal(t, 2*len(`{"id":"42"}`), usage.ToolCallArgsChars)
+
+	anthropic := []byte(`{
+		"model": "claude-3.5-sonnet",
+		"usage": {"input_tokens": 90, "output_tokens": 30},
+		"content": [{"type": "text", "text": "Checking"}, {"type": "tool_use", "name": "get_weather", "input": {"city": "Paris"}}]
+	}`)
+	usage, err = proxy.ExtractResponseUsage(anthropic, "anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.ToolCallCount)
+	assert.Equal(t, len(`{"city":"Paris"}`), usage.ToolCallArgsChars)
+}
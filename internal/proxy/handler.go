@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -17,10 +18,53 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 )
 
+const defaultDisconnectDrainTimeout = 2 * time.Minute
+
+// Options holds optional proxy behaviors that are not required by NewHandler.
+type Options struct {
+	// CancelUpstreamOnDisconnect aborts the upstream request as soon as the client goes away.
+	// When false, the proxy keeps reading the upstream stream so the full billed usage is recorded.
+	CancelUpstreamOnDisconnect bool
+	// DisconnectDrainTimeout bounds how long an upstream stream is drained after a client disconnect.
+	DisconnectDrainTimeout time.Duration
+	// InjectStreamUsage asks OpenAI and Azure OpenAI streams for a usage chunk via
+	// stream_options.include_usage and hides that chunk from clients that did not request it.
+	InjectStreamUsage bool
+	// WebSocketUsage selects whether Realtime WebSocket sessions are recorded once per
+	// response.done event (WebSocketUsagePerResponse) or once when the session closes.
+	WebSocketUsage string
+	// IdempotencyWindow is how long a completed response is replayed to requests repeating its
+	// Idempotency-Key. Zero disables Idempotency-Key handling.
+	IdempotencyWindow time.Duration
+	// EndUserJWT verifies end-user tokens read from EndUserTokenHeader. The EndUserClaim path
+	// of a valid token attributes usage when neither X-LCG-User nor the body names a user.
+	EndUserJWT         *auth.JWTVerifier
+	EndUserTokenHeader string
+	EndUserClaim       string
+	// Transcripts captures redacted, encrypted prompts and completions for opted-in projects.
+	Transcripts *capture.Recorder
+	// Policies inspect each request before it is forwarded and may flag, redact, or block it.
+	Policies []RequestPolicy
+}
+
+// DefaultOptions returns the options used by NewHandler.
+func DefaultOptions() Options {
+	return Options{
+		CancelUpstreamOnDisconnect: true,
+		DisconnectDrainTimeout:     defaultDisconnectDrainTimeout,
+		WebSocketUsage:             WebSocketUsagePerResponse,
+		IdempotencyWindow:          10 * time.Minute,
+	}
+}
+
 // Handler is a transparent proxy that tracks LLM API costs.
 type Handler struct {
 	tracker        *tracker.UsageTracker
@@ -28,12 +72,15 @@ type Handler struct {
 	maxBodySize    int64
 	addHeaders     bool
 	denyOnExceed   bool
+	options        Options
+	idempotency    *idempotencyCache
 	logger         *slog.Logger
 }
 
-// NewHandler creates a new proxy handler.
+// NewHandler creates a new proxy handler. The default project, cost headers, and budget
+// denial apply to tenants whose settings do not override them.
 func NewHandler(t *tracker.UsageTracker, defaultProject string, maxBodySize int64, addHeaders, denyOnExceed bool, logger *slog.Logger) *Handler {
-	return &Handler{
+	h := &Handler{
 		tracker:        t,
 		defaultProject: defaultProject,
 		maxBodySize:    maxBodySize,
@@ -41,6 +88,29 @@ func NewHandler(t *tracker.UsageTracker, defaultProject string, maxBodySize int6
 		denyOnExceed:   denyOnExceed,
 		logger:         logger,
 	}
+	return h.WithOptions(DefaultOptions())
+}
+
+// WithOptions applies optional proxy behaviors and returns the handler.
+func (h *Handler) WithOptions(opts Options) *Handler {
+	if opts.DisconnectDrainTimeout <= 0 {
+		opts.DisconnectDrainTimeout = defaultDisconnectDrainTimeout
+	}
+	if opts.EndUserTokenHeader == "" {
+		opts.EndUserTokenHeader = DefaultEndUserTokenHeader
+	}
+	if opts.EndUserClaim == "" {
+		opts.EndUserClaim = DefaultEndUserClaim
+	}
+	if opts.WebSocketUsage != WebSocketUsagePerSession {
+		opts.WebSocketUsage = WebSocketUsagePerResponse
+	}
+	h.options = opts
+	h.idempotency = nil
+	if opts.IdempotencyWindow > 0 {
+		h.idempotency = newIdempotencyCache(opts.IdempotencyWindow)
+	}
+	return h
 }
 
 // ServeHTTP handles proxied requests.
@@ -59,6 +129,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid target URL", http.StatusBadRequest)
 		return
 	}
+	webSocket := isWebSocketUpgrade(r)
+	if webSocket {
+		target.Scheme = webSocketTarget(target.Scheme)
+	}
 
 	if h.maxBodySize > 0 && r.ContentLength > h.maxBodySize {
 		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
@@ -86,24 +160,195 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Detect provider and extract request info
 	provider := strings.ToLower(strings.TrimSpace(r.Header.Get("X-LCG-Provider")))
 	if provider == "" {
-		provider = DetectProvider(target.Host, target.Path)
+		provider = h.detectProvider(target.Host, target.Path)
 	}
+	format := h.apiFormat(provider)
 
-	reqInfo, _ := ExtractRequestInfo(reqBody, provider, target.Path)
+	reqInfo, _ := ExtractRequestInfo(reqBody, format, target.Path)
 	streamingRequest := isStreamingRequest(r, target.Path, reqBody)
+	tenant, tenantID := defaultTenant(r.Context()), requestTenantID(r.Context())
+	tenantConfig := h.tenantConfig(r.Context(), tenant)
 	project := r.Header.Get("X-LCG-Project")
 	if project == "" {
-		project = h.defaultProject
+		project = tenantConfig.DefaultProject
+	}
+	endUser := h.resolveEndUser(r, reqBody)
+	tags, err := model.ParseTags(r.Header.Get("X-LCG-Tags"))
+	if err != nil {
+		http.Error(w, "invalid X-LCG-Tags header: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	apiKey := requestAPIKey(r.Context())
+	apiKeyID := ""
+	if apiKey != nil {
+		apiKeyID = apiKey.ID
+	}
+
+	var decision PolicyDecision
+	if apiKey != nil && !apiKey.AllowsProject(project) {
+		decision = PolicyDecision{
+			Action:   policy.ActionBlock,
+			Findings: []string{"project_not_allowed"},
+			Policy:   "api_key",
+			Code:     "project_not_allowed",
+			Reason:   fmt.Sprintf("api key %q may not be used for project %q", apiKey.Name, project),
+		}
+	} else if len(h.options.Policies) > 0 {
+		// Policies see the provider the target resolves to. The client's X-LCG-Provider only
+		// counts when the target matches none, so it cannot be used to get past an allow list.
+		policyProvider := h.detectProvider(target.Host, target.Path)
+		if policyProvider == "" {
+			policyProvider = provider
+		}
+		decision = h.runPolicies(r.Context(), &PolicyRequest{
+			Tenant:    tenant,
+			Project:   project,
+			APIKeyID:  apiKeyID,
+			Provider:  policyProvider,
+			Format:    format,
+			Path:      target.Path,
+			Streaming: streamingRequest,
+			Info:      reqInfo,
+			Body:      reqBody,
+		})
+		if decision.Body != nil {
+			reqBody = decision.Body
+			reqInfo, _ = ExtractRequestInfo(reqBody, format, target.Path)
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+			r.ContentLength = int64(len(reqBody))
+			r.Header.Set("Content-Length", strconv.Itoa(len(reqBody)))
+		}
+	}
+	policyFindings := strings.Join(decision.Findings, ",")
+	if transform := decision.Transform; transform != nil {
+		h.logger.Info("request transformed",
+			"tenant", tenant,
+			"project", project,
+			"max_tokens_from", transform.MaxTokensFrom,
+			"max_tokens_to", transform.MaxTokensTo,
+			"trimmed_messages", transform.TrimmedMessages,
+			"prompt_tokens_from", transform.PromptTokensFrom,
+			"prompt_tokens_to", transform.PromptTokensTo,
+		)
+	}
+	if decision.Action == policy.ActionBlock {
+		h.recordBlockedCall(r.Context(), &proxyCall{
+			provider:       provider,
+			format:         format,
+			reqInfo:        reqInfo,
+			tenant:         tenant,
+			tenantID:       tenantID,
+			project:        project,
+			apiKeyID:       apiKeyID,
+			user:           endUser,
+			tags:           tags,
+			streaming:      streamingRequest,
+			start:          start,
+			policyAction:   decision.Action,
+			policyFindings: policyFindings,
+			transform:      decision.Transform,
+		})
+		writePolicyBlock(w, decision)
+		return
+	}
+
+	// Transcripts keep the body after policies ran, so content a policy redacted or a transform
+	// trimmed is never stored, but before the proxy's own stream usage option is added.
+	var transcriptBody []byte
+	if h.options.Transcripts != nil && h.options.Transcripts.Captures(project) {
+		transcriptBody = reqBody
+	}
+
+	stripUsageChunk := false
+	if h.options.InjectStreamUsage && streamingRequest {
+		if rewritten, ok := injectStreamUsageOption(reqBody, format, target.Path); ok {
+			reqBody = rewritten
+			stripUsageChunk = true
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+			r.ContentLength = int64(len(reqBody))
+			r.Header.Set("Content-Length", strconv.Itoa(len(reqBody)))
+		}
+	}
+
+	// Deduplicate client retries before the budget check; replays cost nothing.
+	var idempotency *idempotencyEntry
+	idempotencyKey := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if idempotencyKey != "" && h.idempotency != nil && !webSocket {
+		fingerprint := idempotencyFingerprint(r.Method, target.String(), reqBody)
+		for {
+			entry, owner := h.idempotency.begin(tenant+"\x00"+idempotencyKey, fingerprint)
+			if entry.fingerprint != fingerprint {
+				writeIdempotencyConflict(w)
+				return
+			}
+			if owner {
+				idempotency = entry
+				break
+			}
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if entry.response != nil {
+				h.logger.Info("replayed idempotent response", "tenant", tenant, "idempotency_key", idempotencyKey)
+				writeReplay(w, entry.response, streamUsageMode(r.Header.Get(streamUsageHeader)))
+				return
+			}
+		}
+		defer h.idempotency.finish(idempotency, nil)
 	}
-	tenant := defaultTenant(r.Context())
 
 	// Budget pre-check
-	if h.denyOnExceed {
-		if checkErr := h.tracker.CheckBudgetForProject(r.Context(), tenant, project); checkErr != nil {
+	if tenantConfig.DenyOnExceed {
+		if checkErr := h.tracker.CheckBudgetForTags(r.Context(), tenant, project, tags); checkErr != nil {
 			http.Error(w, fmt.Sprintf("budget exceeded: %v", checkErr), http.StatusPaymentRequired)
 			return
 		}
 	}
+	// Key spend caps are hard limits, enforced whether or not tenant budgets deny.
+	if checkErr := h.tracker.CheckAPIKeySpend(r.Context(), apiKey); checkErr != nil {
+		http.Error(w, fmt.Sprintf("budget exceeded: %v", checkErr), http.StatusPaymentRequired)
+		return
+	}
+
+	call := &proxyCall{
+		provider:    provider,
+		format:      format,
+		reqInfo:     reqInfo,
+		tenant:      tenant,
+		tenantID:    tenantID,
+		project:     project,
+		apiKeyID:    apiKeyID,
+		user:        endUser,
+		tags:        tags,
+		streaming:   streamingRequest,
+		streamUsage: streamUsageMode(r.Header.Get(streamUsageHeader)),
+		stripUsage:  stripUsageChunk,
+		addHeaders:  tenantConfig.AddCostHeaders,
+		start:       start,
+
+		idempotencyKey: idempotencyKey,
+		idempotency:    idempotency,
+		transcriptBody: transcriptBody,
+		policyAction:   decision.Action,
+		policyFindings: policyFindings,
+		transform:      decision.Transform,
+	}
+
+	// Optionally detach the upstream request from the client connection so a disconnect
+	// does not cancel generation that the provider will bill for anyway.
+	upstreamCtx := r.Context()
+	release := func() {}
+	if !h.options.CancelUpstreamOnDisconnect {
+		var cancel context.CancelFunc
+		upstreamCtx, cancel = context.WithCancel(context.WithoutCancel(r.Context()))
+		release = cancel
+	}
+	clientCtx := r.Context()
+	r = r.WithContext(upstreamCtx)
+	streamOwnsUpstream := false
 
 	// Set up reverse proxy
 	proxy := &httputil.ReverseProxy{
@@ -116,84 +361,219 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			req.Header.Del("X-LCG-Project")
 			req.Header.Del("X-LCG-API-Key")
 			req.Header.Del("X-LCG-Tenant")
+			req.Header.Del("X-LCG-Tags")
+			req.Header.Del(streamUsageHeader)
+			h.stripEndUserHeaders(req.Header)
+			if webSocket {
+				// Keep frames uncompressed so provider events can be parsed for usage.
+				req.Header.Del("Sec-WebSocket-Extensions")
+			}
 		},
 		ModifyResponse: func(resp *http.Response) error {
-			return h.captureResponse(r.Context(), resp, provider, reqInfo, tenant, project, streamingRequest, start)
+			call.statusCode = resp.StatusCode
+			call.providerRequestID = providerRequestID(resp.Header)
+			if resp.StatusCode == http.StatusSwitchingProtocols {
+				return h.captureWebSocket(clientCtx, resp, call)
+			}
+			var err error
+			if resp.StatusCode < http.StatusBadRequest &&
+				(streamingRequest || isStreamingContentType(resp.Header.Get("Content-Type"))) {
+				streamOwnsUpstream = true
+				err = h.captureStreamingResponse(clientCtx, resp, call, release)
+			} else {
+				err = h.captureResponse(clientCtx, resp, call)
+				if err == nil && call.idempotency != nil && resp.StatusCode < http.StatusMultipleChoices {
+					h.recordIdempotentResponse(resp, call)
+				}
+			}
+			return err
 		},
-		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
 			h.logger.Error("proxy error", "error", err, "target", targetURL)
+			h.recordFailedCall(clientCtx, call, err)
 			http.Error(w, "proxy error: "+err.Error(), http.StatusBadGateway)
 		},
 	}
 
 	proxy.ServeHTTP(w, r)
+	if !streamOwnsUpstream {
+		release()
+	}
 }
 
-// captureResponse reads the upstream response, extracts usage, calculates cost, and injects headers.
-func (h *Handler) captureResponse(ctx context.Context, resp *http.Response, provider string, reqInfo *RequestInfo, tenant, project string, streamingRequest bool, start time.Time) error {
-	if streamingRequest || isStreamingContentType(resp.Header.Get("Content-Type")) {
-		return h.captureStreamingResponse(ctx, resp, provider, reqInfo, tenant, project, start)
+// proxyCall carries per-request attribution from ServeHTTP through response capture.
+type proxyCall struct {
+	provider          string
+	format            string
+	reqInfo           *RequestInfo
+	tenant            string
+	tenantID          string
+	project           string
+	apiKeyID          string
+	user              string
+	tags              map[string]string
+	streaming         bool
+	streamUsage       string
+	stripUsage        bool
+	addHeaders        bool
+	start             time.Time
+	statusCode        int
+	providerRequestID string
+
+	idempotencyKey string
+	idempotency    *idempotencyEntry
+	// cacheable is set once the response is known to be a complete success that may be replayed.
+	cacheable bool
+	// transcriptBody is the request body as rewritten by policies and transforms, when the
+	// project's transcripts are captured.
+	transcriptBody []byte
+	policyAction   string
+	policyFindings string
+	transform      *model.RequestTransform
+}
+
+// newRecord builds a usage record with the attribution and timing shared by every capture path.
+func (c *proxyCall) newRecord(modelName string, usage *ResponseUsage) *tracker.UsageRecord {
+	if modelName == "" && usage != nil {
+		modelName = usage.Model
+	}
+	if modelName == "" && c.reqInfo != nil {
+		modelName = c.reqInfo.Model
 	}
 
+	record := &tracker.UsageRecord{
+		ID:                uuid.New().String(),
+		TenantID:          c.tenantID,
+		Tenant:            c.tenant,
+		Provider:          c.provider,
+		Model:             modelName,
+		Project:           c.project,
+		APIKeyID:          c.apiKeyID,
+		Metadata:          usageMetadataJSON(c.reqInfo, usage, c.streaming, c.transform),
+		Timestamp:         time.Now().UTC(),
+		CompletionStatus:  model.CompletionStatusComplete,
+		StatusCode:        c.statusCode,
+		LatencyMs:         time.Since(c.start).Milliseconds(),
+		ProviderRequestID: c.providerRequestID,
+		IdempotencyKey:    c.idempotencyKey,
+		User:              c.user,
+		Tags:              c.tags,
+		PolicyAction:      c.policyAction,
+		PolicyFindings:    c.policyFindings,
+	}
+	if usage != nil {
+		record.InputTokens = usage.InputTokens
+		record.OutputTokens = usage.OutputTokens
+	}
+	setOutputThroughput(record)
+	return record
+}
+
+// setOutputThroughput derives output tokens per second from the generation window, which
+// starts at the first token for streams and at request start otherwise.
+func setOutputThroughput(record *tracker.UsageRecord) {
+	generationMs := record.LatencyMs - record.TTFTMs
+	if record.OutputTokens <= 0 || generationMs <= 0 {
+		record.OutputTokensPerSec = 0
+		return
+	}
+	record.OutputTokensPerSec = math.Round(float64(record.OutputTokens)*1000/float64(generationMs)*100) / 100
+}
+
+// captureResponse reads the upstream response, extracts usage, calculates cost, and injects headers.
+func (h *Handler) captureResponse(ctx context.Context, resp *http.Response, call *proxyCall) error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("read response body: %w", err)
 	}
 	resp.Body.Close()
 
+	// Restore body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+
 	// Extract usage from response
-	usage, err := ExtractResponseUsage(body, provider)
+	usage, err := ExtractResponseUsage(body, call.format)
 	if err != nil {
 		h.logger.Warn("failed to extract usage from response", "error", err)
-		resp.Body = io.NopCloser(bytes.NewReader(body))
-		return nil
+		usage = nil
 	}
 
-	if usage == nil {
-		resp.Body = io.NopCloser(bytes.NewReader(body))
+	errorType := classifyUpstreamError(resp.StatusCode, body)
+	if usage == nil && errorType == "" && (call.reqInfo == nil || call.reqInfo.Model == "") {
+		// Not an LLM call we can attribute (e.g. model listing); pass through untracked.
 		return nil
 	}
 
-	modelName := usage.Model
-	if modelName == "" && reqInfo != nil {
-		modelName = reqInfo.Model
-	}
-
-	// Record usage
-	record := &tracker.UsageRecord{
-		ID:           uuid.New().String(),
-		Tenant:       tenant,
-		Provider:     provider,
-		Model:        modelName,
-		InputTokens:  usage.InputTokens,
-		OutputTokens: usage.OutputTokens,
-		Project:      project,
-		Metadata:     usageMetadataJSON(reqInfo, usage, false),
-		Timestamp:    time.Now().UTC(),
+	call.cacheable = resp.StatusCode < http.StatusMultipleChoices && errorType == ""
+	record := call.newRecord("", usage)
+	record.ErrorType = errorType
+	if resp.StatusCode >= http.StatusBadRequest {
+		record.CompletionStatus = completionStatusForError(errorType)
 	}
 
 	if trackErr := h.tracker.TrackWithTokens(ctx, record); trackErr != nil {
 		h.logger.Error("failed to record usage", "error", trackErr)
+	} else {
+		h.saveTranscript(ctx, call, record, body)
+	}
+	if errorType != "" {
+		h.logger.Warn("upstream call failed",
+			"provider", call.provider,
+			"model", record.Model,
+			"status", resp.StatusCode,
+			"error_type", errorType,
+			"provider_request_id", call.providerRequestID,
+		)
 	}
 
 	// Add cost headers
-	if h.addHeaders {
-		latency := time.Since(start)
+	if call.addHeaders {
 		resp.Header.Set("X-LLM-Cost", fmt.Sprintf("%.6f", record.CostUSD))
-		resp.Header.Set("X-LLM-Input-Tokens", strconv.FormatInt(usage.InputTokens, 10))
-		resp.Header.Set("X-LLM-Output-Tokens", strconv.FormatInt(usage.OutputTokens, 10))
-		resp.Header.Set("X-LLM-Provider", provider)
-		resp.Header.Set("X-LLM-Model", modelName)
-		resp.Header.Set("X-LCG-Latency", latency.String())
+		resp.Header.Set("X-LLM-Input-Tokens", strconv.FormatInt(record.InputTokens, 10))
+		resp.Header.Set("X-LLM-Output-Tokens", strconv.FormatInt(record.OutputTokens, 10))
+		resp.Header.Set("X-LLM-Provider", call.provider)
+		resp.Header.Set("X-LLM-Model", record.Model)
+		resp.Header.Set("X-LCG-Latency", time.Since(call.start).String())
 	}
 
-	// Restore body
-	resp.Body = io.NopCloser(bytes.NewReader(body))
-	resp.ContentLength = int64(len(body))
-
 	return nil
 }
 
+// recordFailedCall stores a zero-token record for a request that never produced an upstream response.
+func (h *Handler) recordFailedCall(ctx context.Context, call *proxyCall, err error) {
+	errorType := classifyTransportError(ctx, err)
+	call.statusCode = http.StatusBadGateway
+	if errorType == errorTypeClientClosed {
+		call.statusCode = statusClientClosedRequest
+	}
+
+	record := call.newRecord("", nil)
+	record.ErrorType = errorType
+	record.CompletionStatus = completionStatusForError(errorType)
+	if trackErr := h.tracker.TrackWithTokens(context.WithoutCancel(ctx), record); trackErr != nil {
+		h.logger.Error("failed to record failed call", "error", trackErr)
+	}
+}
+
+// tenantConfig applies the tenant's settings to the handler's configuration. The handler's
+// configuration is used as is when the settings cannot be loaded.
+func (h *Handler) tenantConfig(ctx context.Context, tenant string) model.TenantConfig {
+	config := model.TenantConfig{
+		DenyOnExceed:   h.denyOnExceed,
+		AddCostHeaders: h.addHeaders,
+		DefaultProject: h.defaultProject,
+	}
+	settings, err := h.tracker.TenantSettings(ctx, tenant)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			h.logger.Error("failed to load tenant settings", "tenant", tenant, "error", err)
+		}
+		return config
+	}
+	return settings.Apply(config)
+}
+
 func defaultTenant(ctx context.Context) string {
 	if identity, ok := httpauth.IdentityFromContext(ctx); ok && identity.Tenant.Slug != "" {
 		return identity.Tenant.Slug
@@ -201,8 +581,31 @@ func defaultTenant(ctx context.Context) string {
 	return "default"
 }
 
-func usageMetadataJSON(reqInfo *RequestInfo, usage *ResponseUsage, streaming bool) string {
+// requestTenantID returns the ID of the authenticated tenant, or "" when the request carries
+// no identity and the tenant is resolved by slug.
+func requestTenantID(ctx context.Context) string {
+	if identity, ok := httpauth.IdentityFromContext(ctx); ok {
+		return identity.Tenant.ID
+	}
+	return ""
+}
+
+// requestAPIKey returns the API key that authenticated the request, if any.
+func requestAPIKey(ctx context.Context) *model.APIKey {
+	if identity, ok := httpauth.IdentityFromContext(ctx); ok {
+		return identity.APIKey
+	}
+	return nil
+}
+
+func usageMetadataJSON(reqInfo *RequestInfo, usage *ResponseUsage, streaming bool, transform *model.RequestTransform) string {
 	if reqInfo == nil || usage == nil {
+		if transform != nil {
+			payload, err := json.Marshal(model.UsageMetadata{Streaming: streaming, Transform: transform})
+			if err == nil {
+				return string(payload)
+			}
+		}
 		return "{}"
 	}
 
@@ -216,6 +619,22 @@ func usageMetadataJSON(reqInfo *RequestInfo, usage *ResponseUsage, streaming boo
 		CachedContextCandidate: reqInfo.SystemChars >= 600 || len(reqInfo.Messages) >= 4000,
 		InputOutputRatio:       safeRatio(float64(usage.InputTokens), float64(usage.OutputTokens)),
 		Streaming:              streaming,
+		UsageEstimated:         usage.Estimated,
+		ReasoningTokens:        usage.ReasoningTokens,
+		ToolCount:              reqInfo.ToolCount,
+		ToolSchemaChars:        reqInfo.ToolSchemaChars,
+		ToolChoice:             reqInfo.ToolChoice,
+		ToolCallCount:          usage.ToolCallCount,
+		ToolCallArgsChars:      usage.ToolCallArgsChars,
+		ToolResultCount:        reqInfo.ToolResultCount,
+		ToolResultChars:        reqInfo.ToolResultChars,
+		Transform:              transform,
+	}
+	if schemaTokens := toolSchemaTokens(reqInfo); schemaTokens > 0 {
+		metadata.ToolSchemaTokensEstimate = schemaTokens
+		if usage.InputTokens > 0 {
+			metadata.ToolSchemaInputShare = math.Min(1, float64(schemaTokens)/float64(usage.InputTokens))
+		}
 	}
 
 	payload, err := json.Marshal(metadata)
@@ -5,14 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
@@ -26,6 +31,7 @@ type proxyTestEnv struct {
 	handler  *proxy.Handler
 	upstream *httptest.Server
 	store    storage.Storage
+	registry *providers.Registry
 	calls    *atomic.Int32
 }
 
@@ -63,6 +69,7 @@ func setupProxyTest(t *testing.T, upstreamHandler http.HandlerFunc, maxBodySize
 		handler:  handler,
 		upstream: upstream,
 		store:    store,
+		registry: registry,
 		calls:    calls,
 	}
 }
@@ -175,6 +182,43 @@ func TestProxyHandler_FullRoundTrip(t *testing.T) {
 	assert.Equal(t, "default", records[0].Project)
 }
 
+func TestProxyHandler_RecordsTags(t *testing.T) {
+	upstreamHeaders := make(chan http.Header, 1)
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeaders <- r.Header.Clone()
+		openAIResponseHandler(w, r)
+	}, 1024, false)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(`{"model":"gpt-4o","messages":[]}`)))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("X-LCG-Tags", "Feature=search, env=prod")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, (<-upstreamHeaders).Get("X-LCG-Tags"))
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{Tags: map[string]string{"feature": "search"}})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, map[string]string{"feature": "search", "env": "prod"}, records[0].Tags)
+}
+
+func TestProxyHandler_RejectsMalformedTags(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(`{"model":"gpt-4o","messages":[]}`)))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("X-LCG-Tags", "feature")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "X-LCG-Tags")
+	assert.Zero(t, env.calls.Load())
+}
+
 func TestProxyHandler_ProviderOverride(t *testing.T) {
 	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
 
@@ -263,6 +307,43 @@ func TestProxyHandler_ProjectScopedBudgetEnforcement(t *testing.T) {
 	assert.Equal(t, http.StatusPaymentRequired, blockedResp.Code)
 }
 
+func TestProxyHandler_TenantSettings(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, true)
+	ctx := context.Background()
+
+	require.NoError(t, env.store.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	require.NoError(t, env.store.SetTenantSettings(ctx, &model.TenantSettings{
+		Tenant:         "acme",
+		DenyOnExceed:   model.Ptr(false),
+		AddCostHeaders: model.Ptr(false),
+		DefaultProject: model.Ptr("acme-app"),
+	}))
+	for _, tenant := range []string{"default", "acme"} {
+		require.NoError(t, env.store.SetBudget(ctx, &model.Budget{Name: tenant + "-cap", Tenant: tenant, LimitUSD: 1, Period: model.PeriodMonthly}))
+		require.NoError(t, env.store.UpdateBudgetSpend(ctx, tenant+"-cap", 2))
+	}
+
+	send := func(tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(`{"model":"gpt-4o","messages":[]}`)))
+		req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+		req = req.WithContext(httpauth.WithIdentity(req.Context(), httpauth.Identity{Tenant: model.Tenant{Slug: tenant}}))
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusPaymentRequired, send("default").Code, "the global deny_on_exceed applies")
+
+	w := send("acme")
+	require.Equal(t, http.StatusOK, w.Code, "acme overrides deny_on_exceed")
+	assert.Empty(t, w.Header().Get("X-LLM-Cost"))
+
+	records, err := env.store.QueryUsage(ctx, model.ReportFilter{Tenant: "acme"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "acme-app", records[0].Project)
+}
+
 func TestProxyHandler_OpenAIStreamingRecordsUsage(t *testing.T) {
 	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
 
@@ -286,9 +367,12 @@ func TestProxyHandler_OpenAIStreamingRecordsUsage(t *testing.T) {
 	assert.Equal(t, int64(18), records[0].InputTokens)
 	assert.Equal(t, int64(6), records[0].OutputTokens)
 
+	assert.Equal(t, model.CompletionStatusComplete, records[0].CompletionStatus)
+
 	var metadata model.UsageMetadata
 	require.NoError(t, json.Unmarshal([]byte(records[0].Metadata), &metadata))
 	assert.True(t, metadata.Streaming)
+	assert.False(t, metadata.UsageEstimated)
 }
 
 func TestProxyHandler_AnthropicStreamingMergesUsageEvents(t *testing.T) {
@@ -348,3 +432,499 @@ func TestProxyHandler_InvalidTargetURL(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Zero(t, env.calls.Load())
 }
+
+func TestProxyHandler_RecordsToolMetadata(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3.5-sonnet\",\"usage\":{\"input_tokens\":400}}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"name\":\"get_weather\",\"input\":{}}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"city\\\":\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"\\\"Paris\\\"}\"}}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":12}}\n\n")
+	}, 4096, false)
+
+	body := []byte(`{"model":"claude-3.5-sonnet","stream":true,"tools":[{"name":"get_weather","description":"Look up the current weather for a city","input_schema":{"type":"object","properties":{"city":{"type":"string"}}}}],"messages":[{"role":"user","content":"Weather in Paris?"}]}`)
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/messages")
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(records[0].Metadata), &metadata))
+	assert.Equal(t, 1, metadata.ToolCount)
+	assert.Greater(t, metadata.ToolSchemaTokensEstimate, int64(0))
+	assert.Greater(t, metadata.ToolSchemaInputShare, 0.0)
+	assert.Equal(t, 1, metadata.ToolCallCount)
+	assert.Equal(t, len(`{"city":"Paris"}`), metadata.ToolCallArgsChars)
+}
+
+// abortingStreamTest starts a real proxy server, reads the first streamed chunk, then
+// disconnects the client and lets the upstream finish once the proxy notices.
+func abortingStreamTest(t *testing.T, opts *proxy.Options) *proxyTestEnv {
+	t.Helper()
+
+	resume := make(chan struct{})
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		flusher.Flush()
+
+		select {
+		case <-resume:
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"usage\":{\"prompt_tokens\":18,\"completion_tokens\":40},\"choices\":[]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}, 4096, false)
+	if opts != nil {
+		env.handler.WithOptions(*opts)
+	}
+
+	disconnected := make(chan struct{})
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		go func() {
+			<-r.Context().Done()
+			close(disconnected)
+		}()
+		env.handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxyServer.Close)
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req, err := http.NewRequest("POST", proxyServer.URL+"/v1/chat/completions", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := proxyServer.Client().Do(req)
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	_, err = resp.Body.Read(buf)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	<-disconnected
+	close(resume)
+	return env
+}
+
+func waitForSingleRecord(t *testing.T, store storage.Storage) model.UsageRecord {
+	t.Helper()
+
+	var records []model.UsageRecord
+	require.Eventually(t, func() bool {
+		var err error
+		records, err = store.QueryUsage(context.Background(), model.ReportFilter{})
+		return err == nil && len(records) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	return records[0]
+}
+
+func TestProxyHandler_ClientAbortCancelsUpstream(t *testing.T) {
+	env := abortingStreamTest(t, nil)
+
+	record := waitForSingleRecord(t, env.store)
+	assert.Equal(t, model.CompletionStatusClientAborted, record.CompletionStatus)
+	assert.Less(t, record.OutputTokens, int64(40))
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(record.Metadata), &metadata))
+	assert.True(t, metadata.UsageEstimated)
+
+	summary, err := env.store.AggregateUsage(context.Background(), model.ReportFilter{CompletionStatus: model.CompletionStatusClientAborted})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.RecordCount)
+	assert.Contains(t, summary.ByCompletionStatus, model.CompletionStatusClientAborted)
+}
+
+func TestProxyHandler_ClientAbortDrainsUpstream(t *testing.T) {
+	opts := proxy.DefaultOptions()
+	opts.CancelUpstreamOnDisconnect = false
+	env := abortingStreamTest(t, &opts)
+
+	record := waitForSingleRecord(t, env.store)
+	assert.Equal(t, model.CompletionStatusClientAborted, record.CompletionStatus)
+	assert.Equal(t, int64(18), record.InputTokens)
+	assert.Equal(t, int64(40), record.OutputTokens)
+}
+
+func TestProxyHandler_RecordsUpstreamErrors(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req_abc123")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`)
+	}, 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "Rate limit reached")
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "gpt-4o", records[0].Model)
+	assert.Equal(t, http.StatusTooManyRequests, records[0].StatusCode)
+	assert.Equal(t, "rate_limited", records[0].ErrorType)
+	assert.Equal(t, "req_abc123", records[0].ProviderRequestID)
+	assert.Equal(t, model.CompletionStatusUpstreamError, records[0].CompletionStatus)
+	assert.Zero(t, records[0].CostUSD)
+}
+
+func TestProxyHandler_RecordsContentFilterRefusal(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"gpt-4o","usage":{"prompt_tokens":30,"completion_tokens":0},"choices":[{"finish_reason":"content_filter","message":{"role":"assistant","content":null}}]}`)
+	}, 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "content_filter", records[0].ErrorType)
+	assert.Equal(t, int64(30), records[0].InputTokens)
+	assert.Greater(t, records[0].CostUSD, 0.0)
+}
+
+func TestProxyHandler_RecordsTransportFailure(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", closedURL+"/v1/chat/completions")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, http.StatusBadGateway, records[0].StatusCode)
+	assert.Equal(t, "connection_error", records[0].ErrorType)
+	assert.Equal(t, model.CompletionStatusUpstreamError, records[0].CompletionStatus)
+}
+
+func TestProxyHandler_RecordsStreamErrorEvent(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\n")
+		fmt.Fprint(w, "data: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3.5-sonnet\",\"usage\":{\"input_tokens\":21}}}\n\n")
+		fmt.Fprint(w, "event: error\n")
+		fmt.Fprint(w, "data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}, 1024, false)
+
+	body := []byte(`{"model":"claude-3.5-sonnet","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/messages", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/messages")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "overloaded", records[0].ErrorType)
+	assert.Equal(t, model.CompletionStatusUpstreamError, records[0].CompletionStatus)
+	assert.Equal(t, int64(21), records[0].InputTokens)
+}
+
+func TestProxyHandler_RecordsStreamingTimeToFirstToken(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+
+		// A role-only chunk arrives immediately; the first content token does not.
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		flusher.Flush()
+		time.Sleep(30 * time.Millisecond)
+
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		flusher.Flush()
+		time.Sleep(20 * time.Millisecond)
+
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"usage\":{\"prompt_tokens\":18,\"completion_tokens\":40,\"total_tokens\":58},\"choices\":[]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}, 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.GreaterOrEqual(t, record.TTFTMs, int64(30))
+	assert.GreaterOrEqual(t, record.LatencyMs, record.TTFTMs+20)
+	assert.Greater(t, record.OutputTokensPerSec, 0.0)
+	assert.LessOrEqual(t, record.OutputTokensPerSec, 2000.0)
+}
+
+func TestProxyHandler_RecordsNonStreamingLatency(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		openAIResponseHandler(w, r)
+	}, 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.GreaterOrEqual(t, records[0].LatencyMs, int64(20))
+	assert.Zero(t, records[0].TTFTMs)
+	assert.Greater(t, records[0].OutputTokensPerSec, 0.0)
+}
+
+func TestProxyHandler_StreamUsageEvent(t *testing.T) {
+	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-LCG-Stream-Usage", "event")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	responseBody := w.Body.String()
+	idx := strings.Index(responseBody, "event: lcg.usage\ndata: ")
+	require.GreaterOrEqual(t, idx, 0, responseBody)
+	assert.Less(t, strings.Index(responseBody, "data: [DONE]"), idx)
+
+	data := strings.TrimSpace(strings.TrimPrefix(responseBody[idx:], "event: lcg.usage\ndata: "))
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Equal(t, "gpt-4o", event["model"])
+	assert.Equal(t, float64(18), event["input_tokens"])
+	assert.Equal(t, float64(6), event["output_tokens"])
+	assert.InDelta(t, 0.000105, event["cost_usd"], 0.0000001)
+	assert.Equal(t, false, event["estimated"])
+}
+
+func TestProxyHandler_StreamUsageTrailers(t *testing.T) {
+	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
+	proxyServer := httptest.NewServer(env.handler)
+	t.Cleanup(proxyServer.Close)
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req, err := http.NewRequest("POST", proxyServer.URL+"/v1/chat/completions", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-LCG-Stream-Usage", "trailer")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	streamed, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(streamed), "lcg.usage")
+	assert.Equal(t, "0.000105", resp.Trailer.Get("X-LLM-Cost"))
+	assert.Equal(t, "18", resp.Trailer.Get("X-LLM-Input-Tokens"))
+	assert.Equal(t, "6", resp.Trailer.Get("X-LLM-Output-Tokens"))
+	assert.Equal(t, "false", resp.Trailer.Get("X-LCG-Usage-Estimated"))
+}
+
+func TestProxyHandler_StreamUsageNotSentByDefault(t *testing.T) {
+	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "lcg.usage")
+	assert.Empty(t, w.Header().Get("Trailer"))
+}
+
+func includeUsageAwareStreamHandler(requests chan<- map[string]any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests <- body
+
+		includeUsage := false
+		if options, ok := body["stream_options"].(map[string]any); ok {
+			includeUsage, _ = options["include_usage"].(bool)
+		}
+		openAIStreamingResponseHandler(includeUsage)(w, r)
+	}
+}
+
+func TestProxyHandler_InjectsStreamUsageOption(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	env := setupProxyTest(t, includeUsageAwareStreamHandler(requests), 1024, false)
+	env.handler.WithOptions(proxy.Options{CancelUpstreamOnDisconnect: true, InjectStreamUsage: true})
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	upstreamBody := <-requests
+	assert.Equal(t, map[string]any{"include_usage": true}, upstreamBody["stream_options"])
+	assert.Equal(t, "gpt-4o", upstreamBody["model"])
+
+	responseBody := w.Body.String()
+	assert.NotContains(t, responseBody, `"usage"`)
+	assert.Contains(t, responseBody, "streamed world")
+	assert.Contains(t, responseBody, "data: [DONE]")
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, int64(18), records[0].InputTokens)
+	assert.Equal(t, int64(6), records[0].OutputTokens)
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(records[0].Metadata), &metadata))
+	assert.False(t, metadata.UsageEstimated)
+}
+
+func TestProxyHandler_KeepsClientRequestedUsageChunk(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	env := setupProxyTest(t, includeUsageAwareStreamHandler(requests), 1024, false)
+	env.handler.WithOptions(proxy.Options{CancelUpstreamOnDisconnect: true, InjectStreamUsage: true})
+
+	body := []byte(`{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Accept", "text/event-stream")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	<-requests
+	assert.Contains(t, w.Body.String(), `"usage":{"prompt_tokens":18`)
+}
+
+func TestProxyHandler_OpenAICompatibleProviderByHost(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
+	upstreamURL, err := url.Parse(env.upstream.URL)
+	require.NoError(t, err)
+
+	vllm, err := providers.NewOpenAICompatible(&providers.ProviderConfig{
+		Provider: "vllm-internal",
+		Kind:     providers.KindOpenAICompatible,
+		Hosts:    []string{upstreamURL.Host},
+		GPUHour:  &providers.GPUHourCost{USDPerGPUHour: 3.60, GPUs: 1, InputTokensPerSecond: 1000, OutputTokensPerSecond: 100},
+		Models:   []providers.ModelPricing{{Model: "gpt-4o"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, env.registry.Register(vllm))
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "vllm-internal", w.Header().Get("X-LLM-Provider"))
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "vllm-internal", records[0].Provider)
+	assert.Equal(t, int64(24), records[0].InputTokens)
+	assert.Equal(t, int64(8), records[0].OutputTokens)
+	// $3.60/hour is $0.000001 per input token and $0.00001 per output token.
+	assert.InDelta(t, 24*0.000001+8*0.00001, records[0].CostUSD, 1e-9)
+}
+
+func TestProxyHandler_GeminiJSONArrayStreaming(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		flusher, _ := w.(http.Flusher)
+
+		chunks := []string{
+			"[{\n  \"candidates\": [{\"content\": {\"parts\": [{\"text\": \"Day one: {museums}",
+			" and [parks]\"}], \"role\": \"model\"}}],\n  \"usageMetadata\": {\"promptTokenCount\": 9, \"candidatesTokenCount\": 4, \"thoughtsTokenCount\": 120}\n}\n",
+			",\r\n{\n  \"candidates\": [{\"content\": {\"parts\": [{\"text\": \" Day two: \\\"beach\\\"\"}]}, \"finishReason\": \"STOP\"}],\n",
+			"  \"usageMetadata\": {\"promptTokenCount\": 9, \"candidatesTokenCount\": 11, \"thoughtsTokenCount\": 120},\n  \"modelVersion\": \"gemini-2.5-flash\"\n}\n]",
+		}
+		for _, chunk := range chunks {
+			fmt.Fprint(w, chunk)
+			flusher.Flush()
+		}
+	}, 1024, false)
+	require.NoError(t, env.registry.Register(providers.NewGemini(&providers.ProviderConfig{
+		Provider: "gemini",
+		Models:   []providers.ModelPricing{{Model: "gemini-2.5-flash", InputPerMillion: 0.30, OutputPerMillion: 2.50}},
+	})))
+
+	body := []byte(`{"contents":[{"role":"user","parts":[{"text":"Plan a trip"}]}]}`)
+	req := httptest.NewRequest("POST", "/v1beta/models/gemini-2.5-flash:streamGenerateContent", bytes.NewReader(body))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1beta/models/gemini-2.5-flash:streamGenerateContent")
+	req.Header.Set("X-LCG-Provider", "gemini")
+
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-LCG-Streaming"))
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, "gemini", record.Provider)
+	assert.Equal(t, "gemini-2.5-flash", record.Model)
+	assert.Equal(t, int64(9), record.InputTokens)
+	assert.Equal(t, int64(131), record.OutputTokens)
+	assert.InDelta(t, 9*0.30/1_000_000+131*2.50/1_000_000, record.CostUSD, 1e-12)
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(record.Metadata), &metadata))
+	assert.False(t, metadata.UsageEstimated)
+	assert.Equal(t, int64(120), metadata.ReasoningTokens)
+}
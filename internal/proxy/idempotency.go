@@ -0,0 +1,207 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotentResponseSize = 8 << 20
+	idempotencySweepInterval  = time.Minute
+)
+
+// idempotencyCache deduplicates requests that share a tenant and Idempotency-Key. The first
+// request goes upstream; concurrent duplicates wait for it, and later duplicates within the
+// window replay its response. Failed or oversized responses are not kept, so the next retry
+// goes upstream again and is recorded as duplicate spend under the same key.
+type idempotencyCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	done        chan struct{}
+	once        sync.Once
+	response    *cachedResponse
+	expires     time.Time
+}
+
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newIdempotencyCache(window time.Duration) *idempotencyCache {
+	return &idempotencyCache{window: window, entries: make(map[string]*idempotencyEntry)}
+}
+
+// idempotencyFingerprint identifies the upstream request. X-LCG-Stream-Usage is left out: it
+// only changes what the proxy adds to the response, which writeReplay applies per request.
+func idempotencyFingerprint(method, path string, body []byte) [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+	return sum
+}
+
+// begin returns the entry for key and whether the caller owns the upstream call. When it does
+// not, the caller waits on entry.done and replays entry.response if one was kept.
+func (c *idempotencyCache) begin(key string, fingerprint [sha256.Size]byte) (*idempotencyEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= idempotencySweepInterval {
+		for k, entry := range c.entries {
+			if entry.completed() && now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	if entry, ok := c.entries[key]; ok && (!entry.completed() || now.Before(entry.expires)) {
+		return entry, false
+	}
+	entry := &idempotencyEntry{key: key, fingerprint: fingerprint, done: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true
+}
+
+// finish completes an in-flight entry. A nil response releases waiting duplicates to retry.
+func (c *idempotencyCache) finish(entry *idempotencyEntry, response *cachedResponse) {
+	entry.once.Do(func() {
+		c.mu.Lock()
+		if response == nil {
+			if c.entries[entry.key] == entry {
+				delete(c.entries, entry.key)
+			}
+		} else {
+			entry.response = response
+			entry.expires = time.Now().Add(c.window)
+		}
+		c.mu.Unlock()
+		close(entry.done)
+	})
+}
+
+func (e *idempotencyEntry) completed() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeReplay sends a kept response to a duplicate request. Replays are not billed, so the
+// cost headers of the original call are zeroed and its latency is dropped. Kept streams hold
+// only the upstream bytes; the usage report is added in the mode the duplicate itself asked
+// for, not the one of the original request.
+func writeReplay(w http.ResponseWriter, response *cachedResponse, streamUsage string) {
+	body := response.body
+	var trailer http.Header
+	switch streamUsage {
+	case streamUsageEvent:
+		if event := replayUsageEvent(response.header); event != nil {
+			body = append(bytes.Clone(body), event...)
+		}
+	case streamUsageTrailer:
+		trailer = replayUsageTrailer(response.header)
+	}
+	for name, values := range response.header {
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.Header().Del("Trailer")
+	if w.Header().Get("X-LLM-Cost") != "" {
+		w.Header().Set("X-LLM-Cost", "0.000000")
+		w.Header().Set("X-LLM-Input-Tokens", "0")
+		w.Header().Set("X-LLM-Output-Tokens", "0")
+	}
+	w.Header().Del("X-LCG-Latency")
+	if trailer != nil {
+		// Trailers need a chunked response, so no Content-Length is sent with them.
+		w.Header().Del("Content-Length")
+		for key := range trailer {
+			w.Header().Add("Trailer", key)
+		}
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(response.status)
+	_, _ = w.Write(body)
+	for key, values := range trailer {
+		w.Header()[key] = values
+	}
+}
+
+func writeIdempotencyConflict(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{
+			"type":    "idempotency_key_reused",
+			"message": "Idempotency-Key was already used for a different request",
+		},
+	})
+}
+
+// idempotencyRecorder copies the response body as the client reads it and keeps it for
+// replay once it ends cleanly.
+type idempotencyRecorder struct {
+	io.ReadCloser
+	cache    *idempotencyCache
+	entry    *idempotencyEntry
+	call     *proxyCall
+	status   int
+	header   http.Header
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (h *Handler) recordIdempotentResponse(resp *http.Response, call *proxyCall) {
+	resp.Body = &idempotencyRecorder{
+		ReadCloser: resp.Body,
+		cache:      h.idempotency,
+		entry:      call.idempotency,
+		call:       call,
+		status:     resp.StatusCode,
+		header:     resp.Header.Clone(),
+	}
+}
+
+func (r *idempotencyRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 && !r.overflow {
+		if r.buf.Len()+n > maxIdempotentResponseSize {
+			r.overflow = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		var response *cachedResponse
+		if !r.overflow && r.call.cacheable {
+			response = &cachedResponse{status: r.status, header: r.header, body: bytes.Clone(r.buf.Bytes())}
+		}
+		r.cache.finish(r.entry, response)
+	}
+	return n, err
+}
@@ -0,0 +1,233 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func idempotentRequest(env *proxyTestEnv, key, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(body)))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	return req
+}
+
+const idempotentBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`
+
+func TestProxyHandler_IdempotencyReplaysCompletedResponse(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
+
+	first := httptest.NewRecorder()
+	env.handler.ServeHTTP(first, idempotentRequest(env, "key-1", idempotentBody))
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	second := httptest.NewRecorder()
+	env.handler.ServeHTTP(second, idempotentRequest(env, "key-1", idempotentBody))
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.NotEqual(t, "0.000000", first.Header().Get("X-LLM-Cost"))
+	assert.NotEmpty(t, first.Header().Get("X-LCG-Latency"))
+
+	// The replay costs nothing, so it does not report the original call's spend.
+	assert.Equal(t, "0.000000", second.Header().Get("X-LLM-Cost"))
+	assert.Equal(t, "0", second.Header().Get("X-LLM-Input-Tokens"))
+	assert.Equal(t, "0", second.Header().Get("X-LLM-Output-Tokens"))
+	assert.Equal(t, first.Header().Get("X-LLM-Model"), second.Header().Get("X-LLM-Model"))
+	assert.Empty(t, second.Header().Get("X-LCG-Latency"))
+	assert.Equal(t, int32(1), env.calls.Load())
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "key-1", records[0].IdempotencyKey)
+}
+
+func TestProxyHandler_IdempotencyCollapsesConcurrentDuplicates(t *testing.T) {
+	release := make(chan struct{})
+	var arrived atomic.Int32
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		arrived.Add(1)
+		<-release
+		openAIResponseHandler(w, r)
+	}, 1024, false)
+
+	const duplicates = 5
+	recorders := make([]*httptest.ResponseRecorder, duplicates)
+	var wg sync.WaitGroup
+	for i := range duplicates {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			env.handler.ServeHTTP(recorders[i], idempotentRequest(env, "key-concurrent", idempotentBody))
+		}()
+	}
+
+	require.Eventually(t, func() bool { return arrived.Load() == 1 }, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	replayed := 0
+	for _, rec := range recorders {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		if rec.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	assert.Equal(t, duplicates-1, replayed)
+	assert.Equal(t, int32(1), env.calls.Load())
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestProxyHandler_IdempotencyKeyReusedForDifferentRequest(t *testing.T) {
+	env := setupProxyTest(t, openAIResponseHandler, 1024, false)
+
+	first := httptest.NewRecorder()
+	env.handler.ServeHTTP(first, idempotentRequest(env, "key-2", idempotentBody))
+	require.Equal(t, http.StatusOK, first.Code)
+
+	other := `{"model":"gpt-4o","messages":[{"role":"user","content":"Something else"}]}`
+	second := httptest.NewRecorder()
+	env.handler.ServeHTTP(second, idempotentRequest(env, "key-2", other))
+	assert.Equal(t, http.StatusUnprocessableEntity, second.Code)
+	assert.Contains(t, second.Body.String(), "idempotency_key_reused")
+	assert.Equal(t, int32(1), env.calls.Load())
+}
+
+func TestProxyHandler_IdempotencyRetriesFailedAttempt(t *testing.T) {
+	var attempts atomic.Int32
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":{"message":"overloaded","type":"server_error"}}`))
+			return
+		}
+		openAIResponseHandler(w, r)
+	}, 1024, false)
+
+	first := httptest.NewRecorder()
+	env.handler.ServeHTTP(first, idempotentRequest(env, "key-3", idempotentBody))
+	require.Equal(t, http.StatusServiceUnavailable, first.Code)
+
+	second := httptest.NewRecorder()
+	env.handler.ServeHTTP(second, idempotentRequest(env, "key-3", idempotentBody))
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Empty(t, second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(2), env.calls.Load())
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{IdempotencyKey: "key-3"})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	summary, err := env.store.AggregateUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), summary.DuplicateRequests)
+}
+
+const idempotentStreamBody = `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hello"}]}`
+
+func streamUsageEventOf(t *testing.T, body string) map[string]any {
+	t.Helper()
+	idx := strings.Index(body, "event: lcg.usage\ndata: ")
+	require.GreaterOrEqual(t, idx, 0, body)
+	require.Equal(t, idx, strings.LastIndex(body, "event: lcg.usage"), "expected a single usage event")
+	data := strings.TrimSpace(strings.TrimPrefix(body[idx:], "event: lcg.usage\ndata: "))
+	var event map[string]any
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	return event
+}
+
+func TestProxyHandler_IdempotencyReplaysStreamWithZeroCostUsageEvent(t *testing.T) {
+	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
+
+	send := func() *httptest.ResponseRecorder {
+		req := idempotentRequest(env, "key-stream", idempotentStreamBody)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("X-LCG-Stream-Usage", "event")
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		return w
+	}
+
+	first := send()
+	original := streamUsageEventOf(t, first.Body.String())
+	assert.InDelta(t, 0.000105, original["cost_usd"], 0.0000001)
+
+	second := send()
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	replayed := streamUsageEventOf(t, second.Body.String())
+	assert.Equal(t, "gpt-4o", replayed["model"])
+	assert.Equal(t, float64(0), replayed["cost_usd"])
+	assert.Equal(t, float64(0), replayed["input_tokens"])
+	assert.Equal(t, float64(0), replayed["output_tokens"])
+
+	upstream := func(body string) string {
+		return body[:strings.Index(body, "event: lcg.usage")]
+	}
+	assert.Equal(t, upstream(first.Body.String()), upstream(second.Body.String()))
+	assert.Equal(t, int32(1), env.calls.Load())
+}
+
+func TestProxyHandler_IdempotencyReplayUsesRetryStreamUsageMode(t *testing.T) {
+	env := setupProxyTest(t, openAIStreamingResponseHandler(true), 1024, false)
+	proxyServer := httptest.NewServer(env.handler)
+	t.Cleanup(proxyServer.Close)
+
+	send := func(mode string) (*http.Response, string) {
+		req, err := http.NewRequest("POST", proxyServer.URL+"/v1/chat/completions", strings.NewReader(idempotentStreamBody))
+		require.NoError(t, err)
+		req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Idempotency-Key", "key-stream-mode")
+		if mode != "" {
+			req.Header.Set("X-LCG-Stream-Usage", mode)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		streamed, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp, string(streamed)
+	}
+
+	_, original := send("event")
+	assert.Contains(t, original, "lcg.usage")
+
+	// The key and body match, so each retry is a replay, but it follows its own opt-in.
+	plain, body := send("")
+	assert.Equal(t, "true", plain.Header.Get("Idempotent-Replayed"))
+	assert.NotContains(t, body, "lcg.usage")
+	assert.Empty(t, plain.Trailer)
+
+	trailers, body := send("trailer")
+	assert.Equal(t, "true", trailers.Header.Get("Idempotent-Replayed"))
+	assert.NotContains(t, body, "lcg.usage")
+	assert.Equal(t, "0.000000", trailers.Trailer.Get("X-LLM-Cost"))
+	assert.Equal(t, "0", trailers.Trailer.Get("X-LLM-Input-Tokens"))
+	assert.Equal(t, "0", trailers.Trailer.Get("X-LLM-Output-Tokens"))
+	assert.Equal(t, "gpt-4o", trailers.Trailer.Get("X-LLM-Model"))
+	assert.Equal(t, int32(1), env.calls.Load())
+}
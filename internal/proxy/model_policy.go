@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+)
+
+// ModelAccessPolicy enforces the stored model policies of the request's tenant. Every policy
+// evaluation is written to the audit log.
+type ModelAccessPolicy struct {
+	store  storage.Storage
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	compiled map[string]compiledPolicies
+}
+
+// compiledPolicies holds a tenant's compiled policies and the ids and update times they were
+// compiled from, so globs are only recompiled after a policy changes.
+type compiledPolicies struct {
+	version string
+	rules   []*policy.ModelRules
+}
+
+// NewModelAccessPolicy returns a RequestPolicy backed by the model policies in store.
+func NewModelAccessPolicy(store storage.Storage, logger *slog.Logger) *ModelAccessPolicy {
+	return &ModelAccessPolicy{store: store, logger: logger, compiled: make(map[string]compiledPolicies)}
+}
+
+// Evaluate implements RequestPolicy. Requests are blocked when the policies cannot be loaded.
+func (p *ModelAccessPolicy) Evaluate(ctx context.Context, req *PolicyRequest) PolicyDecision {
+	rules, err := p.load(ctx, req.Tenant)
+	if err != nil {
+		p.logger.Error("load model policies", "tenant", req.Tenant, "error", err)
+		return PolicyDecision{
+			Action:   policy.ActionBlock,
+			Findings: []string{"model_policy_unavailable"},
+			Policy:   "model",
+			Code:     "model_policy_unavailable",
+			Reason:   "model policies could not be loaded",
+		}
+	}
+	if len(rules) == 0 {
+		return PolicyDecision{}
+	}
+
+	modelReq := policy.ModelRequest{
+		Project:   req.Project,
+		APIKeyID:  req.APIKeyID,
+		Provider:  req.Provider,
+		Streaming: req.Streaming,
+	}
+	if req.Info != nil {
+		modelReq.Model = req.Info.Model
+		modelReq.MaxTokens = req.Info.MaxTokens
+	}
+	applied, violation := policy.CheckModels(rules, modelReq)
+	if len(applied) == 0 {
+		return PolicyDecision{}
+	}
+
+	attrs := []any{
+		"tenant", req.Tenant,
+		"project", req.Project,
+		"api_key_id", req.APIKeyID,
+		"provider", modelReq.Provider,
+		"model", modelReq.Model,
+		"max_tokens", modelReq.MaxTokens,
+		"streaming", modelReq.Streaming,
+		"policies", strings.Join(applied, ","),
+	}
+	if violation == nil {
+		p.logger.Info("model policy evaluated", append(attrs, "decision", "allow")...)
+		return PolicyDecision{}
+	}
+	p.logger.Warn("model policy evaluated", append(attrs,
+		"decision", "deny",
+		"policy", violation.Policy,
+		"code", violation.Code,
+	)...)
+	return PolicyDecision{
+		Action:   policy.ActionBlock,
+		Findings: []string{violation.Code},
+		Policy:   violation.Policy,
+		Code:     violation.Code,
+		Reason:   violation.Message,
+	}
+}
+
+// load returns the tenant's compiled policies, compiling them again only when the stored
+// policies changed since the last request.
+func (p *ModelAccessPolicy) load(ctx context.Context, tenant string) ([]*policy.ModelRules, error) {
+	policies, err := p.store.ListModelPolicies(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	var version strings.Builder
+	for _, stored := range policies {
+		version.WriteString(stored.ID)
+		version.WriteByte('@')
+		version.WriteString(strconv.FormatInt(stored.UpdatedAt.UnixNano(), 10))
+		version.WriteByte(',')
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.compiled[tenant]; ok && cached.version == version.String() {
+		return cached.rules, nil
+	}
+	rules := make([]*policy.ModelRules, 0, len(policies))
+	for _, stored := range policies {
+		compiled, err := policy.CompileModelPolicy(stored)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, compiled)
+	}
+	if len(policies) == 0 {
+		delete(p.compiled, tenant)
+	} else {
+		p.compiled[tenant] = compiledPolicies{version: version.String(), rules: rules}
+	}
+	return rules, nil
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+)
+
+// RequestPolicy is a stage that inspects a request after attribution and before it is
+// forwarded. Policies run in the order configured in Options.Policies.
+type RequestPolicy interface {
+	Evaluate(ctx context.Context, req *PolicyRequest) PolicyDecision
+}
+
+// PolicyRequest is the request as seen by a RequestPolicy. Body reflects rewrites made by
+// earlier policies, and Info is extracted from it. Provider is detected from the target, and
+// comes from X-LCG-Provider only when the target does not identify one.
+type PolicyRequest struct {
+	Tenant    string
+	Project   string
+	APIKeyID  string
+	Provider  string
+	Format    string
+	Path      string
+	Streaming bool
+	Info      *RequestInfo
+	Body      []byte
+}
+
+// PolicyDecision is the result of a RequestPolicy. Body, when set, replaces the request body,
+// and Transform records the rewrite in the usage metadata. Policy, Code, and Reason describe a
+// block to the client.
+type PolicyDecision struct {
+	Action    string
+	Findings  []string
+	Body      []byte
+	Transform *model.RequestTransform
+	Policy    string
+	Code      string
+	Reason    string
+}
+
+// runPolicies applies every configured policy and merges their decisions. The strongest
+// action wins, findings are combined, and the first block stops evaluation.
+func (h *Handler) runPolicies(ctx context.Context, req *PolicyRequest) PolicyDecision {
+	var merged PolicyDecision
+	findings := make(map[string]bool)
+	for _, stage := range h.options.Policies {
+		decision := stage.Evaluate(ctx, req)
+		for _, finding := range decision.Findings {
+			findings[finding] = true
+		}
+		if policy.Stronger(decision.Action, merged.Action) {
+			merged.Action = decision.Action
+		}
+		if decision.Body != nil {
+			req.Body = decision.Body
+			req.Info, _ = ExtractRequestInfo(req.Body, req.Format, req.Path)
+			merged.Body = decision.Body
+		}
+		if decision.Transform != nil {
+			merged.Transform = mergeTransforms(merged.Transform, decision.Transform)
+		}
+		if decision.Action == policy.ActionBlock {
+			merged.Policy = decision.Policy
+			merged.Code = decision.Code
+			merged.Reason = decision.Reason
+			break
+		}
+	}
+	for finding := range findings {
+		merged.Findings = append(merged.Findings, finding)
+	}
+	sort.Strings(merged.Findings)
+	return merged
+}
+
+func mergeTransforms(into, from *model.RequestTransform) *model.RequestTransform {
+	if into == nil {
+		copied := *from
+		return &copied
+	}
+	if from.MaxTokensTo > 0 {
+		into.MaxTokensFrom, into.MaxTokensTo = from.MaxTokensFrom, from.MaxTokensTo
+	}
+	if from.TrimmedMessages > 0 {
+		into.TrimmedMessages += from.TrimmedMessages
+		if into.PromptTokensFrom == 0 {
+			into.PromptTokensFrom = from.PromptTokensFrom
+		}
+		into.PromptTokensTo = from.PromptTokensTo
+	}
+	return into
+}
+
+// recordBlockedCall stores a zero-token record for a request a policy refused to forward.
+func (h *Handler) recordBlockedCall(ctx context.Context, call *proxyCall) {
+	call.statusCode = http.StatusForbidden
+	record := call.newRecord("", nil)
+	record.CompletionStatus = model.CompletionStatusBlocked
+	if err := h.tracker.TrackWithTokens(context.WithoutCancel(ctx), record); err != nil {
+		h.logger.Error("failed to record blocked call", "error", err)
+	}
+	h.logger.Warn("request blocked by policy",
+		"tenant", call.tenant,
+		"project", call.project,
+		"findings", record.PolicyFindings,
+	)
+}
+
+// writePolicyBlock answers a blocked request with a 403 JSON error naming the policy and the
+// violated rule.
+func writePolicyBlock(w http.ResponseWriter, decision PolicyDecision) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{
+			"type":     "policy_violation",
+			"code":     decision.Code,
+			"policy":   decision.Policy,
+			"message":  "request blocked by policy: " + decision.Reason,
+			"findings": decision.Findings,
+		},
+	})
+}
+
+// ContentPolicy scans message content for secrets and PII and applies the action configured
+// for the request's project.
+type ContentPolicy struct {
+	engine *policy.Engine
+}
+
+// NewContentPolicy returns a RequestPolicy backed by engine.
+func NewContentPolicy(engine *policy.Engine) *ContentPolicy {
+	return &ContentPolicy{engine: engine}
+}
+
+// Evaluate implements RequestPolicy.
+func (p *ContentPolicy) Evaluate(_ context.Context, req *PolicyRequest) PolicyDecision {
+	text := string(req.Body)
+	if req.Info != nil && strings.TrimSpace(req.Info.Messages) != "" {
+		text = req.Info.Messages
+	}
+	found := p.engine.Evaluate(req.Project, text)
+	decision := PolicyDecision{Action: found.Action, Findings: found.Findings}
+	switch found.Action {
+	case policy.ActionRedact:
+		decision.Body = redactJSONStrings(req.Body, p.engine.Redact)
+	case policy.ActionBlock:
+		decision.Policy = "content"
+		decision.Code = "sensitive_content"
+		decision.Reason = "request contains " + strings.Join(found.Findings, ", ")
+	}
+	return decision
+}
+
+// redactJSONStrings applies redact to every string value of a JSON body, leaving keys and
+// numbers intact. Bodies that are not JSON are redacted as plain text.
+func redactJSONStrings(body []byte, redact func(string) string) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []byte(redact(string(body)))
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactValue(value, redact)); err != nil {
+		return []byte(redact(string(body)))
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func redactValue(value any, redact func(string) string) any {
+	switch typed := value.(type) {
+	case string:
+		return redact(typed)
+	case []any:
+		for i, item := range typed {
+			typed[i] = redactValue(item, redact)
+		}
+		return typed
+	case map[string]any:
+		for key, item := range typed {
+			typed[key] = redactValue(item, redact)
+		}
+		return typed
+	default:
+		return value
+	}
+}
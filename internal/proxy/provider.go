@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"sort"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
+)
+
+// detectProvider prefers host rules from OpenAI-compatible pricing files over built-in vendor
+// detection, so self-hosted endpoints serving /v1/chat/completions are not billed as OpenAI.
+func (h *Handler) detectProvider(host, requestPath string) string {
+	registry := h.tracker.Registry()
+	if registry != nil {
+		all := registry.All()
+		sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+		for _, provider := range all {
+			if matcher, ok := provider.(providers.HostMatcher); ok && matcher.MatchesHost(host) {
+				return provider.Name()
+			}
+		}
+	}
+	return DetectProvider(host, requestPath)
+}
+
+// apiFormat returns the wire format used to parse a provider's requests and responses.
+func (h *Handler) apiFormat(provider string) string {
+	registry := h.tracker.Registry()
+	if registry == nil || provider == "" {
+		return provider
+	}
+	registered, err := registry.Get(provider)
+	if err != nil {
+		return provider
+	}
+	if formatter, ok := registered.(providers.APIFormatter); ok {
+		return formatter.APIFormat()
+	}
+	return provider
+}
@@ -1,42 +1,58 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"math"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/google/uuid"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 )
 
 const maxBufferedStreamText = 1 << 20
 
 type streamCaptureResult struct {
-	usage   *ResponseUsage
-	content string
-	rawSize int
+	usage     *ResponseUsage
+	content   string
+	rawSize   int
+	status    string
+	errorType string
+	// firstTokenAt is when the first generated content arrived, or the first chunk if
+	// the stream carried no recognizable content.
+	firstTokenAt time.Time
 }
 
 type streamingBody struct {
-	inner      io.ReadCloser
-	parser     *streamParser
-	onComplete func(streamCaptureResult)
-	once       sync.Once
-}
-
-func newStreamingBody(inner io.ReadCloser, parser *streamParser, onComplete func(streamCaptureResult)) io.ReadCloser {
+	inner        io.ReadCloser
+	parser       *streamParser
+	onComplete   func(streamCaptureResult)
+	clientCtx    context.Context
+	release      func()
+	drain        bool
+	drainTimeout time.Duration
+	done         atomic.Bool
+	once         sync.Once
+}
+
+func newStreamingBody(inner io.ReadCloser, parser *streamParser, onComplete func(streamCaptureResult)) *streamingBody {
 	return &streamingBody{
 		inner:      inner,
 		parser:     parser,
 		onComplete: onComplete,
+		clientCtx:  context.Background(),
+		release:    func() {},
 	}
 }
 
@@ -45,20 +61,70 @@ func (s *streamingBody) Read(p []byte) (int, error) {
 	if n > 0 {
 		s.parser.Append(p[:n])
 	}
-	if err == io.EOF {
-		s.finish()
+	switch {
+	case err == io.EOF && s.clientCtx.Err() != nil:
+		// Upstream finished while being drained for a client that already went away.
+		s.finish(model.CompletionStatusClientAborted)
+	case err == io.EOF:
+		s.finish(model.CompletionStatusComplete)
+	case err != nil:
+		s.finish(s.errorStatus(err))
 	}
 	return n, err
 }
 
+// Close is called by the reverse proxy once copying stops. Closing before the upstream
+// reached EOF means the client went away; depending on configuration the upstream is
+// either abandoned or drained in the background so the billed usage is still captured.
 func (s *streamingBody) Close() error {
-	s.finish()
-	return s.inner.Close()
+	if s.done.Load() || !s.drain {
+		s.finish(model.CompletionStatusClientAborted)
+		err := s.inner.Close()
+		s.release()
+		return err
+	}
+
+	go s.drainAfterDisconnect()
+	return nil
+}
+
+func (s *streamingBody) drainAfterDisconnect() {
+	timer := time.AfterFunc(s.drainTimeout, s.release)
+	defer timer.Stop()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.inner.Read(buf)
+		if n > 0 {
+			s.parser.Append(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	s.finish(model.CompletionStatusClientAborted)
+	_ = s.inner.Close()
+	s.release()
 }
 
-func (s *streamingBody) finish() {
+func (s *streamingBody) errorStatus(err error) string {
+	if s.clientCtx.Err() != nil {
+		return model.CompletionStatusClientAborted
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return model.CompletionStatusTimeout
+	}
+	return model.CompletionStatusUpstreamError
+}
+
+func (s *streamingBody) finish(status string) {
 	s.once.Do(func() {
-		s.onComplete(s.parser.Finalize())
+		s.done.Store(true)
+		result := s.parser.Finalize()
+		result.status = status
+		s.onComplete(result)
 	})
 }
 
@@ -71,8 +137,27 @@ type streamParser struct {
 	outputText strings.Builder
 	rawText    strings.Builder
 	rawSize    int
+	toolCalls  int
+	toolArgs   int
+	errorType  string
+
+	firstChunkAt time.Time
+	firstTokenAt time.Time
+
+	// format is detected from the first non-space byte: a '[' means the body is a streamed
+	// JSON array (Gemini and Vertex streamGenerateContent without alt=sse) rather than SSE lines.
+	format    streamFormat
+	jsonArray jsonArrayScanner
 }
 
+type streamFormat int
+
+const (
+	streamFormatUnknown streamFormat = iota
+	streamFormatLines
+	streamFormatJSONArray
+)
+
 func newStreamParser(provider string, reqInfo *RequestInfo) *streamParser {
 	return &streamParser{
 		provider: provider,
@@ -85,9 +170,27 @@ func (p *streamParser) Append(chunk []byte) {
 		return
 	}
 
+	if p.firstChunkAt.IsZero() {
+		p.firstChunkAt = time.Now()
+	}
 	p.rawSize += len(chunk)
 	p.appendRawText(chunk)
 
+	if p.format == streamFormatUnknown {
+		trimmed := bytes.TrimLeftFunc(chunk, unicode.IsSpace)
+		switch {
+		case len(trimmed) == 0:
+		case trimmed[0] == '[':
+			p.format = streamFormatJSONArray
+		default:
+			p.format = streamFormatLines
+		}
+	}
+	if p.format == streamFormatJSONArray {
+		p.jsonArray.feed(chunk, p.processPayload)
+		return
+	}
+
 	p.pending += string(chunk)
 	for {
 		idx := strings.IndexByte(p.pending, '\n')
@@ -115,14 +218,18 @@ func (p *streamParser) Finalize() streamCaptureResult {
 		usage.Model = p.reqInfo.Model
 	}
 	if usage.InputTokens == 0 && p.reqInfo != nil {
-		usage.InputTokens = estimateTokens(p.reqInfo.Messages)
+		usage.InputTokens = estimateTokens(p.reqInfo.Messages) + toolSchemaTokens(p.reqInfo)
+		usage.Estimated = true
 	}
+	usage.ToolCallCount = p.toolCalls
+	usage.ToolCallArgsChars = p.toolArgs
 
 	content := strings.TrimSpace(p.outputText.String())
 	if content == "" {
 		content = strings.TrimSpace(p.rawText.String())
 	}
 	if usage.OutputTokens == 0 {
+		usage.Estimated = true
 		usage.OutputTokens = estimateTokens(content)
 		if usage.OutputTokens == 0 && p.rawSize > 0 {
 			usage.OutputTokens = int64(math.Ceil(float64(p.rawSize) / 4.0))
@@ -132,10 +239,17 @@ func (p *streamParser) Finalize() streamCaptureResult {
 		usage = nil
 	}
 
+	firstTokenAt := p.firstTokenAt
+	if firstTokenAt.IsZero() {
+		firstTokenAt = p.firstChunkAt
+	}
+
 	return streamCaptureResult{
-		usage:   usage,
-		content: content,
-		rawSize: p.rawSize,
+		usage:        usage,
+		content:      content,
+		rawSize:      p.rawSize,
+		errorType:    p.errorType,
+		firstTokenAt: firstTokenAt,
 	}
 }
 
@@ -191,7 +305,18 @@ func (p *streamParser) processPayload(payload string) {
 		p.usage = mergeUsage(p.usage, usage)
 	}
 
-	text := extractStreamText([]byte(payload), p.provider)
+	if errorType := extractStreamError([]byte(payload)); errorType != "" && p.errorType == "" {
+		p.errorType = errorType
+	}
+
+	calls, argsChars := extractStreamToolCalls([]byte(payload), p.provider)
+	p.toolCalls += calls
+	p.toolArgs += argsChars
+
+	text, generated := extractStreamContent([]byte(payload), p.provider)
+	if p.firstTokenAt.IsZero() && (generated || calls > 0) {
+		p.firstTokenAt = time.Now()
+	}
 	if text != "" && p.outputText.Len() < maxBufferedStreamText {
 		remaining := maxBufferedStreamText - p.outputText.Len()
 		if len(text) > remaining {
@@ -201,69 +326,103 @@ func (p *streamParser) processPayload(payload string) {
 	}
 }
 
-func (h *Handler) captureStreamingResponse(ctx context.Context, resp *http.Response, provider string, reqInfo *RequestInfo, tenant, project string, start time.Time) error {
-	parser := newStreamParser(provider, reqInfo)
-	model := ""
-	if reqInfo != nil {
-		model = reqInfo.Model
+func (h *Handler) captureStreamingResponse(ctx context.Context, resp *http.Response, call *proxyCall, release func()) error {
+	call.streaming = true
+	parser := newStreamParser(call.format, call.reqInfo)
+	modelName := ""
+	if call.reqInfo != nil {
+		modelName = call.reqInfo.Model
 	}
 
-	if h.addHeaders {
+	if call.addHeaders {
 		resp.Header.Set("X-LCG-Streaming", "true")
-		resp.Header.Set("X-LLM-Provider", provider)
-		if model != "" {
-			resp.Header.Set("X-LLM-Model", model)
+		resp.Header.Set("X-LLM-Provider", call.provider)
+		if modelName != "" {
+			resp.Header.Set("X-LLM-Model", modelName)
 		}
 	}
 
-	resp.Body = newStreamingBody(resp.Body, parser, func(result streamCaptureResult) {
-		h.recordStreamingUsage(ctx, provider, reqInfo, tenant, project, start, result)
+	var usageReport streamUsageReport
+	if call.addHeaders {
+		usageReport = prepareStreamUsageReport(resp, call.streamUsage)
+	}
+
+	body := newStreamingBody(resp.Body, parser, func(result streamCaptureResult) {
+		call.cacheable = result.status == model.CompletionStatusComplete && result.errorType == ""
+		record := h.recordStreamingUsage(ctx, call, result)
+		if usageReport != nil && result.status == model.CompletionStatusComplete {
+			usageReport.complete(record, result.usage)
+		}
 	})
+	body.clientCtx = ctx
+	body.release = release
+	body.drain = !h.options.CancelUpstreamOnDisconnect
+	body.drainTimeout = h.options.DisconnectDrainTimeout
+	resp.Body = body
+	if call.stripUsage {
+		resp.Body = newUsageChunkFilter(body)
+	}
+	// The recorder sits below the usage report so replays never carry the original call's usage.
+	if call.idempotency != nil && resp.StatusCode < http.StatusMultipleChoices {
+		h.recordIdempotentResponse(resp, call)
+	}
+	if usageReport != nil {
+		resp.Body = &streamUsageTail{ReadCloser: resp.Body, tail: usageReport.tail}
+	}
 	return nil
 }
 
-func (h *Handler) recordStreamingUsage(ctx context.Context, provider string, reqInfo *RequestInfo, tenant, project string, start time.Time, result streamCaptureResult) {
-	if result.usage == nil {
-		return
+// recordStreamingUsage stores the usage of a finished stream and returns the record, or nil if
+// nothing was recorded.
+func (h *Handler) recordStreamingUsage(ctx context.Context, call *proxyCall, result streamCaptureResult) *tracker.UsageRecord {
+	if result.usage == nil && result.errorType == "" {
+		return nil
 	}
 
-	model := result.usage.Model
-	if model == "" && reqInfo != nil {
-		model = reqInfo.Model
-	}
-	if model == "" {
-		h.logger.Warn("skipping streaming usage record without model", "provider", provider)
-		return
+	record := call.newRecord("", result.usage)
+	if record.Model == "" {
+		h.logger.Warn("skipping streaming usage record without model", "provider", call.provider)
+		return nil
 	}
-
-	record := &tracker.UsageRecord{
-		ID:           uuid.New().String(),
-		Tenant:       tenant,
-		Provider:     provider,
-		Model:        model,
-		InputTokens:  result.usage.InputTokens,
-		OutputTokens: result.usage.OutputTokens,
-		Project:      project,
-		Metadata:     usageMetadataJSON(reqInfo, result.usage, true),
-		Timestamp:    time.Now().UTC(),
+	if !result.firstTokenAt.IsZero() {
+		record.TTFTMs = result.firstTokenAt.Sub(call.start).Milliseconds()
+		setOutputThroughput(record)
+	}
+	record.CompletionStatus = result.status
+	record.ErrorType = result.errorType
+	if record.ErrorType != "" && record.CompletionStatus == model.CompletionStatusComplete {
+		record.CompletionStatus = model.CompletionStatusUpstreamError
+	}
+	if record.CompletionStatus != model.CompletionStatusComplete {
+		h.logger.Warn("streaming response did not complete",
+			"provider", call.provider,
+			"model", record.Model,
+			"tenant", call.tenant,
+			"project", call.project,
+			"completion_status", record.CompletionStatus,
+			"error_type", record.ErrorType,
+		)
 	}
 
-	if trackErr := h.tracker.TrackWithTokens(ctx, record); trackErr != nil {
-		h.logger.Error("failed to record streaming usage", "error", trackErr, "provider", provider, "model", model)
-		return
+	// The client context may already be canceled after a disconnect; the record must still be written.
+	if trackErr := h.tracker.TrackWithTokens(context.WithoutCancel(ctx), record); trackErr != nil {
+		h.logger.Error("failed to record streaming usage", "error", trackErr, "provider", call.provider, "model", record.Model)
+		return nil
 	}
+	h.saveTranscript(ctx, call, record, []byte(result.content))
 
-	if h.addHeaders {
+	if call.addHeaders {
 		h.logger.Debug("streaming usage recorded",
-			"provider", provider,
-			"model", model,
-			"tenant", tenant,
-			"project", project,
-			"input_tokens", result.usage.InputTokens,
-			"output_tokens", result.usage.OutputTokens,
-			"latency", time.Since(start).String(),
+			"provider", call.provider,
+			"model", record.Model,
+			"tenant", call.tenant,
+			"project", call.project,
+			"input_tokens", record.InputTokens,
+			"output_tokens", record.OutputTokens,
+			"latency", time.Since(call.start).String(),
 		)
 	}
+	return record
 }
 
 func isStreamingRequest(r *http.Request, targetPath string, body []byte) bool {
@@ -330,32 +489,34 @@ func recursiveUsage(value any) *ResponseUsage {
 	return nil
 }
 
-func extractStreamText(payload []byte, provider string) string {
+// extractStreamContent returns the text of a stream payload and whether that text is generated
+// content. Text found only by the generic fallback may be metadata such as roles or model names.
+func extractStreamContent(payload []byte, provider string) (string, bool) {
 	var decoded map[string]any
 	if err := json.Unmarshal(payload, &decoded); err != nil {
-		return ""
+		return "", false
 	}
 
+	known := true
+	var text string
 	switch provider {
 	case "openai", "azure-openai":
-		if text := extractOpenAIStreamText(decoded); text != "" {
-			return text
-		}
+		text = extractOpenAIStreamText(decoded)
 	case "anthropic":
-		if text := extractAnthropicStreamText(decoded); text != "" {
-			return text
-		}
-	case "vertex-ai":
-		if text := extractVertexStreamText(decoded); text != "" {
-			return text
-		}
+		text = extractAnthropicStreamText(decoded)
+	case "vertex-ai", "gemini":
+		text = extractVertexStreamText(decoded)
 	case "bedrock":
-		if text := extractBedrockStreamText(decoded); text != "" {
-			return text
-		}
+		text = extractBedrockStreamText(decoded)
+	default:
+		known = false
+	}
+	if text != "" {
+		return text, true
 	}
 
-	return recursiveText(decoded)
+	text = recursiveText(decoded)
+	return text, text != "" && !known
 }
 
 func extractOpenAIStreamText(decoded map[string]any) string {
@@ -458,6 +619,9 @@ func mergeUsage(current, next *ResponseUsage) *ResponseUsage {
 	if next.OutputTokens > current.OutputTokens {
 		current.OutputTokens = next.OutputTokens
 	}
+	if next.ReasoningTokens > current.ReasoningTokens {
+		current.ReasoningTokens = next.ReasoningTokens
+	}
 	if current.Model == "" && next.Model != "" {
 		current.Model = next.Model
 	}
@@ -0,0 +1,50 @@
+package proxy
+
+// jsonArrayScanner splits a streamed JSON array such as `[{...},\r\n{...}]` into its top-level
+// objects as bytes arrive. Elements may be pretty-printed across many lines and chunks.
+type jsonArrayScanner struct {
+	depth    int
+	inString bool
+	escaped  bool
+	current  []byte
+}
+
+// feed consumes a chunk and calls emit with each complete top-level array element.
+func (s *jsonArrayScanner) feed(chunk []byte, emit func(string)) {
+	for _, c := range chunk {
+		if s.depth >= 2 {
+			s.current = append(s.current, c)
+		}
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			s.inString = true
+		case '[', '{':
+			s.depth++
+			if s.depth == 2 {
+				s.current = append(s.current[:0], c)
+			}
+		case ']', '}':
+			if s.depth == 0 {
+				continue
+			}
+			s.depth--
+			if s.depth == 1 && len(s.current) > 0 {
+				emit(string(s.current))
+				s.current = s.current[:0]
+			}
+		}
+	}
+}
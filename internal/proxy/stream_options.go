@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// injectStreamUsageOption rewrites a streaming OpenAI-style chat or completions request so the
+// upstream sends a final usage chunk. It reports false when the body was left unchanged,
+// including when the client already asked for usage itself.
+func injectStreamUsageOption(body []byte, provider, targetPath string) ([]byte, bool) {
+	if provider != "openai" && provider != "azure-openai" {
+		return nil, false
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(strings.ToLower(targetPath), "/"), "completions") {
+		return nil, false
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+	var stream bool
+	if err := json.Unmarshal(fields["stream"], &stream); err != nil || !stream {
+		return nil, false
+	}
+
+	options := make(map[string]json.RawMessage)
+	if raw, ok := fields["stream_options"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return nil, false
+		}
+	}
+	var includeUsage bool
+	if raw, ok := options["include_usage"]; ok {
+		if err := json.Unmarshal(raw, &includeUsage); err == nil && includeUsage {
+			return nil, false
+		}
+	}
+
+	options["include_usage"] = json.RawMessage("true")
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		return nil, false
+	}
+	fields["stream_options"] = encodedOptions
+
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return rewritten, true
+}
+
+// usageChunkFilter removes the usage-only chunk that include_usage adds to OpenAI streams, so
+// clients that never asked for it see the stream they expect. It buffers one SSE event at a
+// time; upstreams flush whole events, so this does not delay delivery.
+type usageChunkFilter struct {
+	src     io.ReadCloser
+	buf     []byte
+	out     []byte
+	srcDone bool
+	err     error
+}
+
+func newUsageChunkFilter(src io.ReadCloser) *usageChunkFilter {
+	return &usageChunkFilter{src: src}
+}
+
+func (f *usageChunkFilter) Read(p []byte) (int, error) {
+	for len(f.out) == 0 {
+		if f.srcDone {
+			return 0, f.err
+		}
+
+		chunk := make([]byte, len(p))
+		n, err := f.src.Read(chunk)
+		f.buf = append(f.buf, chunk[:n]...)
+		f.emitCompleteEvents()
+		if err != nil {
+			// Whatever is left is an unterminated trailing event; pass it through unfiltered.
+			f.out = append(f.out, f.buf...)
+			f.buf = nil
+			f.srcDone = true
+			f.err = err
+		}
+	}
+
+	n := copy(p, f.out)
+	f.out = f.out[n:]
+	return n, nil
+}
+
+func (f *usageChunkFilter) Close() error {
+	return f.src.Close()
+}
+
+func (f *usageChunkFilter) emitCompleteEvents() {
+	for {
+		end, sepLen := sseEventBoundary(f.buf)
+		if end < 0 {
+			return
+		}
+		event := f.buf[:end+sepLen]
+		if !isUsageOnlyEvent(event[:end]) {
+			f.out = append(f.out, event...)
+		}
+		f.buf = f.buf[end+sepLen:]
+	}
+}
+
+func sseEventBoundary(buf []byte) (int, int) {
+	lf := bytes.Index(buf, []byte("\n\n"))
+	crlf := bytes.Index(buf, []byte("\r\n\r\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return crlf, 4
+	case lf >= 0:
+		return lf, 2
+	default:
+		return -1, 0
+	}
+}
+
+// isUsageOnlyEvent reports whether an SSE event is the final include_usage chunk: a non-null
+// usage object with no choices.
+func isUsageOnlyEvent(event []byte) bool {
+	var data []string
+	for _, line := range strings.Split(string(event), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if len(data) == 0 {
+		return false
+	}
+
+	var chunk struct {
+		Choices []json.RawMessage `json:"choices"`
+		Usage   json.RawMessage   `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &chunk); err != nil {
+		return false
+	}
+	return len(chunk.Choices) == 0 && len(chunk.Usage) > 0 && string(chunk.Usage) != "null"
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+)
+
+// streamUsageHeader lets a client opt in to receiving streaming usage and cost after the
+// upstream stream ends, since response headers are sent before usage is known.
+const streamUsageHeader = "X-LCG-Stream-Usage"
+
+const (
+	streamUsageTrailer = "trailer"
+	streamUsageEvent   = "event"
+
+	streamUsageEventName = "lcg.usage"
+)
+
+var streamUsageTrailerKeys = []string{
+	"X-LLM-Cost",
+	"X-LLM-Input-Tokens",
+	"X-LLM-Output-Tokens",
+	"X-LLM-Model",
+	"X-LCG-Usage-Estimated",
+}
+
+func streamUsageMode(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case streamUsageTrailer, "trailers":
+		return streamUsageTrailer
+	case streamUsageEvent, "sse":
+		return streamUsageEvent
+	default:
+		return ""
+	}
+}
+
+// streamUsageReport delivers the final usage of a stream to the client, either as HTTP
+// trailers or as an extra SSE event appended after the upstream events.
+type streamUsageReport interface {
+	complete(record *tracker.UsageRecord, usage *ResponseUsage)
+	tail() []byte
+}
+
+// prepareStreamUsageReport adjusts the response for the requested mode before headers are sent.
+// It returns nil when no report was requested or the stream format cannot carry one.
+func prepareStreamUsageReport(resp *http.Response, mode string) streamUsageReport {
+	switch mode {
+	case streamUsageTrailer:
+		if resp.Trailer == nil {
+			resp.Trailer = make(http.Header)
+		}
+		for _, key := range streamUsageTrailerKeys {
+			resp.Trailer[http.CanonicalHeaderKey(key)] = nil
+		}
+		return &trailerUsageReport{trailer: resp.Trailer}
+	case streamUsageEvent:
+		// Appending an event is only safe for SSE; NDJSON and binary event streams are left untouched.
+		if !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream") {
+			return nil
+		}
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		return &eventUsageReport{}
+	default:
+		return nil
+	}
+}
+
+type trailerUsageReport struct {
+	trailer http.Header
+}
+
+func (t *trailerUsageReport) complete(record *tracker.UsageRecord, usage *ResponseUsage) {
+	if record == nil {
+		return
+	}
+	t.trailer.Set("X-LLM-Cost", fmt.Sprintf("%.6f", record.CostUSD))
+	t.trailer.Set("X-LLM-Input-Tokens", strconv.FormatInt(record.InputTokens, 10))
+	t.trailer.Set("X-LLM-Output-Tokens", strconv.FormatInt(record.OutputTokens, 10))
+	t.trailer.Set("X-LLM-Model", record.Model)
+	t.trailer.Set("X-LCG-Usage-Estimated", strconv.FormatBool(usage != nil && usage.Estimated))
+}
+
+func (t *trailerUsageReport) tail() []byte {
+	return nil
+}
+
+type streamUsageEventPayload struct {
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	Estimated    bool    `json:"estimated"`
+	LatencyMs    int64   `json:"latency_ms,omitempty"`
+	TTFTMs       int64   `json:"ttft_ms,omitempty"`
+}
+
+type eventUsageReport struct {
+	event []byte
+}
+
+func (e *eventUsageReport) complete(record *tracker.UsageRecord, usage *ResponseUsage) {
+	if record == nil {
+		return
+	}
+	e.event = encodeStreamUsageEvent(streamUsageEventPayload{
+		Provider:     record.Provider,
+		Model:        record.Model,
+		InputTokens:  record.InputTokens,
+		OutputTokens: record.OutputTokens,
+		CostUSD:      record.CostUSD,
+		Estimated:    usage != nil && usage.Estimated,
+		LatencyMs:    record.LatencyMs,
+		TTFTMs:       record.TTFTMs,
+	})
+}
+
+func (e *eventUsageReport) tail() []byte {
+	return e.event
+}
+
+func encodeStreamUsageEvent(payload streamUsageEventPayload) []byte {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	return []byte("event: " + streamUsageEventName + "\ndata: " + string(data) + "\n\n")
+}
+
+// replayUsageEvent is the usage event sent with a replayed stream. A replay is not billed, so
+// it reports the original provider and model with zero tokens and cost. It returns nil when
+// the kept response is not an SSE stream.
+func replayUsageEvent(header http.Header) []byte {
+	if header.Get("X-LCG-Streaming") != "true" ||
+		!strings.Contains(strings.ToLower(header.Get("Content-Type")), "text/event-stream") {
+		return nil
+	}
+	return encodeStreamUsageEvent(streamUsageEventPayload{
+		Provider: header.Get("X-LLM-Provider"),
+		Model:    header.Get("X-LLM-Model"),
+	})
+}
+
+// replayUsageTrailer returns the zero-cost usage trailers sent with a replayed stream, or nil
+// when the kept response is not a stream.
+func replayUsageTrailer(header http.Header) http.Header {
+	if header.Get("X-LCG-Streaming") != "true" {
+		return nil
+	}
+	trailer := make(http.Header)
+	trailer.Set("X-LLM-Cost", "0.000000")
+	trailer.Set("X-LLM-Input-Tokens", "0")
+	trailer.Set("X-LLM-Output-Tokens", "0")
+	trailer.Set("X-LLM-Model", header.Get("X-LLM-Model"))
+	trailer.Set("X-LCG-Usage-Estimated", "false")
+	return trailer
+}
+
+// streamUsageTail appends the usage report's bytes after the wrapped stream reached EOF.
+type streamUsageTail struct {
+	io.ReadCloser
+	tail    func() []byte
+	pending []byte
+	queued  bool
+}
+
+func (t *streamUsageTail) Read(p []byte) (int, error) {
+	if t.queued {
+		return t.readPending(p)
+	}
+	n, err := t.ReadCloser.Read(p)
+	if err == io.EOF {
+		t.queued = true
+		t.pending = t.tail()
+		if n == 0 {
+			return t.readPending(p)
+		}
+		return n, nil
+	}
+	return n, err
+}
+
+func (t *streamUsageTail) readPending(p []byte) (int, error) {
+	if len(t.pending) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, t.pending)
+	t.pending = t.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// messageContent accepts both plain-string and content-block message bodies.
+// Text holds the concatenated textual content, Blocks keeps the raw blocks so
+// tool_use / tool_result entries can be measured.
+type messageContent struct {
+	Text   string
+	Blocks []map[string]any
+}
+
+func (c *messageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		c.Text = text
+		return nil
+	}
+
+	var blocks []map[string]any
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	c.Blocks = blocks
+
+	items := make([]any, 0, len(blocks))
+	for _, block := range blocks {
+		items = append(items, block)
+	}
+	var builder strings.Builder
+	appendTextContent(&builder, items)
+	c.Text = strings.TrimSpace(builder.String())
+	return nil
+}
+
+// toolSchemaTokens estimates the input tokens consumed by tool definitions.
+func toolSchemaTokens(info *RequestInfo) int64 {
+	if info == nil || info.ToolSchemaChars <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(float64(info.ToolSchemaChars) / 4.0))
+}
+
+// measureToolSchemas returns the number of tool definitions and their compact JSON size.
+func measureToolSchemas(value any) (int, int) {
+	switch v := value.(type) {
+	case nil:
+		return 0, 0
+	case []any:
+		if len(v) == 0 {
+			return 0, 0
+		}
+		return len(v), compactSize(mustMarshal(v))
+	case map[string]any:
+		return 1, compactSize(mustMarshal(v))
+	default:
+		return 0, 0
+	}
+}
+
+// toolChoiceLabel normalizes the provider-specific tool choice shapes into a short label
+// such as "auto", "required", "none", or "function:lookup".
+func toolChoiceLabel(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.ToLower(strings.TrimSpace(v))
+	case map[string]any:
+		if mode := stringValue(v["mode"]); mode != "" {
+			return strings.ToLower(mode)
+		}
+
+		name := stringValue(v["name"])
+		if fn, ok := v["function"].(map[string]any); ok && name == "" {
+			name = stringValue(fn["name"])
+		}
+
+		kind := stringValue(v["type"])
+		if kind == "" {
+			for _, candidate := range []string{"auto", "any", "tool"} {
+				inner, ok := v[candidate]
+				if !ok {
+					continue
+				}
+				kind = candidate
+				if nested, ok := inner.(map[string]any); ok && name == "" {
+					name = stringValue(nested["name"])
+				}
+				break
+			}
+		}
+
+		switch {
+		case kind != "" && name != "":
+			return strings.ToLower(kind) + ":" + name
+		case kind != "":
+			return strings.ToLower(kind)
+		case name != "":
+			return "function:" + name
+		}
+	}
+	return ""
+}
+
+// countToolResultBlocks counts content blocks of the given type and their content size.
+func countToolResultBlocks(blocks []map[string]any, blockType string) (int, int) {
+	count, size := 0, 0
+	for _, block := range blocks {
+		if stringValue(block["type"]) != blockType {
+			continue
+		}
+		count++
+		size += countMessageChars(block["content"])
+	}
+	return count, size
+}
+
+// countNestedToolResults walks a decoded payload for objects keyed by key (e.g. toolResult,
+// functionResponse) and returns their count and compact JSON size.
+func countNestedToolResults(value any, key string) (int, int) {
+	count, size := 0, 0
+	walkJSON(value, func(node map[string]any) {
+		if result, ok := node[key]; ok {
+			count++
+			size += compactSize(mustMarshal(result))
+		}
+	})
+	return count, size
+}
+
+// countNestedToolCalls walks a decoded payload for tool call objects keyed by key and
+// returns their count and the compact JSON size of their argsKey values.
+func countNestedToolCalls(value any, key, argsKey string) (int, int) {
+	count, size := 0, 0
+	walkJSON(value, func(node map[string]any) {
+		call, ok := node[key].(map[string]any)
+		if !ok {
+			return
+		}
+		count++
+		switch args := call[argsKey].(type) {
+		case nil:
+		case string:
+			size += len(args)
+		default:
+			size += compactSize(mustMarshal(args))
+		}
+	})
+	return count, size
+}
+
+// extractStreamToolCalls returns tool calls started and argument characters seen in one stream event.
+func extractStreamToolCalls(payload []byte, provider string) (int, int) {
+	var decoded map[string]any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return 0, 0
+	}
+
+	switch provider {
+	case "openai", "azure-openai":
+		return openAIStreamToolCalls(decoded)
+	case "anthropic":
+		return anthropicStreamToolCalls(decoded)
+	case "bedrock":
+		return bedrockStreamToolCalls(decoded)
+	case "vertex-ai", "gemini":
+		return countNestedToolCalls(decoded["candidates"], "functionCall", "args")
+	default:
+		return 0, 0
+	}
+}
+
+func openAIStreamToolCalls(decoded map[string]any) (int, int) {
+	choices, _ := decoded["choices"].([]any)
+	count, size := 0, 0
+	for _, rawChoice := range choices {
+		choice, ok := rawChoice.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, field := range []string{"delta", "message"} {
+			body, ok := choice[field].(map[string]any)
+			if !ok {
+				continue
+			}
+			calls, _ := body["tool_calls"].([]any)
+			for _, rawCall := range calls {
+				call, ok := rawCall.(map[string]any)
+				if !ok {
+					continue
+				}
+				if field == "message" || stringValue(call["id"]) != "" {
+					count++
+				}
+				if fn, ok := call["function"].(map[string]any); ok {
+					size += len(stringValue(fn["arguments"]))
+				}
+			}
+			if fn, ok := body["function_call"].(map[string]any); ok {
+				if field == "message" || stringValue(fn["name"]) != "" {
+					count++
+				}
+				size += len(stringValue(fn["arguments"]))
+			}
+		}
+	}
+	return count, size
+}
+
+func anthropicStreamToolCalls(decoded map[string]any) (int, int) {
+	switch stringValue(decoded["type"]) {
+	case "content_block_start":
+		block, _ := decoded["content_block"].(map[string]any)
+		if stringValue(block["type"]) == "tool_use" {
+			return 1, 0
+		}
+	case "content_block_delta":
+		delta, _ := decoded["delta"].(map[string]any)
+		if stringValue(delta["type"]) == "input_json_delta" {
+			return 0, len(stringValue(delta["partial_json"]))
+		}
+	}
+	return 0, 0
+}
+
+func bedrockStreamToolCalls(decoded map[string]any) (int, int) {
+	count, size := 0, 0
+	walkJSON(decoded, func(node map[string]any) {
+		if start, ok := node["start"].(map[string]any); ok {
+			if _, ok := start["toolUse"]; ok {
+				count++
+			}
+		}
+		if delta, ok := node["delta"].(map[string]any); ok {
+			if toolUse, ok := delta["toolUse"].(map[string]any); ok {
+				size += len(stringValue(toolUse["input"]))
+			}
+		}
+	})
+	return count, size
+}
+
+func walkJSON(value any, visit func(map[string]any)) {
+	switch v := value.(type) {
+	case map[string]any:
+		visit(v)
+		for _, nested := range v {
+			walkJSON(nested, visit)
+		}
+	case []any:
+		for _, item := range v {
+			walkJSON(item, visit)
+		}
+	}
+}
+
+func decodeRaw(raw json.RawMessage) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+func mustMarshal(value any) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func compactSize(raw []byte) int {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return 0
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return len(raw)
+	}
+	return buf.Len()
+}
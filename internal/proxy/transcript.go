@@ -0,0 +1,23 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+)
+
+// saveTranscript stores the request and response of a recorded call when its project opted in
+// to capture. Streams store the generated text rather than the raw event stream.
+func (h *Handler) saveTranscript(ctx context.Context, call *proxyCall, record *tracker.UsageRecord, response []byte) {
+	if h.options.Transcripts == nil || call.transcriptBody == nil {
+		return
+	}
+	if err := h.options.Transcripts.Save(context.WithoutCancel(ctx), record, call.transcriptBody, response); err != nil {
+		h.logger.Error("failed to save transcript",
+			"error", err,
+			"usage_id", record.ID,
+			"tenant", call.tenant,
+			"project", call.project,
+		)
+	}
+}
@@ -0,0 +1,88 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler_CapturesTranscripts(t *testing.T) {
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte(`"stream":true`)) {
+			openAIStreamingResponseHandler(true)(w, r)
+			return
+		}
+		openAIResponseHandler(w, r)
+	}, 4096, false)
+
+	recorder, err := capture.NewRecorder(env.store, capture.Config{Projects: []string{"support"}, MasterKey: make([]byte, 32)})
+	require.NoError(t, err)
+	opts := proxy.DefaultOptions()
+	opts.Transcripts = recorder
+	env.handler.WithOptions(opts)
+
+	send := func(project, body string) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+		req.Header.Set("X-LCG-Project", project)
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	transcriptFor := func(project string) (*model.Transcript, error) {
+		records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{Project: project})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		return recorder.Get(context.Background(), records[0].ID)
+	}
+
+	send("support", `{"model":"gpt-4o","messages":[{"role":"user","content":"reach me at ann@example.com"}]}`)
+	transcript, err := transcriptFor("support")
+	require.NoError(t, err)
+	assert.Contains(t, transcript.Request, "reach me at [REDACTED_EMAIL]")
+	assert.Contains(t, transcript.Response, "Hello!")
+
+	// Records carry the authenticated tenant's ID, which keys transcript encryption.
+	tenant, err := env.store.EnsureTenant(context.Background(), "acme", "Acme")
+	require.NoError(t, err)
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte(`{"model":"gpt-4o","messages":[]}`)))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	req.Header.Set("X-LCG-Project", "support")
+	req = req.WithContext(httpauth.WithIdentity(req.Context(), httpauth.Identity{Tenant: *tenant}))
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{Tenant: "acme", Project: "support"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, tenant.ID, records[0].TenantID)
+	sealed, err := env.store.GetTranscript(context.Background(), records[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, tenant.ID, sealed.TenantID)
+
+	send("billing", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	_, err = transcriptFor("billing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	recorder, err = capture.NewRecorder(env.store, capture.Config{Projects: []string{"streaming"}, MasterKey: make([]byte, 32)})
+	require.NoError(t, err)
+	opts.Transcripts = recorder
+	env.handler.WithOptions(opts)
+
+	send("streaming", `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	transcript, err = transcriptFor("streaming")
+	require.NoError(t, err)
+	assert.Contains(t, transcript.Response, "Hello streamed world")
+}
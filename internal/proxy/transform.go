@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tokenizer"
+)
+
+// messageOverheadTokens approximates the role and formatting tokens added to every message.
+const messageOverheadTokens = 4
+
+// TransformLimits are the request rewrites applied to one project. Zero disables a limit.
+type TransformLimits struct {
+	// MaxTokens is the ceiling for the requested output limit. Requests above it are clamped,
+	// and chat requests without a limit get it added.
+	MaxTokens int64
+	// MaxPromptTokens drops the oldest conversation turns until the estimated prompt fits.
+	// System instructions and the latest turn are always kept.
+	MaxPromptTokens int64
+}
+
+// TransformPolicy rewrites request bodies to cap runaway output and prompt sizes.
+type TransformPolicy struct {
+	defaults TransformLimits
+	projects map[string]TransformLimits
+}
+
+// NewTransformPolicy returns a RequestPolicy applying defaults to every project not listed in
+// projects.
+func NewTransformPolicy(defaults TransformLimits, projects map[string]TransformLimits) *TransformPolicy {
+	return &TransformPolicy{defaults: defaults, projects: projects}
+}
+
+// LimitsFor returns the limits applied to project.
+func (p *TransformPolicy) LimitsFor(project string) TransformLimits {
+	if limits, ok := p.projects[project]; ok {
+		return limits
+	}
+	return p.defaults
+}
+
+// Evaluate implements RequestPolicy. It never blocks; a rewritten body is returned with a
+// description of what changed.
+func (p *TransformPolicy) Evaluate(_ context.Context, req *PolicyRequest) PolicyDecision {
+	limits := p.LimitsFor(req.Project)
+	if limits.MaxTokens <= 0 && limits.MaxPromptTokens <= 0 {
+		return PolicyDecision{}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(req.Body))
+	decoder.UseNumber()
+	var body map[string]any
+	if err := decoder.Decode(&body); err != nil {
+		return PolicyDecision{}
+	}
+
+	var transform model.RequestTransform
+	changed := false
+	if limits.MaxTokens > 0 {
+		if from, ok := clampMaxTokens(body, req.Format, req.Path, limits.MaxTokens); ok {
+			transform.MaxTokensFrom = from
+			transform.MaxTokensTo = limits.MaxTokens
+			changed = true
+		}
+	}
+	if limits.MaxPromptTokens > 0 {
+		modelName := ""
+		if req.Info != nil {
+			modelName = req.Info.Model
+		}
+		trim := trimOldestTurns(body, req.Format, req.Provider, modelName, limits.MaxPromptTokens)
+		if trim.dropped > 0 {
+			transform.TrimmedMessages = trim.dropped
+			transform.PromptTokensFrom = trim.before
+			transform.PromptTokensTo = trim.after
+			changed = true
+		}
+	}
+	if !changed {
+		return PolicyDecision{}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		return PolicyDecision{}
+	}
+	return PolicyDecision{Body: bytes.TrimSuffix(buf.Bytes(), []byte("\n")), Transform: &transform}
+}
+
+// clampMaxTokens lowers every output limit field of body above ceiling and adds the ceiling to
+// chat requests that set none. It returns the largest requested limit and whether body changed.
+func clampMaxTokens(body map[string]any, format, requestPath string, ceiling int64) (int64, bool) {
+	var fields []limitField
+	var missing func()
+	switch format {
+	case "openai", "azure-openai":
+		fields = []limitField{{body, "max_tokens"}, {body, "max_completion_tokens"}, {body, "max_output_tokens"}}
+		switch {
+		case strings.Contains(requestPath, "/responses"):
+			missing = func() { body["max_output_tokens"] = ceiling }
+		case strings.Contains(requestPath, "/chat/completions"):
+			missing = func() { body["max_completion_tokens"] = ceiling }
+		}
+	case "anthropic":
+		fields = []limitField{{body, "max_tokens"}}
+		if strings.Contains(requestPath, "/messages") {
+			missing = func() { body["max_tokens"] = ceiling }
+		}
+	case "vertex-ai", "gemini":
+		config, _ := body["generationConfig"].(map[string]any)
+		fields = []limitField{{config, "maxOutputTokens"}}
+		if strings.Contains(requestPath, "generateContent") {
+			missing = func() {
+				if config == nil {
+					config = make(map[string]any)
+					body["generationConfig"] = config
+				}
+				config["maxOutputTokens"] = ceiling
+			}
+		}
+	case "bedrock":
+		inference, _ := body["inferenceConfig"].(map[string]any)
+		generation, _ := body["textGenerationConfig"].(map[string]any)
+		fields = []limitField{{inference, "maxTokens"}, {generation, "maxTokenCount"}, {body, "max_tokens"}, {body, "max_gen_len"}}
+		if strings.Contains(requestPath, "/converse") {
+			missing = func() {
+				if inference == nil {
+					inference = make(map[string]any)
+					body["inferenceConfig"] = inference
+				}
+				inference["maxTokens"] = ceiling
+			}
+		}
+	default:
+		return 0, false
+	}
+
+	var requested int64
+	found := false
+	for _, field := range fields {
+		value, ok := field.get()
+		if !ok {
+			continue
+		}
+		found = true
+		requested = max(requested, value)
+		if value > ceiling {
+			field.container[field.key] = ceiling
+		}
+	}
+	if found {
+		return requested, requested > ceiling
+	}
+	if missing == nil {
+		return 0, false
+	}
+	missing()
+	return 0, true
+}
+
+type limitField struct {
+	container map[string]any
+	key       string
+}
+
+func (f limitField) get() (int64, bool) {
+	if f.container == nil {
+		return 0, false
+	}
+	value, ok := f.container[f.key]
+	if !ok || value == nil {
+		return 0, false
+	}
+	return int64Value(value), true
+}
+
+type trimResult struct {
+	dropped int
+	before  int64
+	after   int64
+}
+
+// trimOldestTurns drops the oldest conversation messages until the estimated prompt fits
+// limit. The conversation always restarts at a user message that is not a tool result, so
+// tool calls are never separated from their results. System instructions and the latest user
+// turn are kept even when they alone exceed limit.
+func trimOldestTurns(body map[string]any, format, provider, modelName string, limit int64) trimResult {
+	key := "messages"
+	var pinnedTokens int64
+	switch format {
+	case "openai", "azure-openai":
+	case "anthropic", "bedrock":
+		pinnedTokens = estimateValueTokens(body["system"], provider, modelName)
+	case "vertex-ai", "gemini":
+		key = "contents"
+		pinnedTokens = estimateValueTokens(body["systemInstruction"], provider, modelName)
+	default:
+		return trimResult{}
+	}
+	messages, ok := body[key].([]any)
+	if !ok || len(messages) < 2 {
+		return trimResult{}
+	}
+
+	// OpenAI system and developer messages stay in place; the rest form the conversation.
+	// suffix[i] is the estimate of the conversation from message i on.
+	pinned := make([]bool, len(messages))
+	suffix := make([]int64, len(messages)+1)
+	for i := len(messages) - 1; i >= 0; i-- {
+		tokens := messageOverheadTokens + estimateValueTokens(messages[i], provider, modelName)
+		role := messageRole(messages[i])
+		if (format == "openai" || format == "azure-openai") && (role == "system" || role == "developer") {
+			pinned[i] = true
+			pinnedTokens += tokens
+			tokens = 0
+		}
+		suffix[i] = suffix[i+1] + tokens
+	}
+	total := pinnedTokens + suffix[0]
+	if total <= limit {
+		return trimResult{}
+	}
+
+	// Restart at the earliest valid message whose suffix fits, or else at the latest one.
+	start := -1
+	for i := 1; i < len(messages); i++ {
+		if pinned[i] || !validRestart(messages[i], format) {
+			continue
+		}
+		start = i
+		if pinnedTokens+suffix[i] <= limit {
+			break
+		}
+	}
+	if start < 0 {
+		return trimResult{}
+	}
+
+	kept := make([]any, 0, len(messages))
+	dropped := 0
+	for i, message := range messages {
+		if pinned[i] || i >= start {
+			kept = append(kept, message)
+		} else {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return trimResult{}
+	}
+	body[key] = kept
+	return trimResult{dropped: dropped, before: total, after: pinnedTokens + suffix[start]}
+}
+
+func validRestart(message any, format string) bool {
+	entry, ok := message.(map[string]any)
+	if !ok {
+		return false
+	}
+	role := messageRole(message)
+	switch format {
+	case "openai", "azure-openai":
+		return role == "user"
+	case "anthropic":
+		return role == "user" && !hasContentBlock(entry["content"], func(block map[string]any) bool {
+			return block["type"] == "tool_result"
+		})
+	case "bedrock":
+		return role == "user" && !hasContentBlock(entry["content"], func(block map[string]any) bool {
+			_, ok := block["toolResult"]
+			return ok
+		})
+	case "vertex-ai", "gemini":
+		return (role == "user" || role == "") && !hasContentBlock(entry["parts"], func(block map[string]any) bool {
+			_, ok := block["functionResponse"]
+			return ok
+		})
+	default:
+		return false
+	}
+}
+
+func messageRole(message any) string {
+	entry, _ := message.(map[string]any)
+	role, _ := entry["role"].(string)
+	return strings.ToLower(role)
+}
+
+func hasContentBlock(content any, match func(map[string]any) bool) bool {
+	blocks, _ := content.([]any)
+	for _, block := range blocks {
+		if entry, ok := block.(map[string]any); ok && match(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func estimateValueTokens(value any, provider, modelName string) int64 {
+	var text strings.Builder
+	appendTextContent(&text, value)
+	tokens, err := tokenizer.CountTokens(text.String(), provider, modelName)
+	if err != nil {
+		return int64(len(text.String())+3) / 4
+	}
+	return tokens
+}
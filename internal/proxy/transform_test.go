@@ -0,0 +1,156 @@
+package proxy_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHandler_TransformClampsMaxTokens(t *testing.T) {
+	forwarded := make(chan map[string]any, 4)
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, strconv.Itoa(len(body)), r.Header.Get("Content-Length"))
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(body, &decoded))
+		forwarded <- decoded
+		openAIResponseHandler(w, r)
+	}, 4096, false)
+	opts := proxy.DefaultOptions()
+	opts.Policies = []proxy.RequestPolicy{proxy.NewTransformPolicy(
+		proxy.TransformLimits{MaxTokens: 1000},
+		map[string]proxy.TransformLimits{"unlimited": {}},
+	)}
+	env.handler.WithOptions(opts)
+
+	send := func(project, body string) {
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+		req.Header.Set("X-LCG-Project", project)
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	transformFor := func(project string) *model.RequestTransform {
+		records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{Project: project})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		var metadata model.UsageMetadata
+		require.NoError(t, json.Unmarshal([]byte(records[0].Metadata), &metadata))
+		return metadata.Transform
+	}
+
+	send("clamped", `{"model":"gpt-4o","max_tokens":8000,"max_completion_tokens":4000,"messages":[{"role":"user","content":"hi"}]}`)
+	body := <-forwarded
+	assert.EqualValues(t, 1000, body["max_tokens"])
+	assert.EqualValues(t, 1000, body["max_completion_tokens"])
+	assert.Equal(t, &model.RequestTransform{MaxTokensFrom: 8000, MaxTokensTo: 1000}, transformFor("clamped"))
+
+	send("missing", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	body = <-forwarded
+	assert.EqualValues(t, 1000, body["max_completion_tokens"])
+	assert.Equal(t, &model.RequestTransform{MaxTokensTo: 1000}, transformFor("missing"))
+
+	send("within", `{"model":"gpt-4o","max_tokens":200,"messages":[{"role":"user","content":"hi"}]}`)
+	body = <-forwarded
+	assert.EqualValues(t, 200, body["max_tokens"])
+	assert.Nil(t, transformFor("within"))
+
+	send("unlimited", `{"model":"gpt-4o","max_tokens":8000,"messages":[{"role":"user","content":"hi"}]}`)
+	body = <-forwarded
+	assert.EqualValues(t, 8000, body["max_tokens"])
+	assert.Nil(t, transformFor("unlimited"))
+}
+
+func TestProxyHandler_TransformTrimsOldestTurns(t *testing.T) {
+	forwarded := make(chan []byte, 4)
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded <- body
+		openAIResponseHandler(w, r)
+	}, 1<<20, false)
+	opts := proxy.DefaultOptions()
+	opts.Policies = []proxy.RequestPolicy{proxy.NewTransformPolicy(proxy.TransformLimits{MaxPromptTokens: 60}, nil)}
+	env.handler.WithOptions(opts)
+
+	long := strings.Repeat("lorem ipsum dolor ", 10)
+	send := func(path, body string) map[string]any {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("X-LCG-Target", env.upstream.URL+path)
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(<-forwarded, &decoded))
+		return decoded
+	}
+	roles := func(messages any) []string {
+		var out []string
+		for _, message := range messages.([]any) {
+			out = append(out, message.(map[string]any)["role"].(string))
+		}
+		return out
+	}
+
+	// The tool result cannot start the conversation, so trimming restarts at the next user turn.
+	openAI := send("/v1/chat/completions", `{"model":"gpt-4o","messages":[
+		{"role":"system","content":"be brief"},
+		{"role":"user","content":"`+long+`"},
+		{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"c1","content":"`+long+`"},
+		{"role":"user","content":"and now?"}
+	]}`)
+	assert.Equal(t, []string{"system", "user"}, roles(openAI["messages"]))
+
+	records, err := env.store.QueryUsage(context.Background(), model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(records[0].Metadata), &metadata))
+	require.NotNil(t, metadata.Transform)
+	assert.Equal(t, 3, metadata.Transform.TrimmedMessages)
+	assert.Greater(t, metadata.Transform.PromptTokensFrom, int64(60))
+	assert.LessOrEqual(t, metadata.Transform.PromptTokensTo, int64(60))
+	assert.Equal(t, 2, metadata.MessageCount)
+
+	anthropic := send("/v1/messages", `{"model":"claude-3.5-sonnet","max_tokens":100,"system":"be brief","messages":[
+		{"role":"user","content":"`+long+`"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"f","input":{}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"`+long+`"}]},
+		{"role":"assistant","content":"done"},
+		{"role":"user","content":"thanks"}
+	]}`)
+	assert.Equal(t, []string{"user"}, roles(anthropic["messages"]))
+	assert.Equal(t, "be brief", anthropic["system"])
+
+	short := send("/v1/chat/completions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"},{"role":"user","content":"bye"}]}`)
+	assert.Len(t, short["messages"], 3)
+}
+
+func TestProxyHandler_TransformKeepsNonJSONBody(t *testing.T) {
+	forwarded := make(chan []byte, 1)
+	env := setupProxyTest(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded <- body
+		w.WriteHeader(http.StatusBadRequest)
+	}, 4096, false)
+	opts := proxy.DefaultOptions()
+	opts.Policies = []proxy.RequestPolicy{proxy.NewTransformPolicy(proxy.TransformLimits{MaxTokens: 10, MaxPromptTokens: 10}, nil)}
+	env.handler.WithOptions(opts)
+
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader([]byte("not json")))
+	req.Header.Set("X-LCG-Target", env.upstream.URL+"/v1/chat/completions")
+	env.handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "not json", string(<-forwarded))
+}
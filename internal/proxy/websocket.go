@@ -0,0 +1,400 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+)
+
+// WebSocket usage recording modes.
+const (
+	WebSocketUsagePerResponse = "response"
+	WebSocketUsagePerSession  = "session"
+)
+
+// maxWebSocketMessage bounds how much of a single text message is buffered for usage parsing.
+// Larger messages are relayed untouched and skipped by the parser.
+const maxWebSocketMessage = 4 << 20
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get("Upgrade")), "websocket")
+}
+
+// webSocketTarget maps ws:// and wss:// targets onto the HTTP schemes used for the upgrade request.
+func webSocketTarget(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "ws":
+		return "http"
+	case "wss":
+		return "https"
+	default:
+		return scheme
+	}
+}
+
+// captureWebSocket wraps an upgraded upstream connection so provider events relayed to the
+// client are parsed for usage. ReverseProxy copies frames in both directions unchanged.
+func (h *Handler) captureWebSocket(ctx context.Context, resp *http.Response, call *proxyCall) error {
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return nil
+	}
+
+	session := &realtimeSession{
+		handler:    h,
+		ctx:        context.WithoutCancel(ctx),
+		call:       call,
+		model:      realtimeModel(resp.Request),
+		perSession: h.options.WebSocketUsage == WebSocketUsagePerSession,
+		responses:  make(map[string]*realtimeResponse),
+	}
+	resp.Body = &webSocketTap{
+		ReadWriteCloser: conn,
+		frames:          &wsFrameReader{emit: session.handleMessage},
+		onClose:         session.close,
+	}
+	return nil
+}
+
+// realtimeModel reads the model from the upgrade URL (`model` for OpenAI, `deployment` for Azure).
+func realtimeModel(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+	query := req.URL.Query()
+	if modelName := query.Get("model"); modelName != "" {
+		return modelName
+	}
+	return query.Get("deployment")
+}
+
+// webSocketTap feeds upstream-to-client bytes into a frame reader while relaying them unchanged.
+type webSocketTap struct {
+	io.ReadWriteCloser
+	mu      sync.Mutex
+	frames  *wsFrameReader
+	onClose func()
+	once    sync.Once
+}
+
+func (t *webSocketTap) Read(p []byte) (int, error) {
+	n, err := t.ReadWriteCloser.Read(p)
+	if n > 0 {
+		t.mu.Lock()
+		t.frames.feed(p[:n])
+		t.mu.Unlock()
+	}
+	return n, err
+}
+
+func (t *webSocketTap) Close() error {
+	err := t.ReadWriteCloser.Close()
+	t.once.Do(func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.onClose()
+	})
+	return err
+}
+
+// wsFrameReader incrementally decodes RFC 6455 frames and emits complete text messages.
+// Binary, control, compressed, and oversized messages are skipped without buffering.
+type wsFrameReader struct {
+	emit func([]byte)
+
+	buf     []byte
+	message []byte
+	text    bool // the message being assembled is text
+	skip    bool // the message being assembled is ignored
+	discard int64
+	final   bool // the frame being discarded ends its message
+}
+
+func (r *wsFrameReader) feed(p []byte) {
+	r.buf = append(r.buf, p...)
+	for {
+		if r.discard > 0 {
+			n := min(r.discard, int64(len(r.buf)))
+			r.buf = r.buf[n:]
+			r.discard -= n
+			if r.discard > 0 {
+				break
+			}
+			if r.final {
+				r.endMessage()
+			}
+			continue
+		}
+		if !r.next() {
+			break
+		}
+	}
+	if len(r.buf) == 0 {
+		r.buf = nil
+	}
+}
+
+// next consumes one frame from the buffer. It returns false when more bytes are needed.
+func (r *wsFrameReader) next() bool {
+	if len(r.buf) < 2 {
+		return false
+	}
+	fin := r.buf[0]&0x80 != 0
+	compressed := r.buf[0]&0x40 != 0
+	opcode := r.buf[0] & 0x0f
+	masked := r.buf[1]&0x80 != 0
+	length := int64(r.buf[1] & 0x7f)
+
+	pos := 2
+	switch length {
+	case 126:
+		if len(r.buf) < pos+2 {
+			return false
+		}
+		length = int64(binary.BigEndian.Uint16(r.buf[pos:]))
+		pos += 2
+	case 127:
+		if len(r.buf) < pos+8 {
+			return false
+		}
+		length = int64(binary.BigEndian.Uint64(r.buf[pos:]) & (1<<63 - 1))
+		pos += 8
+	}
+	var mask []byte
+	if masked {
+		if len(r.buf) < pos+4 {
+			return false
+		}
+		mask = r.buf[pos : pos+4]
+		pos += 4
+	}
+
+	control := opcode >= 0x8
+	if !control {
+		switch opcode {
+		case 0x1:
+			r.message, r.text, r.skip = r.message[:0], true, compressed
+		case 0x2:
+			r.message, r.text, r.skip = r.message[:0], false, true
+		}
+		if !r.skip && int64(len(r.message))+length > maxWebSocketMessage {
+			r.skip = true
+		}
+	}
+
+	if control || r.skip {
+		r.buf = r.buf[pos:]
+		r.discard = length
+		r.final = fin && !control
+		if length == 0 && r.final {
+			r.endMessage()
+		}
+		return true
+	}
+
+	if int64(len(r.buf)-pos) < length {
+		return false
+	}
+	payload := r.buf[pos : pos+int(length)]
+	start := len(r.message)
+	r.message = append(r.message, payload...)
+	if masked {
+		for i := range r.message[start:] {
+			r.message[start+i] ^= mask[i%4]
+		}
+	}
+	r.buf = r.buf[pos+int(length):]
+	if fin {
+		r.endMessage()
+	}
+	return true
+}
+
+func (r *wsFrameReader) endMessage() {
+	if r.text && !r.skip && len(r.message) > 0 {
+		r.emit(r.message)
+	}
+	r.message, r.text, r.skip, r.final = r.message[:0], false, false, false
+}
+
+// realtimeEvent holds the server event fields used for cost tracking.
+type realtimeEvent struct {
+	Type       string `json:"type"`
+	ResponseID string `json:"response_id"`
+	Session    *struct {
+		Model string `json:"model"`
+	} `json:"session"`
+	Response *struct {
+		ID     string         `json:"id"`
+		Status string         `json:"status"`
+		Usage  *realtimeUsage `json:"usage"`
+	} `json:"response"`
+}
+
+// realtimeUsage is the usage block of a Realtime API `response.done` event.
+type realtimeUsage struct {
+	InputTokens       int64 `json:"input_tokens"`
+	OutputTokens      int64 `json:"output_tokens"`
+	InputTokenDetails struct {
+		TextTokens          int64 `json:"text_tokens"`
+		AudioTokens         int64 `json:"audio_tokens"`
+		CachedTokens        int64 `json:"cached_tokens"`
+		CachedTokensDetails struct {
+			TextTokens  int64 `json:"text_tokens"`
+			AudioTokens int64 `json:"audio_tokens"`
+		} `json:"cached_tokens_details"`
+	} `json:"input_token_details"`
+	OutputTokenDetails struct {
+		TextTokens  int64 `json:"text_tokens"`
+		AudioTokens int64 `json:"audio_tokens"`
+	} `json:"output_token_details"`
+}
+
+// breakdown splits usage into billing classes. Cached tokens are billed once at the cached
+// rate, so they are removed from the text and audio input counts.
+func (u *realtimeUsage) breakdown() tracker.TokenBreakdown {
+	in, out := u.InputTokenDetails, u.OutputTokenDetails
+	tokens := tracker.TokenBreakdown{
+		CachedInput: in.CachedTokens,
+		TextInput:   max(0, in.TextTokens-in.CachedTokensDetails.TextTokens),
+		AudioInput:  max(0, in.AudioTokens-in.CachedTokensDetails.AudioTokens),
+		TextOutput:  out.TextTokens,
+		AudioOutput: out.AudioTokens,
+	}
+	if in.TextTokens == 0 && in.AudioTokens == 0 {
+		tokens.TextInput = max(0, u.InputTokens-in.CachedTokens)
+	}
+	if out.TextTokens == 0 && out.AudioTokens == 0 {
+		tokens.TextOutput = u.OutputTokens
+	}
+	return tokens
+}
+
+// realtimeResponse tracks timing for an in-flight response.
+type realtimeResponse struct {
+	created    time.Time
+	firstToken time.Time
+}
+
+// realtimeSession turns Realtime API server events into usage records.
+type realtimeSession struct {
+	handler    *Handler
+	ctx        context.Context
+	call       *proxyCall
+	model      string
+	perSession bool
+	responses  map[string]*realtimeResponse
+
+	count        int
+	inputTokens  int64
+	outputTokens int64
+	tokens       tracker.TokenBreakdown
+}
+
+func (s *realtimeSession) handleMessage(message []byte) {
+	var event realtimeEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		return
+	}
+
+	switch {
+	case event.Type == "session.created" || event.Type == "session.updated":
+		if s.model == "" && event.Session != nil {
+			s.model = event.Session.Model
+		}
+	case event.Type == "response.created":
+		if event.Response != nil {
+			s.responses[event.Response.ID] = &realtimeResponse{created: time.Now()}
+		}
+	case strings.HasPrefix(event.Type, "response.") && strings.HasSuffix(event.Type, ".delta"):
+		if response, ok := s.responses[event.ResponseID]; ok && response.firstToken.IsZero() {
+			response.firstToken = time.Now()
+		}
+	case event.Type == "response.done":
+		if event.Response == nil {
+			return
+		}
+		response := s.responses[event.Response.ID]
+		delete(s.responses, event.Response.ID)
+		if event.Response.Usage == nil {
+			return
+		}
+		s.recordResponse(event.Response.Status, event.Response.Usage, response)
+	}
+}
+
+func (s *realtimeSession) recordResponse(status string, usage *realtimeUsage, response *realtimeResponse) {
+	tokens := usage.breakdown()
+	if s.perSession {
+		s.count++
+		s.inputTokens += usage.InputTokens
+		s.outputTokens += usage.OutputTokens
+		s.tokens.TextInput += tokens.TextInput
+		s.tokens.CachedInput += tokens.CachedInput
+		s.tokens.AudioInput += tokens.AudioInput
+		s.tokens.TextOutput += tokens.TextOutput
+		s.tokens.AudioOutput += tokens.AudioOutput
+		return
+	}
+
+	record := s.newRecord(usage.InputTokens, usage.OutputTokens, tokens, 1)
+	if response != nil {
+		record.LatencyMs = time.Since(response.created).Milliseconds()
+		if !response.firstToken.IsZero() {
+			record.TTFTMs = response.firstToken.Sub(response.created).Milliseconds()
+		}
+		setOutputThroughput(record)
+	}
+	switch status {
+	case "cancelled":
+		record.CompletionStatus = model.CompletionStatusClientAborted
+	case "failed":
+		record.CompletionStatus = model.CompletionStatusUpstreamError
+		record.ErrorType = errorTypeUpstream
+	}
+	s.track(record)
+}
+
+// close records the accumulated session usage when recording per session.
+func (s *realtimeSession) close() {
+	if !s.perSession || s.count == 0 {
+		return
+	}
+	s.track(s.newRecord(s.inputTokens, s.outputTokens, s.tokens, s.count))
+}
+
+func (s *realtimeSession) newRecord(inputTokens, outputTokens int64, tokens tracker.TokenBreakdown, responses int) *tracker.UsageRecord {
+	record := s.call.newRecord(s.model, &ResponseUsage{InputTokens: inputTokens, OutputTokens: outputTokens})
+	metadata, err := json.Marshal(model.UsageMetadata{
+		PromptTokensEstimate: inputTokens,
+		InputOutputRatio:     safeRatio(float64(inputTokens), float64(outputTokens)),
+		WebSocket:            true,
+		ResponseCount:        responses,
+		CachedInputTokens:    tokens.CachedInput,
+		AudioInputTokens:     tokens.AudioInput,
+		AudioOutputTokens:    tokens.AudioOutput,
+	})
+	if err == nil {
+		record.Metadata = string(metadata)
+	}
+	if p, err := s.handler.tracker.Registry().Get(s.call.provider); err == nil {
+		if cost, err := tracker.CalculateCostWithAudio(p, record.Model, tokens); err == nil {
+			record.CostUSD = cost
+		}
+	}
+	return record
+}
+
+func (s *realtimeSession) track(record *tracker.UsageRecord) {
+	if err := s.handler.tracker.TrackWithTokens(s.ctx, record); err != nil {
+		s.handler.logger.Error("failed to record realtime usage", "error", err)
+	}
+}
@@ -0,0 +1,208 @@
+package proxy_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/proxy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wsFrame encodes a single unmasked server frame.
+func wsFrame(fin bool, opcode byte, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	return append(frame, payload...)
+}
+
+func realtimeDone(id string, audioIn, textIn, cached, textOut, audioOut int64) string {
+	return fmt.Sprintf(`{"type":"response.done","response":{"id":%q,"status":"completed","usage":{`+
+		`"input_tokens":%d,"output_tokens":%d,`+
+		`"input_token_details":{"text_tokens":%d,"audio_tokens":%d,"cached_tokens":%d,"cached_tokens_details":{"text_tokens":%d,"audio_tokens":0}},`+
+		`"output_token_details":{"text_tokens":%d,"audio_tokens":%d}}}}`,
+		id, audioIn+textIn, textOut+audioOut, textIn, audioIn, cached, cached, textOut, audioOut)
+}
+
+// realtimeUpstream completes the WebSocket handshake and sends two Realtime responses. The
+// second response.done is fragmented around a ping and padded past the 16-bit length form.
+func realtimeUpstream(extensions chan<- string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		extensions <- r.Header.Get("Sec-WebSocket-Extensions")
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+
+		fmt.Fprint(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		write := func(frame []byte) {
+			rw.Write(frame)
+			rw.Flush()
+		}
+		write(wsFrame(true, 0x1, []byte(`{"type":"session.created","session":{"model":"gpt-realtime-2025-08-28"}}`)))
+		write(wsFrame(true, 0x1, []byte(`{"type":"response.created","response":{"id":"resp_1"}}`)))
+		time.Sleep(5 * time.Millisecond)
+		write(wsFrame(true, 0x1, []byte(`{"type":"response.output_audio.delta","response_id":"resp_1","delta":"AAAA"}`)))
+		write(wsFrame(true, 0x2, []byte{0x01, 0x02, 0x03}))
+		write(wsFrame(true, 0x1, []byte(realtimeDone("resp_1", 1000, 200, 100, 50, 400))))
+
+		done := realtimeDone("resp_2", 300, 100, 0, 20, 80)
+		padded := done[:len(done)-1] + `,"pad":"` + strings.Repeat("x", 70000) + `"}`
+		frame := wsFrame(false, 0x1, []byte(padded[:10]))
+		write(frame[:1])
+		write(frame[1:])
+		write(wsFrame(true, 0x9, []byte("ping")))
+		write(wsFrame(true, 0x0, []byte(padded[10:])))
+
+		_, _ = io.ReadAll(io.LimitReader(rw, 1))
+	}
+}
+
+func dialRealtime(t *testing.T, proxyURL, target, provider string) []byte {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	req, err := http.NewRequest(http.MethodGet, proxyURL+"/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Extensions", "permessage-deflate")
+	req.Header.Set("X-LCG-Target", target)
+	req.Header.Set("X-LCG-Provider", provider)
+	require.NoError(t, req.Write(conn))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// Send one masked client frame so the upstream closes the session.
+	_, err = conn.Write([]byte{0x81, 0x80, 0x01, 0x02, 0x03, 0x04})
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	relayed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return relayed
+}
+
+func setupRealtimeTest(t *testing.T, opts proxy.Options) (*proxyTestEnv, chan string) {
+	t.Helper()
+	extensions := make(chan string, 1)
+	env := setupProxyTest(t, realtimeUpstream(extensions), 0, false)
+	require.NoError(t, env.registry.Register(providers.NewAzureOpenAI(&providers.ProviderConfig{
+		Provider: "azure-openai",
+		Models: []providers.ModelPricing{{
+			Model: "gpt-realtime", InputPerMillion: 4.00, OutputPerMillion: 16.00, CachedInputPerMillion: 0.40,
+			AudioInputPerMillion: 32.00, AudioOutputPerMillion: 64.00,
+		}},
+	})))
+	env.handler.WithOptions(opts)
+	return env, extensions
+}
+
+func TestProxyHandler_RealtimeWebSocketPerResponse(t *testing.T) {
+	env, extensions := setupRealtimeTest(t, proxy.DefaultOptions())
+	proxyServer := httptest.NewServer(env.handler)
+	defer proxyServer.Close()
+
+	target := strings.Replace(env.upstream.URL, "http://", "ws://", 1) + "/openai/realtime?deployment=gpt-realtime"
+	relayed := dialRealtime(t, proxyServer.URL, target, "azure-openai")
+	assert.Contains(t, string(relayed), `"type":"session.created"`)
+	assert.Contains(t, string(relayed), `"id":"resp_1"`)
+	assert.Empty(t, <-extensions)
+
+	var records []model.UsageRecord
+	require.Eventually(t, func() bool {
+		var err error
+		records, err = env.store.QueryUsage(context.Background(), model.ReportFilter{})
+		return err == nil && len(records) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	byTokens := map[int64]model.UsageRecord{}
+	for _, record := range records {
+		byTokens[record.InputTokens] = record
+	}
+
+	first := byTokens[1200]
+	assert.Equal(t, "azure-openai", first.Provider)
+	assert.Equal(t, "gpt-realtime", first.Model)
+	assert.Equal(t, int64(450), first.OutputTokens)
+	assert.Equal(t, http.StatusSwitchingProtocols, first.StatusCode)
+	assert.Equal(t, model.CompletionStatusComplete, first.CompletionStatus)
+	assert.Positive(t, first.TTFTMs)
+	expected := (4.00*100 + 0.40*100 + 32.00*1000 + 16.00*50 + 64.00*400) / 1_000_000
+	assert.InDelta(t, expected, first.CostUSD, 1e-9)
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(first.Metadata), &metadata))
+	assert.True(t, metadata.WebSocket)
+	assert.Equal(t, int64(1000), metadata.AudioInputTokens)
+	assert.Equal(t, int64(400), metadata.AudioOutputTokens)
+	assert.Equal(t, int64(100), metadata.CachedInputTokens)
+
+	second := byTokens[400]
+	assert.Equal(t, int64(100), second.OutputTokens)
+	assert.InDelta(t, (4.00*100+32.00*300+16.00*20+64.00*80)/1_000_000, second.CostUSD, 1e-9)
+}
+
+func TestProxyHandler_RealtimeWebSocketPerSession(t *testing.T) {
+	opts := proxy.DefaultOptions()
+	opts.WebSocketUsage = proxy.WebSocketUsagePerSession
+	env, extensions := setupRealtimeTest(t, opts)
+	proxyServer := httptest.NewServer(env.handler)
+	defer proxyServer.Close()
+
+	target := strings.Replace(env.upstream.URL, "http://", "ws://", 1) + "/v1/realtime?model=gpt-realtime"
+	dialRealtime(t, proxyServer.URL, target, "azure-openai")
+	<-extensions
+
+	var records []model.UsageRecord
+	require.Eventually(t, func() bool {
+		var err error
+		records, err = env.store.QueryUsage(context.Background(), model.ReportFilter{})
+		return err == nil && len(records) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	record := records[0]
+	assert.Equal(t, int64(1600), record.InputTokens)
+	assert.Equal(t, int64(550), record.OutputTokens)
+	expected := (4.00*200 + 0.40*100 + 32.00*1300 + 16.00*70 + 64.00*480) / 1_000_000
+	assert.InDelta(t, expected, record.CostUSD, 1e-9)
+
+	var metadata model.UsageMetadata
+	require.NoError(t, json.Unmarshal([]byte(record.Metadata), &metadata))
+	assert.Equal(t, 2, metadata.ResponseCount)
+	assert.Equal(t, int64(1300), metadata.AudioInputTokens)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 )
 
@@ -21,6 +22,15 @@ type ProjectChargeback struct {
 	RecordCount       int64
 }
 
+// TagChargeback holds aggregated spend for one value of a cost-allocation tag.
+type TagChargeback struct {
+	Value             string
+	TotalCostUSD      float64
+	TotalInputTokens  int64
+	TotalOutputTokens int64
+	RecordCount       int64
+}
+
 // ReportDocument contains the rendered report payload.
 type ReportDocument struct {
 	Period      string
@@ -30,13 +40,36 @@ type ReportDocument struct {
 	Summary     *tracker.UsageSummary
 	Records     []tracker.UsageRecord
 	Chargebacks []ProjectChargeback
+	// TagChargebacks breaks spend down by the values of Filter.GroupByTag when it is set.
+	TagChargebacks []TagChargeback
 }
 
 // BuildProjectChargebacks aggregates records into project chargeback rows.
 func BuildProjectChargebacks(records []tracker.UsageRecord) []ProjectChargeback {
+	return groupChargebacks(records, func(record tracker.UsageRecord) string { return record.Project })
+}
+
+// BuildTagChargebacks aggregates records into chargeback rows keyed by the value of tag key.
+func BuildTagChargebacks(records []tracker.UsageRecord, key string) []TagChargeback {
+	key = strings.ToLower(key)
+	grouped := groupChargebacks(records, func(record tracker.UsageRecord) string { return record.Tags[key] })
+	rows := make([]TagChargeback, len(grouped))
+	for i, row := range grouped {
+		rows[i] = TagChargeback{
+			Value:             row.Project,
+			TotalCostUSD:      row.TotalCostUSD,
+			TotalInputTokens:  row.TotalInputTokens,
+			TotalOutputTokens: row.TotalOutputTokens,
+			RecordCount:       row.RecordCount,
+		}
+	}
+	return rows
+}
+
+func groupChargebacks(records []tracker.UsageRecord, keyOf func(tracker.UsageRecord) string) []ProjectChargeback {
 	grouped := make(map[string]ProjectChargeback)
 	for _, record := range records {
-		project := record.Project
+		project := keyOf(record)
 		if project == "" {
 			project = "unassigned"
 		}
@@ -96,6 +129,8 @@ func WriteCSV(path string, doc ReportDocument, detailed bool) error {
 			"input_tokens",
 			"output_tokens",
 			"cost_usd",
+			"user",
+			"tags",
 		}); err != nil {
 			return fmt.Errorf("write csv header: %w", err)
 		}
@@ -109,6 +144,30 @@ func WriteCSV(path string, doc ReportDocument, detailed bool) error {
 				fmt.Sprintf("%d", record.InputTokens),
 				fmt.Sprintf("%d", record.OutputTokens),
 				fmt.Sprintf("%.6f", record.CostUSD),
+				record.User,
+				model.FormatTags(record.Tags),
+			}); err != nil {
+				return fmt.Errorf("write csv record: %w", err)
+			}
+		}
+	} else if doc.Filter.GroupByTag != "" {
+		if err := writer.Write([]string{
+			"tag:" + strings.ToLower(doc.Filter.GroupByTag),
+			"record_count",
+			"input_tokens",
+			"output_tokens",
+			"total_cost_usd",
+		}); err != nil {
+			return fmt.Errorf("write csv header: %w", err)
+		}
+
+		for _, chargeback := range doc.TagChargebacks {
+			if err := writer.Write([]string{
+				chargeback.Value,
+				fmt.Sprintf("%d", chargeback.RecordCount),
+				fmt.Sprintf("%d", chargeback.TotalInputTokens),
+				fmt.Sprintf("%d", chargeback.TotalOutputTokens),
+				fmt.Sprintf("%.6f", chargeback.TotalCostUSD),
 			}); err != nil {
 				return fmt.Errorf("write csv record: %w", err)
 			}
@@ -76,6 +76,22 @@ func TestWriteCSV_Detailed(t *testing.T) {
 	assert.Contains(t, string(data), "payments,openai,gpt-4o,100,50,1.250000")
 }
 
+func TestWriteCSV_GroupByTag(t *testing.T) {
+	doc := testReportDocument()
+	doc.Records[0].Tags = map[string]string{"feature": "search"}
+	doc.Filter.GroupByTag = "feature"
+	doc.TagChargebacks = reporting.BuildTagChargebacks(doc.Records, "feature")
+	path := filepath.Join(t.TempDir(), "report-tags.csv")
+
+	require.NoError(t, reporting.WriteCSV(path, doc, false))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "tag:feature,record_count,input_tokens,output_tokens,total_cost_usd")
+	assert.Contains(t, string(data), "search,1,100,50,1.250000")
+	assert.Contains(t, string(data), "unassigned,1,75,20,2.500000")
+}
+
 func TestWritePDF(t *testing.T) {
 	doc := testReportDocument()
 	path := filepath.Join(t.TempDir(), "report.pdf")
@@ -104,3 +120,38 @@ func TestDefaultOutputPath(t *testing.T) {
 	assert.Contains(t, path, filepath.Join("output", "pdf", "chargeback-monthly-"))
 	assert.Contains(t, path, ".pdf")
 }
+
+func TestWriteTenantExport(t *testing.T) {
+	doc := testReportDocument()
+	export := &tracker.TenantExport{
+		ExportedAt: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		Tenant:     tracker.Tenant{ID: "t-1", Slug: "acme", Name: "Acme", Status: "active"},
+		Usage:      doc.Records,
+		Budgets:    []tracker.Budget{{ID: "b-1", Name: "acme-monthly", LimitUSD: 100, Period: tracker.PeriodMonthly}},
+		APIKeys:    []tracker.APIKey{{ID: "k-1", Name: "ci", KeyPrefix: "lcg_ci", KeyHash: "secret-hash", Role: "developer"}},
+	}
+	dir := t.TempDir()
+
+	files, err := reporting.WriteTenantExport(filepath.Join(dir, "json"), export, "json")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"slug": "acme"`)
+	assert.Contains(t, string(data), `"key_prefix": "lcg_ci"`)
+	assert.NotContains(t, string(data), "secret-hash")
+
+	files, err = reporting.WriteTenantExport(filepath.Join(dir, "csv"), export, "csv")
+	require.NoError(t, err)
+	require.Len(t, files, 5)
+	data, err = os.ReadFile(filepath.Join(dir, "csv", "usage.csv"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "payments,openai,gpt-4o,100,50,1.250000")
+	data, err = os.ReadFile(filepath.Join(dir, "csv", "api_keys.csv"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "k-1,ci,lcg_ci,developer")
+	assert.NotContains(t, string(data), "secret-hash")
+
+	_, err = reporting.WriteTenantExport(dir, export, "xml")
+	assert.ErrorContains(t, err, "unsupported export format")
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"sort"
 	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 )
 
 type pdfLine struct {
@@ -42,7 +44,7 @@ func buildPDFLines(doc ReportDocument, detailed bool) []pdfLine {
 	lines := []pdfLine{
 		{Text: "LLM Cost Guardian Chargeback Report", Font: "F2", Size: 18},
 		{Text: fmt.Sprintf("Period: %s (%s to %s)", strings.ToUpper(doc.Period), doc.Start.Format("2006-01-02"), doc.End.Format("2006-01-02")), Font: "F1", Size: 10},
-		{Text: fmt.Sprintf("Filters: provider=%s model=%s project=%s", fallback(doc.Filter.Provider, "*"), fallback(doc.Filter.Model, "*"), fallback(doc.Filter.Project, "*")), Font: "F1", Size: 10},
+		{Text: fmt.Sprintf("Filters: provider=%s model=%s project=%s user=%s tags=%s", fallback(doc.Filter.Provider, "*"), fallback(doc.Filter.Model, "*"), fallback(doc.Filter.Project, "*"), fallback(doc.Filter.User, "*"), fallback(model.FormatTags(doc.Filter.Tags), "*")), Font: "F1", Size: 10},
 		{Text: "", Font: "F1", Size: 8},
 		{Text: "Summary", Font: "F2", Size: 13},
 		{Text: fmt.Sprintf("Total cost: $%.4f", doc.Summary.TotalCostUSD), Font: "F1", Size: 10},
@@ -55,6 +57,9 @@ func buildPDFLines(doc ReportDocument, detailed bool) []pdfLine {
 	lines = append(lines, renderMapSection("Cost by provider", doc.Summary.ByProvider)...)
 	lines = append(lines, renderMapSection("Cost by model", doc.Summary.ByModel)...)
 	lines = append(lines, renderChargebackSection(doc.Chargebacks)...)
+	if doc.Filter.GroupByTag != "" {
+		lines = append(lines, renderTagChargebackSection(doc.Filter.GroupByTag, doc.TagChargebacks)...)
+	}
 
 	if detailed {
 		lines = append(lines,
@@ -135,6 +140,32 @@ func renderChargebackSection(chargebacks []ProjectChargeback) []pdfLine {
 	return lines
 }
 
+func renderTagChargebackSection(key string, chargebacks []TagChargeback) []pdfLine {
+	lines := []pdfLine{
+		{Text: "Chargeback by tag " + strings.ToLower(key), Font: "F2", Size: 13},
+		{Text: "VALUE                REQUESTS      INPUT       OUTPUT        COST", Font: "F2", Size: 9},
+	}
+	if len(chargebacks) == 0 {
+		return append(lines, pdfLine{Text: "No tag data", Font: "F1", Size: 10}, pdfLine{Text: "", Font: "F1", Size: 8})
+	}
+	for _, row := range chargebacks {
+		lines = append(lines, pdfLine{
+			Text: fmt.Sprintf(
+				"%-20s %9d %10d %12d %11.4f",
+				trimWidth(row.Value, 20),
+				row.RecordCount,
+				row.TotalInputTokens,
+				row.TotalOutputTokens,
+				row.TotalCostUSD,
+			),
+			Font: "F1",
+			Size: 10,
+		})
+	}
+	lines = append(lines, pdfLine{Text: "", Font: "F1", Size: 8})
+	return lines
+}
+
 func paginatePDFLines(lines []pdfLine) [][]pdfLine {
 	const usableHeight = 690.0
 	pages := make([][]pdfLine, 0, 1)
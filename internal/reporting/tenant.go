@@ -0,0 +1,185 @@
+package reporting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+)
+
+// DefaultTenantExportDir returns a stable directory for a tenant's export bundle.
+func DefaultTenantExportDir(slug string) string {
+	ts := time.Now().UTC().Format("20060102-150405")
+	return filepath.Join("output", "tenants", fmt.Sprintf("%s-%s", slug, ts))
+}
+
+// WriteTenantExport writes a tenant's export bundle into dir and returns the files written.
+// The json format writes the whole bundle to tenant.json; csv writes one file per section.
+func WriteTenantExport(dir string, export *tracker.TenantExport, format string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create export dir: %w", err)
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		path := filepath.Join(dir, "tenant.json")
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshal tenant export: %w", err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+			return nil, fmt.Errorf("write tenant export: %w", err)
+		}
+		return []string{path}, nil
+	case "csv":
+		files := []struct {
+			name   string
+			header []string
+			rows   [][]string
+		}{
+			{"tenant.csv", []string{"id", "slug", "name", "status", "created_at", "updated_at", "exported_at"}, [][]string{{
+				export.Tenant.ID, export.Tenant.Slug, export.Tenant.Name, export.Tenant.Status,
+				export.Tenant.CreatedAt.Format(time.RFC3339), export.Tenant.UpdatedAt.Format(time.RFC3339),
+				export.ExportedAt.Format(time.RFC3339),
+			}}},
+			{"usage.csv", []string{
+				"id", "timestamp", "project", "provider", "model", "input_tokens", "output_tokens", "cost_usd",
+				"user", "api_key_id", "completion_status", "status_code", "tags",
+			}, usageExportRows(export.Usage)},
+			{"budgets.csv", []string{
+				"id", "name", "project", "period", "limit_usd", "current_spend", "alert_threshold_pct", "tags", "created_at",
+			}, budgetExportRows(export.Budgets)},
+			{"api_keys.csv", []string{
+				"id", "name", "key_prefix", "role", "all_tenants", "status", "spend_limit_usd", "spend_period",
+				"allowed_projects", "created_at", "expires_at", "last_used_at", "revoked_at",
+			}, apiKeyExportRows(export.APIKeys)},
+			{"model_policies.csv", []string{
+				"id", "name", "project", "api_key_id", "allowed_providers", "allowed_models", "denied_models",
+				"max_tokens", "deny_streaming", "updated_at",
+			}, modelPolicyExportRows(export.ModelPolicies)},
+		}
+		paths := make([]string, 0, len(files))
+		for _, file := range files {
+			path := filepath.Join(dir, file.name)
+			if err := writeCSVRows(path, file.header, file.rows); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func usageExportRows(records []model.UsageRecord) [][]string {
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, []string{
+			record.ID,
+			record.Timestamp.Format(time.RFC3339),
+			record.Project,
+			record.Provider,
+			record.Model,
+			strconv.FormatInt(record.InputTokens, 10),
+			strconv.FormatInt(record.OutputTokens, 10),
+			fmt.Sprintf("%.6f", record.CostUSD),
+			record.User,
+			record.APIKeyID,
+			record.CompletionStatus,
+			strconv.Itoa(record.StatusCode),
+			model.FormatTags(record.Tags),
+		})
+	}
+	return rows
+}
+
+func budgetExportRows(budgets []model.Budget) [][]string {
+	rows := make([][]string, 0, len(budgets))
+	for _, budget := range budgets {
+		rows = append(rows, []string{
+			budget.ID,
+			budget.Name,
+			budget.Project,
+			string(budget.Period),
+			fmt.Sprintf("%.2f", budget.LimitUSD),
+			fmt.Sprintf("%.6f", budget.CurrentSpend),
+			fmt.Sprintf("%.0f", budget.AlertThresholdPct),
+			model.FormatTags(budget.Tags),
+			budget.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func apiKeyExportRows(keys []model.APIKey) [][]string {
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		rows = append(rows, []string{
+			key.ID,
+			key.Name,
+			key.KeyPrefix,
+			key.Role,
+			strconv.FormatBool(key.AllTenants),
+			key.Status,
+			fmt.Sprintf("%.2f", key.SpendLimitUSD),
+			string(key.SpendPeriod),
+			strings.Join(key.AllowedProjects, ","),
+			key.CreatedAt.Format(time.RFC3339),
+			formatOptionalTime(key.ExpiresAt),
+			formatOptionalTime(key.LastUsedAt),
+			formatOptionalTime(key.RevokedAt),
+		})
+	}
+	return rows
+}
+
+func modelPolicyExportRows(policies []model.ModelPolicy) [][]string {
+	rows := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		rows = append(rows, []string{
+			policy.ID,
+			policy.Name,
+			policy.Project,
+			policy.APIKeyID,
+			strings.Join(policy.AllowedProviders, ","),
+			strings.Join(policy.AllowedModels, ","),
+			strings.Join(policy.DeniedModels, ","),
+			strconv.FormatInt(policy.MaxTokens, 10),
+			strconv.FormatBool(policy.DenyStreaming),
+			policy.UpdatedAt.Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func writeCSVRows(path string, header []string, rows [][]string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("create csv: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("write csv header: %w", err)
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("write csv record: %w", err)
+	}
+	return nil
+}
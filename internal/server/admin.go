@@ -0,0 +1,552 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
+	keyauth "github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+)
+
+func (s *Server) adminRoutes() {
+	s.mux.HandleFunc("GET /api/v1/admin/schemas/{name}", authorize(model.PermAdminRead, s.handleAdminSchema))
+	s.mux.HandleFunc("GET /api/v1/admin/tenants", authorize(model.PermAdminRead, s.admin(s.handleListTenants)))
+	s.mux.HandleFunc("POST /api/v1/admin/tenants", authorize(model.PermAdminWrite, s.admin(s.handleCreateTenant)))
+	s.mux.HandleFunc("GET /api/v1/admin/tenants/{slug}", authorize(model.PermAdminRead, s.admin(s.handleGetTenant)))
+	s.mux.HandleFunc("PATCH /api/v1/admin/tenants/{slug}", authorize(model.PermAdminWrite, s.admin(s.handleUpdateTenant)))
+	s.mux.HandleFunc("DELETE /api/v1/admin/tenants/{slug}", authorize(model.PermAdminWrite, s.admin(s.handleDeleteTenant)))
+	s.mux.HandleFunc("GET /api/v1/admin/tenants/{slug}/export", authorize(model.PermAdminRead, s.admin(s.handleExportTenant)))
+	s.mux.HandleFunc("GET /api/v1/admin/tenants/{slug}/settings", authorize(model.PermAdminRead, s.admin(s.handleGetTenantSettings)))
+	s.mux.HandleFunc("PATCH /api/v1/admin/tenants/{slug}/settings", authorize(model.PermAdminWrite, s.admin(s.handleUpdateTenantSettings)))
+	s.mux.HandleFunc("GET /api/v1/admin/api-keys", authorize(model.PermAdminRead, s.admin(s.handleListAPIKeys)))
+	s.mux.HandleFunc("POST /api/v1/admin/api-keys", authorize(model.PermAdminWrite, s.admin(s.handleCreateAPIKey)))
+	s.mux.HandleFunc("GET /api/v1/admin/api-keys/{id}", authorize(model.PermAdminRead, s.admin(s.handleGetAPIKey)))
+	s.mux.HandleFunc("PUT /api/v1/admin/api-keys/{id}/role", authorize(model.PermAdminWrite, s.admin(s.handleSetAPIKeyRole)))
+	s.mux.HandleFunc("POST /api/v1/admin/api-keys/{id}/rotate", authorize(model.PermAdminWrite, s.admin(s.handleRotateAPIKey)))
+	s.mux.HandleFunc("DELETE /api/v1/admin/api-keys/{id}", authorize(model.PermAdminWrite, s.admin(s.handleRevokeAPIKey)))
+	s.mux.HandleFunc("GET /api/v1/admin/budgets", authorize(model.PermAdminRead, s.admin(s.handleListBudgets)))
+	s.mux.HandleFunc("GET /api/v1/admin/budgets/{name}", authorize(model.PermAdminRead, s.admin(s.handleGetBudget)))
+	s.mux.HandleFunc("PUT /api/v1/admin/budgets/{name}", authorize(model.PermAdminWrite, s.admin(s.handleSetBudget)))
+}
+
+// admin answers 404 while the server has no store, and bounds the handler's context.
+func (s *Server) admin(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.store == nil {
+			writeAdminError(w, http.StatusNotFound, "admin api is disabled", "")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		handler(ctx, w, r)
+	}
+}
+
+func (s *Server) handleAdminSchema(w http.ResponseWriter, r *http.Request) {
+	schema, ok := adminapi.Schema(r.PathValue("name"))
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("schema %q not found; schemas: %v", r.PathValue("name"), adminapi.SchemaNames()), "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	_, _ = w.Write(schema)
+}
+
+func (s *Server) handleListTenants(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !crossTenant(r) {
+		tenant, err := s.store.GetTenant(ctx, callerTenant(r))
+		if err != nil {
+			s.writeAdminStoreError(w, "get tenant", err)
+			return
+		}
+		s.writeAdminJSON(w, http.StatusOK, []model.Tenant{*tenant})
+		return
+	}
+	tenants, err := s.store.ListTenants(ctx)
+	if err != nil {
+		s.writeAdminStoreError(w, "list tenants", err)
+		return
+	}
+	if tenants == nil {
+		tenants = []model.Tenant{}
+	}
+	s.writeAdminJSON(w, http.StatusOK, tenants)
+}
+
+func (s *Server) handleCreateTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !requireCrossTenant(w, r) {
+		return
+	}
+	var req adminapi.CreateTenantRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	tenant := &model.Tenant{Slug: req.Slug, Name: req.Name, Status: model.TenantStatusActive}
+	if err := s.store.CreateTenant(ctx, tenant); err != nil {
+		s.writeAdminStoreError(w, "create tenant", err)
+		return
+	}
+	s.logAdminChange(r, "tenant created", "tenant", tenant.Slug)
+	s.writeAdminJSON(w, http.StatusCreated, tenant)
+}
+
+func (s *Server) handleGetTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if !canAccessTenant(r, slug) {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("tenant %q not found", slug), "")
+		return
+	}
+	tenant, err := s.store.GetTenant(ctx, slug)
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant", err)
+		return
+	}
+	s.writeAdminJSON(w, http.StatusOK, tenant)
+}
+
+func (s *Server) handleUpdateTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !requireCrossTenant(w, r) {
+		return
+	}
+	var req adminapi.UpdateTenantRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+
+	slug := r.PathValue("slug")
+	if req.Slug != "" || req.Name != "" {
+		renamed, err := s.store.RenameTenant(ctx, slug, req.Slug, req.Name)
+		if err != nil {
+			s.writeAdminStoreError(w, "rename tenant", err)
+			return
+		}
+		slug = renamed.Slug
+	}
+	switch req.Status {
+	case model.TenantStatusActive:
+		if err := s.store.EnableTenant(ctx, slug); err != nil {
+			s.writeAdminStoreError(w, "enable tenant", err)
+			return
+		}
+	case model.TenantStatusDisabled:
+		if err := s.store.DisableTenant(ctx, slug); err != nil {
+			s.writeAdminStoreError(w, "disable tenant", err)
+			return
+		}
+	}
+
+	tenant, err := s.store.GetTenant(ctx, slug)
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant", err)
+		return
+	}
+	s.logAdminChange(r, "tenant updated", "tenant", tenant.Slug)
+	s.writeAdminJSON(w, http.StatusOK, tenant)
+}
+
+func (s *Server) handleDeleteTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !requireCrossTenant(w, r) {
+		return
+	}
+	slug := r.PathValue("slug")
+	if err := s.store.DeleteTenant(ctx, slug); err != nil {
+		s.writeAdminStoreError(w, "delete tenant", err)
+		return
+	}
+	s.logAdminChange(r, "tenant deleted", "tenant", slug)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleExportTenant(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if !canAccessTenant(r, slug) {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("tenant %q not found", slug), "")
+		return
+	}
+	export, err := s.store.ExportTenant(ctx, slug)
+	if err != nil {
+		s.writeAdminStoreError(w, "export tenant", err)
+		return
+	}
+	s.writeAdminJSON(w, http.StatusOK, export)
+}
+
+func (s *Server) handleGetTenantSettings(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+	if !canAccessTenant(r, slug) {
+		writeAdminError(w, http.StatusNotFound, fmt.Sprintf("tenant %q not found", slug), "")
+		return
+	}
+	settings, err := s.store.GetTenantSettings(ctx, slug)
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant settings", err)
+		return
+	}
+	s.writeAdminJSON(w, http.StatusOK, s.tenantSettingsResponse(settings))
+}
+
+// handleUpdateTenantSettings changes a tenant's settings. Like tenant lifecycle changes, it
+// needs a cross-tenant identity: the settings decide whether the tenant's budgets block.
+func (s *Server) handleUpdateTenantSettings(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !requireCrossTenant(w, r) {
+		return
+	}
+	var req adminapi.TenantSettingsRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	settings, err := s.store.GetTenantSettings(ctx, r.PathValue("slug"))
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant settings", err)
+		return
+	}
+	req.Apply(settings)
+	if err := s.store.SetTenantSettings(ctx, settings); err != nil {
+		s.writeAdminStoreError(w, "set tenant settings", err)
+		return
+	}
+	s.logAdminChange(r, "tenant settings updated", "tenant", settings.Tenant)
+	s.writeAdminJSON(w, http.StatusOK, s.tenantSettingsResponse(settings))
+}
+
+// tenantSettingsResponse pairs settings with the effective configuration they produce, with
+// secrets redacted.
+func (s *Server) tenantSettingsResponse(settings *model.TenantSettings) adminapi.TenantSettingsResponse {
+	return adminapi.TenantSettingsResponse{
+		Settings:  settings.Redacted(),
+		Effective: settings.Apply(s.tracker.TenantDefaults()).Redacted(),
+	}
+}
+
+func (s *Server) handleListAPIKeys(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	keys, err := s.store.ListAPIKeys(ctx, tenantFilterFromRequest(r))
+	if err != nil {
+		s.writeAdminStoreError(w, "list api keys", err)
+		return
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+	s.writeAdminJSON(w, http.StatusOK, keys)
+}
+
+func (s *Server) handleCreateAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req adminapi.CreateAPIKeyRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	if !requireGrant(w, r, req.Role, req.AllTenants) {
+		return
+	}
+	tenant, err := s.store.GetTenant(ctx, scopedTenant(r, req.Tenant))
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant", err)
+		return
+	}
+
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	if err != nil {
+		s.writeAdminStoreError(w, "generate api key", err)
+		return
+	}
+	key := &model.APIKey{
+		TenantID:        tenant.ID,
+		Tenant:          tenant.Slug,
+		Name:            req.Name,
+		KeyPrefix:       prefix,
+		KeyHash:         hash,
+		Status:          model.APIKeyStatusActive,
+		Role:            req.Role,
+		AllTenants:      req.AllTenants,
+		SpendLimitUSD:   req.SpendLimitUSD,
+		SpendPeriod:     model.BudgetPeriod(req.SpendPeriod),
+		AllowedProjects: req.AllowedProjects,
+		ExpiresAt:       req.ExpiresAt(time.Now()),
+	}
+	if err := s.store.CreateAPIKey(ctx, key); err != nil {
+		s.writeAdminStoreError(w, "create api key", err)
+		return
+	}
+	s.logAdminChange(r, "api key created", "tenant", key.Tenant, "api_key_id", key.ID)
+	s.writeAdminJSON(w, http.StatusCreated, adminapi.IssuedAPIKey{APIKey: *key, Key: rawKey})
+}
+
+func (s *Server) handleGetAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	key, ok := s.accessibleAPIKey(ctx, w, r)
+	if !ok {
+		return
+	}
+	s.writeAdminJSON(w, http.StatusOK, key)
+}
+
+func (s *Server) handleSetAPIKeyRole(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req adminapi.SetAPIKeyRoleRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	key, ok := s.accessibleAPIKey(ctx, w, r)
+	if !ok || !requireGrant(w, r, key.Role, key.AllTenants) || !requireGrant(w, r, req.Role, req.AllTenants) {
+		return
+	}
+	if err := s.store.SetAPIKeyRole(ctx, key.ID, req.Role, req.AllTenants); err != nil {
+		s.writeAdminStoreError(w, "set api key role", err)
+		return
+	}
+	key.Role, key.AllTenants = req.Role, req.AllTenants
+	s.logAdminChange(r, "api key role set", "tenant", key.Tenant, "api_key_id", key.ID, "role", key.Role)
+	s.writeAdminJSON(w, http.StatusOK, key)
+}
+
+func (s *Server) handleRotateAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req adminapi.RotateAPIKeyRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	key, ok := s.accessibleAPIKey(ctx, w, r)
+	if !ok || !requireGrant(w, r, key.Role, key.AllTenants) {
+		return
+	}
+	if key.Status != model.APIKeyStatusActive {
+		writeAdminError(w, http.StatusConflict, fmt.Sprintf("api key %q is %s", key.ID, key.Status), "")
+		return
+	}
+	if key.SuccessorID != "" {
+		writeAdminError(w, http.StatusConflict, fmt.Sprintf("api key %q was already rotated to %q", key.ID, key.SuccessorID), "")
+		return
+	}
+
+	rawKey, prefix, hash, err := keyauth.GenerateAPIKey()
+	if err != nil {
+		s.writeAdminStoreError(w, "generate api key", err)
+		return
+	}
+	successor := &model.APIKey{KeyPrefix: prefix, KeyHash: hash, ExpiresAt: req.ExpiresAt(time.Now())}
+	old, err := s.tracker.RotateAPIKey(ctx, key.ID, successor, req.GracePeriod())
+	if err != nil {
+		s.writeAdminStoreError(w, "rotate api key", err)
+		return
+	}
+	s.logAdminChange(r, "api key rotated", "tenant", old.Tenant, "api_key_id", old.ID, "successor_api_key_id", successor.ID)
+	s.writeAdminJSON(w, http.StatusCreated, adminapi.IssuedAPIKey{APIKey: *successor, Key: rawKey, Replaces: old})
+}
+
+func (s *Server) handleRevokeAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	key, ok := s.accessibleAPIKey(ctx, w, r)
+	if !ok || !requireGrant(w, r, key.Role, key.AllTenants) {
+		return
+	}
+	if err := s.store.RevokeAPIKey(ctx, key.ID); err != nil {
+		s.writeAdminStoreError(w, "revoke api key", err)
+		return
+	}
+	s.logAdminChange(r, "api key revoked", "tenant", key.Tenant, "api_key_id", key.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// accessibleAPIKey returns the key named by the request path, answering 404 when it does not
+// exist or belongs to a tenant the caller may not manage.
+func (s *Server) accessibleAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) (*model.APIKey, bool) {
+	id := r.PathValue("id")
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err == nil && !canAccessTenant(r, key.Tenant) {
+		err = fmt.Errorf("api key %q %w", id, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.writeAdminStoreError(w, "get api key", err)
+		return nil, false
+	}
+	return key, true
+}
+
+func (s *Server) handleListBudgets(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	budgets, err := s.store.ListBudgets(ctx)
+	if err != nil {
+		s.writeAdminStoreError(w, "list budgets", err)
+		return
+	}
+	tenant := tenantFilterFromRequest(r)
+	filtered := []model.Budget{}
+	for _, budget := range budgets {
+		if tenant == "" || budget.Tenant == tenant {
+			filtered = append(filtered, budget)
+		}
+	}
+	s.writeAdminJSON(w, http.StatusOK, filtered)
+}
+
+func (s *Server) handleGetBudget(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	budget, err := s.store.GetBudget(ctx, name)
+	if err == nil && !canAccessTenant(r, budget.Tenant) {
+		err = fmt.Errorf("budget %q %w", name, storage.ErrNotFound)
+	}
+	if err != nil {
+		s.writeAdminStoreError(w, "get budget", err)
+		return
+	}
+	s.writeAdminJSON(w, http.StatusOK, budget)
+}
+
+func (s *Server) handleSetBudget(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := adminapi.ValidateBudgetName(name); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	var req adminapi.BudgetRequest
+	if !decodeAdminRequest(w, r, &req) {
+		return
+	}
+	tenant, err := s.store.GetTenant(ctx, scopedTenant(r, req.Tenant))
+	if err != nil {
+		s.writeAdminStoreError(w, "get tenant", err)
+		return
+	}
+	// Budget names are unique across tenants; an update must not move a budget between them.
+	existing, err := s.store.GetBudget(ctx, name)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		s.writeAdminStoreError(w, "get budget", err)
+		return
+	}
+	if existing != nil && existing.TenantID != tenant.ID {
+		writeAdminError(w, http.StatusConflict, fmt.Sprintf("budget %q belongs to another tenant", name), "name")
+		return
+	}
+	if req.AlertThresholdPct == 0 {
+		config, err := s.tracker.TenantConfig(ctx, tenant.Slug)
+		if err != nil {
+			s.writeAdminStoreError(w, "get tenant settings", err)
+			return
+		}
+		req.AlertThresholdPct = config.AlertThresholdPct
+	}
+
+	budget := &model.Budget{
+		TenantID:          tenant.ID,
+		Tenant:            tenant.Slug,
+		Name:              name,
+		Project:           req.Project,
+		LimitUSD:          req.LimitUSD,
+		Period:            model.BudgetPeriod(req.Period),
+		AlertThresholdPct: req.AlertThresholdPct,
+		Tags:              req.Tags,
+	}
+	if err := s.store.SetBudget(ctx, budget); err != nil {
+		s.writeAdminStoreError(w, "set budget", err)
+		return
+	}
+	s.logAdminChange(r, "budget set", "tenant", budget.Tenant, "budget", budget.Name)
+	s.writeAdminJSON(w, http.StatusOK, budget)
+}
+
+// crossTenant reports whether the caller may act on every tenant.
+func crossTenant(r *http.Request) bool {
+	identity, ok := httpauth.IdentityFromContext(r.Context())
+	return !ok || identity.AllTenants
+}
+
+// callerTenant returns the slug of the caller's own tenant.
+func callerTenant(r *http.Request) string {
+	identity, _ := httpauth.IdentityFromContext(r.Context())
+	return identity.Tenant.Slug
+}
+
+func canAccessTenant(r *http.Request, slug string) bool {
+	return crossTenant(r) || callerTenant(r) == slug
+}
+
+// requireCrossTenant refuses tenant lifecycle changes from tenant-scoped identities.
+func requireCrossTenant(w http.ResponseWriter, r *http.Request) bool {
+	if !crossTenant(r) {
+		writeAdminError(w, http.StatusForbidden, "forbidden: managing tenants needs a cross-tenant identity", "")
+		return false
+	}
+	return true
+}
+
+// requireGrant refuses to let the caller grant, or change a key holding, a role with a
+// permission its own role lacks, or a cross-tenant role it does not hold itself.
+func requireGrant(w http.ResponseWriter, r *http.Request, role string, allTenants bool) bool {
+	identity, ok := httpauth.IdentityFromContext(r.Context())
+	if !ok {
+		return true
+	}
+	callerRole := identity.Role
+	if callerRole == "" {
+		callerRole = model.RoleDeveloper
+	}
+	if !model.RoleAtLeast(callerRole, role) {
+		writeAdminError(w, http.StatusForbidden, fmt.Sprintf("forbidden: role %s cannot manage %s keys", callerRole, role), "role")
+		return false
+	}
+	if allTenants && !identity.AllTenants {
+		writeAdminError(w, http.StatusForbidden, "forbidden: only cross-tenant identities can manage cross-tenant keys", "all_tenants")
+		return false
+	}
+	return true
+}
+
+// decodeAdminRequest decodes and validates a request body, rejecting unknown fields, and
+// writes a 400 response when it is invalid.
+func decodeAdminRequest(w http.ResponseWriter, r *http.Request, req interface{ Validate() error }) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "")
+		return false
+	}
+	if err := req.Validate(); err != nil {
+		writeValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	var validation *adminapi.ValidationError
+	if errors.As(err, &validation) {
+		writeAdminError(w, http.StatusBadRequest, validation.Error(), validation.Field)
+		return
+	}
+	writeAdminError(w, http.StatusBadRequest, err.Error(), "")
+}
+
+// writeAdminStoreError maps storage errors to 404 and 409 responses, and logs anything else
+// as an internal error.
+func (s *Server) writeAdminStoreError(w http.ResponseWriter, operation string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeAdminError(w, http.StatusNotFound, err.Error(), "")
+	case errors.Is(err, storage.ErrExists), errors.Is(err, storage.ErrDefaultTenant):
+		writeAdminError(w, http.StatusConflict, err.Error(), "")
+	default:
+		s.logger.Error(operation, "error", err)
+		writeAdminError(w, http.StatusInternalServerError, "internal error", "")
+	}
+}
+
+func writeAdminError(w http.ResponseWriter, status int, message, field string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(adminapi.Error{Error: message, Field: field})
+}
+
+func (s *Server) writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Error("encode admin response", "error", err)
+	}
+}
+
+func (s *Server) logAdminChange(r *http.Request, message string, args ...any) {
+	identity, _ := httpauth.IdentityFromContext(r.Context())
+	s.logger.Info(message, append(args, "changed_by", identity.Actor())...)
+}
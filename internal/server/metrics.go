@@ -3,6 +3,7 @@ package server
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
@@ -15,6 +16,50 @@ type metricKey struct {
 	Project  string
 }
 
+type errorMetricKey struct {
+	metricKey
+	ErrorType string
+}
+
+// policyMetricKey counts policy decisions or findings; Value is the action or the rule name.
+type policyMetricKey struct {
+	Tenant  string
+	Project string
+	Value   string
+}
+
+type latencyMetricKey struct {
+	Tenant   string
+	Provider string
+	Model    string
+}
+
+var (
+	latencyBuckets    = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	ttftBuckets       = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	throughputBuckets = []float64{5, 10, 25, 50, 100, 200, 400}
+)
+
+// histogram accumulates cumulative Prometheus bucket counts for one label set.
+type histogram struct {
+	Counts []int64
+	Count  int64
+	Sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, value float64) {
+	if h.Counts == nil {
+		h.Counts = make([]int64, len(bounds))
+	}
+	for i, bound := range bounds {
+		if value <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += value
+}
+
 type metricTotals struct {
 	Requests     int64
 	InputTokens  int64
@@ -42,6 +87,12 @@ func renderPrometheusMetrics(summary *tracker.UsageSummary, records []tracker.Us
 	fmt.Fprintf(&builder, "lcg_output_tokens_total %d\n", summary.TotalOutputTokens)
 
 	grouped := make(map[metricKey]metricTotals)
+	errorCounts := make(map[errorMetricKey]int64)
+	latencies := make(map[latencyMetricKey]*histogram)
+	ttfts := make(map[latencyMetricKey]*histogram)
+	throughputs := make(map[latencyMetricKey]*histogram)
+	policyDecisions := make(map[policyMetricKey]int64)
+	policyFindings := make(map[policyMetricKey]int64)
 	for _, record := range records {
 		key := metricKey{
 			Tenant:   record.Tenant,
@@ -55,6 +106,28 @@ func renderPrometheusMetrics(summary *tracker.UsageSummary, records []tracker.Us
 		total.OutputTokens += record.OutputTokens
 		total.CostUSD += record.CostUSD
 		grouped[key] = total
+		if record.PolicyAction != "" {
+			policyDecisions[policyMetricKey{Tenant: record.Tenant, Project: record.Project, Value: record.PolicyAction}]++
+			for _, finding := range strings.Split(record.PolicyFindings, ",") {
+				if finding != "" {
+					policyFindings[policyMetricKey{Tenant: record.Tenant, Project: record.Project, Value: finding}]++
+				}
+			}
+		}
+		if record.ErrorType != "" {
+			errorCounts[errorMetricKey{metricKey: key, ErrorType: record.ErrorType}]++
+			continue
+		}
+		latencyKey := latencyMetricKey{Tenant: record.Tenant, Provider: record.Provider, Model: record.Model}
+		if record.LatencyMs > 0 {
+			observe(latencies, latencyKey, latencyBuckets, float64(record.LatencyMs)/1000)
+		}
+		if record.TTFTMs > 0 {
+			observe(ttfts, latencyKey, ttftBuckets, float64(record.TTFTMs)/1000)
+		}
+		if record.OutputTokensPerSec > 0 {
+			observe(throughputs, latencyKey, throughputBuckets, record.OutputTokensPerSec)
+		}
 	}
 
 	keys := make([]metricKey, 0, len(grouped))
@@ -62,16 +135,7 @@ func renderPrometheusMetrics(summary *tracker.UsageSummary, records []tracker.Us
 		keys = append(keys, key)
 	}
 	sort.Slice(keys, func(i, j int) bool {
-		if keys[i].Tenant != keys[j].Tenant {
-			return keys[i].Tenant < keys[j].Tenant
-		}
-		if keys[i].Provider != keys[j].Provider {
-			return keys[i].Provider < keys[j].Provider
-		}
-		if keys[i].Model != keys[j].Model {
-			return keys[i].Model < keys[j].Model
-		}
-		return keys[i].Project < keys[j].Project
+		return lessMetricKey(keys[i], keys[j])
 	})
 
 	builder.WriteString("# HELP lcg_requests_total Total LLM requests by tenant, provider, model, and project.\n")
@@ -130,5 +194,138 @@ func renderPrometheusMetrics(summary *tracker.UsageSummary, records []tracker.Us
 		)
 	}
 
+	errorKeys := make([]errorMetricKey, 0, len(errorCounts))
+	for key := range errorCounts {
+		errorKeys = append(errorKeys, key)
+	}
+	sort.Slice(errorKeys, func(i, j int) bool {
+		if errorKeys[i].metricKey != errorKeys[j].metricKey {
+			return lessMetricKey(errorKeys[i].metricKey, errorKeys[j].metricKey)
+		}
+		return errorKeys[i].ErrorType < errorKeys[j].ErrorType
+	})
+
+	builder.WriteString("# HELP lcg_upstream_errors_total Failed upstream LLM calls by tenant, provider, model, project, and error type.\n")
+	builder.WriteString("# TYPE lcg_upstream_errors_total counter\n")
+	for _, key := range errorKeys {
+		fmt.Fprintf(&builder,
+			"lcg_upstream_errors_total{tenant=%q,provider=%q,model=%q,project=%q,error_type=%q} %d\n",
+			key.Tenant,
+			key.Provider,
+			key.Model,
+			key.Project,
+			key.ErrorType,
+			errorCounts[key],
+		)
+	}
+
+	writePolicyCounter(&builder, "lcg_policy_decisions_total", "Requests a policy flagged, redacted, or blocked by tenant, project, and action.", "action", policyDecisions)
+	writePolicyCounter(&builder, "lcg_policy_findings_total", "Requests matching each policy rule by tenant, project, and rule.", "rule", policyFindings)
+
+	writeHistogram(&builder, "lcg_request_latency_seconds", "Upstream latency of successful LLM calls by tenant, provider, and model.", latencyBuckets, latencies)
+	writeHistogram(&builder, "lcg_time_to_first_token_seconds", "Time to first streamed token by tenant, provider, and model.", ttftBuckets, ttfts)
+	writeHistogram(&builder, "lcg_output_tokens_per_second", "Output token throughput by tenant, provider, and model.", throughputBuckets, throughputs)
+
+	return builder.String()
+}
+
+func observe(histograms map[latencyMetricKey]*histogram, key latencyMetricKey, bounds []float64, value float64) {
+	h := histograms[key]
+	if h == nil {
+		h = &histogram{}
+		histograms[key] = h
+	}
+	h.observe(bounds, value)
+}
+
+func writeHistogram(builder *strings.Builder, name, help string, bounds []float64, histograms map[latencyMetricKey]*histogram) {
+	keys := make([]latencyMetricKey, 0, len(histograms))
+	for key := range histograms {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return lessMetricKey(metricKey{Tenant: keys[i].Tenant, Provider: keys[i].Provider, Model: keys[i].Model},
+			metricKey{Tenant: keys[j].Tenant, Provider: keys[j].Provider, Model: keys[j].Model})
+	})
+
+	fmt.Fprintf(builder, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(builder, "# TYPE %s histogram\n", name)
+	for _, key := range keys {
+		h := histograms[key]
+		labels := fmt.Sprintf("tenant=%q,provider=%q,model=%q", key.Tenant, key.Provider, key.Model)
+		for i, bound := range bounds {
+			fmt.Fprintf(builder, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(bound, 'g', -1, 64), h.Counts[i])
+		}
+		fmt.Fprintf(builder, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.Count)
+		fmt.Fprintf(builder, "%s_sum{%s} %.6f\n", name, labels, h.Sum)
+		fmt.Fprintf(builder, "%s_count{%s} %d\n", name, labels, h.Count)
+	}
+}
+
+func writePolicyCounter(builder *strings.Builder, name, help, label string, counts map[policyMetricKey]int64) {
+	keys := make([]policyMetricKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Tenant != keys[j].Tenant {
+			return keys[i].Tenant < keys[j].Tenant
+		}
+		if keys[i].Project != keys[j].Project {
+			return keys[i].Project < keys[j].Project
+		}
+		return keys[i].Value < keys[j].Value
+	})
+
+	fmt.Fprintf(builder, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(builder, "# TYPE %s counter\n", name)
+	for _, key := range keys {
+		fmt.Fprintf(builder, "%s{tenant=%q,project=%q,%s=%q} %d\n", name, key.Tenant, key.Project, label, key.Value, counts[key])
+	}
+}
+
+func lessMetricKey(a, b metricKey) bool {
+	if a.Tenant != b.Tenant {
+		return a.Tenant < b.Tenant
+	}
+	if a.Provider != b.Provider {
+		return a.Provider < b.Provider
+	}
+	if a.Model != b.Model {
+		return a.Model < b.Model
+	}
+	return a.Project < b.Project
+}
+
+// renderWriterMetrics exposes backpressure and throughput of the asynchronous usage writer.
+func renderWriterMetrics(stats tracker.WriterStats) string {
+	var builder strings.Builder
+	gauges := []struct {
+		name, help string
+		value      int64
+	}{
+		{"lcg_usage_queue_depth", "Usage records waiting in the in-memory write queue.", int64(stats.QueueDepth)},
+		{"lcg_usage_queue_capacity", "Capacity of the in-memory usage write queue.", int64(stats.QueueCapacity)},
+		{"lcg_usage_wal_segments", "Usage WAL segments still on disk.", int64(stats.WALSegments)},
+	}
+	for _, gauge := range gauges {
+		fmt.Fprintf(&builder, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", gauge.name, gauge.help, gauge.name, gauge.name, gauge.value)
+	}
+
+	counters := []struct {
+		name, help string
+		value      int64
+	}{
+		{"lcg_usage_enqueued_total", "Usage records accepted into the write queue.", stats.Enqueued},
+		{"lcg_usage_written_total", "Usage records written to storage.", stats.Written},
+		{"lcg_usage_write_batches_total", "Usage write transactions committed.", stats.Batches},
+		{"lcg_usage_spilled_total", "Usage records spilled to the WAL because the queue was full.", stats.Spilled},
+		{"lcg_usage_replayed_total", "Usage records replayed from the WAL.", stats.Replayed},
+		{"lcg_usage_sync_writes_total", "Usage records written on the request path because the queue was full or closed.", stats.SyncWrites},
+		{"lcg_usage_write_failures_total", "Usage records whose write failed and were left for replay.", stats.Failed},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(&builder, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", counter.name, counter.help, counter.name, counter.name, counter.value)
+	}
 	return builder.String()
 }
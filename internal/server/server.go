@@ -3,20 +3,28 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
 )
 
 // Server provides health check and JSON usage API endpoints.
 type Server struct {
-	tracker *tracker.UsageTracker
-	mux     *http.ServeMux
-	logger  *slog.Logger
+	tracker     *tracker.UsageTracker
+	transcripts *capture.Recorder
+	store       storage.Storage
+	mux         *http.ServeMux
+	logger      *slog.Logger
 }
 
 // NewServer creates an API server.
@@ -32,13 +40,33 @@ func NewServer(t *tracker.UsageTracker, logger *slog.Logger) *Server {
 
 func (s *Server) routes() {
 	s.mux.HandleFunc("GET /healthz", s.handleHealth)
-	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
-	s.mux.HandleFunc("GET /api/v1/usage", s.handleUsage)
-	s.mux.HandleFunc("GET /api/v1/summary", s.handleSummary)
-	s.mux.HandleFunc("GET /api/v1/anomalies", s.handleAnomalies)
-	s.mux.HandleFunc("GET /api/v1/forecast", s.handleForecast)
-	s.mux.HandleFunc("GET /api/v1/recommendations", s.handleRecommendations)
-	s.mux.HandleFunc("GET /api/v1/prompt-optimizations", s.handlePromptOptimizations)
+	s.mux.HandleFunc("GET /metrics", authorize(model.PermReportsRead, s.handleMetrics))
+	s.mux.HandleFunc("GET /api/v1/usage", authorize(model.PermReportsRead, s.handleUsage))
+	s.mux.HandleFunc("GET /api/v1/summary", authorize(model.PermReportsRead, s.handleSummary))
+	s.mux.HandleFunc("GET /api/v1/anomalies", authorize(model.PermReportsRead, s.handleAnomalies))
+	s.mux.HandleFunc("GET /api/v1/forecast", authorize(model.PermReportsRead, s.handleForecast))
+	s.mux.HandleFunc("GET /api/v1/recommendations", authorize(model.PermReportsRead, s.handleRecommendations))
+	s.mux.HandleFunc("GET /api/v1/prompt-optimizations", authorize(model.PermReportsRead, s.handlePromptOptimizations))
+	s.mux.HandleFunc("GET /api/v1/errors", authorize(model.PermReportsRead, s.handleErrors))
+	s.mux.HandleFunc("GET /api/v1/users/top", authorize(model.PermReportsRead, s.handleTopUsers))
+	s.mux.HandleFunc("GET /api/v1/transcripts/{id}", authorize(model.PermTranscriptsRead, s.handleTranscript))
+	s.mux.HandleFunc("GET /api/v1/model-policies", authorize(model.PermPoliciesRead, s.handleListModelPolicies))
+	s.mux.HandleFunc("PUT /api/v1/model-policies/{name}", authorize(model.PermPoliciesWrite, s.handleSetModelPolicy))
+	s.mux.HandleFunc("DELETE /api/v1/model-policies/{name}", authorize(model.PermPoliciesWrite, s.handleDeleteModelPolicy))
+	s.mux.HandleFunc("GET /api/v1/audit", authorize(model.PermAuditRead, s.handleAudit))
+	s.adminRoutes()
+}
+
+// WithTranscripts enables the admin transcript endpoint backed by recorder and returns the server.
+func (s *Server) WithTranscripts(recorder *capture.Recorder) *Server {
+	s.transcripts = recorder
+	return s
+}
+
+// WithStore enables the management endpoints backed by store and returns the server.
+func (s *Server) WithStore(store storage.Storage) *Server {
+	s.store = store
+	return s
 }
 
 // Handler returns the HTTP handler for this server.
@@ -57,11 +85,21 @@ func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
+	tags, ok := tagsFromRequest(w, r)
+	if !ok {
+		return
+	}
 	filter := tracker.ReportFilter{
-		Tenant:   tenantFilterFromRequest(r),
-		Provider: r.URL.Query().Get("provider"),
-		Model:    r.URL.Query().Get("model"),
-		Project:  r.URL.Query().Get("project"),
+		Tenant:           tenantFilterFromRequest(r),
+		Provider:         r.URL.Query().Get("provider"),
+		Model:            r.URL.Query().Get("model"),
+		Project:          r.URL.Query().Get("project"),
+		CompletionStatus: r.URL.Query().Get("completion_status"),
+		IdempotencyKey:   r.URL.Query().Get("idempotency_key"),
+		User:             r.URL.Query().Get("user"),
+		PolicyAction:     r.URL.Query().Get("policy_action"),
+		APIKeyID:         r.URL.Query().Get("api_key_id"),
+		Tags:             tags,
 	}
 
 	records, err := s.tracker.Query(ctx, filter)
@@ -86,13 +124,22 @@ func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
 		period = tracker.PeriodDaily
 	}
 
+	tags, ok := tagsFromRequest(w, r)
+	if !ok {
+		return
+	}
 	start, end := tracker.PeriodBounds(period)
 	filter := tracker.ReportFilter{
-		Tenant:    tenantFilterFromRequest(r),
-		Provider:  r.URL.Query().Get("provider"),
-		Project:   r.URL.Query().Get("project"),
-		StartTime: start,
-		EndTime:   end,
+		Tenant:           tenantFilterFromRequest(r),
+		Provider:         r.URL.Query().Get("provider"),
+		Project:          r.URL.Query().Get("project"),
+		StartTime:        start,
+		EndTime:          end,
+		CompletionStatus: r.URL.Query().Get("completion_status"),
+		User:             r.URL.Query().Get("user"),
+		APIKeyID:         r.URL.Query().Get("api_key_id"),
+		Tags:             tags,
+		GroupByTag:       r.URL.Query().Get("group_by_tag"),
 	}
 
 	summary, err := s.tracker.Report(ctx, filter)
@@ -129,18 +176,41 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-	if _, err := fmt.Fprint(w, renderPrometheusMetrics(summary, records)); err != nil {
+	body := renderPrometheusMetrics(summary, records)
+	if stats, ok := s.tracker.WriterStats(); ok {
+		body += renderWriterMetrics(stats)
+	}
+	if _, err := fmt.Fprint(w, body); err != nil {
 		s.logger.Error("write metrics response", "error", err)
 	}
 }
 
+// authorize refuses requests whose identity lacks permission. Requests without an identity,
+// which only reach the server when it is mounted without the auth middleware, act as the
+// developer role.
+func authorize(permission model.Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := httpauth.IdentityFromContext(r.Context())
+		if !identity.Can(permission) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
 func tenantFilterFromRequest(r *http.Request) string {
-	requested := r.URL.Query().Get("tenant")
+	return scopedTenant(r, r.URL.Query().Get("tenant"))
+}
+
+// scopedTenant returns requested when the identity may act on every tenant, and the
+// identity's own tenant otherwise.
+func scopedTenant(r *http.Request, requested string) string {
 	identity, ok := httpauth.IdentityFromContext(r.Context())
 	if !ok {
 		return requested
 	}
-	if identity.Admin && requested != "" {
+	if identity.AllTenants && requested != "" {
 		return requested
 	}
 	return identity.Tenant.Slug
@@ -218,11 +288,269 @@ func (s *Server) handlePromptOptimizations(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+func (s *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	filter := baseFilterFromRequest(r)
+	rates, err := s.tracker.ErrorRates(ctx, filter)
+	if err != nil {
+		s.logger.Error("error rates", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rates); err != nil {
+		s.logger.Error("encode error rates response", "error", err)
+	}
+}
+
+func (s *Server) handleTopUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	limit := 10
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	period := tracker.BudgetPeriod(r.URL.Query().Get("period"))
+	if period == "" {
+		period = tracker.PeriodMonthly
+	}
+	tags, ok := tagsFromRequest(w, r)
+	if !ok {
+		return
+	}
+	filter := baseFilterFromRequest(r)
+	filter.Tags = tags
+	filter.StartTime, filter.EndTime = tracker.PeriodBounds(period)
+
+	users, err := s.tracker.TopUsers(ctx, filter, limit)
+	if err != nil {
+		s.logger.Error("top users", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		s.logger.Error("encode top users response", "error", err)
+	}
+}
+
+// handleTranscript returns the captured prompt and completion of one usage record. Identities
+// that are not cross-tenant may only read their own tenant's transcripts.
+func (s *Server) handleTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	identity, _ := httpauth.IdentityFromContext(r.Context())
+	if s.transcripts == nil {
+		http.Error(w, "transcript capture is disabled", http.StatusNotFound)
+		return
+	}
+
+	transcript, err := s.transcripts.Get(ctx, r.PathValue("id"))
+	if err == nil && scopedTenant(r, transcript.Tenant) != transcript.Tenant {
+		err = storage.ErrNotFound
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "transcript not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("get transcript", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("transcript accessed",
+		"usage_id", transcript.UsageID,
+		"tenant", transcript.Tenant,
+		"accessed_by", identity.Actor(),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(transcript); err != nil {
+		s.logger.Error("encode transcript response", "error", err)
+	}
+}
+
+// handleAudit lists audit events, newest first. Identities that are not cross-tenant only see
+// their own tenant's events.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if s.store == nil {
+		http.Error(w, "audit log is disabled", http.StatusNotFound)
+		return
+	}
+	query := r.URL.Query()
+	filter := model.AuditFilter{
+		Tenant:       tenantFilterFromRequest(r),
+		Actor:        query.Get("actor"),
+		Source:       query.Get("source"),
+		Action:       query.Get("action"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		Limit:        100,
+	}
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			http.Error(w, "invalid limit: must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsed
+	}
+	for _, bound := range []struct {
+		name string
+		dest *time.Time
+	}{{"since", &filter.StartTime}, {"until", &filter.EndTime}} {
+		if raw := query.Get(bound.name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				http.Error(w, "invalid "+bound.name+": must be RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*bound.dest = parsed
+		}
+	}
+
+	events, err := s.store.ListAuditEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error("list audit events", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []model.AuditEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		s.logger.Error("encode audit response", "error", err)
+	}
+}
+
+func (s *Server) handleListModelPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	if s.store == nil {
+		http.Error(w, "model policy management is disabled", http.StatusNotFound)
+		return
+	}
+	policies, err := s.store.ListModelPolicies(ctx, tenantFilterFromRequest(r))
+	if err != nil {
+		s.logger.Error("list model policies", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if policies == nil {
+		policies = []model.ModelPolicy{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(policies); err != nil {
+		s.logger.Error("encode model policies response", "error", err)
+	}
+}
+
+func (s *Server) handleSetModelPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	identity, _ := httpauth.IdentityFromContext(r.Context())
+	if s.store == nil {
+		http.Error(w, "model policy management is disabled", http.StatusNotFound)
+		return
+	}
+
+	var modelPolicy model.ModelPolicy
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&modelPolicy); err != nil {
+		http.Error(w, "invalid model policy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	modelPolicy.ID = ""
+	modelPolicy.TenantID = ""
+	modelPolicy.Name = r.PathValue("name")
+	if modelPolicy.Tenant == "" {
+		modelPolicy.Tenant = tenantFilterFromRequest(r)
+	} else {
+		modelPolicy.Tenant = scopedTenant(r, modelPolicy.Tenant)
+	}
+	if modelPolicy.MaxTokens < 0 {
+		http.Error(w, "invalid model policy: max_tokens must not be negative", http.StatusBadRequest)
+		return
+	}
+	if _, err := policy.CompileModelPolicy(modelPolicy); err != nil {
+		http.Error(w, "invalid model policy: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.SetModelPolicy(ctx, &modelPolicy); err != nil {
+		s.logger.Error("set model policy", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("model policy updated", "tenant", modelPolicy.Tenant, "policy", modelPolicy.Name, "updated_by", identity.Actor())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(modelPolicy); err != nil {
+		s.logger.Error("encode model policy response", "error", err)
+	}
+}
+
+func (s *Server) handleDeleteModelPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	identity, _ := httpauth.IdentityFromContext(r.Context())
+	if s.store == nil {
+		http.Error(w, "model policy management is disabled", http.StatusNotFound)
+		return
+	}
+
+	tenant, name := tenantFilterFromRequest(r), r.PathValue("name")
+	err := s.store.DeleteModelPolicy(ctx, tenant, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "model policy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("delete model policy", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("model policy deleted", "tenant", tenant, "policy", name, "deleted_by", identity.Actor())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tagsFromRequest parses the tags query parameter ("key=value,key=value") and writes a 400
+// response when it is malformed.
+func tagsFromRequest(w http.ResponseWriter, r *http.Request) (map[string]string, bool) {
+	tags, err := model.ParseTags(r.URL.Query().Get("tags"))
+	if err != nil {
+		http.Error(w, "invalid tags: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return tags, true
+}
+
 func baseFilterFromRequest(r *http.Request) tracker.ReportFilter {
 	return tracker.ReportFilter{
 		Tenant:   tenantFilterFromRequest(r),
 		Provider: r.URL.Query().Get("provider"),
 		Model:    r.URL.Query().Get("model"),
 		Project:  r.URL.Query().Get("project"),
+		User:     r.URL.Query().Get("user"),
 	}
 }
@@ -8,10 +8,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/adminapi"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/httpauth"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/internal/server"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
@@ -21,6 +26,12 @@ import (
 )
 
 func setupServer(t *testing.T) *server.Server {
+	t.Helper()
+	srv, _ := setupServerWithTracker(t)
+	return srv
+}
+
+func setupServerWithTracker(t *testing.T) (*server.Server, *tracker.UsageTracker) {
 	t.Helper()
 	registry := providers.NewRegistry()
 	openai := providers.NewOpenAI(&providers.ProviderConfig{
@@ -41,7 +52,7 @@ func setupServer(t *testing.T) *server.Server {
 	_, err = ut.Track(t.Context(), "default", "openai", "gpt-4o", 1000, 500, "test")
 	require.NoError(t, err)
 
-	return server.NewServer(ut, logger)
+	return server.NewServer(ut, logger), ut
 }
 
 func setupAnalyticsServer(t *testing.T) *server.Server {
@@ -92,6 +103,15 @@ func setupAnalyticsServer(t *testing.T) *server.Server {
 		Metadata:     `{"prompt_chars":9000,"prompt_tokens_estimate":1200,"input_output_ratio":8,"large_static_context":true}`,
 		Timestamp:    base.AddDate(0, 0, 7),
 	}))
+	require.NoError(t, ut.TrackWithTokens(context.Background(), &tracker.UsageRecord{
+		Tenant:     "default",
+		Provider:   "openai",
+		Model:      "gpt-4o",
+		Project:    "payments",
+		StatusCode: http.StatusTooManyRequests,
+		ErrorType:  "rate_limited",
+		Timestamp:  base.AddDate(0, 0, 7),
+	}))
 
 	return server.NewServer(ut, logger)
 }
@@ -152,6 +172,40 @@ func TestServer_Usage_WithFilters(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestServer_TopUsers(t *testing.T) {
+	srv, ut := setupServerWithTracker(t)
+	for _, record := range []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 1000, Project: "test", User: "alice"},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 4000, Project: "test", User: "bob"},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 2000, Project: "test", User: "alice"},
+	} {
+		require.NoError(t, ut.TrackWithTokens(t.Context(), record))
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/users/top?limit=1", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var users []model.UserSpend
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+	require.Len(t, users, 1)
+	assert.Equal(t, "bob", users[0].User)
+	assert.Equal(t, int64(1), users[0].RequestCount)
+
+	req = httptest.NewRequest("GET", "/api/v1/usage?user=alice", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	var records []model.UsageRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&records))
+	assert.Len(t, records, 2)
+
+	req = httptest.NewRequest("GET", "/api/v1/users/top?limit=zero", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestServer_Metrics(t *testing.T) {
 	srv := setupServer(t)
 
@@ -174,6 +228,7 @@ func TestServer_IntelligenceEndpoints(t *testing.T) {
 		{path: "/api/v1/forecast?tenant=default"},
 		{path: "/api/v1/recommendations?tenant=default"},
 		{path: "/api/v1/prompt-optimizations?tenant=default"},
+		{path: "/api/v1/errors?tenant=default"},
 	}
 
 	for _, tt := range tests {
@@ -184,3 +239,487 @@ func TestServer_IntelligenceEndpoints(t *testing.T) {
 		assert.NotEqual(t, "[]\n", w.Body.String(), tt.path)
 	}
 }
+
+func TestServer_MetricsIncludeUpstreamErrors(t *testing.T) {
+	srv := setupAnalyticsServer(t)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `lcg_upstream_errors_total{tenant="default",provider="openai",model="gpt-4o",project="payments",error_type="rate_limited"} 1`)
+}
+
+func TestServer_MetricsIncludePolicyDecisions(t *testing.T) {
+	srv, ut := setupServerWithTracker(t)
+	for _, record := range []*tracker.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", Project: "payments", PolicyAction: "block", PolicyFindings: "aws_access_key,email", CompletionStatus: tracker.CompletionStatusBlocked},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, Project: "payments", PolicyAction: "flag", PolicyFindings: "email"},
+	} {
+		require.NoError(t, ut.TrackWithTokens(context.Background(), record))
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	assert.Contains(t, w.Body.String(), `lcg_policy_decisions_total{tenant="default",project="payments",action="block"} 1`)
+	assert.Contains(t, w.Body.String(), `lcg_policy_findings_total{tenant="default",project="payments",rule="email"} 2`)
+
+	req = httptest.NewRequest("GET", "/api/v1/usage?policy_action=block", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	var records []model.UsageRecord
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&records))
+	require.Len(t, records, 1)
+	assert.Equal(t, "aws_access_key,email", records[0].PolicyFindings)
+}
+
+func TestServer_LatencyPercentilesAndHistograms(t *testing.T) {
+	srv, ut := setupServerWithTracker(t)
+	for _, latency := range []int64{100, 200, 300, 400, 5000} {
+		require.NoError(t, ut.TrackWithTokens(context.Background(), &tracker.UsageRecord{
+			Tenant:             "default",
+			Provider:           "openai",
+			Model:              "gpt-4o",
+			InputTokens:        100,
+			OutputTokens:       50,
+			Project:            "alpha",
+			LatencyMs:          latency,
+			TTFTMs:             latency / 4,
+			OutputTokensPerSec: 20,
+		}))
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/summary?period=daily", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary tracker.UsageSummary
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&summary))
+	require.Len(t, summary.Latency, 1)
+	assert.Equal(t, int64(300), summary.Latency[0].LatencyP50Ms)
+	assert.Equal(t, int64(5000), summary.Latency[0].LatencyP95Ms)
+	assert.Equal(t, int64(75), summary.Latency[0].TTFTP50Ms)
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	w = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE lcg_request_latency_seconds histogram")
+	assert.Contains(t, body, `lcg_request_latency_seconds_bucket{tenant="default",provider="openai",model="gpt-4o",le="0.25"} 2`)
+	assert.Contains(t, body, `lcg_request_latency_seconds_bucket{tenant="default",provider="openai",model="gpt-4o",le="+Inf"} 5`)
+	assert.Contains(t, body, `lcg_time_to_first_token_seconds_count{tenant="default",provider="openai",model="gpt-4o"} 5`)
+	assert.Contains(t, body, `lcg_output_tokens_per_second_bucket{tenant="default",provider="openai",model="gpt-4o",le="25"} 5`)
+}
+
+func TestServer_MetricsIncludeUsageWriter(t *testing.T) {
+	srv, ut := setupServerWithTracker(t)
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{QueueSize: 50, WALDir: t.TempDir()}))
+	require.NoError(t, ut.TrackWithTokens(context.Background(), &tracker.UsageRecord{
+		Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, Project: "async",
+	}))
+	require.NoError(t, ut.Close(context.Background()))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "lcg_usage_queue_capacity 50")
+	assert.Contains(t, body, "lcg_usage_enqueued_total 1")
+	assert.Contains(t, body, "lcg_usage_written_total 1")
+}
+
+func TestServer_Transcript(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	recorder, err := capture.NewRecorder(store, capture.Config{Projects: []string{"*"}, MasterKey: make([]byte, 32)})
+	require.NoError(t, err)
+	srv := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).WithTranscripts(recorder)
+
+	require.NoError(t, recorder.Save(t.Context(),
+		&model.UsageRecord{ID: "usage-1", Tenant: "default", Project: "support"},
+		[]byte(`{"messages":[{"content":"hi"}]}`), []byte("hello"),
+	))
+
+	get := func(path string, identity *httpauth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if identity != nil {
+			req = req.WithContext(httpauth.WithIdentity(req.Context(), *identity))
+		}
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	admin := &httpauth.Identity{Tenant: model.Tenant{Slug: "default"}, Role: model.RoleOwner, AllTenants: true}
+
+	w := get("/api/v1/transcripts/usage-1", admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var transcript model.Transcript
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&transcript))
+	assert.Equal(t, "hello", transcript.Response)
+	assert.Equal(t, "support", transcript.Project)
+
+	assert.Equal(t, http.StatusForbidden, get("/api/v1/transcripts/usage-1", &httpauth.Identity{Tenant: model.Tenant{Slug: "default"}}).Code)
+	assert.Equal(t, http.StatusForbidden, get("/api/v1/transcripts/usage-1", nil).Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/transcripts/missing", admin).Code)
+}
+
+func TestServer_ModelPolicies(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).WithStore(store)
+
+	send := func(method, path, body string, identity httpauth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(httpauth.WithIdentity(req.Context(), identity))
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	admin := httpauth.Identity{Tenant: model.Tenant{Slug: "default"}, Role: model.RoleOwner, AllTenants: true}
+	member := httpauth.Identity{Tenant: model.Tenant{Slug: "acme"}}
+
+	const body = `{"tenant":"acme","project":"chat","allowed_models":["gpt-4o-mini*"],"max_tokens":512,"deny_streaming":true}`
+	assert.Equal(t, http.StatusForbidden, send("PUT", "/api/v1/model-policies/mini-only", body, member).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/api/v1/model-policies/mini-only", "{", admin).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/api/v1/model-policies/mini-only", `{"tenant":"acme","allowed_models":["gpt 4o"]}`, admin).Code)
+
+	w := send("PUT", "/api/v1/model-policies/mini-only", body, admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var created model.ModelPolicy
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "mini-only", created.Name)
+	assert.Equal(t, "acme", created.Tenant)
+	assert.NotEmpty(t, created.ID)
+
+	w = send("GET", "/api/v1/model-policies", "", member)
+	require.Equal(t, http.StatusOK, w.Code)
+	var policies []model.ModelPolicy
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&policies))
+	require.Len(t, policies, 1)
+	assert.Equal(t, []string{"gpt-4o-mini*"}, policies[0].AllowedModels)
+	assert.EqualValues(t, 512, policies[0].MaxTokens)
+
+	w = send("GET", "/api/v1/model-policies?tenant=acme", "", httpauth.Identity{Tenant: model.Tenant{Slug: "other"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, send("DELETE", "/api/v1/model-policies/mini-only", "", member).Code)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/api/v1/model-policies/mini-only?tenant=acme", "", admin).Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/api/v1/model-policies/mini-only?tenant=acme", "", admin).Code)
+}
+
+func TestServer_RolePermissions(t *testing.T) {
+	srv := setupServer(t)
+
+	routes := []struct {
+		method     string
+		path       string
+		permission model.Permission
+	}{
+		{"GET", "/metrics", model.PermReportsRead},
+		{"GET", "/api/v1/usage", model.PermReportsRead},
+		{"GET", "/api/v1/summary", model.PermReportsRead},
+		{"GET", "/api/v1/anomalies", model.PermReportsRead},
+		{"GET", "/api/v1/forecast", model.PermReportsRead},
+		{"GET", "/api/v1/recommendations", model.PermReportsRead},
+		{"GET", "/api/v1/prompt-optimizations", model.PermReportsRead},
+		{"GET", "/api/v1/errors", model.PermReportsRead},
+		{"GET", "/api/v1/users/top", model.PermReportsRead},
+		{"GET", "/api/v1/transcripts/usage-1", model.PermTranscriptsRead},
+		{"GET", "/api/v1/model-policies", model.PermPoliciesRead},
+		{"PUT", "/api/v1/model-policies/p", model.PermPoliciesWrite},
+		{"DELETE", "/api/v1/model-policies/p", model.PermPoliciesWrite},
+		{"GET", "/api/v1/audit", model.PermAuditRead},
+		{"GET", "/api/v1/admin/schemas/budget", model.PermAdminRead},
+		{"GET", "/api/v1/admin/tenants", model.PermAdminRead},
+		{"GET", "/api/v1/admin/tenants/default/settings", model.PermAdminRead},
+		{"POST", "/api/v1/admin/api-keys", model.PermAdminWrite},
+		{"PUT", "/api/v1/admin/budgets/b", model.PermAdminWrite},
+	}
+	granted := map[string][]model.Permission{
+		model.RoleOwner:         {model.PermReportsRead, model.PermTranscriptsRead, model.PermPoliciesRead, model.PermPoliciesWrite, model.PermAuditRead, model.PermAdminRead, model.PermAdminWrite},
+		model.RoleAdmin:         {model.PermReportsRead, model.PermTranscriptsRead, model.PermPoliciesRead, model.PermPoliciesWrite, model.PermAuditRead, model.PermAdminRead, model.PermAdminWrite},
+		model.RoleFinanceViewer: {model.PermReportsRead},
+		model.RoleDeveloper:     {model.PermReportsRead, model.PermPoliciesRead},
+		model.RoleProxyOnly:     nil,
+	}
+
+	for _, role := range model.Roles {
+		for _, route := range routes {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader("{}"))
+			req = req.WithContext(httpauth.WithIdentity(req.Context(), httpauth.Identity{
+				Tenant: model.Tenant{Slug: "default"},
+				Role:   role,
+			}))
+			w := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(w, req)
+
+			if slices.Contains(granted[role], route.permission) {
+				assert.NotEqual(t, http.StatusForbidden, w.Code, "%s %s %s", role, route.method, route.path)
+			} else {
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s %s %s", role, route.method, route.path)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_TenantScopedRoles(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	recorder, err := capture.NewRecorder(store, capture.Config{Projects: []string{"*"}, MasterKey: make([]byte, 32)})
+	require.NoError(t, err)
+	srv := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).
+		WithStore(store).
+		WithTranscripts(recorder)
+	_, err = store.EnsureTenant(t.Context(), "acme", "Acme")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save(t.Context(), &model.UsageRecord{ID: "usage-acme", Tenant: "acme"}, []byte(`{}`), []byte("hi")))
+
+	send := func(method, path, body string, identity httpauth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(httpauth.WithIdentity(req.Context(), identity))
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	acmeAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "acme"}, Role: model.RoleAdmin}
+	otherAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "other"}, Role: model.RoleAdmin}
+	globalViewer := httpauth.Identity{Tenant: model.Tenant{Slug: "other"}, Role: model.RoleFinanceViewer, AllTenants: true}
+
+	// A tenant admin cannot write into another tenant; the policy lands in its own.
+	w := send("PUT", "/api/v1/model-policies/p", `{"tenant":"acme","denied_models":["o1*"]}`, otherAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	policies, err := store.ListModelPolicies(t.Context(), "acme")
+	require.NoError(t, err)
+	assert.Empty(t, policies)
+	policies, err = store.ListModelPolicies(t.Context(), "other")
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+
+	assert.Equal(t, http.StatusOK, send("GET", "/api/v1/transcripts/usage-acme", "", acmeAdmin).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/transcripts/usage-acme", "", otherAdmin).Code)
+
+	w = send("GET", "/api/v1/model-policies?tenant=other", "", globalViewer)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = send("GET", "/api/v1/summary?tenant=acme", "", globalViewer)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestServer_AuditLog(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).WithStore(store)
+	_, err = store.EnsureTenant(t.Context(), "acme", "Acme")
+	require.NoError(t, err)
+
+	send := func(method, path, body string, identity httpauth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(httpauth.WithIdentity(req.Context(), identity))
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	acmeAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "acme"}, Role: model.RoleAdmin, User: "dana@example.com"}
+	otherAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "other"}, Role: model.RoleAdmin}
+
+	require.Equal(t, http.StatusOK, send("PUT", "/api/v1/model-policies/mini", `{"allowed_models":["gpt-4o-mini"]}`, acmeAdmin).Code)
+
+	w := send("GET", "/api/v1/audit?action=model_policy.create", "", acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var events []model.AuditEvent
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&events))
+	require.Len(t, events, 1)
+	assert.Equal(t, "user:dana@example.com", events[0].Actor)
+	assert.Equal(t, model.AuditSourceAPI, events[0].Source)
+	assert.Equal(t, "acme", events[0].Tenant)
+	assert.Contains(t, string(events[0].After), "gpt-4o-mini")
+
+	// Tenant-scoped identities only see their own tenant's events.
+	w = send("GET", "/api/v1/audit?tenant=acme", "", otherAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/api/v1/audit?limit=0", "", acmeAdmin).Code)
+	assert.Equal(t, http.StatusBadRequest, send("GET", "/api/v1/audit?since=yesterday", "", acmeAdmin).Code)
+}
+
+func TestServer_AdminAPI(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv := server.NewServer(tracker.NewUsageTracker(providers.NewRegistry(), store, nil, logger), logger).WithStore(store)
+	for _, slug := range []string{"default", "other"} {
+		_, err = store.EnsureTenant(t.Context(), slug, slug)
+		require.NoError(t, err)
+	}
+
+	send := func(method, path, body string, identity httpauth.Identity) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(httpauth.WithIdentity(req.Context(), identity))
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, req)
+		return w
+	}
+	owner := httpauth.Identity{Tenant: model.Tenant{Slug: "default"}, Role: model.RoleOwner, AllTenants: true}
+	acmeAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "acme"}, Role: model.RoleAdmin}
+	otherAdmin := httpauth.Identity{Tenant: model.Tenant{Slug: "other"}, Role: model.RoleAdmin}
+
+	// Tenants.
+	w := send("POST", "/api/v1/admin/tenants", `{"slug":"acme","name":"Acme"}`, owner)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusConflict, send("POST", "/api/v1/admin/tenants", `{"slug":"acme"}`, owner).Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/admin/tenants", `{"slug":"beta"}`, acmeAdmin).Code)
+
+	w = send("POST", "/api/v1/admin/tenants", `{"slug":"Not Valid"}`, owner)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var apiErr map[string]string
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+	assert.Equal(t, "slug", apiErr["field"])
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/api/v1/admin/tenants", `{"slug":"beta","owner":"x"}`, owner).Code)
+
+	w = send("GET", "/api/v1/admin/tenants", "", acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var tenants []model.Tenant
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&tenants))
+	require.Len(t, tenants, 1)
+	assert.Equal(t, "acme", tenants[0].Slug)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/admin/tenants/acme", "", otherAdmin).Code)
+
+	w = send("PATCH", "/api/v1/admin/tenants/acme", `{"name":"Acme Corp","status":"disabled"}`, owner)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var tenant model.Tenant
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&tenant))
+	assert.Equal(t, "Acme Corp", tenant.Name)
+	assert.Equal(t, model.TenantStatusDisabled, tenant.Status)
+	require.Equal(t, http.StatusOK, send("PATCH", "/api/v1/admin/tenants/acme", `{"status":"active"}`, owner).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PATCH", "/api/v1/admin/tenants/acme", `{}`, owner).Code)
+	assert.Equal(t, http.StatusConflict, send("DELETE", "/api/v1/admin/tenants/default", "", owner).Code)
+
+	// API keys: tenant admins issue keys in their own tenant, but never above their role.
+	w = send("POST", "/api/v1/admin/api-keys", `{"tenant":"default","name":"ci","role":"developer","expires_in":"720h"}`, acmeAdmin)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var issued struct {
+		APIKey model.APIKey `json:"api_key"`
+		Key    string       `json:"key"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&issued))
+	assert.Equal(t, "acme", issued.APIKey.Tenant)
+	assert.NotEmpty(t, issued.Key)
+	require.NotNil(t, issued.APIKey.ExpiresAt)
+
+	w = send("POST", "/api/v1/admin/api-keys", `{"role":"owner"}`, acmeAdmin)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, http.StatusForbidden, send("POST", "/api/v1/admin/api-keys", `{"all_tenants":true}`, acmeAdmin).Code)
+	w = send("POST", "/api/v1/admin/api-keys", `{"role":"root"}`, acmeAdmin)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+	assert.Equal(t, "role", apiErr["field"])
+
+	keyPath := "/api/v1/admin/api-keys/" + issued.APIKey.ID
+	assert.Equal(t, http.StatusNotFound, send("GET", keyPath, "", otherAdmin).Code)
+	assert.Equal(t, http.StatusForbidden, send("PUT", keyPath+"/role", `{"role":"owner"}`, acmeAdmin).Code)
+	w = send("PUT", keyPath+"/role", `{"role":"finance-viewer"}`, acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = send("POST", keyPath+"/rotate", `{"grace":"1h"}`, acmeAdmin)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var rotated struct {
+		APIKey   model.APIKey  `json:"api_key"`
+		Replaces *model.APIKey `json:"replaces"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&rotated))
+	require.NotNil(t, rotated.Replaces)
+	assert.Equal(t, issued.APIKey.ID, rotated.Replaces.ID)
+	assert.Equal(t, model.RoleFinanceViewer, rotated.APIKey.Role)
+	assert.Equal(t, http.StatusConflict, send("POST", keyPath+"/rotate", `{}`, acmeAdmin).Code)
+
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/api/v1/admin/api-keys/"+rotated.APIKey.ID, "", acmeAdmin).Code)
+	key, err := store.GetAPIKey(t.Context(), rotated.APIKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.APIKeyStatusRevoked, key.Status)
+
+	// Tenant settings: only cross-tenant identities change them, and secrets are never returned.
+	settingsPath := "/api/v1/admin/tenants/acme/settings"
+	assert.Equal(t, http.StatusForbidden, send("PATCH", settingsPath, `{"deny_on_exceed":false}`, acmeAdmin).Code)
+	w = send("PATCH", settingsPath, `{"unset":["max_body_size"]}`, owner)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+	assert.Equal(t, "unset", apiErr["field"])
+	w = send("PATCH", settingsPath, `{"deny_on_exceed":true,"alert_threshold_pct":60,"webhook_url":"https://hooks.example.com/acme","webhook_secret":"s3cret"}`, owner)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	w = send("PATCH", settingsPath, `{"unset":["deny_on_exceed"]}`, owner)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, send("GET", settingsPath, "", otherAdmin).Code)
+	w = send("GET", settingsPath, "", acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var settings adminapi.TenantSettingsResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&settings))
+	assert.Nil(t, settings.Settings.DenyOnExceed)
+	assert.Equal(t, 60.0, *settings.Settings.AlertThresholdPct)
+	assert.Equal(t, model.RedactedValue, *settings.Settings.WebhookSecret)
+	assert.Equal(t, "https://hooks.example.com/acme", settings.Effective.WebhookURL)
+	assert.Equal(t, 95.0, settings.Effective.AlertCriticalPct, "unset settings are inherited")
+
+	// Budgets: names are unique across tenants, so one tenant cannot take over another's.
+	w = send("PUT", "/api/v1/admin/budgets/acme-monthly", `{"limit_usd":250,"tags":{"env":"prod"}}`, acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var budget model.Budget
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&budget))
+	assert.Equal(t, "acme", budget.Tenant)
+	assert.Equal(t, model.PeriodMonthly, budget.Period)
+	assert.Equal(t, 60.0, budget.AlertThresholdPct, "the threshold defaults to the tenant's setting")
+	assert.Equal(t, http.StatusConflict, send("PUT", "/api/v1/admin/budgets/acme-monthly", `{"limit_usd":1}`, otherAdmin).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/admin/budgets/acme-monthly", "", otherAdmin).Code)
+	w = send("PUT", "/api/v1/admin/budgets/acme-monthly", `{"limit_usd":0}`, acmeAdmin)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+	assert.Equal(t, "limit_usd", apiErr["field"])
+
+	w = send("GET", "/api/v1/admin/budgets", "", otherAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	// Export and delete.
+	w = send("GET", "/api/v1/admin/tenants/acme/export", "", acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	var export model.TenantExport
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&export))
+	assert.Len(t, export.Budgets, 1)
+	assert.Len(t, export.APIKeys, 2)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/api/v1/admin/tenants/acme", "", owner).Code)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/admin/tenants/acme", "", owner).Code)
+
+	// Schemas.
+	w = send("GET", "/api/v1/admin/schemas/budget", "", acmeAdmin)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"limit_usd"`)
+	assert.Equal(t, http.StatusNotFound, send("GET", "/api/v1/admin/schemas/nope", "", acmeAdmin).Code)
+}
@@ -55,6 +55,12 @@ func (s *SlackNotifier) Send(ctx context.Context, alert Alert) error {
 			slackField{Title: "Threshold", Value: fmt.Sprintf("%.0f%%", alert.ThresholdPct), Short: true},
 			slackField{Title: "Usage", Value: fmt.Sprintf("%.1f%%", safeUsagePct(alert)), Short: true},
 		)
+	} else if alert.APIKeyID != "" {
+		fields = append(fields,
+			slackField{Title: "API Key", Value: alert.APIKeyID, Short: true},
+			slackField{Title: "Successor", Value: valueOrDash(alert.SuccessorAPIKeyID), Short: true},
+			slackField{Title: "Message", Value: valueOrDash(alert.Message), Short: false},
+		)
 	} else {
 		fields = append(fields,
 			slackField{Title: "Project", Value: valueOrDash(alert.Project), Short: true},
@@ -9,6 +9,15 @@ const (
 	AlertWarning  AlertLevel = "warning"  // Approaching budget threshold
 	AlertCritical AlertLevel = "critical" // At or near budget limit
 	AlertExceeded AlertLevel = "exceeded" // Budget limit exceeded
+	AlertInfo     AlertLevel = "info"     // Informational event, such as an API key rotation
+)
+
+// Event kinds other than budget and anomaly alerts.
+const (
+	// KindAPIKeyRotated reports that a key was rotated and its grace period started.
+	KindAPIKeyRotated = "api_key_rotated"
+	// KindAPIKeyRotationCompleted reports that a rotated key was revoked after its grace period.
+	KindAPIKeyRotationCompleted = "api_key_rotation_completed"
 )
 
 // Alert represents a budget threshold notification.
@@ -25,6 +34,9 @@ type Alert struct {
 	ThresholdPct float64    `json:"threshold_pct"`
 	Period       string     `json:"period"`
 	Message      string     `json:"message"`
+	// APIKeyID and SuccessorAPIKeyID identify the keys of API key rotation events.
+	APIKeyID          string `json:"api_key_id,omitempty"`
+	SuccessorAPIKeyID string `json:"successor_api_key_id,omitempty"`
 }
 
 // Notifier sends alerts to external systems.
@@ -85,5 +85,9 @@ func alertEvent(alert Alert) string {
 	if alert.Kind == "" || alert.Kind == "budget" {
 		return "budget_alert"
 	}
+	// Informational events are named by their kind alone.
+	if alert.Level == AlertInfo {
+		return alert.Kind
+	}
 	return alert.Kind + "_alert"
 }
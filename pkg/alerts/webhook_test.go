@@ -46,6 +46,28 @@ func TestWebhookNotifier_Send(t *testing.T) {
 	assert.NotEmpty(t, received["timestamp"])
 }
 
+func TestWebhookNotifier_Send_InfoEvent(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	n := alerts.NewWebhookNotifier(server.URL, "")
+	err := n.Send(context.Background(), alerts.Alert{
+		Kind:              alerts.KindAPIKeyRotated,
+		Level:             alerts.AlertInfo,
+		APIKeyID:          "old",
+		SuccessorAPIKeyID: "new",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "api_key_rotated", received["event"])
+	alert := received["alert"].(map[string]any)
+	assert.Equal(t, "old", alert["api_key_id"])
+	assert.Equal(t, "new", alert["successor_api_key_id"])
+}
+
 func TestWebhookNotifier_Send_WithHMAC(t *testing.T) {
 	var signature string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minJWKSReload limits how often an unknown key ID can force the key set to be reloaded.
+const minJWKSReload = time.Minute
+
+// jwksFetchTimeout bounds a key set fetch when the HTTP client sets no timeout of its own.
+const jwksFetchTimeout = 10 * time.Second
+
+// JWKSConfig configures where a JSON Web Key Set is loaded from. Exactly one of File and URL
+// must be set.
+type JWKSConfig struct {
+	File string
+	URL  string
+	// RefreshInterval reloads the key set once it is this old. Zero keeps it until an unknown
+	// key ID is seen.
+	RefreshInterval time.Duration
+	// HTTPClient fetches URL. It defaults to a client with a 10 second timeout. Fetches by a
+	// client without a timeout are given the same 10 second deadline.
+	HTTPClient *http.Client
+}
+
+// JWKS caches the public keys of a JSON Web Key Set by key ID. Keys are reloaded when the set
+// is older than the refresh interval and when a token names a key ID the set does not hold,
+// so identity provider key rotation is picked up without a restart.
+type JWKS struct {
+	cfg JWKSConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+	// missAt is when an unknown key ID last forced a reload.
+	missAt time.Time
+	// reloading is set while a reload fetches the key set outside the lock.
+	reloading bool
+}
+
+// NewJWKS loads the key set described by cfg.
+func NewJWKS(cfg JWKSConfig) (*JWKS, error) {
+	if (cfg.File == "") == (cfg.URL == "") {
+		return nil, errors.New("jwks: exactly one of file or url is required")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: jwksFetchTimeout}
+	}
+	s := &JWKS{cfg: cfg, now: time.Now}
+	s.loadedAt = s.now()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key returns the public key with the given ID. An empty kid matches the only key of a set
+// that holds exactly one.
+func (s *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	if s.startReload(kid) {
+		// A failed reload keeps serving the keys already loaded.
+		_ = s.reload()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("jwt: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// startReload reports whether the caller should reload the key set before looking up kid.
+// Only one reload runs at a time; other callers keep using the keys already loaded.
+func (s *JWKS) startReload(kid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reloading {
+		return false
+	}
+
+	now := s.now()
+	_, known := s.lookup(kid)
+	switch {
+	case s.cfg.RefreshInterval > 0 && now.Sub(s.loadedAt) >= s.cfg.RefreshInterval:
+	case !known && now.Sub(s.missAt) >= minJWKSReload:
+		s.missAt = now
+	default:
+		return false
+	}
+	s.loadedAt = now
+	s.reloading = true
+	return true
+}
+
+func (s *JWKS) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// reload fetches and parses the key set without holding the lock, so verifications with the
+// current keys are not blocked by a slow identity provider, then swaps the new keys in.
+func (s *JWKS) reload() error {
+	data, err := s.fetch()
+	var keys map[string]crypto.PublicKey
+	if err == nil {
+		keys, err = ParseJWKS(data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloading = false
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+func (s *JWKS) fetch() ([]byte, error) {
+	if s.cfg.File != "" {
+		data, err := os.ReadFile(s.cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("read jwks: %w", err)
+		}
+		return data, nil
+	}
+
+	ctx := context.Background()
+	if s.cfg.HTTPClient.Timeout <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, jwksFetchTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	return data, nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses the RSA and EC signing keys of a JSON Web Key Set, keyed by kid. Encryption
+// keys and unsupported key types are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = rsaJWK(jwk)
+		case "EC":
+			key, err = ecJWK(jwk)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("parse jwks: no signing keys found")
+	}
+	return keys, nil
+}
+
+func rsaJWK(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+func ecJWK(jwk jsonWebKey) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch jwk.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil {
+		return nil, fmt.Errorf("x coordinate: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y coordinate: %w", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) > size || len(y) > size {
+		return nil, errors.New("invalid ec key")
+	}
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	copy(point[1+size-len(x):1+size], x)
+	copy(point[1+2*size-len(y):], y)
+	return ecdsa.ParseUncompressedPublicKey(curve, point)
+}
@@ -0,0 +1,216 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]any {
+	return map[string]any{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]any) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func rs256Token(t *testing.T, kid string, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	ecPoint, err := ecKey.PublicKey.Bytes()
+	require.NoError(t, err)
+	size := (len(ecPoint) - 1) / 2
+
+	keys, err := auth.ParseJWKS(jwksJSON(t,
+		rsaJWK("rsa-1", &rsaKey.PublicKey),
+		map[string]any{
+			"kty": "EC", "kid": "ec-1", "crv": "P-384",
+			"x": base64.RawURLEncoding.EncodeToString(ecPoint[1 : 1+size]),
+			"y": base64.RawURLEncoding.EncodeToString(ecPoint[1+size:]),
+		},
+		map[string]any{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		map[string]any{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "AA"},
+	))
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.True(t, rsaKey.PublicKey.Equal(keys["rsa-1"]))
+	assert.True(t, ecKey.PublicKey.Equal(keys["ec-1"]))
+
+	_, err = auth.ParseJWKS([]byte(`{"keys":[]}`))
+	assert.ErrorContains(t, err, "no signing keys")
+	_, err = auth.ParseJWKS([]byte(`{"keys":[{"kty":"EC","kid":"bad","crv":"P-256","x":"AA","y":"AA"}]}`))
+	assert.Error(t, err)
+}
+
+func TestJWKS_VerifiesAndReloadsOnUnknownKey(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var served atomic.Value
+	served.Store(jwksJSON(t, rsaJWK("k1", &first.PublicKey)))
+	var fetches atomic.Int32
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(served.Load().([]byte))
+	}))
+	t.Cleanup(idp.Close)
+
+	jwks, err := auth.NewJWKS(auth.JWKSConfig{URL: idp.URL})
+	require.NoError(t, err)
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{JWKS: jwks, Issuer: "https://idp.example"})
+	require.NoError(t, err)
+
+	claims := map[string]any{"sub": "ann", "iss": "https://idp.example", "exp": time.Now().Add(time.Hour).Unix()}
+	verified, err := verifier.Verify(rs256Token(t, "k1", first, claims))
+	require.NoError(t, err)
+	assert.Equal(t, "ann", auth.ClaimString(verified, "sub"))
+	assert.EqualValues(t, 1, fetches.Load())
+
+	// The identity provider rotates to a new key; the first token naming it reloads the set.
+	served.Store(jwksJSON(t, rsaJWK("k2", &second.PublicKey)))
+	_, err = verifier.Verify(rs256Token(t, "k2", second, claims))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, fetches.Load())
+
+	// Further unknown key IDs within a minute do not hit the identity provider again.
+	_, err = verifier.Verify(rs256Token(t, "k3", second, claims))
+	assert.ErrorContains(t, err, `unknown key id "k3"`)
+	assert.EqualValues(t, 2, fetches.Load())
+
+	// A token signed by another key under a known kid is still rejected.
+	_, err = verifier.Verify(rs256Token(t, "k2", first, claims))
+	assert.ErrorContains(t, err, "invalid signature")
+}
+
+func TestJWKS_ReloadDoesNotBlockKnownKeys(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var fetches atomic.Int32
+	fetching, release := make(chan struct{}), make(chan struct{})
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fetches.Add(1) > 1 {
+			close(fetching)
+			<-release
+		}
+		_, _ = w.Write(jwksJSON(t, rsaJWK("k1", &first.PublicKey)))
+	}))
+	t.Cleanup(idp.Close)
+
+	jwks, err := auth.NewJWKS(auth.JWKSConfig{URL: idp.URL})
+	require.NoError(t, err)
+
+	// An unknown key ID starts a reload that hangs at the identity provider.
+	missed := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key("k2")
+		missed <- err
+	}()
+	<-fetching
+
+	// Known keys are still served while the fetch is in flight.
+	done := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key("k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("key lookup blocked on the jwks fetch")
+	}
+
+	close(release)
+	assert.ErrorContains(t, <-missed, `unknown key id "k2"`)
+	assert.EqualValues(t, 2, fetches.Load())
+}
+
+func TestJWKS_ClientWithoutTimeout(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+		_, _ = w.Write(jwksJSON(t, rsaJWK("k1", &key.PublicKey)))
+	}))
+	t.Cleanup(idp.Close)
+
+	// A client with no timeout of its own gets the default fetch deadline, not a near-zero one.
+	jwks, err := auth.NewJWKS(auth.JWKSConfig{URL: idp.URL, HTTPClient: &http.Client{}})
+	require.NoError(t, err)
+	_, err = jwks.Key("k1")
+	require.NoError(t, err)
+}
+
+func TestJWKS_FileRefresh(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, rsaJWK("", &first.PublicKey)), 0o600))
+	jwks, err := auth.NewJWKS(auth.JWKSConfig{File: path, RefreshInterval: time.Nanosecond})
+	require.NoError(t, err)
+
+	key, err := jwks.Key("")
+	require.NoError(t, err)
+	assert.True(t, first.PublicKey.Equal(key))
+
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, rsaJWK("", &second.PublicKey)), 0o600))
+	key, err = jwks.Key("")
+	require.NoError(t, err)
+	assert.True(t, second.PublicKey.Equal(key))
+
+	// A broken file keeps the last good keys.
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+	key, err = jwks.Key("")
+	require.NoError(t, err)
+	assert.True(t, second.PublicKey.Equal(key))
+
+	_, err = auth.NewJWKS(auth.JWKSConfig{})
+	assert.ErrorContains(t, err, "exactly one of file or url")
+}
@@ -0,0 +1,294 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JWTConfig configures JWT signature and claim verification.
+type JWTConfig struct {
+	// HMACSecret verifies HS256, HS384, and HS512 tokens.
+	HMACSecret string
+	// PublicKeyFile is a PEM-encoded RSA or ECDSA public key for RS* and ES* tokens.
+	PublicKeyFile string
+	// JWKS selects the RS* and ES* verification key by the token's kid header. It takes
+	// precedence over PublicKeyFile.
+	JWKS *JWKS
+	// Issuer and Audience are checked against the iss and aud claims when set.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+// JWTVerifier verifies compact JWS tokens signed with a configured key.
+type JWTVerifier struct {
+	secret    []byte
+	publicKey crypto.PublicKey
+	jwks      *JWKS
+	issuer    string
+	audience  string
+	leeway    time.Duration
+	now       func() time.Time
+}
+
+// NewJWTVerifier builds a verifier from cfg. At least one key must be configured.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		secret:   []byte(cfg.HMACSecret),
+		jwks:     cfg.JWKS,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		leeway:   cfg.Leeway,
+		now:      time.Now,
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read jwt public key: %w", err)
+		}
+		key, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	}
+	if len(v.secret) == 0 && v.publicKey == nil && v.jwks == nil {
+		return nil, errors.New("jwt verifier needs an hmac secret, a public key, or a jwks")
+	}
+	return v, nil
+}
+
+// ParsePublicKeyPEM parses a PKIX, PKCS#1, or certificate PEM block into an RSA or ECDSA key.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwt public key: no PEM block found")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt public key: %w", err)
+		}
+		return key, nil
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse jwt public key: %w", err)
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			return key, nil
+		default:
+			return nil, fmt.Errorf("jwt public key: unsupported key type %T", key)
+		}
+	}
+}
+
+// Verify checks the token signature, exp, nbf, iss, and aud and returns its claims.
+func (v *JWTVerifier) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt: malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("jwt header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("jwt signature: %w", err)
+	}
+	if err := v.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("jwt claims: %w", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) verifySignature(alg, kid, signed string, signature []byte) error {
+	hash, ok := map[string]crypto.Hash{
+		"HS256": crypto.SHA256, "RS256": crypto.SHA256, "ES256": crypto.SHA256, "PS256": crypto.SHA256,
+		"HS384": crypto.SHA384, "RS384": crypto.SHA384, "ES384": crypto.SHA384, "PS384": crypto.SHA384,
+		"HS512": crypto.SHA512, "RS512": crypto.SHA512, "ES512": crypto.SHA512, "PS512": crypto.SHA512,
+	}[alg]
+	if !ok {
+		return fmt.Errorf("jwt: unsupported alg %q", alg)
+	}
+	digest := hash.New()
+	digest.Write([]byte(signed))
+
+	publicKey := v.publicKey
+	if v.jwks != nil && alg[:2] != "HS" {
+		key, err := v.jwks.Key(kid)
+		if err != nil {
+			return err
+		}
+		publicKey = key
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if len(v.secret) == 0 {
+			return fmt.Errorf("jwt: no hmac secret configured for %s", alg)
+		}
+		mac := hmac.New(hash.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("jwt: invalid signature")
+		}
+		return nil
+	case "RS", "PS":
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("jwt: no rsa public key configured for %s", alg)
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(key, hash, digest.Sum(nil), signature)
+		} else {
+			err = rsa.VerifyPSS(key, hash, digest.Sum(nil), signature, nil)
+		}
+		if err != nil {
+			return errors.New("jwt: invalid signature")
+		}
+		return nil
+	default:
+		key, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("jwt: no ecdsa public key configured for %s", alg)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("jwt: invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest.Sum(nil), r, s) {
+			return errors.New("jwt: invalid signature")
+		}
+		return nil
+	}
+}
+
+func (v *JWTVerifier) checkClaims(claims map[string]any) error {
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("jwt: token has no exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return errors.New("jwt: token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("jwt: token not yet valid")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return errors.New("jwt: unexpected issuer")
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return errors.New("jwt: unexpected audience")
+	}
+	return nil
+}
+
+func hasAudience(value any, audience string) bool {
+	switch aud := value.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, item := range aud {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ClaimString returns the string or number at a dot-separated claim path such as
+// "sub" or "ext.user.id". It returns "" when the path is missing.
+func ClaimString(claims map[string]any, path string) string {
+	var value any = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return ""
+		}
+		value = object[key]
+	}
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// ClaimStrings returns the values at a dot-separated claim path. A string or number yields one
+// value, and an array yields its string and number elements.
+func ClaimStrings(claims map[string]any, path string) []string {
+	var value any = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	var values []string
+	for _, item := range items {
+		switch typed := item.(type) {
+		case string:
+			values = append(values, typed)
+		case float64:
+			values = append(values, strconv.FormatFloat(typed, 'f', -1, 64))
+		}
+	}
+	return values
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedToken(t *testing.T, alg string, claims map[string]any, sign func(signed []byte) []byte) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func writePublicKey(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestJWTVerifier_HMAC(t *testing.T) {
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{HMACSecret: "s3cret", Issuer: "https://idp.example", Audience: "lcg"})
+	require.NoError(t, err)
+
+	valid := map[string]any{
+		"sub": "user-42",
+		"iss": "https://idp.example",
+		"aud": []string{"other", "lcg"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	claims, err := verifier.Verify(signedToken(t, "HS256", valid, hs256("s3cret")))
+	require.NoError(t, err)
+	assert.Equal(t, "user-42", auth.ClaimString(claims, "sub"))
+
+	_, err = verifier.Verify(signedToken(t, "HS256", valid, hs256("wrong")))
+	assert.ErrorContains(t, err, "invalid signature")
+
+	expired := map[string]any{"sub": "user-42", "iss": "https://idp.example", "aud": "lcg", "exp": time.Now().Add(-time.Hour).Unix()}
+	_, err = verifier.Verify(signedToken(t, "HS256", expired, hs256("s3cret")))
+	assert.ErrorContains(t, err, "expired")
+
+	noExpiry := map[string]any{"sub": "user-42", "iss": "https://idp.example", "aud": "lcg"}
+	_, err = verifier.Verify(signedToken(t, "HS256", noExpiry, hs256("s3cret")))
+	assert.ErrorContains(t, err, "no exp claim")
+
+	wrongAudience := map[string]any{"sub": "user-42", "iss": "https://idp.example", "aud": "someone-else", "exp": time.Now().Add(time.Hour).Unix()}
+	_, err = verifier.Verify(signedToken(t, "HS256", wrongAudience, hs256("s3cret")))
+	assert.ErrorContains(t, err, "audience")
+
+	_, err = verifier.Verify(signedToken(t, "none", valid, func([]byte) []byte { return nil }))
+	assert.ErrorContains(t, err, "unsupported alg")
+}
+
+func TestJWTVerifier_PublicKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{PublicKeyFile: writePublicKey(t, &rsaKey.PublicKey)})
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := map[string]any{"ext": map[string]any{"user": map[string]any{"id": 1234}}, "exp": exp}
+	token := signedToken(t, "RS256", claims, func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		return sig
+	})
+	verified, err := verifier.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", auth.ClaimString(verified, "ext.user.id"))
+	assert.Empty(t, auth.ClaimString(verified, "ext.missing"))
+
+	// HMAC tokens are rejected when only a public key is configured.
+	_, err = verifier.Verify(signedToken(t, "HS256", claims, hs256("")))
+	assert.Error(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	verifier, err = auth.NewJWTVerifier(auth.JWTConfig{PublicKeyFile: writePublicKey(t, &ecKey.PublicKey)})
+	require.NoError(t, err)
+
+	token = signedToken(t, "ES256", map[string]any{"sub": "ec-user", "exp": exp}, func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		require.NoError(t, err)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	})
+	verified, err = verifier.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "ec-user", auth.ClaimString(verified, "sub"))
+}
+
+func TestNewJWTVerifier_RequiresKey(t *testing.T) {
+	_, err := auth.NewJWTVerifier(auth.JWTConfig{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,224 @@
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+)
+
+const (
+	// DefaultRetention is how long transcripts are kept when Config.Retention is zero.
+	DefaultRetention = 30 * 24 * time.Hour
+	// DefaultMaxBytes caps the stored request and response when Config.MaxBytes is zero.
+	DefaultMaxBytes = 256 * 1024
+)
+
+// Config configures transcript capture.
+type Config struct {
+	// Projects lists the projects whose calls are captured; "*" captures every project.
+	Projects []string
+	// MasterKey is the 32-byte key from which each tenant's encryption key is derived.
+	MasterKey []byte
+	// Retention is how long a transcript is kept before it is purged.
+	Retention time.Duration
+	// MaxBytes truncates the request and the response independently.
+	MaxBytes int
+	// RedactPatterns are extra regular expressions replaced with [REDACTED].
+	RedactPatterns []string
+}
+
+// Recorder redacts, encrypts, and stores transcripts for opted-in projects.
+type Recorder struct {
+	store       storage.Storage
+	redactor    *Redactor
+	keyring     *Keyring
+	projects    map[string]bool
+	allProjects bool
+	retention   time.Duration
+	maxBytes    int
+	now         func() time.Time
+}
+
+// sealedBody is the plaintext encrypted into SealedTranscript.Ciphertext.
+type sealedBody struct {
+	Request   string `json:"request"`
+	Response  string `json:"response"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// NewRecorder builds a recorder that writes to store.
+func NewRecorder(store storage.Storage, cfg Config) (*Recorder, error) {
+	keyring, err := NewKeyring(cfg.MasterKey)
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := NewRedactor(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{
+		store:     store,
+		redactor:  redactor,
+		keyring:   keyring,
+		projects:  make(map[string]bool),
+		retention: cfg.Retention,
+		maxBytes:  cfg.MaxBytes,
+		now:       time.Now,
+	}
+	for _, project := range cfg.Projects {
+		project = strings.TrimSpace(project)
+		if project == "*" {
+			r.allProjects = true
+		} else if project != "" {
+			r.projects[project] = true
+		}
+	}
+	if r.retention <= 0 {
+		r.retention = DefaultRetention
+	}
+	if r.maxBytes <= 0 {
+		r.maxBytes = DefaultMaxBytes
+	}
+	return r, nil
+}
+
+// Captures reports whether calls for project are captured.
+func (r *Recorder) Captures(project string) bool {
+	return r.allProjects || r.projects[project]
+}
+
+// Save redacts and encrypts the request and response of record and stores them under its ID.
+func (r *Recorder) Save(ctx context.Context, record *model.UsageRecord, request, response []byte) error {
+	if record == nil || record.ID == "" {
+		return errors.New("save transcript: usage record has no id")
+	}
+	tenantID, err := r.tenantID(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	requestText, requestTruncated := truncateUTF8(string(request), r.maxBytes)
+	responseText, responseTruncated := truncateUTF8(string(response), r.maxBytes)
+	plaintext, err := json.Marshal(sealedBody{
+		Request:   r.redactor.Redact(requestText),
+		Response:  r.redactor.Redact(responseText),
+		Truncated: requestTruncated || responseTruncated,
+	})
+	if err != nil {
+		return fmt.Errorf("encode transcript: %w", err)
+	}
+	ciphertext, err := r.keyring.Seal(tenantID, plaintext)
+	if err != nil {
+		return err
+	}
+
+	return r.store.SaveTranscript(ctx, &model.SealedTranscript{
+		UsageID:    record.ID,
+		TenantID:   tenantID,
+		Tenant:     record.Tenant,
+		Project:    record.Project,
+		Ciphertext: ciphertext,
+		CreatedAt:  r.now().UTC(),
+	})
+}
+
+// Get decrypts the transcript of a usage record. Transcripts past the retention period are
+// reported as not found even before they are purged.
+func (r *Recorder) Get(ctx context.Context, usageID string) (*model.Transcript, error) {
+	sealed, err := r.store.GetTranscript(ctx, usageID)
+	if err != nil {
+		return nil, err
+	}
+	if sealed.CreatedAt.Before(r.cutoff()) {
+		return nil, fmt.Errorf("transcript %q: %w", usageID, storage.ErrNotFound)
+	}
+
+	plaintext, err := r.keyring.Open(sealed.TenantID, sealed.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	var body sealedBody
+	if err := json.Unmarshal(plaintext, &body); err != nil {
+		return nil, fmt.Errorf("decode transcript: %w", err)
+	}
+	return &model.Transcript{
+		UsageID:   sealed.UsageID,
+		Tenant:    sealed.Tenant,
+		Project:   sealed.Project,
+		Request:   body.Request,
+		Response:  body.Response,
+		Truncated: body.Truncated,
+		CreatedAt: sealed.CreatedAt,
+	}, nil
+}
+
+// Purge deletes transcripts older than the retention period.
+func (r *Recorder) Purge(ctx context.Context) (int64, error) {
+	return r.store.PurgeTranscripts(ctx, r.cutoff())
+}
+
+// RunPurge purges expired transcripts every interval until ctx is canceled.
+func (r *Recorder) RunPurge(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := r.Purge(ctx)
+		if err != nil {
+			logger.Error("purge transcripts", "error", err)
+		} else if removed > 0 {
+			logger.Info("purged expired transcripts", "count", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Recorder) cutoff() time.Time {
+	return r.now().UTC().Add(-r.retention)
+}
+
+// tenantID returns the tenant ID that keys encryption, so renaming a tenant's slug does not
+// orphan its transcripts. Records without one are resolved by slug at save time; slugs are
+// not cached, since a renamed or deleted tenant's slug can be reused by another tenant.
+func (r *Recorder) tenantID(ctx context.Context, record *model.UsageRecord) (string, error) {
+	if record.TenantID != "" {
+		return record.TenantID, nil
+	}
+	tenant, err := r.store.GetTenant(ctx, record.Tenant)
+	if err != nil {
+		return "", fmt.Errorf("resolve transcript tenant: %w", err)
+	}
+	return tenant.ID, nil
+}
+
+func truncateUTF8(text string, limit int) (string, bool) {
+	if len(text) <= limit {
+		return text, false
+	}
+	text = text[:limit]
+	// Drop a rune cut in half by the limit.
+	for i := 0; i < utf8.UTFMax-1 && len(text) > 0; i++ {
+		if r, size := utf8.DecodeLastRuneInString(text); r != utf8.RuneError || size != 1 {
+			break
+		}
+		text = text[:len(text)-1]
+	}
+	return text, true
+}
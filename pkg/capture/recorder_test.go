@@ -0,0 +1,129 @@
+package capture_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/capture"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMasterKey = bytes.Repeat([]byte{7}, 32)
+
+func newTestRecorder(t *testing.T, retention time.Duration) (*capture.Recorder, storage.Storage) {
+	t.Helper()
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "capture.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	recorder, err := capture.NewRecorder(store, capture.Config{
+		Projects:  []string{"support"},
+		MasterKey: testMasterKey,
+		Retention: retention,
+		MaxBytes:  64,
+	})
+	require.NoError(t, err)
+	return recorder, store
+}
+
+func TestRecorder_SaveAndGet(t *testing.T) {
+	recorder, store := newTestRecorder(t, time.Hour)
+	ctx := context.Background()
+
+	assert.True(t, recorder.Captures("support"))
+	assert.False(t, recorder.Captures("billing"))
+
+	record := &model.UsageRecord{ID: "usage-1", Tenant: "default", Project: "support"}
+	require.NoError(t, recorder.Save(ctx, record,
+		[]byte(`{"messages":[{"content":"I am bob@example.com"}]}`),
+		[]byte("Hello Bob, this completion is longer than the sixty-four byte capture limit."),
+	))
+
+	sealed, err := store.GetTranscript(ctx, "usage-1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed.Ciphertext), "messages")
+
+	transcript, err := recorder.Get(ctx, "usage-1")
+	require.NoError(t, err)
+	assert.Equal(t, "default", transcript.Tenant)
+	assert.Equal(t, "support", transcript.Project)
+	assert.Equal(t, `{"messages":[{"content":"I am [REDACTED_EMAIL]"}]}`, transcript.Request)
+	assert.Len(t, transcript.Response, 64)
+	assert.True(t, transcript.Truncated)
+
+	_, err = recorder.Get(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestRecorder_ResolvesReusedSlug(t *testing.T) {
+	recorder, store := newTestRecorder(t, time.Hour)
+	ctx := context.Background()
+
+	original, err := store.EnsureTenant(ctx, "acme", "Acme")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save(ctx, &model.UsageRecord{ID: "usage-1", Tenant: "acme", Project: "support"}, []byte("a"), []byte("b")))
+
+	// Once the slug moves to another tenant, records naming it belong to that tenant.
+	_, err = store.RenameTenant(ctx, "acme", "acme-old", "")
+	require.NoError(t, err)
+	reused, err := store.EnsureTenant(ctx, "acme", "Acme")
+	require.NoError(t, err)
+	require.NoError(t, recorder.Save(ctx, &model.UsageRecord{ID: "usage-2", Tenant: "acme", Project: "support"}, []byte("a"), []byte("b")))
+
+	sealed, err := store.GetTranscript(ctx, "usage-1")
+	require.NoError(t, err)
+	assert.Equal(t, original.ID, sealed.TenantID)
+	sealed, err = store.GetTranscript(ctx, "usage-2")
+	require.NoError(t, err)
+	assert.Equal(t, reused.ID, sealed.TenantID)
+	assert.NotEqual(t, original.ID, reused.ID)
+}
+
+func TestRecorder_PurgesExpired(t *testing.T) {
+	recorder, _ := newTestRecorder(t, time.Millisecond)
+	ctx := context.Background()
+
+	require.NoError(t, recorder.Save(ctx, &model.UsageRecord{ID: "usage-1", Tenant: "default", Project: "support"}, []byte("q"), []byte("a")))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := recorder.Get(ctx, "usage-1")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	removed, err := recorder.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+}
+
+func TestKeyring_TenantIsolation(t *testing.T) {
+	keyring, err := capture.NewKeyring(testMasterKey)
+	require.NoError(t, err)
+
+	sealed, err := keyring.Seal("tenant-a", []byte("secret prompt"))
+	require.NoError(t, err)
+
+	opened, err := keyring.Open("tenant-a", sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret prompt", string(opened))
+
+	_, err = keyring.Open("tenant-b", sealed)
+	assert.Error(t, err)
+}
+
+func TestParseMasterKey(t *testing.T) {
+	key, err := capture.ParseMasterKey(base64.StdEncoding.EncodeToString(testMasterKey))
+	require.NoError(t, err)
+	assert.Equal(t, testMasterKey, key)
+
+	_, err = capture.ParseMasterKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.ErrorContains(t, err, "32 bytes")
+
+	_, err = capture.ParseMasterKey("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,30 @@
+// Package capture records redacted, encrypted prompt and completion transcripts for audit.
+package capture
+
+import (
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/policy"
+)
+
+// Redactor replaces emails, phone numbers, API keys, and configured patterns in transcript text.
+type Redactor struct {
+	scanner *policy.Scanner
+}
+
+// NewRedactor builds a redactor that applies the built-in secret and PII rules followed by
+// patterns, each of which replaces its matches with [REDACTED].
+func NewRedactor(patterns []string) (*Redactor, error) {
+	custom := make([]policy.Pattern, len(patterns))
+	for i, pattern := range patterns {
+		custom[i] = policy.Pattern{Name: "custom", Pattern: pattern}
+	}
+	scanner, err := policy.NewScanner(true, custom)
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{scanner: scanner}, nil
+}
+
+// Redact returns text with every match replaced.
+func (r *Redactor) Redact(text string) string {
+	return r.scanner.Redact(text)
+}
@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const sealVersion byte = 1
+
+// Keyring encrypts transcripts with AES-256-GCM under a key derived per tenant from a master key,
+// so one tenant's key never decrypts another tenant's transcripts.
+type Keyring struct {
+	master []byte
+}
+
+// ParseMasterKey decodes a base64 (standard or URL) master key, which must be 32 bytes.
+func ParseMasterKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, errors.New("capture encryption key is not set")
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(encoded); err == nil {
+			if len(key) != 32 {
+				return nil, fmt.Errorf("capture encryption key must be 32 bytes, got %d", len(key))
+			}
+			return key, nil
+		}
+	}
+	return nil, errors.New("capture encryption key must be base64 encoded")
+}
+
+// NewKeyring returns a keyring for a 32-byte master key.
+func NewKeyring(master []byte) (*Keyring, error) {
+	if len(master) != 32 {
+		return nil, fmt.Errorf("capture master key must be 32 bytes, got %d", len(master))
+	}
+	return &Keyring{master: append([]byte(nil), master...)}, nil
+}
+
+// Seal encrypts plaintext for tenantID. The output is a version byte, the nonce, and the ciphertext.
+func (k *Keyring) Seal(tenantID string, plaintext []byte) ([]byte, error) {
+	aead, err := k.aead(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := append([]byte{sealVersion}, nonce...)
+	return aead.Seal(sealed, nonce, plaintext, []byte(tenantID)), nil
+}
+
+// Open decrypts data sealed for tenantID.
+func (k *Keyring) Open(tenantID string, sealed []byte) ([]byte, error) {
+	aead, err := k.aead(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < 1+aead.NonceSize() || sealed[0] != sealVersion {
+		return nil, errors.New("open transcript: unsupported ciphertext")
+	}
+	nonce := sealed[1 : 1+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, sealed[1+aead.NonceSize():], []byte(tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("open transcript: %w", err)
+	}
+	return plaintext, nil
+}
+
+func (k *Keyring) aead(tenantID string) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, k.master)
+	mac.Write([]byte("lcg transcript key\x00" + tenantID))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("create transcript cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create transcript cipher: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit sources record where an administrative change came from.
+const (
+	AuditSourceCLI = "cli"
+	AuditSourceAPI = "api"
+	// AuditSourceSystem marks changes made by the service itself, such as revoking rotated keys.
+	AuditSourceSystem = "system"
+)
+
+// AuditEvent records one administrative change. Before and After hold the JSON of the changed
+// resource: Before is empty for creations and After for deletions.
+type AuditEvent struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// TenantID and Tenant name the tenant the resource belongs to, as it was at the time.
+	TenantID string `json:"tenant_id,omitempty"`
+	Tenant   string `json:"tenant,omitempty"`
+	// Actor names who made the change, such as "user:dana@example.com" or "api_key:<id>".
+	Actor  string `json:"actor"`
+	Source string `json:"source"`
+	// Action is the resource type and verb, such as "api_key.revoke".
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit events. Empty fields match everything; Limit caps the newest
+// events returned when it is positive.
+type AuditFilter struct {
+	Tenant       string
+	Actor        string
+	Source       string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	StartTime    time.Time
+	EndTime      time.Time
+	Limit        int
+}
@@ -0,0 +1,85 @@
+package model
+
+import "slices"
+
+// Roles grant a fixed set of permissions inside the identity's tenant, or in every tenant when
+// the identity is cross-tenant.
+const (
+	// RoleOwner has every permission.
+	RoleOwner = "owner"
+	// RoleAdmin manages policies, tenants, API keys, and budgets, reads reports, transcripts,
+	// and the audit log, and may call the proxy.
+	RoleAdmin = "admin"
+	// RoleFinanceViewer may only read spend and usage reports.
+	RoleFinanceViewer = "finance-viewer"
+	// RoleDeveloper may call the proxy and read reports and policies.
+	RoleDeveloper = "developer"
+	// RoleProxyOnly may only call the proxy.
+	RoleProxyOnly = "proxy-only"
+)
+
+// Permission is an action a role may be granted.
+type Permission string
+
+const (
+	// PermProxy allows forwarding requests through the proxy.
+	PermProxy Permission = "proxy"
+	// PermReportsRead allows reading usage, summaries, metrics, and analytics.
+	PermReportsRead Permission = "reports:read"
+	// PermPoliciesRead allows listing model policies.
+	PermPoliciesRead Permission = "policies:read"
+	// PermPoliciesWrite allows creating, replacing, and deleting model policies.
+	PermPoliciesWrite Permission = "policies:write"
+	// PermTranscriptsRead allows reading captured prompt and completion transcripts.
+	PermTranscriptsRead Permission = "transcripts:read"
+	// PermAuditRead allows reading the audit log of administrative changes.
+	PermAuditRead Permission = "audit:read"
+	// PermAdminRead allows listing tenants, API keys, and budgets through the admin API.
+	PermAdminRead Permission = "admin:read"
+	// PermAdminWrite allows creating, changing, and deleting tenants, API keys, and budgets
+	// through the admin API.
+	PermAdminWrite Permission = "admin:write"
+)
+
+// Roles lists every role, from most to least privileged. finance-viewer and proxy-only hold
+// disjoint permissions, so neither is at least the other.
+var Roles = []string{RoleOwner, RoleAdmin, RoleDeveloper, RoleFinanceViewer, RoleProxyOnly}
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:         {PermProxy, PermReportsRead, PermPoliciesRead, PermPoliciesWrite, PermTranscriptsRead, PermAuditRead, PermAdminRead, PermAdminWrite},
+	RoleFinanceViewer: {PermReportsRead},
+	RoleDeveloper:     {PermProxy, PermReportsRead, PermPoliciesRead},
+	RoleProxyOnly:     {PermProxy},
+}
+
+// ValidRole reports whether role is one of the defined roles.
+func ValidRole(role string) bool {
+	for _, candidate := range Roles {
+		if candidate == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleAtLeast reports whether role grants every permission other grants. Only owner is at
+// least owner, since owner also holds permissions added later. Unknown roles grant nothing.
+func RoleAtLeast(role, other string) bool {
+	if role == RoleOwner {
+		return true
+	}
+	if other == RoleOwner {
+		return false
+	}
+	for _, permission := range rolePermissions[other] {
+		if !slices.Contains(rolePermissions[role], permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoleAllows reports whether role grants permission. Unknown roles grant nothing.
+func RoleAllows(role string, permission Permission) bool {
+	return role == RoleOwner || slices.Contains(rolePermissions[role], permission)
+}
@@ -0,0 +1,145 @@
+package model
+
+import "time"
+
+// RedactedValue replaces secret settings, such as webhook secrets, in API responses and
+// audit events.
+const RedactedValue = "[redacted]"
+
+// TenantConfig is the effective configuration of one tenant: the global configuration with
+// the tenant's settings applied.
+type TenantConfig struct {
+	DenyOnExceed   bool   `json:"deny_on_exceed"`
+	AddCostHeaders bool   `json:"add_cost_headers"`
+	DefaultProject string `json:"default_project"`
+	// AlertThresholdPct is the alert threshold given to the tenant's budgets created without one.
+	AlertThresholdPct float64 `json:"alert_threshold_pct"`
+	// AlertCriticalPct is the budget usage at which the tenant's alerts become critical.
+	AlertCriticalPct float64 `json:"alert_critical_pct"`
+	// Alert destinations; an empty URL sends no alerts there.
+	SlackWebhookURL string `json:"slack_webhook_url"`
+	SlackChannel    string `json:"slack_channel"`
+	WebhookURL      string `json:"webhook_url"`
+	WebhookSecret   string `json:"webhook_secret"`
+}
+
+// Redacted returns a copy with the Slack webhook URL and webhook secret hidden.
+func (c TenantConfig) Redacted() TenantConfig {
+	c.SlackWebhookURL = redact(c.SlackWebhookURL)
+	c.WebhookSecret = redact(c.WebhookSecret)
+	return c
+}
+
+// TenantSettings overrides the global configuration for one tenant. Nil fields inherit the
+// global value; an empty alert URL turns that destination off for the tenant.
+type TenantSettings struct {
+	TenantID          string    `json:"tenant_id"`
+	Tenant            string    `json:"tenant"`
+	DenyOnExceed      *bool     `json:"deny_on_exceed,omitempty"`
+	AddCostHeaders    *bool     `json:"add_cost_headers,omitempty"`
+	DefaultProject    *string   `json:"default_project,omitempty"`
+	AlertThresholdPct *float64  `json:"alert_threshold_pct,omitempty"`
+	AlertCriticalPct  *float64  `json:"alert_critical_pct,omitempty"`
+	SlackWebhookURL   *string   `json:"slack_webhook_url,omitempty"`
+	SlackChannel      *string   `json:"slack_channel,omitempty"`
+	WebhookURL        *string   `json:"webhook_url,omitempty"`
+	WebhookSecret     *string   `json:"webhook_secret,omitempty"`
+	UpdatedAt         time.Time `json:"updated_at,omitzero"`
+}
+
+// TenantSettingNames lists the settings a tenant can override, by their JSON names.
+var TenantSettingNames = []string{
+	"deny_on_exceed",
+	"add_cost_headers",
+	"default_project",
+	"alert_threshold_pct",
+	"alert_critical_pct",
+	"slack_webhook_url",
+	"slack_channel",
+	"webhook_url",
+	"webhook_secret",
+}
+
+// Apply returns base with the settings' overrides applied.
+func (s *TenantSettings) Apply(base TenantConfig) TenantConfig {
+	if s == nil {
+		return base
+	}
+	applyOverride(&base.DenyOnExceed, s.DenyOnExceed)
+	applyOverride(&base.AddCostHeaders, s.AddCostHeaders)
+	applyOverride(&base.DefaultProject, s.DefaultProject)
+	applyOverride(&base.AlertThresholdPct, s.AlertThresholdPct)
+	applyOverride(&base.AlertCriticalPct, s.AlertCriticalPct)
+	applyOverride(&base.SlackWebhookURL, s.SlackWebhookURL)
+	applyOverride(&base.SlackChannel, s.SlackChannel)
+	applyOverride(&base.WebhookURL, s.WebhookURL)
+	applyOverride(&base.WebhookSecret, s.WebhookSecret)
+	return base
+}
+
+// OverridesSlack reports whether the settings change the Slack alert destination.
+func (s *TenantSettings) OverridesSlack() bool {
+	return s != nil && (s.SlackWebhookURL != nil || s.SlackChannel != nil)
+}
+
+// OverridesWebhook reports whether the settings change the webhook alert destination.
+func (s *TenantSettings) OverridesWebhook() bool {
+	return s != nil && (s.WebhookURL != nil || s.WebhookSecret != nil)
+}
+
+// Unset clears the named setting so it inherits the global value again. It reports whether
+// name is a known setting.
+func (s *TenantSettings) Unset(name string) bool {
+	switch name {
+	case "deny_on_exceed":
+		s.DenyOnExceed = nil
+	case "add_cost_headers":
+		s.AddCostHeaders = nil
+	case "default_project":
+		s.DefaultProject = nil
+	case "alert_threshold_pct":
+		s.AlertThresholdPct = nil
+	case "alert_critical_pct":
+		s.AlertCriticalPct = nil
+	case "slack_webhook_url":
+		s.SlackWebhookURL = nil
+	case "slack_channel":
+		s.SlackChannel = nil
+	case "webhook_url":
+		s.WebhookURL = nil
+	case "webhook_secret":
+		s.WebhookSecret = nil
+	default:
+		return false
+	}
+	return true
+}
+
+// Redacted returns a copy with the Slack webhook URL and webhook secret hidden.
+func (s TenantSettings) Redacted() TenantSettings {
+	if s.SlackWebhookURL != nil {
+		s.SlackWebhookURL = Ptr(redact(*s.SlackWebhookURL))
+	}
+	if s.WebhookSecret != nil {
+		s.WebhookSecret = Ptr(redact(*s.WebhookSecret))
+	}
+	return s
+}
+
+// Ptr returns a pointer to v, for setting TenantSettings fields.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+func applyOverride[T any](dst *T, override *T) {
+	if override != nil {
+		*dst = *override
+	}
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxTags caps the cost-allocation tags accepted on one record or budget.
+	MaxTags        = 20
+	maxTagKeyLen   = 64
+	maxTagValueLen = 256
+)
+
+// ParseTags parses cost-allocation tags written as "key=value,key=value". Keys are
+// lower-cased and may contain letters, digits, '_', '-', '.', ':', and '/'. Values may not
+// contain ',' or '='. An empty string yields nil.
+func ParseTags(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	return ParseTagList(strings.Split(raw, ","))
+}
+
+// ParseTagList parses tags given one "key=value" pair per element.
+func ParseTagList(pairs []string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("tag %q must be key=value", pair)
+		}
+		if len(key) > maxTagKeyLen || !validTagKey(key) {
+			return nil, fmt.Errorf("invalid tag key %q", key)
+		}
+		if len(value) > maxTagValueLen || strings.Contains(value, "=") {
+			return nil, fmt.Errorf("invalid value for tag %q", key)
+		}
+		tags[key] = value
+	}
+	if len(tags) > MaxTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxTags)
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// FormatTags renders tags as "key=value,key=value" sorted by key.
+func FormatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + tags[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// TagsMatch reports whether tags contains every key and value in want.
+func TagsMatch(want, tags map[string]string) bool {
+	for key, value := range want {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func validTagKey(key string) bool {
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == ':', r == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -8,6 +8,13 @@ const (
 
 	APIKeyStatusActive  = "active"
 	APIKeyStatusRevoked = "revoked"
+
+	CompletionStatusComplete      = "complete"
+	CompletionStatusClientAborted = "client_aborted"
+	CompletionStatusUpstreamError = "upstream_error"
+	CompletionStatusTimeout       = "timeout"
+	// CompletionStatusBlocked marks requests a policy refused to forward.
+	CompletionStatusBlocked = "blocked"
 )
 
 // UsageRecord represents a single LLM API call with cost data.
@@ -23,6 +30,28 @@ type UsageRecord struct {
 	Project      string    `json:"project" db:"project"`
 	Metadata     string    `json:"metadata,omitempty" db:"metadata"`
 	Timestamp    time.Time `json:"timestamp" db:"timestamp"`
+	// CompletionStatus is one of complete, client_aborted, upstream_error, or timeout.
+	CompletionStatus  string `json:"completion_status,omitempty" db:"completion_status"`
+	StatusCode        int    `json:"status_code,omitempty" db:"status_code"`
+	ErrorType         string `json:"error_type,omitempty" db:"error_type"`
+	LatencyMs         int64  `json:"latency_ms,omitempty" db:"latency_ms"`
+	ProviderRequestID string `json:"provider_request_id,omitempty" db:"provider_request_id"`
+	// TTFTMs is the time to the first streamed token; it is zero for non-streaming calls.
+	TTFTMs             int64   `json:"ttft_ms,omitempty" db:"ttft_ms"`
+	OutputTokensPerSec float64 `json:"output_tokens_per_sec,omitempty" db:"output_tokens_per_sec"`
+	// IdempotencyKey links client retries that sent the same Idempotency-Key header.
+	IdempotencyKey string `json:"idempotency_key,omitempty" db:"idempotency_key"`
+	// User is the end user the call was made for, taken from X-LCG-User, the request body,
+	// or a verified JWT claim.
+	User string `json:"user,omitempty" db:"end_user"`
+	// Tags are cost-allocation labels such as feature, env, customer, or cost center.
+	Tags map[string]string `json:"tags,omitempty"`
+	// PolicyAction is the strongest action a request policy took (flag, redact, or block), and
+	// PolicyFindings lists the comma-separated rules that matched.
+	PolicyAction   string `json:"policy_action,omitempty" db:"policy_action"`
+	PolicyFindings string `json:"policy_findings,omitempty" db:"policy_findings"`
+	// APIKeyID is the tenant API key that authenticated the call.
+	APIKeyID string `json:"api_key_id,omitempty" db:"api_key_id"`
 }
 
 // BudgetPeriod defines the time window for a budget.
@@ -45,18 +74,29 @@ type Budget struct {
 	Period            BudgetPeriod `json:"period" db:"period"`
 	CurrentSpend      float64      `json:"current_spend" db:"current_spend"`
 	AlertThresholdPct float64      `json:"alert_threshold_pct" db:"alert_threshold_pct"`
-	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+	// Tags limit the budget to usage carrying every listed tag.
+	Tags      map[string]string `json:"tags,omitempty" db:"tags"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
 }
 
 // ReportFilter controls what usage records are included in reports.
 type ReportFilter struct {
-	Tenant    string    `json:"tenant,omitempty"`
-	Provider  string    `json:"provider,omitempty"`
-	Model     string    `json:"model,omitempty"`
-	Project   string    `json:"project,omitempty"`
-	StartTime time.Time `json:"start_time,omitempty"`
-	EndTime   time.Time `json:"end_time,omitempty"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Provider         string    `json:"provider,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	Project          string    `json:"project,omitempty"`
+	StartTime        time.Time `json:"start_time,omitempty"`
+	EndTime          time.Time `json:"end_time,omitempty"`
+	CompletionStatus string    `json:"completion_status,omitempty"`
+	IdempotencyKey   string    `json:"idempotency_key,omitempty"`
+	User             string    `json:"user,omitempty"`
+	PolicyAction     string    `json:"policy_action,omitempty"`
+	APIKeyID         string    `json:"api_key_id,omitempty"`
+	// Tags keeps records that carry every listed tag.
+	Tags map[string]string `json:"tags,omitempty"`
+	// GroupByTag adds a ByTag breakdown of spend by the values of this tag key.
+	GroupByTag string `json:"group_by_tag,omitempty"`
 }
 
 // UsageSummary holds aggregated usage statistics.
@@ -69,6 +109,64 @@ type UsageSummary struct {
 	ByProvider        map[string]float64 `json:"by_provider,omitempty"`
 	ByModel           map[string]float64 `json:"by_model,omitempty"`
 	ByProject         map[string]float64 `json:"by_project,omitempty"`
+
+	ByCompletionStatus map[string]float64 `json:"by_completion_status,omitempty"`
+	// ByTag maps values of ReportFilter.GroupByTag to cost; untagged spend is keyed by "".
+	ByTag   map[string]float64 `json:"by_tag,omitempty"`
+	Latency []LatencyStats     `json:"latency,omitempty"`
+
+	// DuplicateRequests and DuplicateCostUSD cover upstream calls after the first one for the
+	// same idempotency key, i.e. spend caused by client retries.
+	DuplicateRequests int64   `json:"duplicate_requests,omitempty"`
+	DuplicateCostUSD  float64 `json:"duplicate_cost_usd,omitempty"`
+}
+
+// UserSpend summarizes usage attributed to one end user.
+type UserSpend struct {
+	Tenant       string    `json:"tenant"`
+	User         string    `json:"user"`
+	RequestCount int64     `json:"request_count"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// Transcript is the captured prompt and completion of one proxied call, after redaction.
+type Transcript struct {
+	UsageID   string    `json:"usage_id"`
+	Tenant    string    `json:"tenant"`
+	Project   string    `json:"project"`
+	Request   string    `json:"request"`
+	Response  string    `json:"response"`
+	Truncated bool      `json:"truncated,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SealedTranscript is a Transcript as stored, with its request and response encrypted under
+// a key belonging to the tenant.
+type SealedTranscript struct {
+	UsageID    string
+	TenantID   string
+	Tenant     string
+	Project    string
+	Ciphertext []byte
+	CreatedAt  time.Time
+}
+
+// LatencyStats holds latency percentiles for successful calls to one provider and model.
+type LatencyStats struct {
+	Provider              string  `json:"provider"`
+	Model                 string  `json:"model"`
+	RequestCount          int64   `json:"request_count"`
+	LatencyP50Ms          int64   `json:"latency_p50_ms"`
+	LatencyP95Ms          int64   `json:"latency_p95_ms"`
+	LatencyP99Ms          int64   `json:"latency_p99_ms"`
+	StreamingCount        int64   `json:"streaming_count,omitempty"`
+	TTFTP50Ms             int64   `json:"ttft_p50_ms,omitempty"`
+	TTFTP95Ms             int64   `json:"ttft_p95_ms,omitempty"`
+	TTFTP99Ms             int64   `json:"ttft_p99_ms,omitempty"`
+	OutputTokensPerSecP50 float64 `json:"output_tokens_per_sec_p50,omitempty"`
 }
 
 // Tenant identifies a logical customer boundary inside a single deployment.
@@ -81,6 +179,18 @@ type Tenant struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// TenantExport bundles everything stored for one tenant, such as before it is deleted.
+type TenantExport struct {
+	ExportedAt    time.Time     `json:"exported_at"`
+	Tenant        Tenant        `json:"tenant"`
+	Usage         []UsageRecord `json:"usage"`
+	Budgets       []Budget      `json:"budgets"`
+	APIKeys       []APIKey      `json:"api_keys"`
+	ModelPolicies []ModelPolicy `json:"model_policies"`
+	// Settings are the tenant's configuration overrides, with secrets redacted.
+	Settings TenantSettings `json:"settings"`
+}
+
 // APIKey stores a hashed access key bound to a tenant.
 type APIKey struct {
 	ID         string     `json:"id" db:"id"`
@@ -93,6 +203,63 @@ type APIKey struct {
 	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// ExpiresAt, when set, is the moment the key stops authenticating.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// Role sets the key's permissions; see the Role constants.
+	Role string `json:"role" db:"role"`
+	// AllTenants extends the role to every tenant instead of only the key's own.
+	AllTenants bool `json:"all_tenants,omitempty" db:"all_tenants"`
+	// SpendLimitUSD caps the key's proxied spend per SpendPeriod; zero means no cap.
+	SpendLimitUSD float64      `json:"spend_limit_usd,omitempty" db:"spend_limit_usd"`
+	SpendPeriod   BudgetPeriod `json:"spend_period,omitempty" db:"spend_period"`
+	// AllowedProjects restricts the projects the key may proxy for; empty allows any.
+	AllowedProjects []string `json:"allowed_projects,omitempty" db:"allowed_projects"`
+	// SuccessorID is the key that replaced this one through rotation. A rotated key stays
+	// active until its ExpiresAt, the end of the grace period, and is then revoked.
+	SuccessorID string `json:"successor_id,omitempty" db:"successor_id"`
+	// PredecessorID is the key this one replaced through rotation.
+	PredecessorID string `json:"predecessor_id,omitempty" db:"predecessor_id"`
+}
+
+// Expired reports whether the key's expiry has passed at now.
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// AllowsProject reports whether the key may proxy requests for project.
+func (k *APIKey) AllowsProject(project string) bool {
+	if len(k.AllowedProjects) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedProjects {
+		if allowed == project {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelPolicy restricts the providers, models, output limit, and streaming available to a
+// tenant, optionally narrowed to one project or one API key. Every policy that applies to a
+// request must allow it.
+type ModelPolicy struct {
+	ID       string `json:"id" db:"id"`
+	TenantID string `json:"tenant_id,omitempty" db:"tenant_id"`
+	Tenant   string `json:"tenant,omitempty"`
+	Name     string `json:"name" db:"name"`
+	// Project and APIKeyID narrow the policy; empty applies it to every project or key.
+	Project  string `json:"project,omitempty" db:"project"`
+	APIKeyID string `json:"api_key_id,omitempty" db:"api_key_id"`
+	// AllowedProviders and AllowedModels are allow lists; empty allows any. Models and
+	// DeniedModels accept * and ? globs.
+	AllowedProviders []string `json:"allowed_providers,omitempty" db:"allowed_providers"`
+	AllowedModels    []string `json:"allowed_models,omitempty" db:"allowed_models"`
+	DeniedModels     []string `json:"denied_models,omitempty" db:"denied_models"`
+	// MaxTokens caps the requested output token limit; zero means no cap.
+	MaxTokens     int64     `json:"max_tokens,omitempty" db:"max_tokens"`
+	DenyStreaming bool      `json:"deny_streaming,omitempty" db:"deny_streaming"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
 }
 
 // UsageRollup stores an aggregated usage bucket for analytics.
@@ -107,6 +274,14 @@ type UsageRollup struct {
 	InputTokens  int64     `json:"input_tokens"`
 	OutputTokens int64     `json:"output_tokens"`
 	CostUSD      float64   `json:"cost_usd"`
+	// SuccessCount counts the successful calls. The timing totals cover only those calls, so
+	// callers can derive average latency and output throughput per bucket from them, and
+	// average TTFT from TTFTCount.
+	SuccessCount      int64 `json:"success_count"`
+	LatencyMsTotal    int64 `json:"latency_ms_total"`
+	TTFTMsTotal       int64 `json:"ttft_ms_total"`
+	TTFTCount         int64 `json:"ttft_count"`
+	GenerationMsTotal int64 `json:"generation_ms_total"`
 }
 
 // UsageAnomaly describes an abnormal cost spike.
@@ -148,6 +323,20 @@ type ModelRecommendation struct {
 	Reason              string  `json:"reason"`
 }
 
+// ErrorRate summarizes failed upstream calls and the spend they wasted for one workload.
+type ErrorRate struct {
+	Tenant        string           `json:"tenant"`
+	Project       string           `json:"project,omitempty"`
+	Provider      string           `json:"provider"`
+	Model         string           `json:"model"`
+	RequestCount  int64            `json:"request_count"`
+	ErrorCount    int64            `json:"error_count"`
+	ErrorRate     float64          `json:"error_rate"`
+	TotalCostUSD  float64          `json:"total_cost_usd"`
+	WastedCostUSD float64          `json:"wasted_cost_usd"`
+	ByErrorType   map[string]int64 `json:"by_error_type,omitempty"`
+}
+
 // PromptOptimization describes a prompt-efficiency recommendation derived from metadata.
 type PromptOptimization struct {
 	Tenant          string  `json:"tenant"`
@@ -172,6 +361,39 @@ type UsageMetadata struct {
 	CachedContextCandidate bool    `json:"cached_context_candidate,omitempty"`
 	InputOutputRatio       float64 `json:"input_output_ratio,omitempty"`
 	Streaming              bool    `json:"streaming,omitempty"`
+	UsageEstimated         bool    `json:"usage_estimated,omitempty"`
+	ReasoningTokens        int64   `json:"reasoning_tokens,omitempty"`
+
+	// Realtime (WebSocket) sessions and their token classes.
+	WebSocket         bool  `json:"websocket,omitempty"`
+	ResponseCount     int   `json:"response_count,omitempty"`
+	CachedInputTokens int64 `json:"cached_input_tokens,omitempty"`
+	AudioInputTokens  int64 `json:"audio_input_tokens,omitempty"`
+	AudioOutputTokens int64 `json:"audio_output_tokens,omitempty"`
+
+	// Tool / function-calling signals.
+	ToolCount                int     `json:"tool_count,omitempty"`
+	ToolSchemaChars          int     `json:"tool_schema_chars,omitempty"`
+	ToolSchemaTokensEstimate int64   `json:"tool_schema_tokens_estimate,omitempty"`
+	ToolSchemaInputShare     float64 `json:"tool_schema_input_share,omitempty"`
+	ToolChoice               string  `json:"tool_choice,omitempty"`
+	ToolCallCount            int     `json:"tool_call_count,omitempty"`
+	ToolCallArgsChars        int     `json:"tool_call_args_chars,omitempty"`
+	ToolResultCount          int     `json:"tool_result_count,omitempty"`
+	ToolResultChars          int     `json:"tool_result_chars,omitempty"`
+
+	// Transform records how the proxy rewrote the request before forwarding it.
+	Transform *RequestTransform `json:"transform,omitempty"`
+}
+
+// RequestTransform describes the rewrites applied to a request body. MaxTokensFrom is zero
+// when the request did not set an output limit and the ceiling was added.
+type RequestTransform struct {
+	MaxTokensFrom    int64 `json:"max_tokens_from,omitempty"`
+	MaxTokensTo      int64 `json:"max_tokens_to,omitempty"`
+	TrimmedMessages  int   `json:"trimmed_messages,omitempty"`
+	PromptTokensFrom int64 `json:"prompt_tokens_from,omitempty"`
+	PromptTokensTo   int64 `json:"prompt_tokens_to,omitempty"`
 }
 
 // PeriodBounds returns the start and end time for the current period.
@@ -35,3 +35,51 @@ func TestPeriodBounds_Default(t *testing.T) {
 	assert.False(t, start.IsZero())
 	assert.Equal(t, 24*time.Hour, end.Sub(start))
 }
+
+func TestParseTags(t *testing.T) {
+	tags, err := model.ParseTags(" feature=search, Env=prod ,cost-center=cc:42")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"feature": "search", "env": "prod", "cost-center": "cc:42"}, tags)
+	assert.Equal(t, "cost-center=cc:42,env=prod,feature=search", model.FormatTags(tags))
+
+	tags, err = model.ParseTags("")
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+
+	for _, raw := range []string{"feature", "=search", "feature=", "bad key=x", "a=b=c"} {
+		_, err := model.ParseTags(raw)
+		assert.Error(t, err, raw)
+	}
+
+	assert.True(t, model.TagsMatch(map[string]string{"env": "prod"}, map[string]string{"env": "prod", "feature": "search"}))
+	assert.False(t, model.TagsMatch(map[string]string{"env": "prod"}, map[string]string{"feature": "search"}))
+	assert.True(t, model.TagsMatch(nil, nil))
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role, other string
+		want        bool
+	}{
+		{model.RoleOwner, model.RoleOwner, true},
+		{model.RoleAdmin, model.RoleOwner, false},
+		{model.RoleAdmin, model.RoleDeveloper, true},
+		{model.RoleDeveloper, model.RoleFinanceViewer, true},
+		{model.RoleFinanceViewer, model.RoleDeveloper, false},
+		{model.RoleFinanceViewer, model.RoleProxyOnly, false},
+		{model.RoleProxyOnly, model.RoleFinanceViewer, false},
+		{model.RoleDeveloper, model.RoleProxyOnly, true},
+		{model.RoleProxyOnly, "unknown", true},
+		{"unknown", model.RoleProxyOnly, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, model.RoleAtLeast(tt.role, tt.other), "%s >= %s", tt.role, tt.other)
+	}
+
+	// Roles is ordered so that no role is at least an earlier one.
+	for i, role := range model.Roles {
+		for _, earlier := range model.Roles[:i] {
+			assert.False(t, model.RoleAtLeast(role, earlier), "%s >= %s", role, earlier)
+		}
+	}
+}
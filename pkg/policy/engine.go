@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Actions applied to requests whose content matches a rule, from weakest to strongest.
+const (
+	ActionOff    = "off"
+	ActionFlag   = "flag"
+	ActionRedact = "redact"
+	ActionBlock  = "block"
+)
+
+// Config configures an Engine.
+type Config struct {
+	// DefaultAction applies to projects without an entry in ProjectActions.
+	DefaultAction string
+	// ProjectActions overrides the action per project.
+	ProjectActions map[string]string
+	// DetectPII enables the built-in email and phone number rules.
+	DetectPII bool
+	// Patterns are extra PII rules.
+	Patterns []Pattern
+}
+
+// Decision is the outcome of evaluating request content. Action is empty when nothing matched.
+type Decision struct {
+	Action   string
+	Findings []string
+}
+
+// Engine scans request content and picks the action configured for the project.
+type Engine struct {
+	scanner        *Scanner
+	defaultAction  string
+	projectActions map[string]string
+}
+
+// NewEngine validates cfg and builds an engine.
+func NewEngine(cfg Config) (*Engine, error) {
+	defaultAction, err := ParseAction(cfg.DefaultAction)
+	if err != nil {
+		return nil, err
+	}
+	projectActions := make(map[string]string, len(cfg.ProjectActions))
+	for project, action := range cfg.ProjectActions {
+		parsed, err := ParseAction(action)
+		if err != nil {
+			return nil, fmt.Errorf("project %q: %w", project, err)
+		}
+		projectActions[project] = parsed
+	}
+	scanner, err := NewScanner(cfg.DetectPII, cfg.Patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{scanner: scanner, defaultAction: defaultAction, projectActions: projectActions}, nil
+}
+
+// ParseAction normalizes an action name. An empty name means ActionFlag.
+func ParseAction(action string) (string, error) {
+	switch action = strings.ToLower(strings.TrimSpace(action)); action {
+	case "":
+		return ActionFlag, nil
+	case ActionOff, ActionFlag, ActionRedact, ActionBlock:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown policy action %q", action)
+	}
+}
+
+// ActionFor returns the action configured for project.
+func (e *Engine) ActionFor(project string) string {
+	if action, ok := e.projectActions[project]; ok {
+		return action
+	}
+	return e.defaultAction
+}
+
+// Evaluate scans text and returns the project's action with the matched rule names.
+func (e *Engine) Evaluate(project, text string) Decision {
+	action := e.ActionFor(project)
+	if action == ActionOff {
+		return Decision{}
+	}
+	findings := e.scanner.Scan(text)
+	if len(findings) == 0 {
+		return Decision{}
+	}
+	return Decision{Action: action, Findings: findings}
+}
+
+// Redact replaces every match in text.
+func (e *Engine) Redact(text string) string {
+	return e.scanner.Redact(text)
+}
+
+// Stronger reports whether action a takes precedence over b.
+func Stronger(a, b string) bool {
+	return rank(a) > rank(b)
+}
+
+func rank(action string) int {
+	switch action {
+	case ActionFlag:
+		return 1
+	case ActionRedact:
+		return 2
+	case ActionBlock:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+// Violation codes reported when a model policy refuses a request.
+const (
+	CodeProviderNotAllowed = "provider_not_allowed"
+	CodeModelNotAllowed    = "model_not_allowed"
+	CodeModelDenied        = "model_denied"
+	CodeMaxTokensExceeded  = "max_tokens_exceeded"
+	CodeStreamingDenied    = "streaming_not_allowed"
+)
+
+// ModelRequest is the call a model policy is checked against.
+type ModelRequest struct {
+	Project   string
+	APIKeyID  string
+	Provider  string
+	Model     string
+	MaxTokens int64
+	Streaming bool
+}
+
+// ModelViolation explains why a model policy refused a request.
+type ModelViolation struct {
+	Policy  string
+	Code    string
+	Message string
+}
+
+// AppliesTo reports whether policy covers the project and API key of req.
+func AppliesTo(policy model.ModelPolicy, req ModelRequest) bool {
+	if policy.Project != "" && policy.Project != req.Project {
+		return false
+	}
+	return policy.APIKeyID == "" || policy.APIKeyID == req.APIKeyID
+}
+
+// ModelRules is a model policy with its model globs compiled.
+type ModelRules struct {
+	Policy  model.ModelPolicy
+	allowed []modelGlob
+	denied  []modelGlob
+}
+
+type modelGlob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// CompileModelPolicy compiles the model globs of policy. It fails on an empty pattern or one
+// containing whitespace or a comma.
+func CompileModelPolicy(policy model.ModelPolicy) (*ModelRules, error) {
+	allowed, err := compileGlobs(policy.AllowedModels)
+	if err != nil {
+		return nil, fmt.Errorf("model policy %q allowed models: %w", policy.Name, err)
+	}
+	denied, err := compileGlobs(policy.DeniedModels)
+	if err != nil {
+		return nil, fmt.Errorf("model policy %q denied models: %w", policy.Name, err)
+	}
+	return &ModelRules{Policy: policy, allowed: allowed, denied: denied}, nil
+}
+
+// CheckModels evaluates req against every policy that applies to it. It returns the names of
+// the applied policies and the first violation, or nil when all of them allow the request.
+func CheckModels(policies []*ModelRules, req ModelRequest) ([]string, *ModelViolation) {
+	var applied []string
+	for _, rules := range policies {
+		if !AppliesTo(rules.Policy, req) {
+			continue
+		}
+		applied = append(applied, rules.Policy.Name)
+		if violation := rules.check(req); violation != nil {
+			return applied, violation
+		}
+	}
+	return applied, nil
+}
+
+func (r *ModelRules) check(req ModelRequest) *ModelViolation {
+	policy := r.Policy
+	violation := func(code, format string, args ...any) *ModelViolation {
+		return &ModelViolation{Policy: policy.Name, Code: code, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if len(policy.AllowedProviders) > 0 && !containsFold(policy.AllowedProviders, req.Provider) {
+		return violation(CodeProviderNotAllowed, "provider %q is not allowed by policy %q", req.Provider, policy.Name)
+	}
+	if pattern, ok := matchModel(r.denied, req.Model); ok {
+		return violation(CodeModelDenied, "model %q is denied by policy %q (%s)", req.Model, policy.Name, pattern)
+	}
+	if len(policy.AllowedModels) > 0 {
+		if req.Model == "" {
+			return violation(CodeModelNotAllowed, "policy %q allows only listed models and the request names none", policy.Name)
+		}
+		if _, ok := matchModel(r.allowed, req.Model); !ok {
+			return violation(CodeModelNotAllowed, "model %q is not allowed by policy %q", req.Model, policy.Name)
+		}
+	}
+	if policy.MaxTokens > 0 && req.MaxTokens > policy.MaxTokens {
+		return violation(CodeMaxTokensExceeded, "max_tokens %d exceeds the limit of %d set by policy %q", req.MaxTokens, policy.MaxTokens, policy.Name)
+	}
+	if policy.DenyStreaming && req.Streaming {
+		return violation(CodeStreamingDenied, "streaming is not allowed by policy %q", policy.Name)
+	}
+	return nil
+}
+
+// matchModel returns the first glob in globs that matches name. Globs are case-insensitive,
+// * matches any run of characters (including "/" and "."), and ? matches one character.
+func matchModel(globs []modelGlob, name string) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	for _, glob := range globs {
+		if glob.re.MatchString(name) {
+			return glob.pattern, true
+		}
+	}
+	return "", false
+}
+
+func compileGlobs(patterns []string) ([]modelGlob, error) {
+	globs := make([]modelGlob, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("empty model pattern")
+		}
+		if strings.ContainsAny(pattern, ", \t\n") {
+			return nil, fmt.Errorf("invalid model pattern %q: whitespace and commas are not allowed", pattern)
+		}
+		quoted := regexp.QuoteMeta(pattern)
+		quoted = strings.ReplaceAll(quoted, `\*`, `.*`)
+		quoted = strings.ReplaceAll(quoted, `\?`, `.`)
+		re, err := regexp.Compile(`(?i)^` + quoted + `$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model pattern %q: %w", pattern, err)
+		}
+		globs = append(globs, modelGlob{pattern: pattern, re: re})
+	}
+	return globs, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), value) {
+			return true
+		}
+	}
+	return false
+}
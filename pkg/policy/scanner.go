@@ -0,0 +1,105 @@
+// Package policy detects secrets and PII in request content and decides how the proxy handles them.
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// KindSecret marks credentials such as API keys and private keys.
+	KindSecret = "secret"
+	// KindPII marks personal data such as emails and phone numbers.
+	KindPII = "pii"
+)
+
+// Rule is a named detection pattern. Matches are replaced with Replacement when redacting.
+type Rule struct {
+	Name        string
+	Kind        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Pattern is a configured PII pattern.
+type Pattern struct {
+	Name    string
+	Pattern string
+}
+
+// secretRules run before PII rules so a key that contains an email-like or numeric run is
+// replaced as a whole.
+var secretRules = []Rule{
+	{"private_key", KindSecret, regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), "[REDACTED_SECRET]"},
+	{"bearer_token", KindSecret, regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{16,}=*`), "[REDACTED_SECRET]"},
+	{"jwt", KindSecret, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`), "[REDACTED_SECRET]"},
+	{"api_key", KindSecret, regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`), "[REDACTED_SECRET]"},
+	{"aws_access_key", KindSecret, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), "[REDACTED_SECRET]"},
+	{"aws_secret_key", KindSecret, regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`), "[REDACTED_SECRET]"},
+	{"google_api_key", KindSecret, regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`), "[REDACTED_SECRET]"},
+	{"github_token", KindSecret, regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{30,}\b`), "[REDACTED_SECRET]"},
+	{"slack_token", KindSecret, regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), "[REDACTED_SECRET]"},
+}
+
+var piiRules = []Rule{
+	{"email", KindPII, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	{"phone", KindPII, regexp.MustCompile(`\+\d{1,3}[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b|\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`), "[REDACTED_PHONE]"},
+}
+
+// Scanner finds and redacts secrets, built-in PII, and configured patterns.
+type Scanner struct {
+	rules []Rule
+}
+
+// NewScanner builds a scanner for the built-in secret rules, the built-in PII rules when
+// detectPII is set, and patterns, whose matches are replaced with [REDACTED].
+func NewScanner(detectPII bool, patterns []Pattern) (*Scanner, error) {
+	rules := append([]Rule(nil), secretRules...)
+	if detectPII {
+		rules = append(rules, piiRules...)
+	}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compile pattern %q: %w", pattern.Pattern, err)
+		}
+		name := strings.TrimSpace(pattern.Name)
+		if name == "" {
+			name = "custom"
+		}
+		rules = append(rules, Rule{Name: name, Kind: KindPII, Pattern: compiled, Replacement: "[REDACTED]"})
+	}
+	return &Scanner{rules: rules}, nil
+}
+
+// Scan returns the sorted names of the rules that match text.
+func (s *Scanner) Scan(text string) []string {
+	if text == "" {
+		return nil
+	}
+	seen := make(map[string]bool)
+	for _, rule := range s.rules {
+		if !seen[rule.Name] && rule.Pattern.MatchString(text) {
+			seen[rule.Name] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	findings := make([]string, 0, len(seen))
+	for name := range seen {
+		findings = append(findings, name)
+	}
+	sort.Strings(findings)
+	return findings
+}
+
+// Redact returns text with every match replaced.
+func (s *Scanner) Redact(text string) string {
+	for _, rule := range s.rules {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
@@ -0,0 +1,20 @@
+package providers
+
+// Gemini implements the Provider interface for the Gemini API (generativelanguage.googleapis.com).
+type Gemini struct {
+	*StaticProvider
+}
+
+// NewGemini creates a new Gemini API provider from a pricing config.
+func NewGemini(cfg *ProviderConfig) *Gemini {
+	return &Gemini{StaticProvider: NewStaticProvider("gemini", cfg)}
+}
+
+// NewGeminiFromFile creates a new Gemini API provider from a YAML pricing file.
+func NewGeminiFromFile(path string) (*Gemini, error) {
+	cfg, err := LoadPricing(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGemini(cfg), nil
+}
@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// KindOpenAICompatible is the pricing-file kind for named instances that speak the OpenAI API.
+const KindOpenAICompatible = "openai-compatible"
+
+var builtinProviders = map[string]bool{
+	"openai":       true,
+	"anthropic":    true,
+	"azure-openai": true,
+	"bedrock":      true,
+	"vertex-ai":    true,
+	"gemini":       true,
+}
+
+// OpenAICompatible implements the Provider interface for a named OpenAI-compatible endpoint,
+// such as a self-hosted vLLM or Ollama server or a third-party gateway.
+type OpenAICompatible struct {
+	*StaticProvider
+	hosts []string
+}
+
+// NewOpenAICompatible creates a named OpenAI-compatible provider from a pricing config.
+// Models priced by GPU hour are converted to per-token prices.
+func NewOpenAICompatible(cfg *ProviderConfig) (*OpenAICompatible, error) {
+	name := strings.TrimSpace(cfg.Provider)
+	if name == "" {
+		return nil, fmt.Errorf("openai-compatible provider: missing name")
+	}
+	if builtinProviders[name] || name == KindOpenAICompatible {
+		return nil, fmt.Errorf("openai-compatible provider %q: name is reserved", name)
+	}
+
+	resolved := *cfg
+	resolved.Models = make([]ModelPricing, len(cfg.Models))
+	for i, model := range cfg.Models {
+		gpu := model.GPUHour
+		if gpu == nil {
+			gpu = cfg.GPUHour
+		}
+		if gpu != nil && model.InputPerMillion == 0 && model.OutputPerMillion == 0 {
+			input, output, err := gpu.PerMillion()
+			if err != nil {
+				return nil, fmt.Errorf("openai-compatible provider %q model %q: %w", name, model.Model, err)
+			}
+			model.InputPerMillion = input
+			model.OutputPerMillion = output
+		}
+		resolved.Models[i] = model
+	}
+
+	hosts := make([]string, 0, len(cfg.Hosts))
+	for _, host := range cfg.Hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	return &OpenAICompatible{
+		StaticProvider: NewStaticProvider(name, &resolved),
+		hosts:          hosts,
+	}, nil
+}
+
+// NewOpenAICompatibleFromFile creates a named OpenAI-compatible provider from a YAML pricing file.
+func NewOpenAICompatibleFromFile(path string) (*OpenAICompatible, error) {
+	cfg, err := LoadPricing(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenAICompatible(cfg)
+}
+
+// APIFormat reports that requests and responses use the OpenAI wire format.
+func (p *OpenAICompatible) APIFormat() string {
+	return "openai"
+}
+
+// Hosts returns the configured host rules.
+func (p *OpenAICompatible) Hosts() []string {
+	return p.hosts
+}
+
+// MatchesHost reports whether an upstream host matches one of the configured rules. A rule is
+// an exact host, a host:port pair, or a "*.domain" wildcard for subdomains.
+func (p *OpenAICompatible) MatchesHost(host string) bool {
+	host = strings.ToLower(strings.TrimSpace(host))
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	for _, rule := range p.hosts {
+		candidate := hostname
+		if strings.Contains(rule, ":") {
+			candidate = host
+		}
+		if suffix, ok := strings.CutPrefix(rule, "*."); ok {
+			if strings.HasSuffix(candidate, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if candidate == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// PerMillion returns the input and output prices per million tokens implied by the GPU cost.
+func (c *GPUHourCost) PerMillion() (float64, float64, error) {
+	if c.USDPerGPUHour <= 0 {
+		return 0, 0, fmt.Errorf("gpu_hour: usd_per_gpu_hour must be positive")
+	}
+	if c.InputTokensPerSecond <= 0 || c.OutputTokensPerSecond <= 0 {
+		return 0, 0, fmt.Errorf("gpu_hour: input and output tokens_per_second must be positive")
+	}
+	gpus := c.GPUs
+	if gpus <= 0 {
+		gpus = 1
+	}
+
+	hourlyUSD := c.USDPerGPUHour * float64(gpus)
+	input := hourlyUSD / (c.InputTokensPerSecond * 3600) * 1_000_000
+	output := hourlyUSD / (c.OutputTokensPerSecond * 3600) * 1_000_000
+	return input, output, nil
+}
@@ -87,6 +87,7 @@ func TestNewProvider_SupportedProviders(t *testing.T) {
 		{name: "azure-openai", provider: "azure-openai", model: "gpt-4o"},
 		{name: "bedrock", provider: "bedrock", model: "anthropic.claude-3-5-sonnet-20241022-v2:0"},
 		{name: "vertex-ai", provider: "vertex-ai", model: "gemini-1.5-pro"},
+		{name: "gemini", provider: "gemini", model: "gemini-2.5-flash"},
 	}
 
 	for _, tt := range tests {
@@ -112,3 +113,84 @@ func TestNewProvider_Unsupported(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "unsupported provider")
 }
+
+func TestNewProvider_OpenAICompatible(t *testing.T) {
+	provider, err := providers.NewProvider(&providers.ProviderConfig{
+		Provider: "groq",
+		Kind:     providers.KindOpenAICompatible,
+		Hosts:    []string{"api.groq.com", "*.groq.internal", "localhost:11434"},
+		Models:   []providers.ModelPricing{{Model: "llama-3.1-70b-versatile", InputPerMillion: 0.59, OutputPerMillion: 0.79}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "groq", provider.Name())
+
+	formatter, ok := provider.(providers.APIFormatter)
+	require.True(t, ok)
+	assert.Equal(t, "openai", formatter.APIFormat())
+
+	matcher, ok := provider.(providers.HostMatcher)
+	require.True(t, ok)
+	assert.True(t, matcher.MatchesHost("api.groq.com"))
+	assert.True(t, matcher.MatchesHost("API.GROQ.COM:443"))
+	assert.True(t, matcher.MatchesHost("edge.groq.internal"))
+	assert.False(t, matcher.MatchesHost("groq.internal"))
+	assert.True(t, matcher.MatchesHost("localhost:11434"))
+	assert.False(t, matcher.MatchesHost("localhost:8000"))
+	assert.False(t, matcher.MatchesHost("api.openai.com"))
+}
+
+func TestNewProvider_OpenAICompatibleGPUHourPricing(t *testing.T) {
+	provider, err := providers.NewProvider(&providers.ProviderConfig{
+		Provider: "vllm-internal",
+		Kind:     providers.KindOpenAICompatible,
+		GPUHour: &providers.GPUHourCost{
+			USDPerGPUHour:         2.00,
+			GPUs:                  2,
+			InputTokensPerSecond:  10000,
+			OutputTokensPerSecond: 1000,
+		},
+		Models: []providers.ModelPricing{
+			{Model: "meta-llama/Llama-3.1-70B-Instruct"},
+			{Model: "pinned", InputPerMillion: 0.10, OutputPerMillion: 0.20},
+		},
+	})
+	require.NoError(t, err)
+
+	// $4/hour across 36M input or 3.6M output tokens per hour.
+	input, err := provider.PricePerToken("meta-llama/Llama-3.1-70B-Instruct", providers.TokenInput)
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0/36_000_000, input, 1e-12)
+	output, err := provider.PricePerToken("meta-llama/Llama-3.1-70B-Instruct", providers.TokenOutput)
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0/3_600_000, output, 1e-12)
+
+	pinned, err := provider.PricePerToken("pinned", providers.TokenOutput)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.20/1_000_000, pinned, 1e-12)
+}
+
+func TestNewProvider_OpenAICompatibleValidation(t *testing.T) {
+	_, err := providers.NewProvider(&providers.ProviderConfig{
+		Provider: "openai",
+		Kind:     providers.KindOpenAICompatible,
+		Models:   []providers.ModelPricing{{Model: "x", InputPerMillion: 1, OutputPerMillion: 1}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reserved")
+
+	_, err = providers.NewProvider(&providers.ProviderConfig{
+		Provider: "ollama",
+		Kind:     providers.KindOpenAICompatible,
+		Models:   []providers.ModelPricing{{Model: "llama3", GPUHour: &providers.GPUHourCost{USDPerGPUHour: 1}}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tokens_per_second")
+
+	_, err = providers.NewProvider(&providers.ProviderConfig{
+		Provider: "mystery",
+		Kind:     "grpc",
+		Models:   []providers.ModelPricing{{Model: "x", InputPerMillion: 1, OutputPerMillion: 1}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported kind")
+}
@@ -47,6 +47,16 @@ func (p *StaticProvider) PricePerToken(model string, tokenType TokenType) (float
 			return pricing.CachedInputPerMillion / 1_000_000, nil
 		}
 		return pricing.InputPerMillion / 1_000_000, nil
+	case TokenAudioInput:
+		if pricing.AudioInputPerMillion > 0 {
+			return pricing.AudioInputPerMillion / 1_000_000, nil
+		}
+		return pricing.InputPerMillion / 1_000_000, nil
+	case TokenAudioOutput:
+		if pricing.AudioOutputPerMillion > 0 {
+			return pricing.AudioOutputPerMillion / 1_000_000, nil
+		}
+		return pricing.OutputPerMillion / 1_000_000, nil
 	default:
 		return 0, fmt.Errorf("%s: unknown token type %d", p.name, tokenType)
 	}
@@ -59,6 +69,14 @@ func (p *StaticProvider) SupportsModel(model string) bool {
 
 // NewProvider builds a typed provider from pricing config.
 func NewProvider(cfg *ProviderConfig) (Provider, error) {
+	switch cfg.Kind {
+	case "":
+	case KindOpenAICompatible:
+		return NewOpenAICompatible(cfg)
+	default:
+		return nil, fmt.Errorf("provider %q: unsupported kind %q", cfg.Provider, cfg.Kind)
+	}
+
 	switch cfg.Provider {
 	case "openai":
 		return NewOpenAI(cfg), nil
@@ -70,6 +88,8 @@ func NewProvider(cfg *ProviderConfig) (Provider, error) {
 		return NewBedrock(cfg), nil
 	case "vertex-ai":
 		return NewVertexAI(cfg), nil
+	case "gemini":
+		return NewGemini(cfg), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider %q", cfg.Provider)
 	}
@@ -7,6 +7,8 @@ const (
 	TokenInput       TokenType = iota // Standard input tokens
 	TokenOutput                       // Standard output tokens
 	TokenCachedInput                  // Cached input tokens (Anthropic)
+	TokenAudioInput                   // Audio input tokens (OpenAI Realtime)
+	TokenAudioOutput                  // Audio output tokens (OpenAI Realtime)
 )
 
 // ModelPricing contains per-model pricing information.
@@ -15,13 +17,32 @@ type ModelPricing struct {
 	InputPerMillion       float64 `yaml:"input_per_million"`
 	OutputPerMillion      float64 `yaml:"output_per_million"`
 	CachedInputPerMillion float64 `yaml:"cached_input_per_million,omitempty"`
+	// Audio prices apply to speech tokens on realtime models. They fall back to the text prices.
+	AudioInputPerMillion  float64 `yaml:"audio_input_per_million,omitempty"`
+	AudioOutputPerMillion float64 `yaml:"audio_output_per_million,omitempty"`
+	// GPUHour prices a self-hosted model from GPU rental cost and measured throughput when
+	// per-token prices are not set. It overrides the provider-level GPUHour.
+	GPUHour *GPUHourCost `yaml:"gpu_hour,omitempty"`
+}
+
+// GPUHourCost converts a per-GPU-hour price into per-token prices using sustained throughput.
+type GPUHourCost struct {
+	USDPerGPUHour         float64 `yaml:"usd_per_gpu_hour"`
+	GPUs                  int     `yaml:"gpus"`
+	InputTokensPerSecond  float64 `yaml:"input_tokens_per_second"`
+	OutputTokensPerSecond float64 `yaml:"output_tokens_per_second"`
 }
 
 // ProviderConfig holds YAML-loaded pricing data for a provider.
 type ProviderConfig struct {
-	Provider string         `yaml:"provider"`
-	Updated  string         `yaml:"updated"`
-	Models   []ModelPricing `yaml:"models"`
+	Provider string `yaml:"provider"`
+	// Kind is empty for built-in vendors. KindOpenAICompatible marks Provider as the name of an
+	// OpenAI wire-compatible instance such as vLLM, Ollama, Groq, or OpenRouter.
+	Kind    string         `yaml:"kind,omitempty"`
+	Hosts   []string       `yaml:"hosts,omitempty"`
+	GPUHour *GPUHourCost   `yaml:"gpu_hour,omitempty"`
+	Updated string         `yaml:"updated"`
+	Models  []ModelPricing `yaml:"models"`
 }
 
 // Provider is the core interface for LLM cost providers.
@@ -38,3 +59,15 @@ type Provider interface {
 	// SupportsModel reports whether this provider has pricing for the given model.
 	SupportsModel(model string) bool
 }
+
+// HostMatcher is implemented by providers that are selected by upstream host rules from their
+// pricing file instead of built-in vendor detection.
+type HostMatcher interface {
+	MatchesHost(host string) bool
+}
+
+// APIFormatter is implemented by providers whose requests and responses use another
+// provider's wire format.
+type APIFormatter interface {
+	APIFormat() string
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+type actorKey struct{}
+
+type actor struct {
+	name   string
+	source string
+}
+
+// WithActor attributes the changes made with ctx to the named actor, arriving through source
+// (model.AuditSourceCLI or model.AuditSourceAPI). Changes without an actor are audited as
+// the system's.
+func WithActor(ctx context.Context, name, source string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor{name: name, source: source})
+}
+
+func actorFromContext(ctx context.Context) actor {
+	if a, ok := ctx.Value(actorKey{}).(actor); ok {
+		return a
+	}
+	return actor{name: model.AuditSourceSystem, source: model.AuditSourceSystem}
+}
+
+// auditChange describes a change for its audit event; before and after are marshaled to JSON.
+type auditChange struct {
+	action     string
+	tenantID   string
+	tenant     string
+	resourceID string
+	before     any
+	after      any
+}
+
+// auditTx runs fn in a transaction and appends the audit events it returns in the same
+// transaction, so a change is never committed without its audit record.
+func (s *SQLite) auditTx(ctx context.Context, fn func(tx *sql.Tx) ([]auditChange, error)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	changes, err := fn(tx)
+	if err != nil {
+		return err
+	}
+	a := actorFromContext(ctx)
+	now := time.Now().UTC()
+	for _, change := range changes {
+		resourceType, _, _ := strings.Cut(change.action, ".")
+		before, err := auditJSON(change.before)
+		if err != nil {
+			return err
+		}
+		after, err := auditJSON(change.after)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO audit_events (id, timestamp, tenant_id, tenant, actor, source, action, resource_type, resource_id, before, after)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), now, change.tenantID, change.tenant, a.name, a.source,
+			change.action, resourceType, change.resourceID, before, after,
+		); err != nil {
+			return fmt.Errorf("record audit event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+func auditJSON(v any) (string, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal audit value: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *SQLite) ListAuditEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error) {
+	query := `SELECT id, timestamp, tenant_id, tenant, actor, source, action, resource_type, resource_id, before, after
+		FROM audit_events WHERE 1 = 1`
+	var args []any
+	for _, condition := range []struct {
+		column string
+		value  string
+	}{
+		{"tenant", normalizeTenantSlug(filter.Tenant)},
+		{"actor", filter.Actor},
+		{"source", filter.Source},
+		{"action", filter.Action},
+		{"resource_type", filter.ResourceType},
+		{"resource_id", filter.ResourceID},
+	} {
+		if condition.value != "" {
+			query += " AND " + condition.column + " = ?"
+			args = append(args, condition.value)
+		}
+	}
+	if !filter.StartTime.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, filter.StartTime.UTC())
+	}
+	if !filter.EndTime.IsZero() {
+		query += " AND timestamp < ?"
+		args = append(args, filter.EndTime.UTC())
+	}
+	query += " ORDER BY timestamp DESC, rowid DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []model.AuditEvent
+	for rows.Next() {
+		var event model.AuditEvent
+		var before, after string
+		if err := rows.Scan(&event.ID, &event.Timestamp, &event.TenantID, &event.Tenant, &event.Actor, &event.Source,
+			&event.Action, &event.ResourceType, &event.ResourceID, &before, &after); err != nil {
+			return nil, fmt.Errorf("scan audit event row: %w", err)
+		}
+		if before != "" {
+			event.Before = json.RawMessage(before)
+		}
+		if after != "" {
+			event.After = json.RawMessage(after)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
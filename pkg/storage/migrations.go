@@ -142,6 +142,130 @@ var migrations = []string{
 
 	CREATE INDEX IF NOT EXISTS idx_rollups_bucket ON usage_rollups(granularity, bucket_start);
 	CREATE INDEX IF NOT EXISTS idx_rollups_tenant ON usage_rollups(tenant_id);`,
+	// Migration 4: Track whether each response completed, was aborted, or failed upstream.
+	`ALTER TABLE usage_records ADD COLUMN completion_status TEXT NOT NULL DEFAULT 'complete';
+
+	CREATE INDEX IF NOT EXISTS idx_usage_completion_status ON usage_records(completion_status);`,
+	// Migration 5: Record upstream status, error classification, latency, and provider request IDs.
+	`ALTER TABLE usage_records ADD COLUMN status_code INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_records ADD COLUMN error_type TEXT NOT NULL DEFAULT '';
+	ALTER TABLE usage_records ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_records ADD COLUMN provider_request_id TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS idx_usage_error_type ON usage_records(error_type);`,
+	// Migration 6: Capture time-to-first-token and output throughput, and roll timing up with usage.
+	`ALTER TABLE usage_records ADD COLUMN ttft_ms INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_records ADD COLUMN output_tokens_per_sec REAL NOT NULL DEFAULT 0;
+
+	ALTER TABLE usage_rollups ADD COLUMN latency_ms_total INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_rollups ADD COLUMN ttft_ms_total INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_rollups ADD COLUMN ttft_count INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE usage_rollups ADD COLUMN generation_ms_total INTEGER NOT NULL DEFAULT 0;`,
+	// Migration 7: Link client retries through the Idempotency-Key header.
+	`ALTER TABLE usage_records ADD COLUMN idempotency_key TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS idx_usage_idempotency_key ON usage_records(tenant_id, idempotency_key);`,
+	// Migration 8: Attribute usage to end users.
+	`ALTER TABLE usage_records ADD COLUMN end_user TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS idx_usage_end_user ON usage_records(tenant_id, end_user, timestamp);`,
+	// Migration 9: Cost-allocation tags on usage records and budgets.
+	`CREATE TABLE IF NOT EXISTS usage_tags (
+		usage_id TEXT NOT NULL,
+		key      TEXT NOT NULL,
+		value    TEXT NOT NULL,
+		PRIMARY KEY (usage_id, key),
+		FOREIGN KEY(usage_id) REFERENCES usage_records(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_usage_tags_key_value ON usage_tags(key, value);
+
+	ALTER TABLE budgets ADD COLUMN tags TEXT NOT NULL DEFAULT '';`,
+	// Migration 10: Encrypted prompt and completion transcripts captured for audit.
+	`CREATE TABLE IF NOT EXISTS usage_transcripts (
+		usage_id   TEXT PRIMARY KEY,
+		tenant_id  TEXT NOT NULL,
+		project    TEXT NOT NULL DEFAULT '',
+		ciphertext BLOB NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_usage_transcripts_created_at ON usage_transcripts(created_at);
+	CREATE INDEX IF NOT EXISTS idx_usage_transcripts_tenant ON usage_transcripts(tenant_id, created_at);`,
+	// Migration 11: Record request policy decisions.
+	`ALTER TABLE usage_records ADD COLUMN policy_action TEXT NOT NULL DEFAULT '';
+	ALTER TABLE usage_records ADD COLUMN policy_findings TEXT NOT NULL DEFAULT '';`,
+	// Migration 12: Model allow/deny policies per tenant, project, and API key.
+	`CREATE TABLE IF NOT EXISTS model_policies (
+		id                TEXT PRIMARY KEY,
+		tenant_id         TEXT NOT NULL REFERENCES tenants(id),
+		name              TEXT NOT NULL,
+		project           TEXT NOT NULL DEFAULT '',
+		api_key_id        TEXT NOT NULL DEFAULT '',
+		allowed_providers TEXT NOT NULL DEFAULT '',
+		allowed_models    TEXT NOT NULL DEFAULT '',
+		denied_models     TEXT NOT NULL DEFAULT '',
+		max_tokens        INTEGER NOT NULL DEFAULT 0,
+		deny_streaming    INTEGER NOT NULL DEFAULT 0,
+		created_at        DATETIME NOT NULL,
+		updated_at        DATETIME NOT NULL,
+		UNIQUE(tenant_id, name)
+	);`,
+	// Migration 13: API key expiry, spend caps, and project restrictions.
+	`ALTER TABLE api_keys ADD COLUMN expires_at DATETIME;
+	ALTER TABLE api_keys ADD COLUMN spend_limit_usd REAL NOT NULL DEFAULT 0;
+	ALTER TABLE api_keys ADD COLUMN spend_period TEXT NOT NULL DEFAULT 'monthly';
+	ALTER TABLE api_keys ADD COLUMN allowed_projects TEXT NOT NULL DEFAULT '';
+	ALTER TABLE usage_records ADD COLUMN api_key_id TEXT NOT NULL DEFAULT '';
+
+	CREATE INDEX IF NOT EXISTS idx_usage_records_api_key ON usage_records(api_key_id, timestamp);`,
+	// Migration 14: API key roles and cross-tenant access. Existing keys become tenant-scoped developers.
+	`ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT 'developer';
+	ALTER TABLE api_keys ADD COLUMN all_tenants INTEGER NOT NULL DEFAULT 0;`,
+	// Migration 15: API key rotation links a rotated key to its successor.
+	`ALTER TABLE api_keys ADD COLUMN successor_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE api_keys ADD COLUMN predecessor_id TEXT NOT NULL DEFAULT '';`,
+	// Migration 16: append-only audit log of administrative changes. Tenants are copied rather
+	// than referenced so events outlive the resources they describe.
+	`CREATE TABLE IF NOT EXISTS audit_events (
+		id            TEXT PRIMARY KEY,
+		timestamp     DATETIME NOT NULL,
+		tenant_id     TEXT NOT NULL DEFAULT '',
+		tenant        TEXT NOT NULL DEFAULT '',
+		actor         TEXT NOT NULL,
+		source        TEXT NOT NULL,
+		action        TEXT NOT NULL,
+		resource_type TEXT NOT NULL,
+		resource_id   TEXT NOT NULL DEFAULT '',
+		before        TEXT NOT NULL DEFAULT '',
+		after         TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_events_tenant ON audit_events(tenant, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_audit_events_resource ON audit_events(resource_type, resource_id);
+
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;
+	CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+	BEGIN SELECT RAISE(ABORT, 'audit_events is append-only'); END;`,
+	// Migration 17: per-tenant overrides of the global configuration. NULL inherits the global value.
+	`CREATE TABLE IF NOT EXISTS tenant_settings (
+		tenant_id           TEXT PRIMARY KEY REFERENCES tenants(id),
+		deny_on_exceed      INTEGER,
+		add_cost_headers    INTEGER,
+		default_project     TEXT,
+		alert_threshold_pct REAL,
+		alert_critical_pct  REAL,
+		slack_webhook_url   TEXT,
+		slack_channel       TEXT,
+		webhook_url         TEXT,
+		webhook_secret      TEXT,
+		updated_at          DATETIME NOT NULL
+	);`,
+	// Migration 18: rollup timing totals only cover successful calls, counted separately. Earlier
+	// buckets cannot be split, so they count every request as timed.
+	`ALTER TABLE usage_rollups ADD COLUMN success_count INTEGER NOT NULL DEFAULT 0;
+	UPDATE usage_rollups SET success_count = request_count;`,
 }
 
 // runMigrations applies pending schema migrations.
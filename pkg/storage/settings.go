@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+func (s *SQLite) GetTenantSettings(ctx context.Context, tenant string) (*model.TenantSettings, error) {
+	return getTenantSettings(ctx, s.db, tenant)
+}
+
+func (s *SQLite) SetTenantSettings(ctx context.Context, settings *model.TenantSettings) error {
+	settings.UpdatedAt = time.Now().UTC()
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getTenantSettings(ctx, tx, settings.Tenant)
+		if err != nil {
+			return nil, err
+		}
+		settings.TenantID = before.TenantID
+		settings.Tenant = before.Tenant
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO tenant_settings (tenant_id, deny_on_exceed, add_cost_headers, default_project, alert_threshold_pct,
+			   alert_critical_pct, slack_webhook_url, slack_channel, webhook_url, webhook_secret, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(tenant_id) DO UPDATE SET
+			   deny_on_exceed = excluded.deny_on_exceed,
+			   add_cost_headers = excluded.add_cost_headers,
+			   default_project = excluded.default_project,
+			   alert_threshold_pct = excluded.alert_threshold_pct,
+			   alert_critical_pct = excluded.alert_critical_pct,
+			   slack_webhook_url = excluded.slack_webhook_url,
+			   slack_channel = excluded.slack_channel,
+			   webhook_url = excluded.webhook_url,
+			   webhook_secret = excluded.webhook_secret,
+			   updated_at = excluded.updated_at`,
+			settings.TenantID, settings.DenyOnExceed, settings.AddCostHeaders, settings.DefaultProject,
+			settings.AlertThresholdPct, settings.AlertCriticalPct, settings.SlackWebhookURL, settings.SlackChannel,
+			settings.WebhookURL, settings.WebhookSecret, settings.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("set tenant settings: %w", err)
+		}
+
+		// Audit events keep which secrets changed, but not their values.
+		redactedBefore, redactedAfter := before.Redacted(), settings.Redacted()
+		return []auditChange{{
+			action:     "tenant_settings.update",
+			tenantID:   settings.TenantID,
+			tenant:     settings.Tenant,
+			resourceID: settings.TenantID,
+			before:     &redactedBefore,
+			after:      &redactedAfter,
+		}}, nil
+	})
+}
+
+// getTenantSettings returns the settings of an existing tenant, which are empty when it
+// overrides nothing.
+func getTenantSettings(ctx context.Context, q queryer, slug string) (*model.TenantSettings, error) {
+	tenant, err := getTenant(ctx, q, slug)
+	if err != nil {
+		return nil, err
+	}
+	settings := &model.TenantSettings{TenantID: tenant.ID, Tenant: tenant.Slug}
+
+	var (
+		denyOnExceed, addCostHeaders                                             sql.Null[bool]
+		defaultProject, slackWebhookURL, slackChannel, webhookURL, webhookSecret sql.Null[string]
+		alertThresholdPct, alertCriticalPct                                      sql.Null[float64]
+	)
+	err = q.QueryRowContext(ctx,
+		`SELECT deny_on_exceed, add_cost_headers, default_project, alert_threshold_pct, alert_critical_pct,
+		   slack_webhook_url, slack_channel, webhook_url, webhook_secret, updated_at
+		 FROM tenant_settings WHERE tenant_id = ?`, tenant.ID,
+	).Scan(&denyOnExceed, &addCostHeaders, &defaultProject, &alertThresholdPct, &alertCriticalPct,
+		&slackWebhookURL, &slackChannel, &webhookURL, &webhookSecret, &settings.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tenant settings: %w", err)
+	}
+
+	settings.DenyOnExceed = nullPtr(denyOnExceed)
+	settings.AddCostHeaders = nullPtr(addCostHeaders)
+	settings.DefaultProject = nullPtr(defaultProject)
+	settings.AlertThresholdPct = nullPtr(alertThresholdPct)
+	settings.AlertCriticalPct = nullPtr(alertCriticalPct)
+	settings.SlackWebhookURL = nullPtr(slackWebhookURL)
+	settings.SlackChannel = nullPtr(slackChannel)
+	settings.WebhookURL = nullPtr(webhookURL)
+	settings.WebhookSecret = nullPtr(webhookSecret)
+	return settings, nil
+}
+
+// nullPtr maps a NULL column to a nil (inherited) setting.
+func nullPtr[T any](n sql.Null[T]) *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.V
+}
@@ -3,9 +3,13 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -63,8 +67,11 @@ func (s *SQLite) RecordUsage(ctx context.Context, record *model.UsageRecord) err
 	if record.Metadata == "" {
 		record.Metadata = "{}"
 	}
+	if record.CompletionStatus == "" {
+		record.CompletionStatus = model.CompletionStatusComplete
+	}
 
-	tenant, err := s.resolveTenant(ctx, record.TenantID, record.Tenant)
+	tenant, err := s.resolveRecordTenant(ctx, record.TenantID, record.Tenant)
 	if err != nil {
 		return err
 	}
@@ -72,28 +79,112 @@ func (s *SQLite) RecordUsage(ctx context.Context, record *model.UsageRecord) err
 	record.Tenant = tenant.Slug
 
 	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO usage_records (id, tenant_id, provider, model, input_tokens, output_tokens, cost_usd, project, metadata, timestamp)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO usage_records (id, tenant_id, provider, model, input_tokens, output_tokens, cost_usd, project, metadata, timestamp,
+		                            completion_status, status_code, error_type, latency_ms, provider_request_id, ttft_ms, output_tokens_per_sec,
+		                            idempotency_key, end_user, policy_action, policy_findings, api_key_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		record.ID, record.TenantID, record.Provider, record.Model,
 		record.InputTokens, record.OutputTokens, record.CostUSD,
 		record.Project, record.Metadata, record.Timestamp,
+		record.CompletionStatus, record.StatusCode, record.ErrorType, record.LatencyMs, record.ProviderRequestID,
+		record.TTFTMs, record.OutputTokensPerSec, record.IdempotencyKey, record.User,
+		record.PolicyAction, record.PolicyFindings, record.APIKeyID,
 	)
 	if err != nil {
 		return fmt.Errorf("insert usage record: %w", err)
 	}
 
-	if err := s.recordUsageRollup(ctx, record, "hourly"); err != nil {
+	if err := recordUsageTags(ctx, s.db, record); err != nil {
 		return err
 	}
-	if err := s.recordUsageRollup(ctx, record, "daily"); err != nil {
+	if err := recordUsageRollups(ctx, s.db, record); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// RecordUsageBatch persists records and their rollups in a single transaction. Records whose
+// ID is already stored are skipped, so replaying a batch never double-counts rollups. Records
+// of a deleted tenant are dropped rather than re-creating the tenant from its slug.
+func (s *SQLite) RecordUsageBatch(ctx context.Context, records []*model.UsageRecord) ([]*model.UsageRecord, error) {
+	var dropped []*model.UsageRecord
+	kept := make([]*model.UsageRecord, 0, len(records))
+	for _, record := range records {
+		if record.ID == "" {
+			record.ID = uuid.New().String()
+		}
+		if record.Timestamp.IsZero() {
+			record.Timestamp = time.Now().UTC()
+		}
+		if record.Metadata == "" {
+			record.Metadata = "{}"
+		}
+		if record.CompletionStatus == "" {
+			record.CompletionStatus = model.CompletionStatusComplete
+		}
+
+		tenant, err := s.resolveRecordTenant(ctx, record.TenantID, record.Tenant)
+		if errors.Is(err, ErrTenantDeleted) {
+			dropped = append(dropped, record)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		record.TenantID = tenant.ID
+		record.Tenant = tenant.Slug
+		kept = append(kept, record)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin usage batch: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	inserted := make([]*model.UsageRecord, 0, len(kept))
+	for _, record := range kept {
+		result, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO usage_records (id, tenant_id, provider, model, input_tokens, output_tokens, cost_usd, project, metadata, timestamp,
+			                                      completion_status, status_code, error_type, latency_ms, provider_request_id, ttft_ms, output_tokens_per_sec,
+			                                      idempotency_key, end_user, policy_action, policy_findings, api_key_id)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			record.ID, record.TenantID, record.Provider, record.Model,
+			record.InputTokens, record.OutputTokens, record.CostUSD,
+			record.Project, record.Metadata, record.Timestamp,
+			record.CompletionStatus, record.StatusCode, record.ErrorType, record.LatencyMs, record.ProviderRequestID,
+			record.TTFTMs, record.OutputTokensPerSec, record.IdempotencyKey, record.User,
+			record.PolicyAction, record.PolicyFindings, record.APIKeyID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("insert usage record: %w", err)
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			continue
+		}
+		if err := recordUsageTags(ctx, tx, record); err != nil {
+			return nil, err
+		}
+		if err := recordUsageRollups(ctx, tx, record); err != nil {
+			return nil, err
+		}
+		inserted = append(inserted, record)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit usage batch: %w", err)
+	}
+	if len(dropped) > 0 {
+		return inserted, &DroppedUsageError{Records: dropped}
+	}
+	return inserted, nil
+}
+
 func (s *SQLite) QueryUsage(ctx context.Context, filter model.ReportFilter) ([]model.UsageRecord, error) {
-	query := `SELECT u.id, u.tenant_id, t.slug, u.provider, u.model, u.input_tokens, u.output_tokens, u.cost_usd, u.project, u.metadata, u.timestamp
+	query := `SELECT u.id, u.tenant_id, t.slug, u.provider, u.model, u.input_tokens, u.output_tokens, u.cost_usd, u.project, u.metadata, u.timestamp,
+		u.completion_status, u.status_code, u.error_type, u.latency_ms, u.provider_request_id, u.ttft_ms, u.output_tokens_per_sec,
+		u.idempotency_key, u.end_user, u.policy_action, u.policy_findings, u.api_key_id
 		FROM usage_records u
 		JOIN tenants t ON u.tenant_id = t.id`
 	where, args := buildWhereClause(filter, "u", "t")
@@ -112,12 +203,61 @@ func (s *SQLite) QueryUsage(ctx context.Context, filter model.ReportFilter) ([]m
 	for rows.Next() {
 		var r model.UsageRecord
 		if err := rows.Scan(&r.ID, &r.TenantID, &r.Tenant, &r.Provider, &r.Model, &r.InputTokens, &r.OutputTokens,
-			&r.CostUSD, &r.Project, &r.Metadata, &r.Timestamp); err != nil {
+			&r.CostUSD, &r.Project, &r.Metadata, &r.Timestamp,
+			&r.CompletionStatus, &r.StatusCode, &r.ErrorType, &r.LatencyMs, &r.ProviderRequestID,
+			&r.TTFTMs, &r.OutputTokensPerSec, &r.IdempotencyKey, &r.User, &r.PolicyAction, &r.PolicyFindings, &r.APIKeyID); err != nil {
 			return nil, fmt.Errorf("scan usage row: %w", err)
 		}
 		records = append(records, r)
 	}
-	return records, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.loadUsageTags(ctx, filter, records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// loadUsageTags fills in the tags of records returned for filter.
+func (s *SQLite) loadUsageTags(ctx context.Context, filter model.ReportFilter, records []model.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	query := `SELECT g.usage_id, g.key, g.value
+		FROM usage_tags g
+		JOIN usage_records u ON g.usage_id = u.id
+		JOIN tenants t ON u.tenant_id = t.id`
+	where, args := buildWhereClause(filter, "u", "t")
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query usage tags: %w", err)
+	}
+	defer rows.Close()
+
+	index := make(map[string]int, len(records))
+	for i := range records {
+		index[records[i].ID] = i
+	}
+	for rows.Next() {
+		var usageID, key, value string
+		if err := rows.Scan(&usageID, &key, &value); err != nil {
+			return fmt.Errorf("scan usage tag: %w", err)
+		}
+		i, ok := index[usageID]
+		if !ok {
+			continue
+		}
+		if records[i].Tags == nil {
+			records[i].Tags = make(map[string]string)
+		}
+		records[i].Tags[key] = value
+	}
+	return rows.Err()
 }
 
 func (s *SQLite) AggregateUsage(ctx context.Context, filter model.ReportFilter) (*model.UsageSummary, error) {
@@ -160,10 +300,119 @@ func (s *SQLite) AggregateUsage(ctx context.Context, filter model.ReportFilter)
 	if err != nil {
 		return nil, err
 	}
+	summary.ByCompletionStatus, err = s.aggregateByField(ctx, "u.completion_status", filter)
+	if err != nil {
+		return nil, err
+	}
+	if filter.GroupByTag != "" {
+		summary.ByTag, err = s.aggregateByTag(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	summary.Latency, err = s.aggregateLatency(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.aggregateDuplicates(ctx, filter, summary); err != nil {
+		return nil, err
+	}
 
 	return summary, nil
 }
 
+// aggregateDuplicates counts upstream calls that repeated an earlier call's idempotency key
+// and sums their cost. The earliest record for each key is the original request.
+func (s *SQLite) aggregateDuplicates(ctx context.Context, filter model.ReportFilter, summary *model.UsageSummary) error {
+	query := `SELECT COUNT(*), COALESCE(SUM(cost_usd), 0) FROM (
+		SELECT u.cost_usd, ROW_NUMBER() OVER (PARTITION BY u.tenant_id, u.idempotency_key ORDER BY u.timestamp, u.id) AS attempt
+		FROM usage_records u
+		JOIN tenants t ON u.tenant_id = t.id`
+	where, args := buildWhereClause(filter, "u", "t")
+	conditions := []string{"u.idempotency_key != ''"}
+	if where != "" {
+		conditions = append([]string{where}, conditions...)
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ") + ") WHERE attempt > 1"
+
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&summary.DuplicateRequests, &summary.DuplicateCostUSD); err != nil {
+		return fmt.Errorf("aggregate duplicate requests: %w", err)
+	}
+	return nil
+}
+
+// TopUsers returns the end users with the highest spend, most expensive first. Records
+// without an end user are excluded.
+func (s *SQLite) TopUsers(ctx context.Context, filter model.ReportFilter, limit int) ([]model.UserSpend, error) {
+	query := `SELECT t.slug, u.end_user, COUNT(*), COALESCE(SUM(u.input_tokens), 0), COALESCE(SUM(u.output_tokens), 0),
+		COALESCE(SUM(u.cost_usd), 0), MAX(u.timestamp)
+		FROM usage_records u
+		JOIN tenants t ON u.tenant_id = t.id`
+	where, args := buildWhereClause(filter, "u", "t")
+	conditions := []string{"u.end_user != ''"}
+	if where != "" {
+		conditions = append([]string{where}, conditions...)
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " GROUP BY t.slug, u.end_user ORDER BY SUM(u.cost_usd) DESC, COUNT(*) DESC, u.end_user"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query top users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []model.UserSpend
+	for rows.Next() {
+		var user model.UserSpend
+		var lastSeen string
+		if err := rows.Scan(&user.Tenant, &user.User, &user.RequestCount, &user.InputTokens, &user.OutputTokens,
+			&user.CostUSD, &lastSeen); err != nil {
+			return nil, fmt.Errorf("scan top user row: %w", err)
+		}
+		user.LastSeen = parseSQLiteTime(lastSeen)
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// aggregateByTag sums cost by the value of the filter's GroupByTag key. Records without the
+// tag are reported under "".
+func (s *SQLite) aggregateByTag(ctx context.Context, filter model.ReportFilter) (map[string]float64, error) {
+	query := `SELECT COALESCE(g.value, ''), COALESCE(SUM(u.cost_usd), 0)
+		FROM usage_records u
+		JOIN tenants t ON u.tenant_id = t.id
+		LEFT JOIN usage_tags g ON g.usage_id = u.id AND g.key = ?`
+	args := []any{strings.ToLower(filter.GroupByTag)}
+	where, whereArgs := buildWhereClause(filter, "u", "t")
+	if where != "" {
+		query += " WHERE " + where
+		args = append(args, whereArgs...)
+	}
+	query += " GROUP BY COALESCE(g.value, '')"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate by tag %s: %w", filter.GroupByTag, err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]float64)
+	for rows.Next() {
+		var value string
+		var total float64
+		if err := rows.Scan(&value, &total); err != nil {
+			return nil, fmt.Errorf("scan tag aggregate: %w", err)
+		}
+		result[value] = total
+	}
+	return result, rows.Err()
+}
+
 func (s *SQLite) aggregateByField(ctx context.Context, field string, filter model.ReportFilter) (map[string]float64, error) {
 	query := fmt.Sprintf(`SELECT %s, COALESCE(SUM(u.cost_usd), 0)
 		FROM usage_records u
@@ -196,6 +445,96 @@ func (s *SQLite) aggregateByField(ctx context.Context, field string, filter mode
 	return result, rows.Err()
 }
 
+// aggregateLatency computes nearest-rank latency, TTFT, and throughput percentiles per provider
+// and model. Failed, aborted, and blocked calls and records without timing are excluded.
+func (s *SQLite) aggregateLatency(ctx context.Context, filter model.ReportFilter) ([]model.LatencyStats, error) {
+	query := `SELECT u.provider, u.model, u.latency_ms, u.ttft_ms, u.output_tokens_per_sec
+		FROM usage_records u
+		JOIN tenants t ON u.tenant_id = t.id`
+	where, args := buildWhereClause(filter, "u", "t")
+	conditions := []string{"u.latency_ms > 0", "u.error_type = ''", "u.completion_status = '" + model.CompletionStatusComplete + "'"}
+	if where != "" {
+		conditions = append([]string{where}, conditions...)
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate latency: %w", err)
+	}
+	defer rows.Close()
+
+	type latencyKey struct{ provider, model string }
+	type latencySamples struct {
+		latency    []int64
+		ttft       []int64
+		throughput []float64
+	}
+	grouped := make(map[latencyKey]*latencySamples)
+	for rows.Next() {
+		var key latencyKey
+		var latencyMs, ttftMs int64
+		var tokensPerSec float64
+		if err := rows.Scan(&key.provider, &key.model, &latencyMs, &ttftMs, &tokensPerSec); err != nil {
+			return nil, fmt.Errorf("scan latency row: %w", err)
+		}
+		samples := grouped[key]
+		if samples == nil {
+			samples = &latencySamples{}
+			grouped[key] = samples
+		}
+		samples.latency = append(samples.latency, latencyMs)
+		if ttftMs > 0 {
+			samples.ttft = append(samples.ttft, ttftMs)
+		}
+		if tokensPerSec > 0 {
+			samples.throughput = append(samples.throughput, tokensPerSec)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]model.LatencyStats, 0, len(grouped))
+	for key, samples := range grouped {
+		slices.Sort(samples.latency)
+		slices.Sort(samples.ttft)
+		slices.Sort(samples.throughput)
+		stats = append(stats, model.LatencyStats{
+			Provider:              key.provider,
+			Model:                 key.model,
+			RequestCount:          int64(len(samples.latency)),
+			LatencyP50Ms:          percentile(samples.latency, 50),
+			LatencyP95Ms:          percentile(samples.latency, 95),
+			LatencyP99Ms:          percentile(samples.latency, 99),
+			StreamingCount:        int64(len(samples.ttft)),
+			TTFTP50Ms:             percentile(samples.ttft, 50),
+			TTFTP95Ms:             percentile(samples.ttft, 95),
+			TTFTP99Ms:             percentile(samples.ttft, 99),
+			OutputTokensPerSecP50: percentile(samples.throughput, 50),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Provider != stats[j].Provider {
+			return stats[i].Provider < stats[j].Provider
+		}
+		return stats[i].Model < stats[j].Model
+	})
+	return stats, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values, or zero when empty.
+func percentile[T int64 | float64](sorted []T, pct float64) T {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
 func (s *SQLite) SetBudget(ctx context.Context, budget *model.Budget) error {
 	if budget.ID == "" {
 		budget.ID = uuid.New().String()
@@ -214,46 +553,73 @@ func (s *SQLite) SetBudget(ctx context.Context, budget *model.Budget) error {
 	budget.TenantID = tenant.ID
 	budget.Tenant = tenant.Slug
 
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO budgets (id, tenant_id, name, project, limit_usd, period, current_spend, alert_threshold_pct, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		 ON CONFLICT(name) DO UPDATE SET
-		   tenant_id = excluded.tenant_id,
-		   project = excluded.project,
-		   limit_usd = excluded.limit_usd,
-		   period = excluded.period,
-		   alert_threshold_pct = excluded.alert_threshold_pct,
-		   updated_at = excluded.updated_at`,
-		budget.ID, budget.TenantID, budget.Name, budget.Project, budget.LimitUSD, budget.Period,
-		budget.CurrentSpend, budget.AlertThresholdPct, budget.CreatedAt, budget.UpdatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("set budget: %w", err)
-	}
-	return nil
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getBudget(ctx, tx, budget.Name)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		action := "budget.create"
+		if before != nil {
+			// An existing budget keeps its id, creation time, and current spend.
+			budget.ID = before.ID
+			budget.CreatedAt = before.CreatedAt
+			budget.CurrentSpend = before.CurrentSpend
+			action = "budget.update"
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO budgets (id, tenant_id, name, project, limit_usd, period, current_spend, alert_threshold_pct, created_at, updated_at, tags)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(name) DO UPDATE SET
+			   tenant_id = excluded.tenant_id,
+			   project = excluded.project,
+			   tags = excluded.tags,
+			   limit_usd = excluded.limit_usd,
+			   period = excluded.period,
+			   alert_threshold_pct = excluded.alert_threshold_pct,
+			   updated_at = excluded.updated_at`,
+			budget.ID, budget.TenantID, budget.Name, budget.Project, budget.LimitUSD, budget.Period,
+			budget.CurrentSpend, budget.AlertThresholdPct, budget.CreatedAt, budget.UpdatedAt, model.FormatTags(budget.Tags),
+		); err != nil {
+			return nil, fmt.Errorf("set budget: %w", err)
+		}
+		after := *budget
+		return []auditChange{{action: action, tenantID: budget.TenantID, tenant: budget.Tenant, resourceID: budget.ID, before: before, after: &after}}, nil
+	})
 }
 
 func (s *SQLite) GetBudget(ctx context.Context, name string) (*model.Budget, error) {
+	return getBudget(ctx, s.db, name)
+}
+
+// queryer runs single-row queries on the database or inside a transaction.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getBudget(ctx context.Context, q queryer, name string) (*model.Budget, error) {
 	var b model.Budget
-	err := s.db.QueryRowContext(ctx,
-		`SELECT b.id, b.tenant_id, t.slug, b.name, b.project, b.limit_usd, b.period, b.current_spend, b.alert_threshold_pct, b.created_at, b.updated_at
+	var tags string
+	err := q.QueryRowContext(ctx,
+		`SELECT b.id, b.tenant_id, t.slug, b.name, b.project, b.limit_usd, b.period, b.current_spend, b.alert_threshold_pct, b.created_at, b.updated_at, b.tags
 		 FROM budgets b
 		 JOIN tenants t ON b.tenant_id = t.id
 		 WHERE b.name = ?`, name,
 	).Scan(&b.ID, &b.TenantID, &b.Tenant, &b.Name, &b.Project, &b.LimitUSD, &b.Period, &b.CurrentSpend,
-		&b.AlertThresholdPct, &b.CreatedAt, &b.UpdatedAt)
+		&b.AlertThresholdPct, &b.CreatedAt, &b.UpdatedAt, &tags)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("budget %q not found", name)
+		return nil, fmt.Errorf("budget %q %w", name, ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get budget: %w", err)
 	}
+	b.Tags, _ = model.ParseTags(tags)
 	return &b, nil
 }
 
 func (s *SQLite) ListBudgets(ctx context.Context) ([]model.Budget, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT b.id, b.tenant_id, t.slug, b.name, b.project, b.limit_usd, b.period, b.current_spend, b.alert_threshold_pct, b.created_at, b.updated_at
+		`SELECT b.id, b.tenant_id, t.slug, b.name, b.project, b.limit_usd, b.period, b.current_spend, b.alert_threshold_pct, b.created_at, b.updated_at, b.tags
 		 FROM budgets b
 		 JOIN tenants t ON b.tenant_id = t.id
 		 ORDER BY t.slug, b.name`)
@@ -265,10 +631,12 @@ func (s *SQLite) ListBudgets(ctx context.Context) ([]model.Budget, error) {
 	var budgets []model.Budget
 	for rows.Next() {
 		var b model.Budget
+		var tags string
 		if err := rows.Scan(&b.ID, &b.TenantID, &b.Tenant, &b.Name, &b.Project, &b.LimitUSD, &b.Period, &b.CurrentSpend,
-			&b.AlertThresholdPct, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			&b.AlertThresholdPct, &b.CreatedAt, &b.UpdatedAt, &tags); err != nil {
 			return nil, fmt.Errorf("scan budget row: %w", err)
 		}
+		b.Tags, _ = model.ParseTags(tags)
 		budgets = append(budgets, b)
 	}
 	return budgets, rows.Err()
@@ -306,27 +674,22 @@ func (s *SQLite) EnsureTenant(ctx context.Context, slug, name string) (*model.Te
 		return tenant, nil
 	}
 
-	id := uuid.New().String()
 	now := time.Now().UTC()
-	if _, err := s.db.ExecContext(ctx,
-		`INSERT INTO tenants (id, slug, name, status, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		id, slug, name, model.TenantStatusActive, now, now,
-	); err != nil {
-		if strings.Contains(err.Error(), "UNIQUE") {
-			return s.GetTenant(ctx, slug)
-		}
-		return nil, fmt.Errorf("create tenant: %w", err)
-	}
-
-	return &model.Tenant{
-		ID:        id,
+	tenant = &model.Tenant{
+		ID:        uuid.New().String(),
 		Slug:      slug,
 		Name:      name,
 		Status:    model.TenantStatusActive,
 		CreatedAt: now,
 		UpdatedAt: now,
-	}, nil
+	}
+	if err := s.insertTenant(ctx, tenant); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return s.GetTenant(ctx, slug)
+		}
+		return nil, fmt.Errorf("create tenant: %w", err)
+	}
+	return tenant, nil
 }
 
 func (s *SQLite) CreateTenant(ctx context.Context, tenant *model.Tenant) error {
@@ -349,25 +712,41 @@ func (s *SQLite) CreateTenant(ctx context.Context, tenant *model.Tenant) error {
 	}
 	tenant.UpdatedAt = now
 
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO tenants (id, slug, name, status, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		tenant.ID, tenant.Slug, tenant.Name, tenant.Status, tenant.CreatedAt, tenant.UpdatedAt,
-	)
-	if err != nil {
+	if err := s.insertTenant(ctx, tenant); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return fmt.Errorf("create tenant: tenant %q %w", tenant.Slug, ErrExists)
+		}
 		return fmt.Errorf("create tenant: %w", err)
 	}
 	return nil
 }
 
+func (s *SQLite) insertTenant(ctx context.Context, tenant *model.Tenant) error {
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO tenants (id, slug, name, status, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			tenant.ID, tenant.Slug, tenant.Name, tenant.Status, tenant.CreatedAt, tenant.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		after := *tenant
+		return []auditChange{{action: "tenant.create", tenantID: tenant.ID, tenant: tenant.Slug, resourceID: tenant.ID, after: &after}}, nil
+	})
+}
+
 func (s *SQLite) GetTenant(ctx context.Context, slug string) (*model.Tenant, error) {
+	return getTenant(ctx, s.db, slug)
+}
+
+func getTenant(ctx context.Context, q queryer, slug string) (*model.Tenant, error) {
 	var tenant model.Tenant
-	err := s.db.QueryRowContext(ctx,
+	err := q.QueryRowContext(ctx,
 		`SELECT id, slug, name, status, created_at, updated_at FROM tenants WHERE slug = ?`,
 		normalizeTenantSlug(slug),
 	).Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("tenant %q not found", slug)
+		return nil, fmt.Errorf("tenant %q %w", slug, ErrNotFound)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get tenant: %w", err)
@@ -395,19 +774,137 @@ func (s *SQLite) ListTenants(ctx context.Context) ([]model.Tenant, error) {
 }
 
 func (s *SQLite) DisableTenant(ctx context.Context, slug string) error {
-	result, err := s.db.ExecContext(ctx,
-		`UPDATE tenants SET status = ?, updated_at = ? WHERE slug = ?`,
-		model.TenantStatusDisabled, time.Now().UTC(), normalizeTenantSlug(slug),
-	)
-	if err != nil {
+	if err := s.setTenantStatus(ctx, slug, model.TenantStatusDisabled, "tenant.disable"); err != nil {
 		return fmt.Errorf("disable tenant: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	return nil
+}
+
+func (s *SQLite) EnableTenant(ctx context.Context, slug string) error {
+	if err := s.setTenantStatus(ctx, slug, model.TenantStatusActive, "tenant.enable"); err != nil {
+		return fmt.Errorf("enable tenant: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) setTenantStatus(ctx context.Context, slug, status, action string) error {
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getTenant(ctx, tx, slug)
+		if err != nil {
+			return nil, err
+		}
+		after := *before
+		after.Status = status
+		after.UpdatedAt = time.Now().UTC()
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE tenants SET status = ?, updated_at = ? WHERE id = ?`,
+			after.Status, after.UpdatedAt, before.ID,
+		); err != nil {
+			return nil, err
+		}
+		return []auditChange{{action: action, tenantID: before.ID, tenant: before.Slug, resourceID: before.ID, before: before, after: &after}}, nil
+	})
+}
+
+func (s *SQLite) RenameTenant(ctx context.Context, slug, newSlug, name string) (*model.Tenant, error) {
+	var renamed *model.Tenant
+	err := s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getTenant(ctx, tx, slug)
+		if err != nil {
+			return nil, err
+		}
+		after := *before
+		if newSlug = normalizeTenantSlug(newSlug); newSlug != "" && newSlug != before.Slug {
+			if before.Slug == defaultTenantSlug {
+				return nil, fmt.Errorf("cannot change the slug of the %w", ErrDefaultTenant)
+			}
+			if _, err := getTenant(ctx, tx, newSlug); err == nil {
+				return nil, fmt.Errorf("tenant %q %w", newSlug, ErrExists)
+			} else if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+			after.Slug = newSlug
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			after.Name = name
+		}
+		after.UpdatedAt = time.Now().UTC()
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE tenants SET slug = ?, name = ?, updated_at = ? WHERE id = ?`,
+			after.Slug, after.Name, after.UpdatedAt, before.ID,
+		); err != nil {
+			return nil, err
+		}
+		renamed = &after
+		return []auditChange{{action: "tenant.rename", tenantID: before.ID, tenant: after.Slug, resourceID: before.ID, before: before, after: &after}}, nil
+	})
 	if err != nil {
-		return fmt.Errorf("check rows affected: %w", err)
+		return nil, fmt.Errorf("rename tenant: %w", err)
 	}
-	if rows == 0 {
-		return fmt.Errorf("tenant %q not found", slug)
+	return renamed, nil
+}
+
+func (s *SQLite) ExportTenant(ctx context.Context, slug string) (*model.TenantExport, error) {
+	tenant, err := s.GetTenant(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	export := &model.TenantExport{ExportedAt: time.Now().UTC(), Tenant: *tenant}
+
+	if export.Usage, err = s.QueryUsage(ctx, model.ReportFilter{Tenant: tenant.Slug}); err != nil {
+		return nil, fmt.Errorf("export usage: %w", err)
+	}
+	budgets, err := s.ListBudgets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("export budgets: %w", err)
+	}
+	for _, budget := range budgets {
+		if budget.TenantID == tenant.ID {
+			export.Budgets = append(export.Budgets, budget)
+		}
+	}
+	if export.APIKeys, err = s.ListAPIKeys(ctx, tenant.Slug); err != nil {
+		return nil, fmt.Errorf("export api keys: %w", err)
+	}
+	if export.ModelPolicies, err = s.ListModelPolicies(ctx, tenant.Slug); err != nil {
+		return nil, fmt.Errorf("export model policies: %w", err)
+	}
+	settings, err := s.GetTenantSettings(ctx, tenant.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("export settings: %w", err)
+	}
+	export.Settings = settings.Redacted()
+	return export, nil
+}
+
+func (s *SQLite) DeleteTenant(ctx context.Context, slug string) error {
+	err := s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		tenant, err := getTenant(ctx, tx, slug)
+		if err != nil {
+			return nil, err
+		}
+		if tenant.Slug == defaultTenantSlug {
+			return nil, fmt.Errorf("cannot delete the %w", ErrDefaultTenant)
+		}
+		for _, query := range []string{
+			`DELETE FROM usage_tags WHERE usage_id IN (SELECT id FROM usage_records WHERE tenant_id = ?)`,
+			`DELETE FROM usage_transcripts WHERE tenant_id = ?`,
+			`DELETE FROM usage_rollups WHERE tenant_id = ?`,
+			`DELETE FROM usage_records WHERE tenant_id = ?`,
+			`DELETE FROM budgets WHERE tenant_id = ?`,
+			`DELETE FROM model_policies WHERE tenant_id = ?`,
+			`DELETE FROM tenant_settings WHERE tenant_id = ?`,
+			`DELETE FROM api_keys WHERE tenant_id = ?`,
+			`DELETE FROM tenants WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, tenant.ID); err != nil {
+				return nil, err
+			}
+		}
+		return []auditChange{{action: "tenant.delete", tenantID: tenant.ID, tenant: tenant.Slug, resourceID: tenant.ID, before: tenant}}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete tenant: %w", err)
 	}
 	return nil
 }
@@ -429,23 +926,42 @@ func (s *SQLite) CreateAPIKey(ctx context.Context, key *model.APIKey) error {
 	if key.Status == "" {
 		key.Status = model.APIKeyStatusActive
 	}
+	if key.Role == "" {
+		key.Role = model.RoleDeveloper
+	}
+	if !model.ValidRole(key.Role) {
+		return fmt.Errorf("invalid api key role %q", key.Role)
+	}
+	if key.SpendPeriod == "" {
+		key.SpendPeriod = model.PeriodMonthly
+	}
 	if key.CreatedAt.IsZero() {
 		key.CreatedAt = time.Now().UTC()
 	}
 
-	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO api_keys (id, tenant_id, name, key_prefix, key_hash, status, last_used_at, created_at, revoked_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		if err := insertAPIKey(ctx, tx, key); err != nil {
+			return nil, fmt.Errorf("create api key: %w", err)
+		}
+		after := *key
+		return []auditChange{{action: "api_key.create", tenantID: key.TenantID, tenant: key.Tenant, resourceID: key.ID, after: &after}}, nil
+	})
+}
+
+func insertAPIKey(ctx context.Context, tx *sql.Tx, key *model.APIKey) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO api_keys (id, tenant_id, name, key_prefix, key_hash, status, last_used_at, created_at, revoked_at,
+		                       expires_at, role, all_tenants, spend_limit_usd, spend_period, allowed_projects, successor_id, predecessor_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		key.ID, key.TenantID, key.Name, key.KeyPrefix, key.KeyHash, key.Status, key.LastUsedAt, key.CreatedAt, key.RevokedAt,
+		key.ExpiresAt, key.Role, key.AllTenants, key.SpendLimitUSD, key.SpendPeriod, joinList(key.AllowedProjects),
+		key.SuccessorID, key.PredecessorID,
 	)
-	if err != nil {
-		return fmt.Errorf("create api key: %w", err)
-	}
-	return nil
+	return err
 }
 
 func (s *SQLite) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey, error) {
-	query := `SELECT k.id, k.tenant_id, t.slug, k.name, k.key_prefix, k.key_hash, k.status, k.last_used_at, k.created_at, k.revoked_at
+	query := `SELECT ` + apiKeyColumns + `
 		FROM api_keys k
 		JOIN tenants t ON k.tenant_id = t.id`
 	var args []any
@@ -464,7 +980,7 @@ func (s *SQLite) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey
 	var keys []model.APIKey
 	for rows.Next() {
 		var key model.APIKey
-		if err := rows.Scan(&key.ID, &key.TenantID, &key.Tenant, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.Status, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt); err != nil {
+		if err := scanAPIKey(rows, &key); err != nil {
 			return nil, fmt.Errorf("scan api key row: %w", err)
 		}
 		keys = append(keys, key)
@@ -472,39 +988,227 @@ func (s *SQLite) ListAPIKeys(ctx context.Context, tenant string) ([]model.APIKey
 	return keys, rows.Err()
 }
 
-func (s *SQLite) RevokeAPIKey(ctx context.Context, id string) error {
-	now := time.Now().UTC()
-	result, err := s.db.ExecContext(ctx,
-		`UPDATE api_keys SET status = ?, revoked_at = ? WHERE id = ?`,
-		model.APIKeyStatusRevoked, now, id,
-	)
+// apiKeyColumns selects the columns read by scanAPIKey from api_keys k joined with tenants t.
+const apiKeyColumns = `k.id, k.tenant_id, t.slug, k.name, k.key_prefix, k.key_hash, k.status, k.last_used_at, k.created_at, k.revoked_at,
+		k.expires_at, k.role, k.all_tenants, k.spend_limit_usd, k.spend_period, k.allowed_projects, k.successor_id, k.predecessor_id`
+
+func scanAPIKey(row interface{ Scan(...any) error }, key *model.APIKey, extra ...any) error {
+	var allowedProjects string
+	dest := []any{&key.ID, &key.TenantID, &key.Tenant, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.Status, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt,
+		&key.ExpiresAt, &key.Role, &key.AllTenants, &key.SpendLimitUSD, &key.SpendPeriod, &allowedProjects, &key.SuccessorID, &key.PredecessorID}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	key.AllowedProjects = splitList(allowedProjects)
+	return nil
+}
+
+func (s *SQLite) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	return getAPIKey(ctx, s.db, id)
+}
+
+func getAPIKey(ctx context.Context, q queryer, id string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := scanAPIKey(q.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys k JOIN tenants t ON k.tenant_id = t.id WHERE k.id = ?`, id,
+	), &key)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("api key %q %w", id, ErrNotFound)
+	}
 	if err != nil {
-		return fmt.Errorf("revoke api key: %w", err)
+		return nil, fmt.Errorf("get api key: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	return &key, nil
+}
+
+func (s *SQLite) RotateAPIKey(ctx context.Context, id string, successor *model.APIKey, graceEndsAt time.Time) (*model.APIKey, error) {
+	if strings.TrimSpace(successor.KeyHash) == "" {
+		return nil, fmt.Errorf("api key hash is required")
+	}
+
+	var rotated *model.APIKey
+	err := s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getAPIKey(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if before.Status != model.APIKeyStatusActive {
+			return nil, fmt.Errorf("api key %q is %s", id, before.Status)
+		}
+		if before.SuccessorID != "" {
+			return nil, fmt.Errorf("api key %q was already rotated to %q", id, before.SuccessorID)
+		}
+
+		if successor.ID == "" {
+			successor.ID = uuid.New().String()
+		}
+		successor.TenantID = before.TenantID
+		successor.Tenant = before.Tenant
+		successor.Name = before.Name
+		successor.Status = model.APIKeyStatusActive
+		successor.Role = before.Role
+		successor.AllTenants = before.AllTenants
+		successor.SpendLimitUSD = before.SpendLimitUSD
+		successor.SpendPeriod = before.SpendPeriod
+		successor.AllowedProjects = before.AllowedProjects
+		successor.PredecessorID = before.ID
+		if successor.CreatedAt.IsZero() {
+			successor.CreatedAt = time.Now().UTC()
+		}
+		after := *before
+		after.SuccessorID = successor.ID
+		graceEndsAt = graceEndsAt.UTC()
+		if after.ExpiresAt == nil || graceEndsAt.Before(*after.ExpiresAt) {
+			after.ExpiresAt = &graceEndsAt
+		}
+
+		if err := insertAPIKey(ctx, tx, successor); err != nil {
+			return nil, fmt.Errorf("create successor api key: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE api_keys SET successor_id = ?, expires_at = ? WHERE id = ?`,
+			after.SuccessorID, after.ExpiresAt, after.ID,
+		); err != nil {
+			return nil, fmt.Errorf("rotate api key: %w", err)
+		}
+		rotated = &after
+		created := *successor
+		return []auditChange{
+			{action: "api_key.rotate", tenantID: after.TenantID, tenant: after.Tenant, resourceID: after.ID, before: before, after: &after},
+			{action: "api_key.create", tenantID: created.TenantID, tenant: created.Tenant, resourceID: created.ID, after: &created},
+		}, nil
+	})
 	if err != nil {
-		return fmt.Errorf("check rows affected: %w", err)
+		return nil, err
 	}
-	if rows == 0 {
-		return fmt.Errorf("api key %q not found", id)
+	return rotated, nil
+}
+
+func (s *SQLite) RevokeRotatedAPIKeys(ctx context.Context, now time.Time) ([]model.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT k.id FROM api_keys k WHERE k.status = ? AND k.successor_id != '' AND k.expires_at <= ?`,
+		model.APIKeyStatusActive, now.UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list rotated api keys: %w", err)
 	}
-	return nil
+	var due []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan api key row: %w", err)
+		}
+		due = append(due, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("list rotated api keys: %w", err)
+	}
+
+	var revoked []model.APIKey
+	for _, id := range due {
+		key, err := s.revokeAPIKey(ctx, id, now.UTC())
+		// Another sweep may have revoked the key first; only report keys revoked here.
+		if errors.Is(err, errAlreadyRevoked) {
+			continue
+		}
+		if err != nil {
+			return revoked, fmt.Errorf("revoke rotated api key: %w", err)
+		}
+		revoked = append(revoked, *key)
+	}
+	return revoked, nil
+}
+
+func (s *SQLite) RevokeAPIKey(ctx context.Context, id string) error {
+	_, err := s.revokeAPIKey(ctx, id, time.Now().UTC())
+	if errors.Is(err, errAlreadyRevoked) {
+		return nil
+	}
+	return err
+}
+
+var errAlreadyRevoked = errors.New("api key already revoked")
+
+func (s *SQLite) revokeAPIKey(ctx context.Context, id string, now time.Time) (*model.APIKey, error) {
+	var revoked *model.APIKey
+	err := s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getAPIKey(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if before.Status == model.APIKeyStatusRevoked {
+			return nil, errAlreadyRevoked
+		}
+		after := *before
+		after.Status = model.APIKeyStatusRevoked
+		after.RevokedAt = &now
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE api_keys SET status = ?, revoked_at = ? WHERE id = ?`,
+			after.Status, now, id,
+		); err != nil {
+			return nil, fmt.Errorf("revoke api key: %w", err)
+		}
+		revoked = &after
+		return []auditChange{{action: "api_key.revoke", tenantID: after.TenantID, tenant: after.Tenant, resourceID: id, before: before, after: &after}}, nil
+	})
+	return revoked, err
+}
+
+func (s *SQLite) SetAPIKeyRole(ctx context.Context, id, role string, allTenants bool) error {
+	if !model.ValidRole(role) {
+		return fmt.Errorf("invalid api key role %q", role)
+	}
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getAPIKey(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		after := *before
+		after.Role = role
+		after.AllTenants = allTenants
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE api_keys SET role = ?, all_tenants = ? WHERE id = ?`,
+			role, allTenants, id,
+		); err != nil {
+			return nil, fmt.Errorf("set api key role: %w", err)
+		}
+		return []auditChange{{action: "api_key.set_role", tenantID: after.TenantID, tenant: after.Tenant, resourceID: id, before: before, after: &after}}, nil
+	})
+}
+
+func (s *SQLite) APIKeySpend(ctx context.Context, ids []string, start, end time.Time) (float64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	args := make([]any, 0, len(ids)+2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, start, end)
+
+	var spent float64
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(cost_usd), 0) FROM usage_records
+		 WHERE api_key_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`) AND timestamp >= ? AND timestamp < ?`,
+		args...,
+	).Scan(&spent); err != nil {
+		return 0, fmt.Errorf("api key spend: %w", err)
+	}
+	return spent, nil
 }
 
 func (s *SQLite) ResolveAPIKey(ctx context.Context, keyHash string) (*model.APIKey, *model.Tenant, error) {
 	var key model.APIKey
 	var tenant model.Tenant
-	err := s.db.QueryRowContext(ctx,
-		`SELECT k.id, k.tenant_id, t.slug, k.name, k.key_prefix, k.key_hash, k.status, k.last_used_at, k.created_at, k.revoked_at,
+	err := scanAPIKey(s.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+`,
 		        t.id, t.slug, t.name, t.status, t.created_at, t.updated_at
 		 FROM api_keys k
 		 JOIN tenants t ON k.tenant_id = t.id
 		 WHERE k.key_hash = ? AND k.status = ? AND t.status = ?`,
 		keyHash, model.APIKeyStatusActive, model.TenantStatusActive,
-	).Scan(
-		&key.ID, &key.TenantID, &key.Tenant, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.Status, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt,
-		&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt,
-	)
+	), &key, &tenant.ID, &tenant.Slug, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil, fmt.Errorf("api key not found")
 	}
@@ -512,7 +1216,11 @@ func (s *SQLite) ResolveAPIKey(ctx context.Context, keyHash string) (*model.APIK
 		return nil, nil, fmt.Errorf("resolve api key: %w", err)
 	}
 
+	// Expired keys are returned for the caller to reject, without counting as used.
 	now := time.Now().UTC()
+	if key.Expired(now) {
+		return &key, &tenant, nil
+	}
 	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, now, key.ID); err != nil {
 		return nil, nil, fmt.Errorf("touch api key: %w", err)
 	}
@@ -521,7 +1229,8 @@ func (s *SQLite) ResolveAPIKey(ctx context.Context, keyHash string) (*model.APIK
 }
 
 func (s *SQLite) QueryUsageRollups(ctx context.Context, filter model.ReportFilter, granularity string, start, end time.Time) ([]model.UsageRollup, error) {
-	query := `SELECT t.slug, r.provider, r.model, r.project, r.granularity, r.bucket_start, r.request_count, r.input_tokens, r.output_tokens, r.cost_usd
+	query := `SELECT t.slug, r.provider, r.model, r.project, r.granularity, r.bucket_start, r.request_count, r.input_tokens, r.output_tokens, r.cost_usd,
+		r.success_count, r.latency_ms_total, r.ttft_ms_total, r.ttft_count, r.generation_ms_total
 		FROM usage_rollups r
 		JOIN tenants t ON r.tenant_id = t.id
 		WHERE r.granularity = ?`
@@ -562,7 +1271,8 @@ func (s *SQLite) QueryUsageRollups(ctx context.Context, filter model.ReportFilte
 	var rollups []model.UsageRollup
 	for rows.Next() {
 		var rollup model.UsageRollup
-		if err := rows.Scan(&rollup.Tenant, &rollup.Provider, &rollup.Model, &rollup.Project, &rollup.Granularity, &rollup.BucketStart, &rollup.RequestCount, &rollup.InputTokens, &rollup.OutputTokens, &rollup.CostUSD); err != nil {
+		if err := rows.Scan(&rollup.Tenant, &rollup.Provider, &rollup.Model, &rollup.Project, &rollup.Granularity, &rollup.BucketStart, &rollup.RequestCount, &rollup.InputTokens, &rollup.OutputTokens, &rollup.CostUSD,
+			&rollup.SuccessCount, &rollup.LatencyMsTotal, &rollup.TTFTMsTotal, &rollup.TTFTCount, &rollup.GenerationMsTotal); err != nil {
 			return nil, fmt.Errorf("scan usage rollup: %w", err)
 		}
 		rollups = append(rollups, rollup)
@@ -574,6 +1284,192 @@ func (s *SQLite) Close() error {
 	return s.db.Close()
 }
 
+func (s *SQLite) SetModelPolicy(ctx context.Context, policy *model.ModelPolicy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" {
+		return fmt.Errorf("set model policy: name is required")
+	}
+	if policy.ID == "" {
+		policy.ID = uuid.New().String()
+	}
+	policy.Project = strings.TrimSpace(policy.Project)
+	policy.APIKeyID = strings.TrimSpace(policy.APIKeyID)
+	now := time.Now().UTC()
+	if policy.CreatedAt.IsZero() {
+		policy.CreatedAt = now
+	}
+	policy.UpdatedAt = now
+
+	tenant, err := s.resolveTenant(ctx, policy.TenantID, policy.Tenant)
+	if err != nil {
+		return err
+	}
+	policy.TenantID = tenant.ID
+	policy.Tenant = tenant.Slug
+
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getModelPolicy(ctx, tx, policy.Tenant, policy.Name)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		action := "model_policy.create"
+		if before != nil {
+			action = "model_policy.update"
+		}
+
+		// An existing policy keeps its id and creation time.
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO model_policies (id, tenant_id, name, project, api_key_id, allowed_providers, allowed_models, denied_models, max_tokens, deny_streaming, created_at, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(tenant_id, name) DO UPDATE SET
+			   project = excluded.project,
+			   api_key_id = excluded.api_key_id,
+			   allowed_providers = excluded.allowed_providers,
+			   allowed_models = excluded.allowed_models,
+			   denied_models = excluded.denied_models,
+			   max_tokens = excluded.max_tokens,
+			   deny_streaming = excluded.deny_streaming,
+			   updated_at = excluded.updated_at
+			 RETURNING id, created_at`,
+			policy.ID, policy.TenantID, policy.Name, policy.Project, policy.APIKeyID,
+			joinList(policy.AllowedProviders), joinList(policy.AllowedModels), joinList(policy.DeniedModels),
+			policy.MaxTokens, policy.DenyStreaming, policy.CreatedAt, policy.UpdatedAt,
+		).Scan(&policy.ID, &policy.CreatedAt); err != nil {
+			return nil, fmt.Errorf("set model policy: %w", err)
+		}
+		after := *policy
+		return []auditChange{{action: action, tenantID: policy.TenantID, tenant: policy.Tenant, resourceID: policy.ID, before: before, after: &after}}, nil
+	})
+}
+
+func (s *SQLite) ListModelPolicies(ctx context.Context, tenant string) ([]model.ModelPolicy, error) {
+	query := `SELECT ` + modelPolicyColumns + `
+		FROM model_policies p
+		JOIN tenants t ON p.tenant_id = t.id`
+	var args []any
+	if tenant = normalizeTenantSlug(tenant); tenant != "" {
+		query += " WHERE t.slug = ?"
+		args = append(args, tenant)
+	}
+	query += " ORDER BY t.slug, p.name"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list model policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []model.ModelPolicy
+	for rows.Next() {
+		var policy model.ModelPolicy
+		if err := scanModelPolicy(rows, &policy); err != nil {
+			return nil, fmt.Errorf("scan model policy row: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// modelPolicyColumns selects the columns read by scanModelPolicy from model_policies p joined
+// with tenants t.
+const modelPolicyColumns = `p.id, p.tenant_id, t.slug, p.name, p.project, p.api_key_id, p.allowed_providers, p.allowed_models, p.denied_models,
+		p.max_tokens, p.deny_streaming, p.created_at, p.updated_at`
+
+func scanModelPolicy(row interface{ Scan(...any) error }, policy *model.ModelPolicy) error {
+	var providers, allowed, denied string
+	if err := row.Scan(&policy.ID, &policy.TenantID, &policy.Tenant, &policy.Name, &policy.Project, &policy.APIKeyID,
+		&providers, &allowed, &denied, &policy.MaxTokens, &policy.DenyStreaming, &policy.CreatedAt, &policy.UpdatedAt); err != nil {
+		return err
+	}
+	policy.AllowedProviders = splitList(providers)
+	policy.AllowedModels = splitList(allowed)
+	policy.DeniedModels = splitList(denied)
+	return nil
+}
+
+func getModelPolicy(ctx context.Context, q queryer, tenant, name string) (*model.ModelPolicy, error) {
+	var policy model.ModelPolicy
+	err := scanModelPolicy(q.QueryRowContext(ctx,
+		`SELECT `+modelPolicyColumns+`
+		 FROM model_policies p
+		 JOIN tenants t ON p.tenant_id = t.id
+		 WHERE t.slug = ? AND p.name = ?`,
+		normalizeTenantSlug(tenant), strings.TrimSpace(name),
+	), &policy)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("model policy %q: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get model policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (s *SQLite) DeleteModelPolicy(ctx context.Context, tenant, name string) error {
+	return s.auditTx(ctx, func(tx *sql.Tx) ([]auditChange, error) {
+		before, err := getModelPolicy(ctx, tx, tenant, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM model_policies WHERE id = ?`, before.ID); err != nil {
+			return nil, fmt.Errorf("delete model policy: %w", err)
+		}
+		return []auditChange{{action: "model_policy.delete", tenantID: before.TenantID, tenant: before.Tenant, resourceID: before.ID, before: before}}, nil
+	})
+}
+
+func (s *SQLite) SaveTranscript(ctx context.Context, transcript *model.SealedTranscript) error {
+	if transcript.CreatedAt.IsZero() {
+		transcript.CreatedAt = time.Now().UTC()
+	}
+	tenant, err := s.resolveRecordTenant(ctx, transcript.TenantID, transcript.Tenant)
+	if err != nil {
+		return err
+	}
+	transcript.TenantID = tenant.ID
+	transcript.Tenant = tenant.Slug
+
+	_, err = s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO usage_transcripts (usage_id, tenant_id, project, ciphertext, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		transcript.UsageID, transcript.TenantID, transcript.Project, transcript.Ciphertext, transcript.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("save transcript: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) GetTranscript(ctx context.Context, usageID string) (*model.SealedTranscript, error) {
+	var transcript model.SealedTranscript
+	err := s.db.QueryRowContext(ctx,
+		`SELECT x.usage_id, x.tenant_id, COALESCE(t.slug, ''), x.project, x.ciphertext, x.created_at
+		 FROM usage_transcripts x
+		 LEFT JOIN tenants t ON t.id = x.tenant_id
+		 WHERE x.usage_id = ?`,
+		usageID,
+	).Scan(&transcript.UsageID, &transcript.TenantID, &transcript.Tenant, &transcript.Project, &transcript.Ciphertext, &transcript.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transcript %q: %w", usageID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get transcript: %w", err)
+	}
+	return &transcript, nil
+}
+
+func (s *SQLite) PurgeTranscripts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM usage_transcripts WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("purge transcripts: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("purge transcripts: %w", err)
+	}
+	return removed, nil
+}
+
 func (s *SQLite) resolveTenant(ctx context.Context, tenantID, tenantSlug string) (*model.Tenant, error) {
 	if strings.TrimSpace(tenantID) != "" {
 		var tenant model.Tenant
@@ -592,18 +1488,82 @@ func (s *SQLite) resolveTenant(ctx context.Context, tenantID, tenantSlug string)
 	return s.EnsureTenant(ctx, tenantSlug, tenantNameFromSlug(tenantSlug))
 }
 
-func (s *SQLite) recordUsageRollup(ctx context.Context, record *model.UsageRecord, granularity string) error {
+// resolveRecordTenant resolves the tenant of a usage record or transcript. A TenantID that no
+// longer exists means the tenant was deleted after the request was served, so the tenant is
+// not re-created from the slug.
+func (s *SQLite) resolveRecordTenant(ctx context.Context, tenantID, tenantSlug string) (*model.Tenant, error) {
+	if strings.TrimSpace(tenantID) == "" {
+		return s.resolveTenant(ctx, "", tenantSlug)
+	}
+	var tenant model.Tenant
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, slug, name, status, created_at, updated_at FROM tenants WHERE id = ?`,
+		tenantID,
+	).Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.Status, &tenant.CreatedAt, &tenant.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("tenant %q %w", tenantID, ErrTenantDeleted)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tenant by id: %w", err)
+	}
+	return &tenant, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func recordUsageTags(ctx context.Context, db execer, record *model.UsageRecord) error {
+	for key, value := range record.Tags {
+		if _, err := db.ExecContext(ctx,
+			`INSERT OR REPLACE INTO usage_tags (usage_id, key, value) VALUES (?, ?, ?)`,
+			record.ID, key, value,
+		); err != nil {
+			return fmt.Errorf("insert usage tag: %w", err)
+		}
+	}
+	return nil
+}
+
+func recordUsageRollups(ctx context.Context, db execer, record *model.UsageRecord) error {
+	if err := recordUsageRollup(ctx, db, record, "hourly"); err != nil {
+		return err
+	}
+	return recordUsageRollup(ctx, db, record, "daily")
+}
+
+// recordUsageRollup adds record to its bucket. Only successful calls add to the timing totals,
+// so failures, timeouts, and blocked requests do not skew average latency.
+func recordUsageRollup(ctx context.Context, db execer, record *model.UsageRecord, granularity string) error {
 	bucketStart := truncateBucket(record.Timestamp, granularity)
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO usage_rollups (tenant_id, granularity, bucket_start, provider, model, project, request_count, input_tokens, output_tokens, cost_usd)
-		 VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, ?)
+	var successCount, latencyMs, ttftMs, ttftCount, generationMs int64
+	if successfulCall(record) {
+		successCount, latencyMs, ttftMs = 1, record.LatencyMs, record.TTFTMs
+		if record.TTFTMs > 0 {
+			ttftCount = 1
+		}
+		if record.OutputTokens > 0 && record.LatencyMs > record.TTFTMs {
+			generationMs = record.LatencyMs - record.TTFTMs
+		}
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO usage_rollups (tenant_id, granularity, bucket_start, provider, model, project, request_count, input_tokens, output_tokens, cost_usd,
+		                           success_count, latency_ms_total, ttft_ms_total, ttft_count, generation_ms_total)
+		 VALUES (?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(tenant_id, granularity, bucket_start, provider, model, project) DO UPDATE SET
 		   request_count = usage_rollups.request_count + 1,
 		   input_tokens = usage_rollups.input_tokens + excluded.input_tokens,
 		   output_tokens = usage_rollups.output_tokens + excluded.output_tokens,
-		   cost_usd = usage_rollups.cost_usd + excluded.cost_usd`,
+		   cost_usd = usage_rollups.cost_usd + excluded.cost_usd,
+		   success_count = usage_rollups.success_count + excluded.success_count,
+		   latency_ms_total = usage_rollups.latency_ms_total + excluded.latency_ms_total,
+		   ttft_ms_total = usage_rollups.ttft_ms_total + excluded.ttft_ms_total,
+		   ttft_count = usage_rollups.ttft_count + excluded.ttft_count,
+		   generation_ms_total = usage_rollups.generation_ms_total + excluded.generation_ms_total`,
 		record.TenantID, granularity, bucketStart, record.Provider, record.Model, record.Project,
 		record.InputTokens, record.OutputTokens, record.CostUSD,
+		successCount, latencyMs, ttftMs, ttftCount, generationMs,
 	)
 	if err != nil {
 		return fmt.Errorf("record %s rollup: %w", granularity, err)
@@ -611,6 +1571,11 @@ func (s *SQLite) recordUsageRollup(ctx context.Context, record *model.UsageRecor
 	return nil
 }
 
+// successfulCall reports whether record is a call the provider completed without error.
+func successfulCall(record *model.UsageRecord) bool {
+	return record.ErrorType == "" && record.CompletionStatus == model.CompletionStatusComplete
+}
+
 func buildWhereClause(filter model.ReportFilter, usageAlias, tenantAlias string) (string, []any) {
 	var conditions []string
 	var args []any
@@ -631,6 +1596,35 @@ func buildWhereClause(filter model.ReportFilter, usageAlias, tenantAlias string)
 		conditions = append(conditions, usageAlias+".project = ?")
 		args = append(args, filter.Project)
 	}
+	if filter.CompletionStatus != "" {
+		conditions = append(conditions, usageAlias+".completion_status = ?")
+		args = append(args, filter.CompletionStatus)
+	}
+	if filter.IdempotencyKey != "" {
+		conditions = append(conditions, usageAlias+".idempotency_key = ?")
+		args = append(args, filter.IdempotencyKey)
+	}
+	if filter.User != "" {
+		conditions = append(conditions, usageAlias+".end_user = ?")
+		args = append(args, filter.User)
+	}
+	if filter.PolicyAction != "" {
+		conditions = append(conditions, usageAlias+".policy_action = ?")
+		args = append(args, filter.PolicyAction)
+	}
+	if filter.APIKeyID != "" {
+		conditions = append(conditions, usageAlias+".api_key_id = ?")
+		args = append(args, filter.APIKeyID)
+	}
+	tagKeys := make([]string, 0, len(filter.Tags))
+	for key := range filter.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM usage_tags tag WHERE tag.usage_id = "+usageAlias+".id AND tag.key = ? AND tag.value = ?)")
+		args = append(args, strings.ToLower(key), filter.Tags[key])
+	}
 	if !filter.StartTime.IsZero() {
 		conditions = append(conditions, usageAlias+".timestamp >= ?")
 		args = append(args, filter.StartTime)
@@ -643,6 +1637,24 @@ func buildWhereClause(filter model.ReportFilter, usageAlias, tenantAlias string)
 	return strings.Join(conditions, " AND "), args
 }
 
+// joinList stores a string list as comma-separated text, dropping blank entries.
+func joinList(values []string) string {
+	kept := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+func splitList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
+
 func normalizeTenantSlug(slug string) string {
 	slug = strings.ToLower(strings.TrimSpace(slug))
 	slug = strings.ReplaceAll(slug, " ", "-")
@@ -676,3 +1688,17 @@ func truncateBucket(ts time.Time, granularity string) time.Time {
 		return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
 	}
 }
+
+// parseSQLiteTime parses a DATETIME value returned by an aggregate, which loses the column
+// type and comes back as the text the driver stored.
+func parseSQLiteTime(value string) time.Time {
+	if i := strings.Index(value, " m="); i >= 0 {
+		value = value[:i]
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999 -0700 MST", time.RFC3339Nano, time.DateTime} {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts.UTC()
+		}
+	}
+	return time.Time{}
+}
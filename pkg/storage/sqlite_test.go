@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"context"
+	"database/sql"
 	"path/filepath"
 	"testing"
 	"time"
@@ -131,6 +132,32 @@ func TestSQLite_AggregateUsage(t *testing.T) {
 	assert.InDelta(t, 3.00, summary.ByModel["gpt-4o"], 0.001)
 }
 
+func TestSQLite_CompletionStatus(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	records := []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, CostUSD: 0.002, Project: "proj-a"},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 10, CostUSD: 0.001, Project: "proj-a", CompletionStatus: model.CompletionStatusClientAborted},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 5, CostUSD: 0.0005, Project: "proj-a", CompletionStatus: model.CompletionStatusTimeout},
+	}
+	for _, r := range records {
+		require.NoError(t, db.RecordUsage(ctx, r))
+	}
+	assert.Equal(t, model.CompletionStatusComplete, records[0].CompletionStatus)
+
+	aborted, err := db.QueryUsage(ctx, model.ReportFilter{CompletionStatus: model.CompletionStatusClientAborted})
+	require.NoError(t, err)
+	require.Len(t, aborted, 1)
+	assert.Equal(t, int64(10), aborted[0].OutputTokens)
+
+	summary, err := db.AggregateUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.002, summary.ByCompletionStatus[model.CompletionStatusComplete], 1e-9)
+	assert.InDelta(t, 0.001, summary.ByCompletionStatus[model.CompletionStatusClientAborted], 1e-9)
+	assert.InDelta(t, 0.0005, summary.ByCompletionStatus[model.CompletionStatusTimeout], 1e-9)
+}
+
 func TestSQLite_Budget(t *testing.T) {
 	db := newTestDB(t)
 	ctx := context.Background()
@@ -352,3 +379,597 @@ func TestSQLite_QueryUsageRollups(t *testing.T) {
 	assert.Equal(t, "proj-a", daily[0].Project)
 	assert.Equal(t, "proj-b", daily[1].Project)
 }
+
+func TestSQLite_LatencyPercentilesAndRollups(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, time.January, 15, 9, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= 100; i++ {
+		record := &model.UsageRecord{
+			Tenant:       "default",
+			Provider:     "openai",
+			Model:        "gpt-4o",
+			InputTokens:  10,
+			OutputTokens: 100,
+			Project:      "proj-a",
+			Timestamp:    base.Add(time.Duration(i) * time.Second),
+			LatencyMs:    int64(i * 10),
+		}
+		if i%2 == 0 {
+			record.TTFTMs = int64(i)
+		}
+		record.OutputTokensPerSec = float64(record.OutputTokens) * 1000 / float64(record.LatencyMs-record.TTFTMs)
+		require.NoError(t, db.RecordUsage(ctx, record))
+	}
+	// Failed calls must not skew latency percentiles.
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{
+		Tenant:    "default",
+		Provider:  "openai",
+		Model:     "gpt-4o",
+		Project:   "proj-a",
+		Timestamp: base,
+		LatencyMs: 60000,
+		ErrorType: "timeout",
+	}))
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{
+		Tenant:           "default",
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		Project:          "proj-a",
+		Timestamp:        base,
+		LatencyMs:        30000,
+		TTFTMs:           400,
+		OutputTokens:     10,
+		CompletionStatus: model.CompletionStatusClientAborted,
+	}))
+
+	summary, err := db.AggregateUsage(ctx, model.ReportFilter{Tenant: "default"})
+	require.NoError(t, err)
+	require.Len(t, summary.Latency, 1)
+	stats := summary.Latency[0]
+	assert.Equal(t, "openai", stats.Provider)
+	assert.Equal(t, int64(100), stats.RequestCount)
+	assert.Equal(t, int64(500), stats.LatencyP50Ms)
+	assert.Equal(t, int64(950), stats.LatencyP95Ms)
+	assert.Equal(t, int64(990), stats.LatencyP99Ms)
+	assert.Equal(t, int64(50), stats.StreamingCount)
+	assert.Equal(t, int64(50), stats.TTFTP50Ms)
+	assert.Equal(t, int64(96), stats.TTFTP95Ms)
+	assert.Equal(t, int64(100), stats.TTFTP99Ms)
+	assert.Greater(t, stats.OutputTokensPerSecP50, 0.0)
+
+	hourly, err := db.QueryUsageRollups(ctx, model.ReportFilter{Tenant: "default"}, "hourly", base, base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hourly, 1)
+	assert.Equal(t, int64(102), hourly[0].RequestCount)
+	assert.Equal(t, int64(100), hourly[0].SuccessCount)
+	assert.Equal(t, int64(50500), hourly[0].LatencyMsTotal)
+	assert.Equal(t, int64(2550), hourly[0].TTFTMsTotal)
+	assert.Equal(t, int64(50), hourly[0].TTFTCount)
+	assert.Equal(t, int64(50500-2550), hourly[0].GenerationMsTotal)
+}
+
+func TestSQLite_RecordUsageBatchSkipsKnownIDs(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, time.January, 15, 9, 30, 0, 0, time.UTC)
+
+	records := []*model.UsageRecord{
+		{ID: "batch-1", Provider: "openai", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, CostUSD: 1.00, Project: "proj-a", Timestamp: base},
+		{ID: "batch-2", Provider: "openai", Model: "gpt-4o", InputTokens: 200, OutputTokens: 75, CostUSD: 2.00, Project: "proj-a", Timestamp: base},
+	}
+	inserted, err := db.RecordUsageBatch(ctx, records)
+	require.NoError(t, err)
+	assert.Len(t, inserted, 2)
+	assert.Equal(t, "default", records[0].Tenant)
+
+	// Replaying the same IDs alongside a new record only inserts the new one.
+	replay := []*model.UsageRecord{
+		{ID: "batch-2", Provider: "openai", Model: "gpt-4o", InputTokens: 200, OutputTokens: 75, CostUSD: 2.00, Project: "proj-a", Timestamp: base},
+		{ID: "batch-3", Provider: "openai", Model: "gpt-4o", InputTokens: 300, OutputTokens: 25, CostUSD: 3.00, Project: "proj-a", Timestamp: base},
+	}
+	inserted, err = db.RecordUsageBatch(ctx, replay)
+	require.NoError(t, err)
+	require.Len(t, inserted, 1)
+	assert.Equal(t, "batch-3", inserted[0].ID)
+
+	stored, err := db.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Len(t, stored, 3)
+
+	hourly, err := db.QueryUsageRollups(ctx, model.ReportFilter{Tenant: "default"}, "hourly", base.Add(-time.Hour), base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, hourly, 1)
+	assert.Equal(t, int64(3), hourly[0].RequestCount)
+	assert.InDelta(t, 6.00, hourly[0].CostUSD, 0.001)
+}
+
+func TestSQLite_DuplicateIdempotentRequests(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, time.January, 15, 9, 30, 0, 0, time.UTC)
+
+	records := []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 0.50, IdempotencyKey: "retry-1", Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 0.75, IdempotencyKey: "retry-1", Timestamp: base.Add(time.Second)},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 0.25, IdempotencyKey: "retry-1", Timestamp: base.Add(2 * time.Second)},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 1.00, IdempotencyKey: "retry-2", Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 2.00, Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 2.00, Timestamp: base},
+	}
+	for _, r := range records {
+		require.NoError(t, db.RecordUsage(ctx, r))
+	}
+
+	keyed, err := db.QueryUsage(ctx, model.ReportFilter{IdempotencyKey: "retry-1"})
+	require.NoError(t, err)
+	assert.Len(t, keyed, 3)
+
+	summary, err := db.AggregateUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), summary.DuplicateRequests)
+	assert.InDelta(t, 1.00, summary.DuplicateCostUSD, 0.001)
+}
+
+func TestSQLite_TopUsers(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	base := time.Date(2026, time.January, 15, 9, 30, 0, 0, time.UTC)
+
+	records := []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 100, CostUSD: 1.00, User: "alice", Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 200, CostUSD: 2.00, User: "alice", Timestamp: base.Add(time.Hour)},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 300, CostUSD: 4.00, User: "bob", Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 400, CostUSD: 0.50, User: "carol", Timestamp: base},
+		{Provider: "openai", Model: "gpt-4o", InputTokens: 500, CostUSD: 9.00, Timestamp: base},
+	}
+	for _, r := range records {
+		require.NoError(t, db.RecordUsage(ctx, r))
+	}
+
+	users, err := db.TopUsers(ctx, model.ReportFilter{}, 2)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "bob", users[0].User)
+	assert.Equal(t, "alice", users[1].User)
+	assert.Equal(t, int64(2), users[1].RequestCount)
+	assert.Equal(t, int64(300), users[1].InputTokens)
+	assert.InDelta(t, 3.00, users[1].CostUSD, 0.001)
+	assert.Equal(t, base.Add(time.Hour), users[1].LastSeen)
+
+	alice, err := db.QueryUsage(ctx, model.ReportFilter{User: "alice"})
+	require.NoError(t, err)
+	assert.Len(t, alice, 2)
+}
+
+func TestSQLite_UsageTags(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	records := []*model.UsageRecord{
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 1.00, Tags: map[string]string{"feature": "search", "env": "prod"}},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 2.00, Tags: map[string]string{"feature": "chat", "env": "prod"}},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 4.00, Tags: map[string]string{"feature": "search", "env": "dev"}},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 8.00},
+	}
+	for _, r := range records {
+		require.NoError(t, db.RecordUsage(ctx, r))
+	}
+
+	search, err := db.QueryUsage(ctx, model.ReportFilter{Tags: map[string]string{"feature": "search"}})
+	require.NoError(t, err)
+	require.Len(t, search, 2)
+	for _, r := range search {
+		assert.Equal(t, "search", r.Tags["feature"])
+	}
+
+	prodSearch, err := db.QueryUsage(ctx, model.ReportFilter{Tags: map[string]string{"feature": "search", "env": "prod"}})
+	require.NoError(t, err)
+	require.Len(t, prodSearch, 1)
+	assert.Equal(t, map[string]string{"feature": "search", "env": "prod"}, prodSearch[0].Tags)
+
+	summary, err := db.AggregateUsage(ctx, model.ReportFilter{GroupByTag: "feature"})
+	require.NoError(t, err)
+	assert.InDelta(t, 5.00, summary.ByTag["search"], 0.001)
+	assert.InDelta(t, 2.00, summary.ByTag["chat"], 0.001)
+	assert.InDelta(t, 8.00, summary.ByTag[""], 0.001)
+}
+
+func TestSQLite_BudgetTags(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.SetBudget(ctx, &model.Budget{
+		Name:     "search-prod",
+		LimitUSD: 50,
+		Period:   model.PeriodMonthly,
+		Tags:     map[string]string{"feature": "search", "env": "prod"},
+	}))
+
+	budget, err := db.GetBudget(ctx, "search-prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"feature": "search", "env": "prod"}, budget.Tags)
+
+	budgets, err := db.ListBudgets(ctx)
+	require.NoError(t, err)
+	require.Len(t, budgets, 1)
+	assert.Equal(t, "search", budgets[0].Tags["feature"])
+}
+
+func TestSQLite_ModelPolicies(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	policy := &model.ModelPolicy{
+		Tenant:           "acme",
+		Name:             "mini-only",
+		Project:          "chat",
+		AllowedProviders: []string{"openai", " "},
+		AllowedModels:    []string{"gpt-4o-mini*", "o3-mini"},
+		MaxTokens:        512,
+		DenyStreaming:    true,
+	}
+	require.NoError(t, db.SetModelPolicy(ctx, policy))
+	id := policy.ID
+
+	update := &model.ModelPolicy{Tenant: "acme", Name: "mini-only", AllowedModels: []string{"gpt-4o-mini"}}
+	require.NoError(t, db.SetModelPolicy(ctx, update))
+	assert.Equal(t, id, update.ID)
+	require.NoError(t, db.SetModelPolicy(ctx, &model.ModelPolicy{Tenant: "other", Name: "mini-only"}))
+
+	policies, err := db.ListModelPolicies(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, "acme", policies[0].Tenant)
+	assert.Empty(t, policies[0].Project)
+	assert.Nil(t, policies[0].AllowedProviders)
+	assert.Equal(t, []string{"gpt-4o-mini"}, policies[0].AllowedModels)
+	assert.False(t, policies[0].DenyStreaming)
+
+	all, err := db.ListModelPolicies(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	require.NoError(t, db.DeleteModelPolicy(ctx, "acme", "mini-only"))
+	assert.ErrorIs(t, db.DeleteModelPolicy(ctx, "acme", "mini-only"), storage.ErrNotFound)
+	all, err = db.ListModelPolicies(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func TestSQLite_APIKeyLimits(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	expiresAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	key := &model.APIKey{
+		Tenant:          "acme",
+		Name:            "ci",
+		KeyPrefix:       "lcg_ci",
+		KeyHash:         "hash-ci",
+		ExpiresAt:       &expiresAt,
+		Role:            model.RoleProxyOnly,
+		SpendLimitUSD:   25,
+		SpendPeriod:     model.PeriodDaily,
+		AllowedProjects: []string{"chat", "search"},
+	}
+	require.NoError(t, db.CreateAPIKey(ctx, key))
+
+	keys, err := db.ListAPIKeys(ctx, "acme")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].ExpiresAt)
+	assert.True(t, expiresAt.Equal(*keys[0].ExpiresAt))
+	assert.Equal(t, model.RoleProxyOnly, keys[0].Role)
+	assert.False(t, keys[0].AllTenants)
+	assert.Equal(t, 25.0, keys[0].SpendLimitUSD)
+	assert.Equal(t, model.PeriodDaily, keys[0].SpendPeriod)
+	assert.Equal(t, []string{"chat", "search"}, keys[0].AllowedProjects)
+
+	resolved, _, err := db.ResolveAPIKey(ctx, "hash-ci")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chat", "search"}, resolved.AllowedProjects)
+	assert.True(t, resolved.AllowsProject("chat"))
+	assert.False(t, resolved.AllowsProject("batch"))
+
+	defaults := &model.APIKey{Tenant: "acme", Name: "plain", KeyHash: "hash-plain"}
+	require.NoError(t, db.CreateAPIKey(ctx, defaults))
+	assert.Equal(t, model.RoleDeveloper, defaults.Role)
+	assert.Equal(t, model.PeriodMonthly, defaults.SpendPeriod)
+	assert.True(t, defaults.AllowsProject("anything"))
+
+	// Expired keys still resolve so the caller can reject them, but are not marked as used.
+	expired := time.Now().UTC().Add(-time.Minute)
+	require.NoError(t, db.CreateAPIKey(ctx, &model.APIKey{Tenant: "acme", Name: "old", KeyHash: "hash-old", ExpiresAt: &expired}))
+	resolved, _, err = db.ResolveAPIKey(ctx, "hash-old")
+	require.NoError(t, err)
+	assert.True(t, resolved.Expired(time.Now().UTC()))
+	assert.Nil(t, resolved.LastUsedAt)
+
+	err = db.CreateAPIKey(ctx, &model.APIKey{Tenant: "acme", Name: "bad", KeyHash: "hash-bad", Role: "root"})
+	assert.ErrorContains(t, err, `invalid api key role "root"`)
+
+	require.NoError(t, db.SetAPIKeyRole(ctx, key.ID, model.RoleFinanceViewer, true))
+	resolved, _, err = db.ResolveAPIKey(ctx, "hash-ci")
+	require.NoError(t, err)
+	assert.Equal(t, model.RoleFinanceViewer, resolved.Role)
+	assert.True(t, resolved.AllTenants)
+	assert.ErrorContains(t, db.SetAPIKeyRole(ctx, key.ID, "root", false), `invalid api key role "root"`)
+	assert.ErrorContains(t, db.SetAPIKeyRole(ctx, "missing", model.RoleAdmin, false), "not found")
+
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{
+		ID: "u-ci", Tenant: "acme", Provider: "openai", Model: "gpt-4o", CostUSD: 1.5,
+		Project: "chat", APIKeyID: key.ID, Timestamp: time.Now().UTC(),
+	}))
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{
+		ID: "u-other", Tenant: "acme", Provider: "openai", Model: "gpt-4o", CostUSD: 2,
+		Project: "chat", Timestamp: time.Now().UTC(),
+	}))
+	records, err := db.QueryUsage(ctx, model.ReportFilter{APIKeyID: key.ID})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, key.ID, records[0].APIKeyID)
+
+	now := time.Now().UTC()
+	spent, err := db.APIKeySpend(ctx, []string{key.ID}, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, spent)
+}
+
+func TestSQLite_APIKeySpend(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for _, r := range []struct {
+		id, key string
+		cost    float64
+		at      time.Time
+	}{
+		{"u-new", "key-new", 1, now},
+		{"u-old", "key-old", 2, now.Add(-time.Minute)},
+		{"u-stale", "key-old", 4, now.Add(-48 * time.Hour)},
+		{"u-other", "key-other", 8, now},
+	} {
+		require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{
+			ID: r.id, Tenant: "acme", Provider: "openai", Model: "gpt-4o",
+			CostUSD: r.cost, APIKeyID: r.key, Timestamp: r.at,
+		}))
+	}
+
+	// The predecessor's spend in the window counts; older usage and other keys do not.
+	spent, err := db.APIKeySpend(ctx, []string{"key-new", "key-old"}, now.Add(-24*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, spent)
+
+	spent, err = db.APIKeySpend(ctx, []string{"key-new"}, now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	assert.Zero(t, spent, "the end of the window is exclusive")
+
+	spent, err = db.APIKeySpend(ctx, nil, now.Add(-24*time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, spent)
+}
+
+func TestSQLite_AuditEvents(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "audit.db")
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := storage.WithActor(context.Background(), "user:dana@example.com", model.AuditSourceAPI)
+
+	require.NoError(t, db.SetBudget(ctx, &model.Budget{Name: "prod", Tenant: "acme", LimitUSD: 100, Period: model.PeriodMonthly}))
+	require.NoError(t, db.UpdateBudgetSpend(ctx, "prod", 40))
+	require.NoError(t, db.SetBudget(ctx, &model.Budget{Name: "prod", Tenant: "acme", LimitUSD: 200, Period: model.PeriodMonthly}))
+	require.NoError(t, db.SetModelPolicy(ctx, &model.ModelPolicy{Name: "mini", Tenant: "acme", AllowedModels: []string{"gpt-4o-mini"}}))
+	require.NoError(t, db.DeleteModelPolicy(ctx, "acme", "mini"))
+
+	budget, err := db.GetBudget(ctx, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, budget.CurrentSpend, "updating a budget keeps its spend")
+
+	events, err := db.ListAuditEvents(ctx, model.AuditFilter{Tenant: "acme"})
+	require.NoError(t, err)
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		assert.Equal(t, "user:dana@example.com", event.Actor)
+		assert.Equal(t, model.AuditSourceAPI, event.Source)
+		assert.Equal(t, "acme", event.Tenant)
+	}
+	// Newest first; spend updates are usage, not administrative changes.
+	assert.Equal(t, []string{"model_policy.delete", "model_policy.create", "budget.update", "budget.create", "tenant.create"}, actions)
+
+	update := events[2]
+	assert.Equal(t, "budget", update.ResourceType)
+	assert.Equal(t, budget.ID, update.ResourceID)
+	assert.Contains(t, string(update.Before), `"limit_usd":100`)
+	assert.Contains(t, string(update.After), `"limit_usd":200`)
+	assert.Empty(t, events[3].Before)
+	assert.Empty(t, events[0].After)
+
+	limited, err := db.ListAuditEvents(ctx, model.AuditFilter{ResourceType: "budget", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, "budget.update", limited[0].Action)
+
+	// Changes without an actor are the system's.
+	require.NoError(t, db.DisableTenant(context.Background(), "acme"))
+	system, err := db.ListAuditEvents(ctx, model.AuditFilter{Source: model.AuditSourceSystem})
+	require.NoError(t, err)
+	require.Len(t, system, 1)
+	assert.Equal(t, "tenant.disable", system[0].Action)
+	assert.Equal(t, model.AuditSourceSystem, system[0].Actor)
+	assert.Contains(t, string(system[0].After), `"status":"disabled"`)
+
+	// The table rejects changes to recorded events.
+	raw, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { raw.Close() })
+	_, err = raw.Exec(`UPDATE audit_events SET actor = 'someone-else'`)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = raw.Exec(`DELETE FROM audit_events`)
+	assert.ErrorContains(t, err, "append-only")
+}
+
+func TestSQLite_TenantLifecycle(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := storage.NewSQLite(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	require.NoError(t, db.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	assert.ErrorIs(t, db.CreateTenant(ctx, &model.Tenant{Slug: "acme"}), storage.ErrExists)
+	require.NoError(t, db.DisableTenant(ctx, "acme"))
+	require.NoError(t, db.EnableTenant(ctx, "acme"))
+	enabled, err := db.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, model.TenantStatusActive, enabled.Status)
+
+	renamed, err := db.RenameTenant(ctx, "acme", "acme-corp", "Acme Corporation")
+	require.NoError(t, err)
+	assert.Equal(t, enabled.ID, renamed.ID)
+	assert.Equal(t, "acme-corp", renamed.Slug)
+	assert.Equal(t, "Acme Corporation", renamed.Name)
+	_, err = db.GetTenant(ctx, "acme")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = db.RenameTenant(ctx, "acme-corp", "default", "")
+	assert.ErrorIs(t, err, storage.ErrExists)
+	assert.ErrorContains(t, err, `tenant "default" already exists`)
+	_, err = db.RenameTenant(ctx, "default", "other", "")
+	assert.ErrorIs(t, err, storage.ErrDefaultTenant)
+	assert.ErrorContains(t, err, "cannot change the slug of the default tenant")
+
+	key := &model.APIKey{Tenant: "acme-corp", Name: "ci", KeyPrefix: "lcg_ci", KeyHash: "ci-hash"}
+	require.NoError(t, db.CreateAPIKey(ctx, key))
+	require.NoError(t, db.SetBudget(ctx, &model.Budget{Name: "acme-monthly", Tenant: "acme-corp", LimitUSD: 100, Period: model.PeriodMonthly}))
+	require.NoError(t, db.SetModelPolicy(ctx, &model.ModelPolicy{Tenant: "acme-corp", Name: "no-gpt4", DeniedModels: []string{"gpt-4*"}}))
+	require.NoError(t, db.SetTenantSettings(ctx, &model.TenantSettings{Tenant: "acme-corp", WebhookSecret: model.Ptr("s3cret")}))
+	record := &model.UsageRecord{Tenant: "acme-corp", Provider: "openai", Model: "gpt-4o", CostUSD: 1.5, APIKeyID: key.ID, Tags: map[string]string{"env": "prod"}}
+	require.NoError(t, db.RecordUsage(ctx, record))
+	require.NoError(t, db.SaveTranscript(ctx, &model.SealedTranscript{UsageID: record.ID, TenantID: renamed.ID, Ciphertext: []byte("sealed"), CreatedAt: time.Now().UTC()}))
+	require.NoError(t, db.RecordUsage(ctx, &model.UsageRecord{Provider: "openai", Model: "gpt-4o", CostUSD: 2}))
+
+	export, err := db.ExportTenant(ctx, "acme-corp")
+	require.NoError(t, err)
+	assert.Equal(t, "acme-corp", export.Tenant.Slug)
+	require.Len(t, export.Usage, 1)
+	assert.Equal(t, map[string]string{"env": "prod"}, export.Usage[0].Tags)
+	require.Len(t, export.Budgets, 1)
+	require.Len(t, export.APIKeys, 1)
+	require.Len(t, export.ModelPolicies, 1)
+	assert.Equal(t, model.RedactedValue, *export.Settings.WebhookSecret)
+
+	assert.ErrorContains(t, db.DeleteTenant(ctx, "default"), "cannot delete the default tenant")
+	require.NoError(t, db.DeleteTenant(ctx, "acme-corp"))
+	_, err = db.GetTenant(ctx, "acme-corp")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, _, err = db.ResolveAPIKey(ctx, "ci-hash")
+	assert.Error(t, err)
+
+	raw, err := sql.Open("sqlite", dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { raw.Close() })
+	for _, table := range []string{"usage_records", "usage_rollups", "usage_transcripts", "budgets", "model_policies", "tenant_settings", "api_keys", "tenants"} {
+		column := "tenant_id"
+		if table == "tenants" {
+			column = "id"
+		}
+		var count int
+		require.NoError(t, raw.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE `+column+` = ?`, renamed.ID).Scan(&count))
+		assert.Zero(t, count, table)
+	}
+	var tagCount int
+	require.NoError(t, raw.QueryRow(`SELECT COUNT(*) FROM usage_tags WHERE usage_id = ?`, record.ID).Scan(&tagCount))
+	assert.Zero(t, tagCount)
+
+	// Other tenants keep their data.
+	usage, err := db.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "default", usage[0].Tenant)
+
+	// Usage still queued for the deleted tenant is dropped instead of re-creating the tenant.
+	late := []*model.UsageRecord{
+		{TenantID: renamed.ID, Tenant: "acme-corp", Provider: "openai", Model: "gpt-4o", CostUSD: 3},
+		{Provider: "openai", Model: "gpt-4o", CostUSD: 4},
+	}
+	inserted, err := db.RecordUsageBatch(ctx, late)
+	var dropped *storage.DroppedUsageError
+	require.ErrorAs(t, err, &dropped)
+	assert.ErrorIs(t, err, storage.ErrTenantDeleted)
+	assert.Equal(t, []*model.UsageRecord{late[0]}, dropped.Records)
+	require.Len(t, inserted, 1)
+	assert.Equal(t, late[1].ID, inserted[0].ID)
+	err = db.RecordUsage(ctx, &model.UsageRecord{TenantID: renamed.ID, Tenant: "acme-corp", Provider: "openai", Model: "gpt-4o"})
+	assert.ErrorIs(t, err, storage.ErrTenantDeleted)
+	_, err = db.GetTenant(ctx, "acme-corp")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// The audit log outlives the tenant.
+	events, err := db.ListAuditEvents(ctx, model.AuditFilter{ResourceID: renamed.ID})
+	require.NoError(t, err)
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+	}
+	assert.Equal(t, []string{"tenant.delete", "tenant_settings.update", "tenant.rename", "tenant.enable", "tenant.disable", "tenant.create"}, actions)
+	assert.Contains(t, string(events[0].Before), `"slug":"acme-corp"`)
+	assert.Empty(t, events[0].After)
+}
+
+func TestSQLite_TenantSettings(t *testing.T) {
+	db, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	_, err = db.GetTenantSettings(ctx, "acme")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, db.SetTenantSettings(ctx, &model.TenantSettings{Tenant: "acme"}), storage.ErrNotFound)
+
+	require.NoError(t, db.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	settings, err := db.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Nil(t, settings.DenyOnExceed, "a tenant without settings inherits everything")
+	assert.True(t, settings.UpdatedAt.IsZero())
+
+	require.NoError(t, db.SetTenantSettings(ctx, &model.TenantSettings{
+		Tenant:            "acme",
+		DenyOnExceed:      model.Ptr(false),
+		AlertThresholdPct: model.Ptr(60.0),
+		WebhookURL:        model.Ptr(""),
+		WebhookSecret:     model.Ptr("s3cret"),
+	}))
+	settings, err = db.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", settings.Tenant)
+	require.NotNil(t, settings.DenyOnExceed)
+	assert.False(t, *settings.DenyOnExceed)
+	assert.Equal(t, 60.0, *settings.AlertThresholdPct)
+	assert.Equal(t, "", *settings.WebhookURL, "an empty override is kept, not inherited")
+	assert.Equal(t, "s3cret", *settings.WebhookSecret)
+	assert.Nil(t, settings.AddCostHeaders)
+	assert.Nil(t, settings.DefaultProject)
+	assert.False(t, settings.UpdatedAt.IsZero())
+
+	settings.Unset("deny_on_exceed")
+	settings.DefaultProject = model.Ptr("acme-app")
+	require.NoError(t, db.SetTenantSettings(ctx, settings))
+	updated, err := db.GetTenantSettings(ctx, "acme")
+	require.NoError(t, err)
+	assert.Nil(t, updated.DenyOnExceed)
+	assert.Equal(t, "acme-app", *updated.DefaultProject)
+
+	events, err := db.ListAuditEvents(ctx, model.AuditFilter{ResourceType: "tenant_settings"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "tenant_settings.update", events[0].Action)
+	assert.Equal(t, settings.TenantID, events[0].ResourceID)
+	assert.Contains(t, string(events[0].Before), `"deny_on_exceed":false`)
+	assert.NotContains(t, string(events[0].After), "deny_on_exceed")
+	assert.NotContains(t, string(events[0].After), "s3cret")
+	assert.Contains(t, string(events[0].After), `"webhook_secret":"[redacted]"`)
+}
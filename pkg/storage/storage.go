@@ -2,22 +2,61 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 )
 
-// Storage defines the persistence layer for usage records and budgets.
+var (
+	// ErrNotFound is wrapped by lookups whose row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrExists is wrapped by creations whose row already exists.
+	ErrExists = errors.New("already exists")
+	// ErrDefaultTenant is wrapped by changes the default tenant does not allow.
+	ErrDefaultTenant = errors.New("default tenant")
+	// ErrTenantDeleted is wrapped by writes for a tenant id that no longer exists, such as
+	// usage still queued when the tenant was deleted.
+	ErrTenantDeleted = errors.New("tenant deleted")
+)
+
+// DroppedUsageError is returned by RecordUsageBatch together with the inserted records when
+// some records were dropped because their tenant was deleted. The rest of the batch is stored.
+type DroppedUsageError struct {
+	Records []*model.UsageRecord
+}
+
+func (e *DroppedUsageError) Error() string {
+	return fmt.Sprintf("dropped %d usage records: %v", len(e.Records), ErrTenantDeleted)
+}
+
+func (e *DroppedUsageError) Unwrap() error {
+	return ErrTenantDeleted
+}
+
+// Storage defines the persistence layer for usage records and budgets. Every method that
+// changes budgets, tenants, tenant settings, API keys, or model policies appends an audit
+// event in the same transaction, attributed to the actor set on the context with WithActor.
 type Storage interface {
-	// RecordUsage persists a single usage record.
+	// RecordUsage persists a single usage record. It wraps ErrTenantDeleted when the record's
+	// TenantID no longer exists.
 	RecordUsage(ctx context.Context, record *model.UsageRecord) error
 
+	// RecordUsageBatch persists records in one transaction and returns the ones that were
+	// inserted. Records whose ID already exists are skipped. Records whose TenantID no longer
+	// exists are dropped and reported in a *DroppedUsageError.
+	RecordUsageBatch(ctx context.Context, records []*model.UsageRecord) ([]*model.UsageRecord, error)
+
 	// QueryUsage retrieves usage records matching the given filter.
 	QueryUsage(ctx context.Context, filter model.ReportFilter) ([]model.UsageRecord, error)
 
 	// AggregateUsage returns total cost and tokens for a time range.
 	AggregateUsage(ctx context.Context, filter model.ReportFilter) (*model.UsageSummary, error)
 
+	// TopUsers returns the end users with the highest spend, up to limit when it is positive.
+	TopUsers(ctx context.Context, filter model.ReportFilter, limit int) ([]model.UserSpend, error)
+
 	// SetBudget creates or updates a budget.
 	SetBudget(ctx context.Context, budget *model.Budget) error
 
@@ -45,6 +84,28 @@ type Storage interface {
 	// DisableTenant disables a tenant.
 	DisableTenant(ctx context.Context, slug string) error
 
+	// EnableTenant re-enables a disabled tenant.
+	EnableTenant(ctx context.Context, slug string) error
+
+	// RenameTenant changes a tenant's slug and display name; empty values keep the current
+	// ones. The default tenant keeps its slug.
+	RenameTenant(ctx context.Context, slug, newSlug, name string) (*model.Tenant, error)
+
+	// ExportTenant returns the tenant with its usage, budgets, API keys, and model policies.
+	ExportTenant(ctx context.Context, slug string) (*model.TenantExport, error)
+
+	// DeleteTenant deletes a tenant and purges every row stored for it, including usage,
+	// rollups, transcripts, budgets, API keys, model policies, and settings. Audit events are
+	// kept. The default tenant cannot be deleted.
+	DeleteTenant(ctx context.Context, slug string) error
+
+	// GetTenantSettings returns the tenant's overrides of the global configuration, with nil
+	// fields for settings it inherits. It wraps ErrNotFound when the tenant does not exist.
+	GetTenantSettings(ctx context.Context, tenant string) (*model.TenantSettings, error)
+
+	// SetTenantSettings replaces the overrides of the tenant named by settings.Tenant.
+	SetTenantSettings(ctx context.Context, settings *model.TenantSettings) error
+
 	// CreateAPIKey stores a hashed API key.
 	CreateAPIKey(ctx context.Context, key *model.APIKey) error
 
@@ -54,12 +115,54 @@ type Storage interface {
 	// RevokeAPIKey revokes a key by id.
 	RevokeAPIKey(ctx context.Context, id string) error
 
+	// GetAPIKey returns a key by id.
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+
+	// RotateAPIKey stores successor as the replacement of the active key id, copying the old
+	// key's tenant, name, role, spend cap, and projects. The old key stays valid until
+	// graceEndsAt, or its own earlier expiry, and is returned updated.
+	RotateAPIKey(ctx context.Context, id string, successor *model.APIKey, graceEndsAt time.Time) (*model.APIKey, error)
+
+	// RevokeRotatedAPIKeys revokes the rotated keys whose grace period ended by now and
+	// returns them.
+	RevokeRotatedAPIKeys(ctx context.Context, now time.Time) ([]model.APIKey, error)
+
+	// SetAPIKeyRole changes the role of a key and whether it applies to every tenant.
+	SetAPIKeyRole(ctx context.Context, id, role string, allTenants bool) error
+
+	// APIKeySpend returns the cost of the usage recorded by the given keys in [start, end).
+	APIKeySpend(ctx context.Context, ids []string, start, end time.Time) (float64, error)
+
 	// ResolveAPIKey returns an active API key and tenant by hash and updates last_used_at.
+	// Expired keys are returned without being touched; callers must check APIKey.Expired.
 	ResolveAPIKey(ctx context.Context, keyHash string) (*model.APIKey, *model.Tenant, error)
 
+	// SetModelPolicy creates or updates a model policy, matched by tenant and name.
+	SetModelPolicy(ctx context.Context, policy *model.ModelPolicy) error
+
+	// ListModelPolicies returns model policies, optionally filtered by tenant slug.
+	ListModelPolicies(ctx context.Context, tenant string) ([]model.ModelPolicy, error)
+
+	// DeleteModelPolicy deletes a tenant's model policy by name, wrapping ErrNotFound when
+	// it does not exist.
+	DeleteModelPolicy(ctx context.Context, tenant, name string) error
+
 	// QueryUsageRollups returns aggregated hourly or daily usage buckets.
 	QueryUsageRollups(ctx context.Context, filter model.ReportFilter, granularity string, start, end time.Time) ([]model.UsageRollup, error)
 
+	// SaveTranscript stores or replaces the sealed transcript of a usage record.
+	SaveTranscript(ctx context.Context, transcript *model.SealedTranscript) error
+
+	// GetTranscript returns the sealed transcript of a usage record, wrapping ErrNotFound when
+	// none was captured.
+	GetTranscript(ctx context.Context, usageID string) (*model.SealedTranscript, error)
+
+	// PurgeTranscripts deletes transcripts captured before the cutoff and returns how many were removed.
+	PurgeTranscripts(ctx context.Context, before time.Time) (int64, error)
+
+	// ListAuditEvents returns audit events matching filter, newest first.
+	ListAuditEvents(ctx context.Context, filter model.AuditFilter) ([]model.AuditEvent, error)
+
 	// Close releases resources.
 	Close() error
 }
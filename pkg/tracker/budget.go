@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 )
 
@@ -14,6 +15,7 @@ import (
 type BudgetManager struct {
 	storage   storage.Storage
 	notifiers []alerts.Notifier
+	defaults  model.TenantConfig
 	logger    *slog.Logger
 }
 
@@ -22,17 +24,27 @@ func NewBudgetManager(store storage.Storage, notifiers []alerts.Notifier, logger
 	return &BudgetManager{
 		storage:   store,
 		notifiers: notifiers,
+		defaults:  DefaultTenantConfig(),
 		logger:    logger,
 	}
 }
 
 // RecordSpend adds the given amount to applicable global and project budgets.
 func (m *BudgetManager) RecordSpend(ctx context.Context, tenant, project string, amount float64) error {
-	budgets, err := m.applicableBudgets(ctx, tenant, project)
+	return m.RecordTaggedSpend(ctx, tenant, project, nil, amount)
+}
+
+// RecordTaggedSpend adds the given amount to applicable global, project, and tag-scoped budgets.
+func (m *BudgetManager) RecordTaggedSpend(ctx context.Context, tenant, project string, tags map[string]string, amount float64) error {
+	budgets, err := m.applicableBudgets(ctx, tenant, project, tags)
 	if err != nil {
 		return fmt.Errorf("list budgets: %w", err)
 	}
+	if len(budgets) == 0 {
+		return nil
+	}
 
+	alerting := m.alerting(ctx, tenant)
 	for _, budget := range budgets {
 		if err := m.storage.UpdateBudgetSpend(ctx, budget.Name, amount); err != nil {
 			m.logger.Error("update budget spend", "budget", budget.Name, "error", err)
@@ -46,7 +58,7 @@ func (m *BudgetManager) RecordSpend(ctx context.Context, tenant, project string,
 			continue
 		}
 
-		m.checkThresholds(ctx, updated)
+		m.checkThresholds(ctx, alerting, updated)
 	}
 
 	return nil
@@ -54,7 +66,12 @@ func (m *BudgetManager) RecordSpend(ctx context.Context, tenant, project string,
 
 // CheckApplicable checks applicable global and project budgets against their thresholds.
 func (m *BudgetManager) CheckApplicable(ctx context.Context, tenant, project string) error {
-	budgets, err := m.applicableBudgets(ctx, tenant, project)
+	return m.CheckApplicableTagged(ctx, tenant, project, nil)
+}
+
+// CheckApplicableTagged checks applicable global, project, and tag-scoped budgets against their limits.
+func (m *BudgetManager) CheckApplicableTagged(ctx context.Context, tenant, project string, tags map[string]string) error {
+	budgets, err := m.applicableBudgets(ctx, tenant, project, tags)
 	if err != nil {
 		return fmt.Errorf("list budgets: %w", err)
 	}
@@ -84,7 +101,9 @@ func (m *BudgetManager) CheckAll(ctx context.Context) error {
 	return nil
 }
 
-func (m *BudgetManager) applicableBudgets(ctx context.Context, tenant, project string) ([]Budget, error) {
+// applicableBudgets returns the tenant's budgets whose project scope is empty or matches
+// project and whose tags are all present in tags.
+func (m *BudgetManager) applicableBudgets(ctx context.Context, tenant, project string, tags map[string]string) ([]Budget, error) {
 	budgets, err := m.storage.ListBudgets(ctx)
 	if err != nil {
 		return nil, err
@@ -92,34 +111,26 @@ func (m *BudgetManager) applicableBudgets(ctx context.Context, tenant, project s
 
 	tenant = strings.TrimSpace(tenant)
 	project = strings.TrimSpace(project)
-	if project == "" {
-		var globals []Budget
-		for _, budget := range budgets {
-			if strings.TrimSpace(budget.Tenant) != tenant {
-				continue
-			}
-			if strings.TrimSpace(budget.Project) == "" {
-				globals = append(globals, budget)
-			}
-		}
-		return globals, nil
-	}
-
 	var applicable []Budget
 	for _, budget := range budgets {
 		if strings.TrimSpace(budget.Tenant) != tenant {
 			continue
 		}
 		scope := strings.TrimSpace(budget.Project)
-		if scope == "" || scope == project {
-			applicable = append(applicable, budget)
+		if scope != "" && scope != project {
+			continue
+		}
+		if !model.TagsMatch(budget.Tags, tags) {
+			continue
 		}
+		applicable = append(applicable, budget)
 	}
 	return applicable, nil
 }
 
-// checkThresholds evaluates a budget and dispatches alerts if thresholds are crossed.
-func (m *BudgetManager) checkThresholds(ctx context.Context, budget *Budget) {
+// checkThresholds evaluates a budget and dispatches alerts to the tenant's notifiers if
+// thresholds are crossed.
+func (m *BudgetManager) checkThresholds(ctx context.Context, alerting tenantAlerting, budget *Budget) {
 	if budget.LimitUSD <= 0 {
 		return
 	}
@@ -130,7 +141,7 @@ func (m *BudgetManager) checkThresholds(ctx context.Context, budget *Budget) {
 	switch {
 	case pct >= 100:
 		level = alerts.AlertExceeded
-	case pct >= 95:
+	case pct >= alerting.config.AlertCriticalPct:
 		level = alerts.AlertCritical
 	case pct >= budget.AlertThresholdPct:
 		level = alerts.AlertWarning
@@ -140,6 +151,7 @@ func (m *BudgetManager) checkThresholds(ctx context.Context, budget *Budget) {
 
 	alert := alerts.Alert{
 		Level:        level,
+		Tenant:       budget.Tenant,
 		BudgetName:   budget.Name,
 		LimitUSD:     budget.LimitUSD,
 		CurrentSpend: budget.CurrentSpend,
@@ -156,15 +168,7 @@ func (m *BudgetManager) checkThresholds(ctx context.Context, budget *Budget) {
 		"limit", budget.LimitUSD,
 	)
 
-	for _, notifier := range m.notifiers {
-		if err := notifier.Send(ctx, alert); err != nil {
-			m.logger.Error("send alert failed",
-				"notifier", notifier.Name(),
-				"budget", budget.Name,
-				"error", err,
-			)
-		}
-	}
+	m.sendTo(ctx, alerting.notifiers, alert)
 }
 
 // ResetBudgetSpend resets the current spend for a budget (used for period rollovers).
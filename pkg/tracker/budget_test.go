@@ -277,3 +277,24 @@ func TestBudgetManager_CheckApplicable(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "proj-b")
 }
+
+func TestBudgetManager_RecordTaggedSpend(t *testing.T) {
+	mgr, store := newTestBudgetManager(t, nil)
+	ctx := context.Background()
+
+	require.NoError(t, store.SetBudget(ctx, &model.Budget{
+		Tenant:   "default",
+		Name:     "search",
+		LimitUSD: 100.00,
+		Period:   model.PeriodMonthly,
+		Tags:     map[string]string{"feature": "search"},
+	}))
+
+	require.NoError(t, mgr.RecordTaggedSpend(ctx, "default", "proj-a", map[string]string{"feature": "chat"}, 10.00))
+	require.NoError(t, mgr.RecordTaggedSpend(ctx, "default", "proj-a", map[string]string{"feature": "search", "env": "prod"}, 25.00))
+	require.NoError(t, mgr.RecordSpend(ctx, "default", "proj-a", 5.00))
+
+	budget, err := store.GetBudget(ctx, "search")
+	require.NoError(t, err)
+	assert.InDelta(t, 25.00, budget.CurrentSpend, 0.001)
+}
@@ -63,3 +63,38 @@ func CalculateCostWithCache(p providers.Provider, model string, inputTokens, cac
 		float64(outputTokens)*outputPrice
 	return cost, nil
 }
+
+// TokenBreakdown splits a call's tokens by billing class for models that price text, cached,
+// and audio tokens separately.
+type TokenBreakdown struct {
+	TextInput   int64
+	CachedInput int64
+	AudioInput  int64
+	TextOutput  int64
+	AudioOutput int64
+}
+
+// CalculateCostWithAudio computes cost for realtime calls that mix text and audio tokens.
+func CalculateCostWithAudio(p providers.Provider, model string, tokens TokenBreakdown) (float64, error) {
+	prices := []struct {
+		tokenType providers.TokenType
+		count     int64
+		label     string
+	}{
+		{providers.TokenInput, tokens.TextInput, "input"},
+		{providers.TokenCachedInput, tokens.CachedInput, "cached input"},
+		{providers.TokenAudioInput, tokens.AudioInput, "audio input"},
+		{providers.TokenOutput, tokens.TextOutput, "output"},
+		{providers.TokenAudioOutput, tokens.AudioOutput, "audio output"},
+	}
+
+	cost := 0.0
+	for _, price := range prices {
+		perToken, err := p.PricePerToken(model, price.tokenType)
+		if err != nil {
+			return 0, fmt.Errorf("%s pricing: %w", price.label, err)
+		}
+		cost += float64(price.count) * perToken
+	}
+	return cost, nil
+}
@@ -112,6 +112,29 @@ func TestCalculateCostWithCache(t *testing.T) {
 	assert.InDelta(t, expected, cost, 1e-10)
 }
 
+func TestCalculateCostWithAudio(t *testing.T) {
+	p := providers.NewOpenAI(&providers.ProviderConfig{
+		Provider: "openai",
+		Models: []providers.ModelPricing{
+			{Model: "gpt-realtime", InputPerMillion: 4.00, OutputPerMillion: 16.00, CachedInputPerMillion: 0.40,
+				AudioInputPerMillion: 32.00, AudioOutputPerMillion: 64.00},
+			{Model: "gpt-4o", InputPerMillion: 2.50, OutputPerMillion: 10.00},
+		},
+	})
+
+	cost, err := tracker.CalculateCostWithAudio(p, "gpt-realtime", tracker.TokenBreakdown{
+		TextInput: 1000, CachedInput: 500, AudioInput: 2000, TextOutput: 300, AudioOutput: 700,
+	})
+	require.NoError(t, err)
+	expected := (4.00*1000 + 0.40*500 + 32.00*2000 + 16.00*300 + 64.00*700) / 1_000_000
+	assert.InDelta(t, expected, cost, 1e-10)
+
+	// Models without audio prices bill audio tokens at the text rates.
+	cost, err = tracker.CalculateCostWithAudio(p, "gpt-4o", tracker.TokenBreakdown{AudioInput: 1000, AudioOutput: 1000})
+	require.NoError(t, err)
+	assert.InDelta(t, (2.50*1000+10.00*1000)/1_000_000, cost, 1e-10)
+}
+
 func BenchmarkCalculateCost(b *testing.B) {
 	registry := providers.NewRegistry()
 	openai := providers.NewOpenAI(&providers.ProviderConfig{
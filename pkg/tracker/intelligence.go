@@ -39,6 +39,9 @@ type promptAggregate struct {
 	totalRepeatedRatio float64
 	largeContextCount  int
 	cachedContextCount int
+	totalToolShare     float64
+	toolRequestCount   int
+	totalToolCalls     int
 	tenant             string
 	project            string
 	provider           string
@@ -311,6 +314,9 @@ func (t *UsageTracker) PromptOptimizations(ctx context.Context, filter ReportFil
 
 	grouped := make(map[rollupKey]*promptAggregate)
 	for _, record := range records {
+		if record.ErrorType != "" {
+			continue
+		}
 		meta := parseUsageMetadata(record.Metadata)
 		key := rollupKey{Tenant: record.Tenant, Project: record.Project, Provider: record.Provider, Model: record.Model}
 		aggregate, ok := grouped[key]
@@ -334,6 +340,11 @@ func (t *UsageTracker) PromptOptimizations(ctx context.Context, filter ReportFil
 		if meta.CachedContextCandidate {
 			aggregate.cachedContextCount++
 		}
+		if meta.ToolCount > 0 {
+			aggregate.toolRequestCount++
+			aggregate.totalToolShare += meta.ToolSchemaInputShare
+			aggregate.totalToolCalls += meta.ToolCallCount
+		}
 	}
 
 	var suggestions []PromptOptimization
@@ -347,8 +358,18 @@ func (t *UsageTracker) PromptOptimizations(ctx context.Context, filter ReportFil
 		avgRepeatedRatio := aggregate.totalRepeatedRatio / float64(aggregate.count)
 		largeContextRatio := float64(aggregate.largeContextCount) / float64(aggregate.count)
 		cachedContextRatio := float64(aggregate.cachedContextCount) / float64(aggregate.count)
+		avgToolShare := 0.0
+		toolCallRate := 0.0
+		if aggregate.toolRequestCount > 0 {
+			avgToolShare = aggregate.totalToolShare / float64(aggregate.toolRequestCount)
+			toolCallRate = float64(aggregate.totalToolCalls) / float64(aggregate.toolRequestCount)
+		}
 
 		switch {
+		case avgToolShare >= 0.40:
+			suggestions = append(suggestions, promptSuggestion(aggregate, "warning", "Trim tool schemas or send only the tools each request needs", fmt.Sprintf("Tool schemas account for %.0f%% of input tokens.", avgToolShare*100), "High"))
+		case aggregate.toolRequestCount >= 5 && toolCallRate < 0.10 && avgToolShare >= 0.15:
+			suggestions = append(suggestions, promptSuggestion(aggregate, "info", "Drop unused tool definitions", fmt.Sprintf("Tools were sent with %d requests but called %.0f%% of the time.", aggregate.toolRequestCount, toolCallRate*100), "Medium"))
 		case avgRatio >= 6:
 			suggestions = append(suggestions, promptSuggestion(aggregate, "warning", "Reduce oversized prompts or split requests", fmt.Sprintf("Average input/output token ratio is %.2fx.", avgRatio), "High"))
 		case avgPromptChars >= 6000 || avgPromptTokens >= 1500:
@@ -369,8 +390,11 @@ func (t *UsageTracker) PromptOptimizations(ctx context.Context, filter ReportFil
 	return suggestions, nil
 }
 
+// maybeSendAnomalyAlert alerts on a critical anomaly in the record's series. The tenant's
+// alert destinations are only resolved once an anomaly fires, keeping the settings lookup off
+// the path of every record.
 func (t *UsageTracker) maybeSendAnomalyAlert(ctx context.Context, record *UsageRecord) {
-	if t.budget == nil || len(t.budget.notifiers) == 0 {
+	if t.budget == nil {
 		return
 	}
 
@@ -401,11 +425,7 @@ func (t *UsageTracker) maybeSendAnomalyAlert(ctx context.Context, record *UsageR
 		LimitUSD:     anomaly.BaselineCostUSD,
 		Message:      anomaly.Message,
 	}
-	for _, notifier := range t.budget.notifiers {
-		if err := notifier.Send(ctx, alert); err != nil {
-			t.logger.Error("send anomaly alert failed", "notifier", notifier.Name(), "error", err)
-		}
-	}
+	t.budget.send(ctx, alert)
 }
 
 func analyticsWindow(filter ReportFilter, fallbackDays int) (time.Time, time.Time) {
@@ -149,3 +149,27 @@ func TestUsageTracker_PromptOptimizations(t *testing.T) {
 	assert.Equal(t, "anthropic", optimizations[0].Provider)
 	assert.NotEmpty(t, optimizations[0].Suggestion)
 }
+
+func TestUsageTracker_PromptOptimizations_ToolSchemas(t *testing.T) {
+	usageTracker, _ := setupIntelligenceTracker(t)
+	base := time.Now().UTC().AddDate(0, 0, -3)
+
+	for day := 0; day < 3; day++ {
+		seedUsageRecord(t, usageTracker, "default", "openai", "gpt-4o", "agents", 2000, 400, base.AddDate(0, 0, day), model.UsageMetadata{
+			PromptChars:              1200,
+			PromptTokensEstimate:     2000,
+			InputOutputRatio:         5,
+			ToolCount:                24,
+			ToolSchemaChars:          4000,
+			ToolSchemaTokensEstimate: 1000,
+			ToolSchemaInputShare:     0.5,
+			ToolCallCount:            1,
+		})
+	}
+
+	optimizations, err := usageTracker.PromptOptimizations(context.Background(), tracker.ReportFilter{Tenant: "default", Project: "agents"})
+	require.NoError(t, err)
+	require.Len(t, optimizations, 1)
+	assert.Contains(t, optimizations[0].Suggestion, "tool schemas")
+	assert.Equal(t, "Tool schemas account for 50% of input tokens.", optimizations[0].Evidence)
+}
@@ -0,0 +1,77 @@
+package tracker
+
+import (
+	"context"
+	"sort"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+)
+
+// ErrorRates returns per tenant/project/provider/model error rates and the spend
+// wasted on calls that failed or were abandoned and typically retried by clients.
+func (t *UsageTracker) ErrorRates(ctx context.Context, filter ReportFilter) ([]ErrorRate, error) {
+	start, end := analyticsWindow(filter, 30)
+	filter.StartTime = start
+	filter.EndTime = end
+
+	records, err := t.storage.QueryUsage(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[rollupKey]*ErrorRate)
+	for _, record := range records {
+		key := rollupKey{Tenant: record.Tenant, Project: record.Project, Provider: record.Provider, Model: record.Model}
+		rate, ok := grouped[key]
+		if !ok {
+			rate = &ErrorRate{
+				Tenant:   record.Tenant,
+				Project:  record.Project,
+				Provider: record.Provider,
+				Model:    record.Model,
+			}
+			grouped[key] = rate
+		}
+
+		rate.RequestCount++
+		rate.TotalCostUSD += record.CostUSD
+		if isFailedRecord(record) {
+			rate.WastedCostUSD += record.CostUSD
+		}
+		if record.ErrorType != "" {
+			rate.ErrorCount++
+			if rate.ByErrorType == nil {
+				rate.ByErrorType = make(map[string]int64)
+			}
+			rate.ByErrorType[record.ErrorType]++
+		}
+	}
+
+	rates := make([]ErrorRate, 0, len(grouped))
+	for _, rate := range grouped {
+		if rate.ErrorCount == 0 && rate.WastedCostUSD == 0 {
+			continue
+		}
+		rate.ErrorRate = float64(rate.ErrorCount) / float64(rate.RequestCount)
+		rates = append(rates, *rate)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].WastedCostUSD != rates[j].WastedCostUSD {
+			return rates[i].WastedCostUSD > rates[j].WastedCostUSD
+		}
+		if rates[i].ErrorRate != rates[j].ErrorRate {
+			return rates[i].ErrorRate > rates[j].ErrorRate
+		}
+		return rates[i].Model < rates[j].Model
+	})
+	return rates, nil
+}
+
+// isFailedRecord reports whether the caller did not receive a usable response for the spend.
+func isFailedRecord(record UsageRecord) bool {
+	if record.ErrorType != "" {
+		return true
+	}
+	return record.CompletionStatus != "" && record.CompletionStatus != model.CompletionStatusComplete
+}
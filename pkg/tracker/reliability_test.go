@@ -0,0 +1,46 @@
+package tracker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker_ErrorRates(t *testing.T) {
+	usageTracker, _ := setupIntelligenceTracker(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Add(-time.Hour)
+
+	records := []*tracker.UsageRecord{
+		{Tenant: "default", Provider: "openai", Model: "gpt-4o", Project: "search", InputTokens: 1000, OutputTokens: 200, Timestamp: now, StatusCode: 200},
+		{Tenant: "default", Provider: "openai", Model: "gpt-4o", Project: "search", StatusCode: 429, ErrorType: "rate_limited", Timestamp: now, CompletionStatus: model.CompletionStatusUpstreamError},
+		{Tenant: "default", Provider: "openai", Model: "gpt-4o", Project: "search", StatusCode: 502, ErrorType: "timeout", Timestamp: now, CompletionStatus: model.CompletionStatusTimeout},
+		{Tenant: "default", Provider: "openai", Model: "gpt-4o", Project: "search", InputTokens: 1000, OutputTokens: 400, Timestamp: now, StatusCode: 200, CompletionStatus: model.CompletionStatusClientAborted},
+		{Tenant: "default", Provider: "openai", Model: "gpt-4o-mini", Project: "search", InputTokens: 1000, OutputTokens: 200, Timestamp: now, StatusCode: 200},
+		{Tenant: "default", Provider: "openai", Model: "unpriced-model", Project: "search", StatusCode: 404, ErrorType: "not_found", Timestamp: now, CompletionStatus: model.CompletionStatusUpstreamError},
+	}
+	for _, record := range records {
+		require.NoError(t, usageTracker.TrackWithTokens(ctx, record))
+	}
+
+	rates, err := usageTracker.ErrorRates(ctx, tracker.ReportFilter{Tenant: "default"})
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	gpt4o := rates[0]
+	assert.Equal(t, "gpt-4o", gpt4o.Model)
+	assert.Equal(t, int64(4), gpt4o.RequestCount)
+	assert.Equal(t, int64(2), gpt4o.ErrorCount)
+	assert.InDelta(t, 0.5, gpt4o.ErrorRate, 1e-9)
+	assert.InDelta(t, records[3].CostUSD, gpt4o.WastedCostUSD, 1e-9)
+	assert.Equal(t, map[string]int64{"rate_limited": 1, "timeout": 1}, gpt4o.ByErrorType)
+
+	assert.Equal(t, "unpriced-model", rates[1].Model)
+	assert.Zero(t, rates[1].WastedCostUSD)
+	assert.InDelta(t, 1.0, rates[1].ErrorRate, 1e-9)
+}
@@ -0,0 +1,90 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+)
+
+// RotateAPIKey stores successor as the replacement of key id. The old key keeps
+// authenticating for grace and is then revoked by RevokeRotatedAPIKeys. A rotation event is
+// sent to the configured notifiers. It returns the old key, updated with its successor.
+func (t *UsageTracker) RotateAPIKey(ctx context.Context, id string, successor *APIKey, grace time.Duration) (*APIKey, error) {
+	if grace < 0 {
+		return nil, fmt.Errorf("grace period must not be negative")
+	}
+	old, err := t.storage.RotateAPIKey(ctx, id, successor, time.Now().UTC().Add(grace))
+	if err != nil {
+		return nil, err
+	}
+
+	t.sendEvent(ctx, alerts.Alert{
+		Kind:              alerts.KindAPIKeyRotated,
+		Level:             alerts.AlertInfo,
+		Tenant:            old.Tenant,
+		APIKeyID:          old.ID,
+		SuccessorAPIKeyID: successor.ID,
+		Message: fmt.Sprintf("API key %q (%s) rotated to %s; the old key is revoked at %s",
+			old.Name, old.KeyPrefix, successor.KeyPrefix, old.ExpiresAt.Format(time.RFC3339)),
+	})
+	return old, nil
+}
+
+// RevokeRotatedAPIKeys revokes rotated keys whose grace period has ended and sends a
+// rotation-completed event for each. It returns the number of keys revoked.
+func (t *UsageTracker) RevokeRotatedAPIKeys(ctx context.Context) (int, error) {
+	revoked, err := t.storage.RevokeRotatedAPIKeys(ctx, time.Now().UTC())
+	for _, key := range revoked {
+		lastUsed := "never"
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.RFC3339)
+		}
+		t.sendEvent(ctx, alerts.Alert{
+			Kind:              alerts.KindAPIKeyRotationCompleted,
+			Level:             alerts.AlertInfo,
+			Tenant:            key.Tenant,
+			APIKeyID:          key.ID,
+			SuccessorAPIKeyID: key.SuccessorID,
+			Message:           fmt.Sprintf("Rotated API key %q (%s) revoked after its grace period; last used %s", key.Name, key.KeyPrefix, lastUsed),
+		})
+	}
+	if err != nil {
+		return len(revoked), fmt.Errorf("revoke rotated api keys: %w", err)
+	}
+	return len(revoked), nil
+}
+
+// RunRotationSweep revokes rotated keys past their grace period every interval until ctx is
+// canceled.
+func (t *UsageTracker) RunRotationSweep(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		revoked, err := t.RevokeRotatedAPIKeys(ctx)
+		if err != nil {
+			logger.Error("revoke rotated api keys", "error", err)
+		} else if revoked > 0 {
+			logger.Info("revoked rotated api keys", "count", revoked)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *UsageTracker) sendEvent(ctx context.Context, alert alerts.Alert) {
+	if t.budget == nil {
+		return
+	}
+	t.budget.send(ctx, alert)
+}
@@ -0,0 +1,119 @@
+package tracker_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []alerts.Alert
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Send(_ context.Context, alert alerts.Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestUsageTracker_RotateAPIKey(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	notifier := &recordingNotifier{}
+	ut := tracker.NewUsageTracker(newTestRegistry(t), store, tracker.NewBudgetManager(store, []alerts.Notifier{notifier}, logger), logger)
+	ctx := context.Background()
+
+	old := &model.APIKey{
+		Tenant:          "acme",
+		Name:            "ci",
+		KeyPrefix:       "lcg_old",
+		KeyHash:         "old-hash",
+		Role:            model.RoleProxyOnly,
+		SpendLimitUSD:   1,
+		AllowedProjects: []string{"chat"},
+	}
+	require.NoError(t, store.CreateAPIKey(ctx, old))
+	require.NoError(t, ut.TrackWithTokens(ctx, &model.UsageRecord{
+		Provider: "openai", Model: "gpt-4o", Tenant: "acme", CostUSD: 0.6, APIKeyID: old.ID,
+	}))
+
+	successor := &model.APIKey{KeyPrefix: "lcg_new", KeyHash: "new-hash"}
+	rotated, err := ut.RotateAPIKey(ctx, old.ID, successor, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, successor.ID, rotated.SuccessorID)
+	require.NotNil(t, rotated.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *rotated.ExpiresAt, time.Minute)
+	assert.Equal(t, old.ID, successor.PredecessorID)
+	assert.Equal(t, model.RoleProxyOnly, successor.Role)
+	assert.Equal(t, []string{"chat"}, successor.AllowedProjects)
+
+	require.Len(t, notifier.alerts, 1)
+	assert.Equal(t, alerts.KindAPIKeyRotated, notifier.alerts[0].Kind)
+	assert.Equal(t, alerts.AlertInfo, notifier.alerts[0].Level)
+	assert.Equal(t, old.ID, notifier.alerts[0].APIKeyID)
+	assert.Equal(t, successor.ID, notifier.alerts[0].SuccessorAPIKeyID)
+
+	// Both keys authenticate during the grace period.
+	_, _, err = store.ResolveAPIKey(ctx, "old-hash")
+	require.NoError(t, err)
+	resolved, _, err := store.ResolveAPIKey(ctx, "new-hash")
+	require.NoError(t, err)
+
+	// The successor inherits the old key's spend toward the cap.
+	require.NoError(t, ut.CheckAPIKeySpend(ctx, resolved))
+	require.NoError(t, ut.TrackWithTokens(ctx, &model.UsageRecord{
+		Provider: "openai", Model: "gpt-4o", Tenant: "acme", CostUSD: 0.5, APIKeyID: successor.ID,
+	}))
+	assert.ErrorContains(t, ut.CheckAPIKeySpend(ctx, resolved), "spend limit exceeded: $1.10 / $1.00")
+
+	_, err = ut.RotateAPIKey(ctx, old.ID, &model.APIKey{KeyPrefix: "lcg_x", KeyHash: "x-hash"}, time.Hour)
+	assert.ErrorContains(t, err, "already rotated")
+
+	// Nothing is due until the grace period ends.
+	revoked, err := ut.RevokeRotatedAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, revoked)
+
+	// A second rotation with no grace makes the successor due at the next sweep.
+	third := &model.APIKey{KeyPrefix: "lcg_third", KeyHash: "third-hash"}
+	_, err = ut.RotateAPIKey(ctx, successor.ID, third, 0)
+	require.NoError(t, err)
+	assert.Equal(t, successor.ID, third.PredecessorID)
+
+	// Rotating twice still counts the spend of every key in the chain toward the cap.
+	resolvedThird, _, err := store.ResolveAPIKey(ctx, "third-hash")
+	require.NoError(t, err)
+	assert.ErrorContains(t, ut.CheckAPIKeySpend(ctx, resolvedThird), "spend limit exceeded: $1.10 / $1.00")
+
+	revoked, err = ut.RevokeRotatedAPIKeys(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+
+	revokedKey, err := store.GetAPIKey(ctx, successor.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.APIKeyStatusRevoked, revokedKey.Status)
+	_, _, err = store.ResolveAPIKey(ctx, "new-hash")
+	assert.Error(t, err)
+
+	require.Len(t, notifier.alerts, 3)
+	assert.Equal(t, alerts.KindAPIKeyRotationCompleted, notifier.alerts[2].Kind)
+	assert.Equal(t, successor.ID, notifier.alerts[2].APIKeyID)
+	assert.Contains(t, notifier.alerts[2].Message, "last used")
+}
@@ -0,0 +1,107 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+)
+
+// DefaultTenantConfig returns the tenant defaults used until SetTenantDefaults is called.
+func DefaultTenantConfig() model.TenantConfig {
+	return model.TenantConfig{AlertThresholdPct: 80, AlertCriticalPct: 95}
+}
+
+// SetTenantDefaults sets the global configuration that tenant settings override. Alert
+// destinations in defaults are only used to fill in tenants that override part of one; tenants
+// without alert overrides use the budget manager's notifiers.
+func (t *UsageTracker) SetTenantDefaults(defaults model.TenantConfig) {
+	t.tenantDefaults = defaults
+	if t.budget != nil {
+		t.budget.defaults = defaults
+	}
+}
+
+// TenantDefaults returns the global configuration that tenant settings override.
+func (t *UsageTracker) TenantDefaults() model.TenantConfig {
+	return t.tenantDefaults
+}
+
+// TenantSettings returns the tenant's configuration overrides.
+func (t *UsageTracker) TenantSettings(ctx context.Context, tenant string) (*model.TenantSettings, error) {
+	return t.storage.GetTenantSettings(ctx, tenant)
+}
+
+// TenantConfig returns the tenant's effective configuration: the tenant defaults with its
+// settings applied. Unknown tenants get the defaults.
+func (t *UsageTracker) TenantConfig(ctx context.Context, tenant string) (model.TenantConfig, error) {
+	settings, err := t.storage.GetTenantSettings(ctx, tenant)
+	if errors.Is(err, storage.ErrNotFound) {
+		return t.tenantDefaults, nil
+	}
+	if err != nil {
+		return t.tenantDefaults, err
+	}
+	return settings.Apply(t.tenantDefaults), nil
+}
+
+// tenantAlerting is where, and at which levels, a tenant's alerts are sent.
+type tenantAlerting struct {
+	config    model.TenantConfig
+	notifiers []alerts.Notifier
+}
+
+// alerting resolves the tenant's alert configuration. A tenant that overrides a Slack or
+// webhook destination gets its own notifier for it in place of the global one; an empty
+// URL turns that destination off. Lookup failures fall back to the global configuration.
+func (m *BudgetManager) alerting(ctx context.Context, tenant string) tenantAlerting {
+	settings, err := m.storage.GetTenantSettings(ctx, tenant)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			m.logger.Error("get tenant settings", "tenant", tenant, "error", err)
+		}
+		return tenantAlerting{config: m.defaults, notifiers: m.notifiers}
+	}
+
+	config := settings.Apply(m.defaults)
+	overrideSlack, overrideWebhook := settings.OverridesSlack(), settings.OverridesWebhook()
+	if !overrideSlack && !overrideWebhook {
+		return tenantAlerting{config: config, notifiers: m.notifiers}
+	}
+
+	var notifiers []alerts.Notifier
+	for _, notifier := range m.notifiers {
+		if (overrideSlack && notifier.Name() == "slack") || (overrideWebhook && notifier.Name() == "webhook") {
+			continue
+		}
+		notifiers = append(notifiers, notifier)
+	}
+	if overrideSlack && config.SlackWebhookURL != "" {
+		notifiers = append(notifiers, alerts.NewSlackNotifier(config.SlackWebhookURL, config.SlackChannel))
+	}
+	if overrideWebhook && config.WebhookURL != "" {
+		notifiers = append(notifiers, alerts.NewWebhookNotifier(config.WebhookURL, config.WebhookSecret))
+	}
+	return tenantAlerting{config: config, notifiers: notifiers}
+}
+
+// send dispatches alert to the notifiers of its tenant.
+func (m *BudgetManager) send(ctx context.Context, alert alerts.Alert) {
+	m.sendTo(ctx, m.alerting(ctx, alert.Tenant).notifiers, alert)
+}
+
+func (m *BudgetManager) sendTo(ctx context.Context, notifiers []alerts.Notifier, alert alerts.Alert) {
+	for _, notifier := range notifiers {
+		if err := notifier.Send(ctx, alert); err != nil {
+			m.logger.Error("send alert failed",
+				"kind", alert.Kind,
+				"notifier", notifier.Name(),
+				"budget", alert.BudgetName,
+				"tenant", alert.Tenant,
+				"error", err,
+			)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/alerts"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker_TenantConfig(t *testing.T) {
+	store, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	ut := tracker.NewUsageTracker(newTestRegistry(t), store, nil, logger)
+	ctx := context.Background()
+
+	assert.Equal(t, tracker.DefaultTenantConfig(), ut.TenantDefaults())
+	ut.SetTenantDefaults(model.TenantConfig{DenyOnExceed: true, DefaultProject: "shared", AlertThresholdPct: 75, AlertCriticalPct: 90})
+
+	unknown, err := ut.TenantConfig(ctx, "missing")
+	require.NoError(t, err)
+	assert.Equal(t, ut.TenantDefaults(), unknown)
+
+	require.NoError(t, store.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	require.NoError(t, store.SetTenantSettings(ctx, &model.TenantSettings{
+		Tenant:         "acme",
+		DenyOnExceed:   model.Ptr(false),
+		DefaultProject: model.Ptr("acme-app"),
+	}))
+	config, err := ut.TenantConfig(ctx, "acme")
+	require.NoError(t, err)
+	assert.False(t, config.DenyOnExceed)
+	assert.Equal(t, "acme-app", config.DefaultProject)
+	assert.Equal(t, 75.0, config.AlertThresholdPct, "settings the tenant does not override are inherited")
+	assert.Equal(t, 90.0, config.AlertCriticalPct)
+}
+
+func TestBudgetManager_TenantAlerting(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []alerts.Alert
+	)
+	tenantWebhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Alert alerts.Alert `json:"alert"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err == nil {
+			mu.Lock()
+			received = append(received, payload.Alert)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer tenantWebhook.Close()
+
+	global := &recordingNotifier{}
+	mgr, store := newTestBudgetManager(t, []alerts.Notifier{global})
+	ctx := context.Background()
+
+	require.NoError(t, store.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	require.NoError(t, store.SetTenantSettings(ctx, &model.TenantSettings{
+		Tenant:           "acme",
+		AlertCriticalPct: model.Ptr(50.0),
+		WebhookURL:       model.Ptr(tenantWebhook.URL),
+	}))
+	for _, budget := range []*model.Budget{
+		{Name: "acme-monthly", Tenant: "acme", LimitUSD: 100, Period: model.PeriodMonthly, AlertThresholdPct: 40},
+		{Name: "default-monthly", Tenant: "default", LimitUSD: 100, Period: model.PeriodMonthly, AlertThresholdPct: 40},
+	} {
+		require.NoError(t, store.SetBudget(ctx, budget))
+	}
+
+	// The global notifier is not a webhook, so acme's webhook is added alongside it.
+	require.NoError(t, mgr.RecordSpend(ctx, "acme", "", 60))
+	require.NoError(t, mgr.RecordSpend(ctx, "default", "", 60))
+
+	require.Len(t, received, 1)
+	assert.Equal(t, "acme", received[0].Tenant)
+	assert.Equal(t, alerts.AlertCritical, received[0].Level, "acme's critical threshold is 50%")
+
+	require.Len(t, global.alerts, 2)
+	assert.Equal(t, alerts.AlertCritical, global.alerts[0].Level)
+	assert.Equal(t, "default", global.alerts[1].Tenant)
+	assert.Equal(t, alerts.AlertWarning, global.alerts[1].Level, "the default tenant keeps the global 95%")
+}
+
+func TestBudgetManager_TenantAlertingReplacesGlobalDestination(t *testing.T) {
+	globalSent := false
+	globalWebhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		globalSent = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer globalWebhook.Close()
+
+	mgr, store := newTestBudgetManager(t, []alerts.Notifier{alerts.NewWebhookNotifier(globalWebhook.URL, "")})
+	ctx := context.Background()
+
+	// An empty URL turns the webhook off for acme.
+	require.NoError(t, store.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	require.NoError(t, store.SetTenantSettings(ctx, &model.TenantSettings{Tenant: "acme", WebhookURL: model.Ptr("")}))
+	require.NoError(t, store.SetBudget(ctx, &model.Budget{Name: "acme-monthly", Tenant: "acme", LimitUSD: 10, Period: model.PeriodMonthly, AlertThresholdPct: 50}))
+
+	require.NoError(t, mgr.RecordSpend(ctx, "acme", "", 20))
+	assert.False(t, globalSent)
+}
+
+// settingsCountingStore counts tenant settings lookups.
+type settingsCountingStore struct {
+	storage.Storage
+	lookups atomic.Int32
+}
+
+func (s *settingsCountingStore) GetTenantSettings(ctx context.Context, tenant string) (*model.TenantSettings, error) {
+	s.lookups.Add(1)
+	return s.Storage.GetTenantSettings(ctx, tenant)
+}
+
+func TestUsageTracker_AnomalyAlertsSkipSettingsWithoutAnomaly(t *testing.T) {
+	db, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	store := &settingsCountingStore{Storage: db}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	global := &recordingNotifier{}
+	ut := tracker.NewUsageTracker(newTestRegistry(t), store, tracker.NewBudgetManager(store, []alerts.Notifier{global}, logger), logger)
+
+	for range 3 {
+		require.NoError(t, ut.TrackWithTokens(context.Background(), usageRecord("chat")))
+	}
+	assert.Zero(t, store.lookups.Load(), "alert destinations are resolved only once an anomaly fires")
+	assert.Empty(t, global.alerts)
+}
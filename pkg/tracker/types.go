@@ -11,11 +11,15 @@ type (
 	UsageSummary        = model.UsageSummary
 	Tenant              = model.Tenant
 	APIKey              = model.APIKey
+	TenantExport        = model.TenantExport
 	UsageRollup         = model.UsageRollup
 	UsageAnomaly        = model.UsageAnomaly
 	SpendForecast       = model.SpendForecast
 	ModelRecommendation = model.ModelRecommendation
 	PromptOptimization  = model.PromptOptimization
+	ErrorRate           = model.ErrorRate
+	LatencyStats        = model.LatencyStats
+	UserSpend           = model.UserSpend
 )
 
 // Re-export constants.
@@ -23,7 +27,16 @@ const (
 	PeriodDaily   = model.PeriodDaily
 	PeriodWeekly  = model.PeriodWeekly
 	PeriodMonthly = model.PeriodMonthly
+
+	CompletionStatusComplete      = model.CompletionStatusComplete
+	CompletionStatusClientAborted = model.CompletionStatusClientAborted
+	CompletionStatusUpstreamError = model.CompletionStatusUpstreamError
+	CompletionStatusTimeout       = model.CompletionStatusTimeout
+	CompletionStatusBlocked       = model.CompletionStatusBlocked
 )
 
 // PeriodBounds wraps model.PeriodBounds.
 var PeriodBounds = model.PeriodBounds
+
+// ParseTags wraps model.ParseTags.
+var ParseTags = model.ParseTags
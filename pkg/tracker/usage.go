@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/providers"
 	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
 )
@@ -17,17 +18,21 @@ type UsageTracker struct {
 	storage    storage.Storage
 	calculator *CostCalculator
 	budget     *BudgetManager
-	logger     *slog.Logger
+	writer     *usageWriter
+	// tenantDefaults is the global configuration that tenant settings override.
+	tenantDefaults model.TenantConfig
+	logger         *slog.Logger
 }
 
 // NewUsageTracker creates a usage tracker with the given dependencies.
 func NewUsageTracker(registry *providers.Registry, store storage.Storage, budget *BudgetManager, logger *slog.Logger) *UsageTracker {
 	return &UsageTracker{
-		registry:   registry,
-		storage:    store,
-		calculator: NewCostCalculator(registry),
-		budget:     budget,
-		logger:     logger,
+		registry:       registry,
+		storage:        store,
+		calculator:     NewCostCalculator(registry),
+		budget:         budget,
+		tenantDefaults: DefaultTenantConfig(),
+		logger:         logger,
 	}
 }
 
@@ -66,7 +71,7 @@ func (t *UsageTracker) Track(ctx context.Context, tenant, providerName, model st
 
 	// Check budgets
 	if t.budget != nil {
-		if checkErr := t.budget.RecordSpend(ctx, record.Tenant, record.Project, record.CostUSD); checkErr != nil {
+		if checkErr := t.budget.RecordTaggedSpend(ctx, record.Tenant, record.Project, record.Tags, record.CostUSD); checkErr != nil {
 			t.logger.Error("budget check failed", "error", checkErr)
 		}
 	}
@@ -87,8 +92,9 @@ func (t *UsageTracker) TrackWithTokens(ctx context.Context, record *UsageRecord)
 		record.Tenant = defaultTenant()
 	}
 
-	// Calculate cost if not provided
-	if record.CostUSD == 0 {
+	// Calculate cost if not provided. Failed calls with no tokens are recorded at zero cost
+	// even when the model is unknown to the pricing registry.
+	if record.CostUSD == 0 && (record.InputTokens > 0 || record.OutputTokens > 0) {
 		cost, err := t.calculator.Calculate(record.Provider, record.Model, record.InputTokens, record.OutputTokens)
 		if err != nil {
 			return fmt.Errorf("calculate cost: %w", err)
@@ -96,19 +102,31 @@ func (t *UsageTracker) TrackWithTokens(ctx context.Context, record *UsageRecord)
 		record.CostUSD = cost
 	}
 
+	if t.writer != nil {
+		return t.writer.enqueue(ctx, record)
+	}
+
 	if err := t.storage.RecordUsage(ctx, record); err != nil {
 		return fmt.Errorf("store usage: %w", err)
 	}
+	t.afterRecord(ctx, record)
 
-	// Check budgets
+	return nil
+}
+
+// afterRecord applies budget spend and anomaly alerts for a stored record.
+func (t *UsageTracker) afterRecord(ctx context.Context, record *UsageRecord) {
 	if t.budget != nil {
-		if checkErr := t.budget.RecordSpend(ctx, record.Tenant, record.Project, record.CostUSD); checkErr != nil {
+		if checkErr := t.budget.RecordTaggedSpend(ctx, record.Tenant, record.Project, record.Tags, record.CostUSD); checkErr != nil {
 			t.logger.Error("budget check failed", "error", checkErr)
 		}
 	}
 	t.maybeSendAnomalyAlert(ctx, record)
+}
 
-	return nil
+// Registry returns the provider registry used for pricing.
+func (t *UsageTracker) Registry() *providers.Registry {
+	return t.registry
 }
 
 // Report generates a usage summary for the given filter.
@@ -121,6 +139,11 @@ func (t *UsageTracker) Query(ctx context.Context, filter ReportFilter) ([]UsageR
 	return t.storage.QueryUsage(ctx, filter)
 }
 
+// TopUsers returns the end users with the highest spend for the given filter.
+func (t *UsageTracker) TopUsers(ctx context.Context, filter ReportFilter, limit int) ([]UserSpend, error) {
+	return t.storage.TopUsers(ctx, filter, limit)
+}
+
 // CheckBudget verifies if spending is within budget limits. Returns an error if any budget is exceeded.
 func (t *UsageTracker) CheckBudget(ctx context.Context) error {
 	return t.CheckBudgetForProject(ctx, defaultTenant(), "")
@@ -128,10 +151,44 @@ func (t *UsageTracker) CheckBudget(ctx context.Context) error {
 
 // CheckBudgetForProject verifies if applicable budgets are within limits for the given project.
 func (t *UsageTracker) CheckBudgetForProject(ctx context.Context, tenant, project string) error {
+	return t.CheckBudgetForTags(ctx, tenant, project, nil)
+}
+
+// CheckBudgetForTags verifies if budgets applicable to the project and tags are within limits.
+func (t *UsageTracker) CheckBudgetForTags(ctx context.Context, tenant, project string, tags map[string]string) error {
 	if t.budget == nil {
 		return nil
 	}
-	return t.budget.CheckApplicable(ctx, tenant, project)
+	return t.budget.CheckApplicableTagged(ctx, tenant, project, tags)
+}
+
+// CheckAPIKeySpend verifies that the key's spend in its current period is below its cap.
+// A rotated-in key also counts the spend of every key it replaced along its rotation chain, so
+// rotation does not reset the cap. Keys without a cap always pass.
+func (t *UsageTracker) CheckAPIKeySpend(ctx context.Context, key *APIKey) error {
+	if key == nil || key.SpendLimitUSD <= 0 {
+		return nil
+	}
+	start, end := PeriodBounds(key.SpendPeriod)
+	ids := []string{key.ID}
+	seen := map[string]bool{key.ID: true}
+	for predecessorID := key.PredecessorID; predecessorID != "" && !seen[predecessorID]; {
+		seen[predecessorID] = true
+		ids = append(ids, predecessorID)
+		predecessor, err := t.storage.GetAPIKey(ctx, predecessorID)
+		if err != nil {
+			return fmt.Errorf("check api key spend: load predecessor %q: %w", predecessorID, err)
+		}
+		predecessorID = predecessor.PredecessorID
+	}
+	spent, err := t.storage.APIKeySpend(ctx, ids, start, end)
+	if err != nil {
+		return fmt.Errorf("check api key spend: %w", err)
+	}
+	if spent >= key.SpendLimitUSD {
+		return fmt.Errorf("api key %q spend limit exceeded: $%.2f / $%.2f %s", key.Name, spent, key.SpendLimitUSD, key.SpendPeriod)
+	}
+	return nil
 }
 
 func defaultTenant() string {
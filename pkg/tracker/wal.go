@@ -0,0 +1,228 @@
+package tracker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	walSegmentPrefix = "usage-"
+	walSegmentSuffix = ".wal"
+)
+
+// usageWAL journals every queued record as a JSON line before it is written to storage.
+// Segments are synced and rotated on each flush interval and deleted once all of their records
+// are stored. With sync set, every append is also synced before it returns. Segments holding
+// records that never reached the queue, failed to write, or were left behind by a crash are
+// replayed. Replays are safe because storage skips known IDs.
+type usageWAL struct {
+	mu       sync.Mutex
+	dir      string
+	sync     bool
+	next     uint64
+	active   *walSegment
+	segments map[uint64]*walSegment
+}
+
+type walSegment struct {
+	id      uint64
+	path    string
+	file    *os.File
+	records int
+	pending int  // records still in the in-memory queue
+	replay  bool // the segment must be re-read and written from disk
+	closed  bool
+}
+
+func openUsageWAL(dir string, sync bool) (*usageWAL, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create wal directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read wal directory: %w", err)
+	}
+
+	w := &usageWAL{dir: dir, sync: sync, next: 1, segments: make(map[uint64]*walSegment)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		w.segments[id] = &walSegment{id: id, path: filepath.Join(dir, name), replay: true, closed: true}
+		w.next = max(w.next, id+1)
+	}
+	return w, nil
+}
+
+// append journals a record and returns the segment that holds it.
+func (w *usageWAL) append(record *UsageRecord) (uint64, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return 0, fmt.Errorf("encode wal record: %w", err)
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.active == nil {
+		segment := &walSegment{id: w.next, path: filepath.Join(w.dir, fmt.Sprintf("%s%020d%s", walSegmentPrefix, w.next, walSegmentSuffix))}
+		file, err := os.OpenFile(segment.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return 0, fmt.Errorf("open wal segment: %w", err)
+		}
+		segment.file = file
+		w.next++
+		w.active = segment
+		w.segments[segment.id] = segment
+	}
+	if _, err := w.active.file.Write(line); err != nil {
+		return 0, fmt.Errorf("write wal segment: %w", err)
+	}
+	if w.sync {
+		if err := w.active.file.Sync(); err != nil {
+			return 0, fmt.Errorf("sync wal segment: %w", err)
+		}
+	}
+	w.active.records++
+	w.active.pending++
+	return w.active.id, nil
+}
+
+// spill marks a journaled record that did not fit in the queue. It is written by replay.
+func (w *usageWAL) spill(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if segment, ok := w.segments[id]; ok {
+		segment.pending--
+		segment.replay = true
+	}
+}
+
+// ack records the outcome of writing n queued records from a segment.
+func (w *usageWAL) ack(id uint64, n int, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	segment, ok := w.segments[id]
+	if !ok {
+		return
+	}
+	segment.pending -= n
+	if failed {
+		segment.replay = true
+	}
+	w.removeIfDone(segment)
+}
+
+// rotate syncs and closes the active segment so it can be replayed or removed.
+func (w *usageWAL) rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotateLocked()
+}
+
+func (w *usageWAL) rotateLocked() error {
+	segment := w.active
+	if segment == nil {
+		return nil
+	}
+	w.active = nil
+	segment.closed = true
+	syncErr := segment.file.Sync()
+	closeErr := segment.file.Close()
+	segment.file = nil
+	w.removeIfDone(segment)
+	if syncErr != nil {
+		return fmt.Errorf("sync wal segment: %w", syncErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close wal segment: %w", closeErr)
+	}
+	return nil
+}
+
+// replayable returns closed segments that must be written from disk, oldest first.
+func (w *usageWAL) replayable() []uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var ids []uint64
+	for id, segment := range w.segments {
+		if segment.closed && segment.replay {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// read loads the records of a closed segment. A torn final line from a crash is skipped.
+func (w *usageWAL) read(id uint64) ([]*UsageRecord, error) {
+	w.mu.Lock()
+	segment, ok := w.segments[id]
+	w.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	file, err := os.Open(segment.path)
+	if err != nil {
+		return nil, fmt.Errorf("open wal segment: %w", err)
+	}
+	defer file.Close()
+
+	var records []*UsageRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read wal segment: %w", err)
+	}
+	return records, nil
+}
+
+// replayed marks a segment as fully written from disk.
+func (w *usageWAL) replayed(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if segment, ok := w.segments[id]; ok {
+		segment.replay = false
+		w.removeIfDone(segment)
+	}
+}
+
+// segmentCount returns the number of segments still on disk.
+func (w *usageWAL) segmentCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.segments)
+}
+
+func (w *usageWAL) removeIfDone(segment *walSegment) {
+	if !segment.closed || segment.pending > 0 || segment.replay {
+		return
+	}
+	_ = os.Remove(segment.path)
+	delete(w.segments, segment.id)
+}
+
+func (w *usageWAL) close() error {
+	return w.rotate()
+}
@@ -0,0 +1,355 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+)
+
+const writeTimeout = 30 * time.Second
+
+// WriterConfig configures asynchronous usage writes.
+type WriterConfig struct {
+	// QueueSize bounds the number of records held in memory.
+	QueueSize int
+	// Workers is the number of goroutines writing batches to storage.
+	Workers int
+	// BatchSize caps the records written in one transaction.
+	BatchSize int
+	// FlushInterval controls how often WAL segments are synced and rotated and spilled
+	// records are replayed.
+	FlushInterval time.Duration
+	// WALDir journals queued records so they survive a crash. When empty, a full queue falls
+	// back to synchronous writes instead of spilling to disk.
+	WALDir string
+	// WALSync fsyncs the WAL after every record, before it is queued, so a power loss cannot
+	// lose it. Otherwise the WAL is synced when it rotates, every FlushInterval.
+	WALSync bool
+}
+
+// WriterStats reports queue depth and throughput of the asynchronous usage writer.
+type WriterStats struct {
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Enqueued      int64 `json:"enqueued"`
+	Written       int64 `json:"written"`
+	Batches       int64 `json:"batches"`
+	Spilled       int64 `json:"spilled"`
+	Replayed      int64 `json:"replayed"`
+	SyncWrites    int64 `json:"sync_writes"`
+	Failed        int64 `json:"failed"`
+	WALSegments   int   `json:"wal_segments"`
+}
+
+type queuedRecord struct {
+	record  *UsageRecord
+	segment uint64
+}
+
+// usageWriter moves storage writes, budget updates, and anomaly alerts off the request path.
+type usageWriter struct {
+	tracker *UsageTracker
+	cfg     WriterConfig
+	queue   chan queuedRecord
+	wal     *usageWAL
+
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	enqueued   atomic.Int64
+	written    atomic.Int64
+	batches    atomic.Int64
+	spilled    atomic.Int64
+	replayed   atomic.Int64
+	syncWrites atomic.Int64
+	failed     atomic.Int64
+}
+
+// EnableAsyncWrites starts the asynchronous usage writer. TrackWithTokens then returns as soon
+// as a record is queued. Records left in the WAL by a previous run are replayed.
+func (t *UsageTracker) EnableAsyncWrites(cfg WriterConfig) error {
+	if t.writer != nil {
+		return errors.New("async writes already enabled")
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	w := &usageWriter{
+		tracker: t,
+		cfg:     cfg,
+		queue:   make(chan queuedRecord, cfg.QueueSize),
+		stop:    make(chan struct{}),
+	}
+	if cfg.WALDir != "" {
+		wal, err := openUsageWAL(cfg.WALDir, cfg.WALSync)
+		if err != nil {
+			return err
+		}
+		w.wal = wal
+	}
+
+	for range cfg.Workers {
+		w.wg.Add(1)
+		go w.work()
+	}
+	w.wg.Add(1)
+	go w.maintain()
+
+	t.writer = w
+	return nil
+}
+
+// WriterStats returns the asynchronous writer state and whether async writes are enabled.
+func (t *UsageTracker) WriterStats() (WriterStats, bool) {
+	if t.writer == nil {
+		return WriterStats{}, false
+	}
+	return t.writer.stats(), true
+}
+
+// Close drains queued usage writes. Records that cannot be written before ctx ends stay in
+// the WAL and are replayed on the next start.
+func (t *UsageTracker) Close(ctx context.Context) error {
+	if t.writer == nil {
+		return nil
+	}
+	return t.writer.close(ctx)
+}
+
+func (w *usageWriter) enqueue(ctx context.Context, record *UsageRecord) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return w.writeNow(ctx, record)
+	}
+
+	var segment uint64
+	if w.wal != nil {
+		id, err := w.wal.append(record)
+		if err != nil {
+			w.tracker.logger.Error("usage wal append failed", "error", err)
+		}
+		segment = id
+	}
+
+	select {
+	case w.queue <- queuedRecord{record: record, segment: segment}:
+		w.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	// The queue is full. Journaled records wait on disk; otherwise the caller absorbs the write.
+	if segment != 0 {
+		w.wal.spill(segment)
+		w.spilled.Add(1)
+		return nil
+	}
+	return w.writeNow(ctx, record)
+}
+
+func (w *usageWriter) writeNow(ctx context.Context, record *UsageRecord) error {
+	w.syncWrites.Add(1)
+	if err := w.tracker.storage.RecordUsage(ctx, record); err != nil {
+		w.failed.Add(1)
+		return fmt.Errorf("store usage: %w", err)
+	}
+	w.written.Add(1)
+	w.tracker.afterRecord(ctx, record)
+	return nil
+}
+
+// work writes queued records, taking whatever is already waiting up to BatchSize per batch.
+func (w *usageWriter) work() {
+	defer w.wg.Done()
+	batch := make([]queuedRecord, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case item := <-w.queue:
+			batch = append(batch[:0], item)
+		fill:
+			for len(batch) < w.cfg.BatchSize {
+				select {
+				case next := <-w.queue:
+					batch = append(batch, next)
+				default:
+					break fill
+				}
+			}
+			w.writeBatch(batch)
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *usageWriter) writeBatch(batch []queuedRecord) {
+	records := make([]*UsageRecord, len(batch))
+	for i, item := range batch {
+		records[i] = item.record
+	}
+
+	err := w.store(records)
+	if err != nil {
+		w.failed.Add(int64(len(batch)))
+		w.tracker.logger.Error("usage batch write failed", "records", len(batch), "error", err)
+	}
+	if w.wal == nil {
+		return
+	}
+	acks := make(map[uint64]int)
+	for _, item := range batch {
+		if item.segment != 0 {
+			acks[item.segment]++
+		}
+	}
+	for segment, n := range acks {
+		w.wal.ack(segment, n, err != nil)
+	}
+}
+
+// store writes one transaction and applies budgets and alerts to newly inserted records.
+func (w *usageWriter) store(records []*UsageRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	inserted, err := w.tracker.storage.RecordUsageBatch(ctx, records)
+	var dropped *storage.DroppedUsageError
+	if errors.As(err, &dropped) {
+		for _, record := range dropped.Records {
+			w.tracker.logger.Warn("dropping usage record of deleted tenant",
+				"id", record.ID, "tenant_id", record.TenantID, "tenant", record.Tenant, "cost_usd", record.CostUSD)
+		}
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	w.batches.Add(1)
+	w.written.Add(int64(len(inserted)))
+	for _, record := range inserted {
+		w.tracker.afterRecord(ctx, record)
+	}
+	return nil
+}
+
+// maintain rotates WAL segments and replays spilled or failed records while the queue has room.
+func (w *usageWriter) maintain() {
+	defer w.wg.Done()
+	if w.wal == nil {
+		return
+	}
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	w.replay()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.wal.rotate(); err != nil {
+				w.tracker.logger.Error("usage wal rotate failed", "error", err)
+			}
+			if len(w.queue) < cap(w.queue)/2 {
+				w.replay()
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *usageWriter) replay() {
+	for _, id := range w.wal.replayable() {
+		records, err := w.wal.read(id)
+		if err != nil {
+			w.tracker.logger.Error("usage wal replay failed", "error", err)
+			return
+		}
+		for start := 0; start < len(records); start += w.cfg.BatchSize {
+			batch := records[start:min(start+w.cfg.BatchSize, len(records))]
+			if err := w.store(batch); err != nil {
+				w.tracker.logger.Error("usage wal replay failed", "error", err)
+				return
+			}
+		}
+		w.replayed.Add(int64(len(records)))
+		w.wal.replayed(id)
+	}
+}
+
+func (w *usageWriter) close(ctx context.Context) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	// Drain everything already queued before stopping the workers.
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for len(w.queue) > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+	close(w.stop)
+	w.wg.Wait()
+
+	if w.wal != nil {
+		if err := w.wal.close(); err != nil {
+			return err
+		}
+		if ctx.Err() == nil {
+			w.replay()
+		}
+	}
+	if remaining := len(w.queue); remaining > 0 {
+		return fmt.Errorf("usage writer closed with %d queued records", remaining)
+	}
+	return nil
+}
+
+func (w *usageWriter) stats() WriterStats {
+	stats := WriterStats{
+		QueueDepth:    len(w.queue),
+		QueueCapacity: cap(w.queue),
+		Enqueued:      w.enqueued.Load(),
+		Written:       w.written.Load(),
+		Batches:       w.batches.Load(),
+		Spilled:       w.spilled.Load(),
+		Replayed:      w.replayed.Load(),
+		SyncWrites:    w.syncWrites.Load(),
+		Failed:        w.failed.Load(),
+	}
+	if w.wal != nil {
+		stats.WALSegments = w.wal.segmentCount()
+	}
+	return stats
+}
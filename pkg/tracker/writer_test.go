@@ -0,0 +1,221 @@
+package tracker_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/model"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/storage"
+	"github.com/ogulcanaydogan/LLM-Cost-Guardian/pkg/tracker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingStore holds batch writes until released so the queue can be filled deterministically.
+type blockingStore struct {
+	storage.Storage
+	release chan struct{}
+}
+
+func (s *blockingStore) RecordUsageBatch(ctx context.Context, records []*model.UsageRecord) ([]*model.UsageRecord, error) {
+	<-s.release
+	return s.Storage.RecordUsageBatch(ctx, records)
+}
+
+func newBlockingTracker(t *testing.T) (*tracker.UsageTracker, *blockingStore) {
+	t.Helper()
+	db, err := storage.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	store := &blockingStore{Storage: db, release: make(chan struct{})}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	return tracker.NewUsageTracker(newTestRegistry(t), store, tracker.NewBudgetManager(store, nil, logger), logger), store
+}
+
+func usageRecord(project string) *model.UsageRecord {
+	return &model.UsageRecord{Provider: "openai", Model: "gpt-4o", InputTokens: 1000, OutputTokens: 500, Project: project}
+}
+
+func TestUsageTracker_AsyncWritesBatchAndDrain(t *testing.T) {
+	ut, store := newTestTracker(t)
+	ctx := context.Background()
+	walDir := filepath.Join(t.TempDir(), "wal")
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{QueueSize: 100, Workers: 2, BatchSize: 10, WALDir: walDir}))
+
+	require.NoError(t, store.SetBudget(ctx, &model.Budget{Name: "global", LimitUSD: 100, Period: model.PeriodMonthly, AlertThresholdPct: 80}))
+	for range 25 {
+		record := usageRecord("async")
+		require.NoError(t, ut.TrackWithTokens(ctx, record))
+		assert.Greater(t, record.CostUSD, 0.0, "cost is known before the write completes")
+	}
+	require.NoError(t, ut.Close(ctx))
+
+	records, err := store.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 25)
+
+	budget, err := store.GetBudget(ctx, "global")
+	require.NoError(t, err)
+	assert.InDelta(t, 25*0.0075, budget.CurrentSpend, 1e-9)
+
+	stats, ok := ut.WriterStats()
+	require.True(t, ok)
+	assert.Equal(t, int64(25), stats.Enqueued)
+	assert.Equal(t, int64(25), stats.Written)
+	assert.Zero(t, stats.WALSegments)
+
+	segments, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestUsageTracker_AsyncWritesWithWALSync(t *testing.T) {
+	ut, store := newTestTracker(t)
+	ctx := context.Background()
+	walDir := filepath.Join(t.TempDir(), "wal")
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{WALDir: walDir, WALSync: true}))
+
+	for range 5 {
+		require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("synced")))
+	}
+	require.NoError(t, ut.Close(ctx))
+
+	records, err := store.QueryUsage(ctx, model.ReportFilter{Project: "synced"})
+	require.NoError(t, err)
+	assert.Len(t, records, 5)
+
+	segments, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestUsageTracker_AsyncWritesDropUsageOfDeletedTenant(t *testing.T) {
+	ut, store := newTestTracker(t)
+	ctx := context.Background()
+	walDir := filepath.Join(t.TempDir(), "wal")
+	require.NoError(t, store.CreateTenant(ctx, &model.Tenant{Slug: "acme"}))
+	tenant, err := store.GetTenant(ctx, "acme")
+	require.NoError(t, err)
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{WALDir: walDir}))
+
+	// The tenant is deleted while its last requests are still on their way to the writer.
+	require.NoError(t, store.DeleteTenant(ctx, "acme"))
+	late := usageRecord("late")
+	late.TenantID = tenant.ID
+	late.Tenant = tenant.Slug
+	require.NoError(t, ut.TrackWithTokens(ctx, late))
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("other")))
+	require.NoError(t, ut.Close(ctx))
+
+	_, err = store.GetTenant(ctx, "acme")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	records, err := store.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "other", records[0].Project)
+
+	stats, _ := ut.WriterStats()
+	assert.Zero(t, stats.Failed)
+	segments, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}
+
+func TestUsageTracker_AsyncWritesSpillToWAL(t *testing.T) {
+	ut, store := newBlockingTracker(t)
+	ctx := context.Background()
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{
+		QueueSize: 1, Workers: 1, BatchSize: 10, FlushInterval: 20 * time.Millisecond, WALDir: t.TempDir(),
+	}))
+
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("first")))
+	require.Eventually(t, func() bool {
+		stats, _ := ut.WriterStats()
+		return stats.QueueDepth == 0
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("queued")))
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("spilled")))
+	stats, _ := ut.WriterStats()
+	assert.Equal(t, int64(1), stats.Spilled)
+	assert.Equal(t, 1, stats.QueueDepth)
+
+	close(store.release)
+	require.NoError(t, ut.Close(ctx))
+
+	records, err := store.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+	stats, _ = ut.WriterStats()
+	assert.Zero(t, stats.WALSegments)
+}
+
+func TestUsageTracker_AsyncWritesFallBackToSyncWithoutWAL(t *testing.T) {
+	ut, store := newBlockingTracker(t)
+	ctx := context.Background()
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{QueueSize: 1, Workers: 1}))
+
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("first")))
+	require.Eventually(t, func() bool {
+		stats, _ := ut.WriterStats()
+		return stats.QueueDepth == 0
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("queued")))
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("direct")))
+
+	records, err := store.QueryUsage(ctx, model.ReportFilter{Project: "direct"})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	stats, _ := ut.WriterStats()
+	assert.Equal(t, int64(1), stats.SyncWrites)
+
+	close(store.release)
+	require.NoError(t, ut.Close(ctx))
+	records, err = store.QueryUsage(ctx, model.ReportFilter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestUsageTracker_AsyncWritesReplayWALAfterCrash(t *testing.T) {
+	ut, store := newTestTracker(t)
+	ctx := context.Background()
+
+	// A segment left behind by a crash: one record already committed, one not, and a torn line.
+	committed := &model.UsageRecord{ID: "committed", Tenant: "default", Provider: "openai", Model: "gpt-4o",
+		InputTokens: 1000, OutputTokens: 500, CostUSD: 0.0075, Project: "wal", Timestamp: time.Now().UTC()}
+	require.NoError(t, store.RecordUsage(ctx, committed))
+	lost := *committed
+	lost.ID = "lost"
+
+	walDir := t.TempDir()
+	var data []byte
+	for _, record := range []*model.UsageRecord{committed, &lost} {
+		line, err := json.Marshal(record)
+		require.NoError(t, err)
+		data = append(append(data, line...), '\n')
+	}
+	data = append(data, []byte(`{"id":"torn","provider":"ope`)...)
+	require.NoError(t, os.WriteFile(filepath.Join(walDir, "usage-00000000000000000007.wal"), data, 0o600))
+
+	require.NoError(t, ut.EnableAsyncWrites(tracker.WriterConfig{WALDir: walDir}))
+	require.NoError(t, ut.TrackWithTokens(ctx, usageRecord("wal")))
+	require.NoError(t, ut.Close(ctx))
+
+	records, err := store.QueryUsage(ctx, model.ReportFilter{Project: "wal"})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	summary, err := store.AggregateUsage(ctx, model.ReportFilter{Project: "wal"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.RecordCount)
+
+	segments, err := os.ReadDir(walDir)
+	require.NoError(t, err)
+	assert.Empty(t, segments)
+}